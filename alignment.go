@@ -0,0 +1,132 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ianlewis/go-dictzip/index"
+)
+
+// AlignmentReport describes how the entries of a dictd(8) index align with
+// the chunks of an archive. Entries that span many chunks require inflating
+// each of those chunks to look up, so the report is intended to help choose
+// a chunk size or reorder entries for better lookup latency.
+type AlignmentReport struct {
+	// ChunkSize is the uncompressed chunk size.
+	ChunkSize int
+
+	// Entries is the number of entries checked.
+	Entries int
+
+	// MaxChunks is the maximum number of chunks spanned by an entry and
+	// AvgChunks is the average.
+	MaxChunks int
+	AvgChunks float64
+
+	// Distribution is the distribution of the number of chunks spanned by
+	// the entries. Distribution[n] is the number of entries spanning n
+	// chunks. Empty entries span zero chunks.
+	Distribution []int
+
+	// Straddling are the entries spanning at least the minimum number of
+	// chunks given to [Reader.AlignmentReport] sorted by decreasing number of
+	// chunks spanned. Entries spanning the same number of chunks are in
+	// index order.
+	Straddling []EntryAlignment
+
+	// OutOfRange are the entries which extend past the end of the
+	// uncompressed data in index order.
+	OutOfRange []index.Entry
+}
+
+// EntryAlignment describes the chunks spanned by an index entry.
+type EntryAlignment struct {
+	// Entry is the index entry.
+	Entry index.Entry
+
+	// FirstChunk is the index of the first chunk holding the entry.
+	FirstChunk int
+
+	// Chunks is the number of chunks spanned by the entry.
+	Chunks int
+}
+
+// AlignmentReport checks the alignment of the index entries with the chunk
+// boundaries of the archive. Entries spanning minChunks or more chunks are
+// listed in [AlignmentReport.Straddling]. minChunks values less than 2 are
+// treated as 2 so that only entries straddling a chunk boundary are listed.
+// It returns an error wrapping [ErrPlainGzip] for plain gzip files and an
+// error wrapping [ErrMultistream] for multistream files.
+func (z *Reader) AlignmentReport(entries []index.Entry, minChunks int) (*AlignmentReport, error) {
+	if z.PlainGzip() {
+		return nil, fmt.Errorf("%w: alignment report", ErrPlainGzip)
+	}
+	if z.Members() != nil {
+		return nil, fmt.Errorf("%w: alignment report", ErrMultistream)
+	}
+	size, err := z.Size()
+	if err != nil {
+		return nil, err
+	}
+	if minChunks < 2 {
+		minChunks = 2
+	}
+
+	chunkSize := int64(z.ChunkSize())
+
+	report := &AlignmentReport{
+		ChunkSize:    int(chunkSize),
+		Distribution: []int{},
+	}
+	var total int
+	for _, e := range entries {
+		if e.Offset < 0 || e.Size < 0 || e.Offset+e.Size > size {
+			report.OutOfRange = append(report.OutOfRange, e)
+			continue
+		}
+		report.Entries++
+
+		a := EntryAlignment{
+			Entry:      e,
+			FirstChunk: int(e.Offset / chunkSize),
+		}
+		if e.Size > 0 {
+			a.Chunks = int((e.Offset+e.Size-1)/chunkSize) - a.FirstChunk + 1
+		}
+
+		for len(report.Distribution) <= a.Chunks {
+			report.Distribution = append(report.Distribution, 0)
+		}
+		report.Distribution[a.Chunks]++
+		total += a.Chunks
+		if a.Chunks > report.MaxChunks {
+			report.MaxChunks = a.Chunks
+		}
+		if a.Chunks >= minChunks {
+			report.Straddling = append(report.Straddling, a)
+		}
+	}
+	if report.Entries > 0 {
+		report.AvgChunks = float64(total) / float64(report.Entries)
+	}
+
+	sort.SliceStable(report.Straddling, func(i, j int) bool {
+		return report.Straddling[i].Chunks > report.Straddling[j].Chunks
+	})
+
+	return report, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/go-dictzip/index"
+)
+
+func TestReader_AlignmentReport(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 10)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(bytes.Repeat([]byte("0123456789"), 10)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()), WithLocking())
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	aligned := index.Entry{Headword: "aligned", Offset: 10, Size: 10}
+	straddling := index.Entry{Headword: "straddling", Offset: 15, Size: 10}
+	long := index.Entry{Headword: "long", Offset: 5, Size: 40}
+	empty := index.Entry{Headword: "empty", Offset: 50, Size: 0}
+	outOfRange := index.Entry{Headword: "out of range", Offset: 95, Size: 10}
+	entries := []index.Entry{aligned, straddling, long, empty, outOfRange}
+
+	testCases := map[string]struct {
+		minChunks int
+		want      *AlignmentReport
+	}{
+		"default": {
+			minChunks: 0,
+			want: &AlignmentReport{
+				ChunkSize:    10,
+				Entries:      4,
+				MaxChunks:    5,
+				AvgChunks:    2,
+				Distribution: []int{1, 1, 1, 0, 0, 1},
+				Straddling: []EntryAlignment{
+					{Entry: long, FirstChunk: 0, Chunks: 5},
+					{Entry: straddling, FirstChunk: 1, Chunks: 2},
+				},
+				OutOfRange: []index.Entry{outOfRange},
+			},
+		},
+		"min chunks": {
+			minChunks: 3,
+			want: &AlignmentReport{
+				ChunkSize:    10,
+				Entries:      4,
+				MaxChunks:    5,
+				AvgChunks:    2,
+				Distribution: []int{1, 1, 1, 0, 0, 1},
+				Straddling: []EntryAlignment{
+					{Entry: long, FirstChunk: 0, Chunks: 5},
+				},
+				OutOfRange: []index.Entry{outOfRange},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := z.AlignmentReport(entries, tc.minChunks)
+			if err != nil {
+				t.Fatalf("AlignmentReport: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("AlignmentReport (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
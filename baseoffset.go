@@ -0,0 +1,121 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// WithBaseOffset configures the [Reader] to read the dictzip file starting at
+// offset base of the underlying reader rather than at its beginning, such as
+// a dictzip file embedded in another file. The header is parsed at base and
+// the chunk offsets reported by the Reader, such as by [Reader.ChunkRange],
+// are relative to base. The dictzip file must extend to the end of the
+// underlying reader. Use [io.NewSectionReader] to read a file followed by
+// other data.
+//
+// To read from a stream that is already positioned at the start of the
+// dictzip file, pass the offset returned by r.Seek(0, io.SeekCurrent).
+func WithBaseOffset(base int64) ReaderOption {
+	return func(z *Reader) {
+		z.base = base
+	}
+}
+
+// ResetAt is like [Reader.Reset] but reads the dictzip file starting at
+// offset base of r. See [WithBaseOffset]. The base offset is also used by
+// later calls to [Reader.Reset].
+func (z *Reader) ResetAt(r io.ReadSeeker, base int64) error {
+	z.lock()
+	defer z.unlock()
+
+	z.base = base
+	return z.reset(r)
+}
+
+// baseReader is an [io.ReadSeeker] reading r from offset base on. Offsets are
+// relative to base.
+type baseReader struct {
+	r    io.ReadSeeker
+	base int64
+}
+
+// baseReaderAt is a [baseReader] whose underlying reader implements
+// [io.ReaderAt].
+type baseReaderAt struct {
+	*baseReader
+	ra io.ReaderAt
+}
+
+// newBaseReader returns a reader reading r from offset base on. The returned
+// reader implements [io.ReaderAt] if r does.
+func newBaseReader(r io.ReadSeeker, base int64) io.ReadSeeker {
+	br := &baseReader{r: r, base: base}
+	if ra, ok := r.(io.ReaderAt); ok {
+		return &baseReaderAt{baseReader: br, ra: ra}
+	}
+	return br
+}
+
+// Read implements [io.Reader.Read].
+func (b *baseReader) Read(p []byte) (int, error) {
+	//nolint:wrapcheck // errors are wrapped by the Reader.
+	return b.r.Read(p)
+}
+
+// ReadContext implements [ContextReader]. ctx is passed to the underlying
+// reader if it implements [ContextReader].
+func (b *baseReader) ReadContext(ctx context.Context, p []byte) (int, error) {
+	if cr, ok := b.r.(ContextReader); ok {
+		//nolint:wrapcheck // errors are wrapped by the Reader.
+		return cr.ReadContext(ctx, p)
+	}
+	//nolint:wrapcheck // errors are wrapped by the Reader.
+	return b.r.Read(p)
+}
+
+// Seek implements [io.Seeker.Seek].
+func (b *baseReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		if offset < 0 {
+			return 0, ErrNegativeOffset
+		}
+		offset += b.base
+	case io.SeekCurrent, io.SeekEnd:
+	default:
+		return 0, fmt.Errorf("%w: %v", ErrUnsupportedSeek, whence)
+	}
+	n, err := b.r.Seek(offset, whence)
+	if err != nil {
+		//nolint:wrapcheck // errors are wrapped by the Reader.
+		return 0, err
+	}
+	if n < b.base {
+		return 0, ErrNegativeOffset
+	}
+	return n - b.base, nil
+}
+
+// ReadAt implements [io.ReaderAt.ReadAt].
+func (b *baseReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+	//nolint:wrapcheck // errors are wrapped by the Reader.
+	return b.ra.ReadAt(p, b.base+off)
+}
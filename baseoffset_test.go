@@ -0,0 +1,121 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestWithBaseOffset(t *testing.T) {
+	t.Parallel()
+
+	data := benchmarkData(10000)
+	prefix := []byte("some leading data")
+	archive := append(bytes.Clone(prefix), compressStream(t, data, 1024)...)
+
+	for _, positional := range []bool{true, false} {
+		positional := positional
+		name := "seek"
+		if positional {
+			name = "positional"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(readSeekerOnly(archive, positional), WithBaseOffset(int64(len(prefix))))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			got, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if !bytes.Equal(data, got) {
+				t.Errorf("ReadAll: data does not match")
+			}
+
+			buf := make([]byte, 3000)
+			n, err := z.ReadAt(buf, 5000)
+			if err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if !bytes.Equal(data[5000:5000+n], buf[:n]) {
+				t.Errorf("ReadAt: data does not match")
+			}
+
+			if err := z.Verify(); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestReader_ResetAt(t *testing.T) {
+	t.Parallel()
+
+	data := benchmarkData(5000)
+	compressed := compressStream(t, data, 1024)
+
+	z, err := NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	// The stream is already positioned at the start of the dictzip file.
+	r := bytes.NewReader(append([]byte("prefix"), compressed...))
+	if _, err := r.Seek(6, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	base, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	if err := z.ResetAt(r, base); err != nil {
+		t.Fatalf("ResetAt: %v", err)
+	}
+
+	buf := make([]byte, 100)
+	if _, err := z.ReadAt(buf, 2000); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if diff := cmp.Diff(data[2000:2100], buf); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+
+	// Reset uses the same base offset.
+	if err := z.Reset(r); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Errorf("ReadAll: data does not match")
+	}
+
+	err = z.ResetAt(r, -1)
+	if diff := cmp.Diff(ErrNegativeOffset, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("ResetAt (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"container/list"
+)
+
+// lruEntry is an entry in an lru cache.
+type lruEntry[K comparable] struct {
+	key   K
+	value []byte
+}
+
+// lru is a least recently used cache of byte slices bounded by the total
+// number of bytes stored.
+type lru[K comparable] struct {
+	// maxBytes is the maximum total size of all values in the cache.
+	maxBytes int
+
+	// size is the current total size of all values in the cache.
+	size int
+
+	// ll is the list of entries ordered from most to least recently used.
+	ll *list.List
+
+	entries map[K]*list.Element
+}
+
+// newLRU returns a new lru cache that stores at most maxBytes bytes.
+func newLRU[K comparable](maxBytes int) *lru[K] {
+	return &lru[K]{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		entries:  make(map[K]*list.Element),
+	}
+}
+
+// get returns the value for key and marks it as recently used.
+func (c *lru[K]) get(key K) ([]byte, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*lruEntry[K]).value, true
+}
+
+// add adds the value to the cache, evicting the least recently used entries
+// as necessary. Values larger than the cache are not stored. The cache takes
+// ownership of value.
+func (c *lru[K]) add(key K, value []byte) {
+	if len(value) > c.maxBytes {
+		return
+	}
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+
+	for c.size+len(value) > c.maxBytes {
+		c.remove(c.ll.Back())
+	}
+
+	c.entries[key] = c.ll.PushFront(&lruEntry[K]{key: key, value: value})
+	c.size += len(value)
+}
+
+// remove removes the list element from the cache.
+func (c *lru[K]) remove(e *list.Element) {
+	entry := c.ll.Remove(e).(*lruEntry[K])
+	delete(c.entries, entry.key)
+	c.size -= len(entry.value)
+}
+
+// clear removes all entries from the cache.
+func (c *lru[K]) clear() {
+	c.ll.Init()
+	c.entries = make(map[K]*list.Element)
+	c.size = 0
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLRU(t *testing.T) {
+	t.Parallel()
+
+	c := newLRU[int](10)
+
+	c.add(1, []byte("aaaa"))
+	c.add(2, []byte("bbbb"))
+
+	// Mark 1 as recently used.
+	if v, ok := c.get(1); !ok || string(v) != "aaaa" {
+		t.Errorf("get(1): got %q, %v", v, ok)
+	}
+
+	// Adding 3 should evict 2.
+	c.add(3, []byte("cccc"))
+	if _, ok := c.get(2); ok {
+		t.Errorf("get(2): want evicted")
+	}
+	if diff := cmp.Diff(8, c.size); diff != "" {
+		t.Errorf("size (-want, +got):\n%s", diff)
+	}
+
+	// Values larger than the cache are not stored.
+	c.add(4, []byte("dddddddddddd"))
+	if _, ok := c.get(4); ok {
+		t.Errorf("get(4): want not stored")
+	}
+
+	// Replacing a value updates the size.
+	c.add(1, []byte("a"))
+	if diff := cmp.Diff(5, c.size); diff != "" {
+		t.Errorf("size (-want, +got):\n%s", diff)
+	}
+
+	c.clear()
+	if _, ok := c.get(1); ok {
+		t.Errorf("get(1): want cleared")
+	}
+	if diff := cmp.Diff(0, c.size); diff != "" {
+		t.Errorf("size (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+// Capability is an optional file format feature that may or may not be
+// supported by this package. Tools can use [Capability.Supported] to detect
+// features at runtime rather than relying on module versions.
+type Capability int
+
+const (
+	// CapabilityRAv1 is version 1 of the dictzip random access (RA) EXTRA
+	// subfield as written by dictzip(1).
+	CapabilityRAv1 Capability = iota
+
+	// CapabilityRAv2 is version 2 of the random access (RA) EXTRA subfield.
+	CapabilityRAv2
+
+	// CapabilitySharedWindow is the shared deflate window (RW) EXTRA subfield
+	// written with [WithSharedWindow].
+	CapabilitySharedWindow
+
+	// CapabilityBGZF is the blocked gzip format (BGZF) used by samtools.
+	CapabilityBGZF
+
+	// CapabilityZstdSeekable is the Zstandard seekable format.
+	CapabilityZstdSeekable
+
+	// CapabilityChunkCRC is the per-chunk CRC-32 (RC) EXTRA subfield written
+	// with [WithChunkChecksums].
+	CapabilityChunkCRC
+
+	// CapabilityChunkCRC32C is the per-chunk CRC-32C (RK) EXTRA subfield
+	// written with [WithCastagnoliChunkChecksums].
+	CapabilityChunkCRC32C
+
+	// CapabilityUTF8Subfields is the UTF-8 NAME (UN) and COMMENT (UC) EXTRA
+	// subfields written with [WithWriteUTF8].
+	CapabilityUTF8Subfields
+
+	// CapabilityManifest is the metadata manifest (DM) EXTRA subfield
+	// written with [WithManifest].
+	CapabilityManifest
+)
+
+// capabilities is the list of all known capabilities.
+var capabilities = []Capability{
+	CapabilityRAv1,
+	CapabilityRAv2,
+	CapabilitySharedWindow,
+	CapabilityChunkCRC,
+	CapabilityChunkCRC32C,
+	CapabilityUTF8Subfields,
+	CapabilityManifest,
+	CapabilityBGZF,
+	CapabilityZstdSeekable,
+}
+
+// String returns a short name for the capability.
+func (c Capability) String() string {
+	switch c {
+	case CapabilityRAv1:
+		return "ra-v1"
+	case CapabilityRAv2:
+		return "ra-v2"
+	case CapabilitySharedWindow:
+		return "shared-window"
+	case CapabilityChunkCRC:
+		return "chunk-crc"
+	case CapabilityChunkCRC32C:
+		return "chunk-crc32c"
+	case CapabilityUTF8Subfields:
+		return "utf8-subfields"
+	case CapabilityManifest:
+		return "manifest"
+	case CapabilityBGZF:
+		return "bgzf"
+	case CapabilityZstdSeekable:
+		return "zstd-seekable"
+	default:
+		return "unknown"
+	}
+}
+
+// Supported returns true if the capability is supported by this package.
+// Supported capabilities can be both read and written, except for
+// [CapabilityRAv2] which is only read.
+func (c Capability) Supported() bool {
+	switch c {
+	case CapabilityRAv1, CapabilityRAv2, CapabilitySharedWindow, CapabilityChunkCRC,
+		CapabilityChunkCRC32C, CapabilityUTF8Subfields, CapabilityManifest:
+		return true
+	case CapabilityBGZF, CapabilityZstdSeekable:
+		return false
+	default:
+		return false
+	}
+}
+
+// Capabilities returns all capabilities supported by this package.
+func Capabilities() []Capability {
+	var supported []Capability
+	for _, c := range capabilities {
+		if c.Supported() {
+			supported = append(supported, c)
+		}
+	}
+	return supported
+}
+
+// FormatVersionsSupported returns the versions of the random access (RA)
+// EXTRA subfield that can be read by this package. Files are always written
+// using the first version in the list.
+func FormatVersionsSupported() []int {
+	return []int{int(raVersion), int(raVersion2)}
+}
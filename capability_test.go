@@ -0,0 +1,256 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+func TestCapability(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		c         Capability
+		name      string
+		supported bool
+	}{
+		"ra v1": {
+			c:         CapabilityRAv1,
+			name:      "ra-v1",
+			supported: true,
+		},
+		"ra v2": {
+			c:         CapabilityRAv2,
+			name:      "ra-v2",
+			supported: true,
+		},
+		"shared window": {
+			c:         CapabilitySharedWindow,
+			name:      "shared-window",
+			supported: true,
+		},
+		"chunk crc": {
+			c:         CapabilityChunkCRC,
+			name:      "chunk-crc",
+			supported: true,
+		},
+		"chunk crc32c": {
+			c:         CapabilityChunkCRC32C,
+			name:      "chunk-crc32c",
+			supported: true,
+		},
+		"utf8 subfields": {
+			c:         CapabilityUTF8Subfields,
+			name:      "utf8-subfields",
+			supported: true,
+		},
+		"manifest": {
+			c:         CapabilityManifest,
+			name:      "manifest",
+			supported: true,
+		},
+		"bgzf": {
+			c:         CapabilityBGZF,
+			name:      "bgzf",
+			supported: false,
+		},
+		"zstd seekable": {
+			c:         CapabilityZstdSeekable,
+			name:      "zstd-seekable",
+			supported: false,
+		},
+		"unknown": {
+			c:         Capability(-1),
+			name:      "unknown",
+			supported: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tc.name, tc.c.String()); diff != "" {
+				t.Errorf("String (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.supported, tc.c.Supported()); diff != "" {
+				t.Errorf("Supported (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	want := []Capability{
+		CapabilityRAv1,
+		CapabilityRAv2,
+		CapabilitySharedWindow,
+		CapabilityChunkCRC,
+		CapabilityChunkCRC32C,
+		CapabilityUTF8Subfields,
+		CapabilityManifest,
+	}
+	if diff := cmp.Diff(want, Capabilities()); diff != "" {
+		t.Errorf("Capabilities (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCapabilities_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	// The writer options, name, and subfield IDs of each capability that can
+	// be written. CapabilityRAv2 is only read.
+	testCases := map[Capability]struct {
+		opts []WriterOption
+		name string
+		id   [2]byte
+	}{
+		CapabilityRAv1: {
+			id: [2]byte{format.RASI1, format.RASI2},
+		},
+		CapabilitySharedWindow: {
+			opts: []WriterOption{WithSharedWindow()},
+			id:   [2]byte{format.WindowSI1, format.WindowSI2},
+		},
+		CapabilityChunkCRC: {
+			opts: []WriterOption{WithChunkChecksums()},
+			id:   [2]byte{format.ChunkCRCSI1, format.ChunkCRCSI2},
+		},
+		CapabilityChunkCRC32C: {
+			opts: []WriterOption{WithCastagnoliChunkChecksums()},
+			id:   [2]byte{format.ChunkCRC32CSI1, format.ChunkCRC32CSI2},
+		},
+		CapabilityUTF8Subfields: {
+			opts: []WriterOption{WithWriteUTF8()},
+			name: "世界.txt",
+			id:   [2]byte{format.UTF8NameSI1, format.UTF8NameSI2},
+		},
+		CapabilityManifest: {
+			opts: []WriterOption{WithManifest(Manifest{ManifestBuild: "test"})},
+			id:   [2]byte{format.ManifestSI1, format.ManifestSI2},
+		},
+	}
+
+	for _, c := range Capabilities() {
+		c := c
+		tc, ok := testCases[c]
+		if !ok {
+			if c != CapabilityRAv2 {
+				t.Errorf("no test case for %v", c)
+			}
+			continue
+		}
+		t.Run(c.String(), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			w.Name = tc.name
+			if _, err := w.Write([]byte("Hello, capabilities!")); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			h, _, err := format.Parse(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			found := false
+			for _, id := range h.Subfields {
+				found = found || id == tc.id
+			}
+			if !found {
+				t.Errorf("subfield %q not found in %q", tc.id[:], h.Subfields)
+			}
+
+			z, err := NewReader(bytes.NewReader(buf.Bytes()), WithReadUTF8())
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+			if err := z.Verify(); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if diff := cmp.Diff(tc.name, z.Name); diff != "" {
+				t.Errorf("Name (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatVersionsSupported(t *testing.T) {
+	t.Parallel()
+
+	want := []int{1, 2}
+	if diff := cmp.Diff(want, FormatVersionsSupported()); diff != "" {
+		t.Errorf("FormatVersionsSupported (-want, +got):\n%s", diff)
+	}
+}
+
+// raHeader returns a gzip header with an RA subfield of the given version
+// without chunks.
+func raHeader(version int) []byte {
+	fieldLen := 2
+	if version == int(raVersion2) {
+		fieldLen = 4
+	}
+	ra := binary.LittleEndian.AppendUint16(nil, uint16(version))
+	ra = append(ra, make([]byte, 2*fieldLen)...)
+	ra[2] = 0x4 // CHLEN = 1024
+
+	hdr := []byte{hdrGzipID1, hdrGzipID2, hdrDeflateCM, 1 << 2, 0, 0, 0, 0, 0, 0xff}
+	hdr = binary.LittleEndian.AppendUint16(hdr, uint16(4+len(ra)))
+	hdr = append(hdr, format.RASI1, format.RASI2)
+	hdr = binary.LittleEndian.AppendUint16(hdr, uint16(len(ra)))
+	return append(hdr, ra...)
+}
+
+func TestFormatVersionsSupported_parse(t *testing.T) {
+	t.Parallel()
+
+	// NOTE: The versions reported as supported are those accepted when
+	// parsing the header.
+	supported := map[int]bool{}
+	for _, v := range FormatVersionsSupported() {
+		supported[v] = true
+	}
+	for version := 0; version <= 4; version++ {
+		_, _, err := format.Parse(raHeader(version))
+		if diff := cmp.Diff(supported[version], err == nil); diff != "" {
+			t.Errorf("version %d parsed (-want, +got):\n%s\nerr: %v", version, diff, err)
+		}
+	}
+
+	if diff := cmp.Diff(supported[1], CapabilityRAv1.Supported()); diff != "" {
+		t.Errorf("CapabilityRAv1.Supported (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(supported[2], CapabilityRAv2.Supported()); diff != "" {
+		t.Errorf("CapabilityRAv2.Supported (-want, +got):\n%s", diff)
+	}
+}
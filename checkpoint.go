@@ -0,0 +1,365 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// ErrCheckpoint indicates that a checkpoint can not be written or is invalid.
+var ErrCheckpoint = fmt.Errorf("%w: invalid checkpoint", errDictzip)
+
+// checkpointVersion is the version of the checkpoint file format.
+const checkpointVersion = 1
+
+// checkpointChunksSuffix is appended to the checkpoint path to get the path
+// of the file holding the compressed chunks.
+const checkpointChunksSuffix = ".chunks"
+
+// checkpointState is the [Writer] state stored in a checkpoint file. It is
+// encoded as JSON.
+type checkpointState struct {
+	// Version is the checkpoint file format version.
+	Version int `json:"version"`
+
+	// Level is the compression level.
+	Level int `json:"level"`
+
+	// ChunkSize is the uncompressed chunk size.
+	ChunkSize int `json:"chunkSize"`
+
+	// Interval is the number of chunks between checkpoints.
+	Interval int `json:"interval"`
+
+	// Offset is the number of uncompressed bytes in the chunks written.
+	Offset int64 `json:"offset"`
+
+	// Size is the number of compressed bytes in the chunk file.
+	Size int64 `json:"size"`
+
+	// Sizes are the compressed sizes of the chunks written.
+	Sizes []int `json:"sizes"`
+
+	// ChunkCRCs are the CRC-32 checksums of the chunks written if chunk
+	// checksums are written.
+	ChunkCRCs []uint32 `json:"chunkCRCs,omitempty"`
+
+	// ChunkCRCCastagnoli indicates that ChunkCRCs use the Castagnoli
+	// polynomial. See [WithCastagnoliChunkChecksums].
+	ChunkCRCCastagnoli bool `json:"chunkCRCCastagnoli,omitempty"`
+
+	// Digest is the binary encoding of the CRC-32 digest of the
+	// uncompressed data written.
+	Digest []byte `json:"digest"`
+
+	// ContentHash is the binary encoding of the SHA-256 digest of the
+	// uncompressed data written if the content hash is written. See
+	// [WithContentHash].
+	ContentHash []byte `json:"contentHash,omitempty"`
+}
+
+// validate checks that the checkpoint state is consistent.
+func (s *checkpointState) validate() error {
+	if s.Version != checkpointVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrCheckpoint, s.Version)
+	}
+	if s.ChunkSize <= 0 || s.ChunkSize > MaxChunkSize {
+		return fmt.Errorf("%w: %w: %d", ErrCheckpoint, ErrChunkSize, s.ChunkSize)
+	}
+	if want := int64(len(s.Sizes)) * int64(s.ChunkSize); s.Offset != want {
+		return fmt.Errorf("%w: offset %d does not match %d chunks", ErrCheckpoint, s.Offset, len(s.Sizes))
+	}
+	var size int64
+	for _, n := range s.Sizes {
+		if n <= 0 {
+			return fmt.Errorf("%w: invalid chunk size %d", ErrCheckpoint, n)
+		}
+		size += int64(n)
+	}
+	if s.Size != size {
+		return fmt.Errorf("%w: size %d does not match chunk sizes", ErrCheckpoint, s.Size)
+	}
+	if len(s.ChunkCRCs) != 0 && len(s.ChunkCRCs) != len(s.Sizes) {
+		return fmt.Errorf("%w: %d chunk checksums for %d chunks", ErrCheckpoint, len(s.ChunkCRCs), len(s.Sizes))
+	}
+	return nil
+}
+
+// WithCheckpoint configures the [Writer] to keep the compressed chunks in the
+// file path + ".chunks" rather than in a temporary file and to write its
+// state to a checkpoint file at path every interval chunks so that an
+// interrupted compression can be continued with [ResumeWriter]. If interval
+// is less than 1, checkpoints are only written by [Writer.Checkpoint].
+//
+// Checkpoints are taken at chunk boundaries. The state includes the chunk
+// table, the size of the chunk file, and the CRC-32 state but not the
+// [Header], which must be set again after resuming. Both files are removed
+// when the Writer is closed successfully.
+//
+// [NewWriterLevel] returns an error wrapping [ErrCheckpoint] if this option
+// is combined with [WithSharedWindow] or [WithMemoryBuffer].
+func WithCheckpoint(path string, interval int) WriterOption {
+	return func(z *Writer) {
+		z.checkpointPath = path
+		z.checkpointInterval = interval
+	}
+}
+
+// withResume configures the [Writer] to continue from the checkpoint state s.
+func withResume(s *checkpointState) WriterOption {
+	return func(z *Writer) {
+		z.resume = s
+	}
+}
+
+// ResumeWriter creates a [Writer] continuing the compression recorded in
+// the checkpoint file at path (see [WithCheckpoint]). The compression level
+// and chunk size are read from the checkpoint. opts should include the
+// options given to the original Writer. Checkpoints continue to be written
+// at the original interval unless [WithCheckpoint] is given in opts.
+//
+// ResumeWriter returns the offset of the uncompressed data covered by the
+// checkpoint. The caller must set the Header and write the input starting at
+// that offset. An error wrapping [ErrCheckpoint] is returned if the
+// checkpoint is invalid, does not match the chunk file, or does not match the
+// options.
+func ResumeWriter(w io.Writer, path string, opts ...WriterOption) (*Writer, int64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: reading checkpoint: %w", errDictzip, err)
+	}
+	var s checkpointState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, 0, err
+	}
+
+	opts = append([]WriterOption{WithCheckpoint(path, s.Interval)}, opts...)
+	opts = append(opts, withResume(&s))
+	z, err := NewWriterLevel(w, s.Level, s.ChunkSize, opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return z, s.Offset, nil
+}
+
+// openChunkFile opens the file holding the compressed chunks when
+// checkpoints are enabled. If resuming, the chunks written after the
+// checkpoint are discarded and the Writer state is restored.
+func (z *Writer) openChunkFile() error {
+	if z.sharedWindow || z.memoryBuffer {
+		return fmt.Errorf("%w: checkpoints are not supported with shared windows or memory buffers", ErrCheckpoint)
+	}
+
+	chunksPath := z.checkpointPath + checkpointChunksSuffix
+	s := z.resume
+	if s == nil {
+		f, err := os.OpenFile(chunksPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+		if err != nil {
+			return fmt.Errorf("%w: creating chunk file: %w", errDictzip, err)
+		}
+		z.tmp = f
+		return nil
+	}
+
+	if len(s.Sizes) > 0 && (z.chunkDigest != nil) != (len(s.ChunkCRCs) > 0) {
+		return fmt.Errorf("%w: chunk checksums option does not match", ErrCheckpoint)
+	}
+	if len(s.ChunkCRCs) > 0 && z.chunkCRCCastagnoli != s.ChunkCRCCastagnoli {
+		return fmt.Errorf("%w: chunk checksum polynomial does not match", ErrCheckpoint)
+	}
+	if len(s.Sizes) > 0 && (z.contentHash != nil) != (len(s.ContentHash) > 0) {
+		return fmt.Errorf("%w: content hash option does not match", ErrCheckpoint)
+	}
+	if len(s.ContentHash) > 0 {
+		//nolint:forcetypeassert // sha256 digests implement encoding.BinaryUnmarshaler.
+		if err := z.contentHash.(encoding.BinaryUnmarshaler).UnmarshalBinary(s.ContentHash); err != nil {
+			return fmt.Errorf("%w: content hash: %w", ErrCheckpoint, err)
+		}
+	}
+	digest := crc32.NewIEEE()
+	//nolint:forcetypeassert // crc32 digests implement encoding.BinaryUnmarshaler.
+	if err := digest.(encoding.BinaryUnmarshaler).UnmarshalBinary(s.Digest); err != nil {
+		return fmt.Errorf("%w: digest: %w", ErrCheckpoint, err)
+	}
+
+	f, err := os.OpenFile(chunksPath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("%w: opening chunk file: %w", errDictzip, err)
+	}
+	fInfo, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("%w: stat %q: %w", errDictzip, chunksPath, err)
+	}
+	if fInfo.Size() < s.Size {
+		_ = f.Close()
+		return fmt.Errorf("%w: chunk file is %d bytes, want at least %d", ErrCheckpoint, fInfo.Size(), s.Size)
+	}
+	// Discard chunks written after the checkpoint.
+	if err := f.Truncate(s.Size); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("%w: truncating chunk file: %w", errDictzip, err)
+	}
+	if _, err := f.Seek(s.Size, io.SeekStart); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("%w: seek: %w", errDictzip, err)
+	}
+
+	z.tmp = f
+	z.digest = digest
+	z.isize = s.Offset
+	z.sizes = s.Sizes
+	z.chunkCRCs = s.ChunkCRCs
+	z.checkpointDigest = s.Digest
+	z.checkpointContentHash = s.ContentHash
+	return nil
+}
+
+// checkpointChunk records the state after a full chunk has been written to
+// the chunk file and writes a checkpoint if one is due.
+func (z *Writer) checkpointChunk() error {
+	if z.checkpointPath == "" || z.closed {
+		return nil
+	}
+
+	//nolint:forcetypeassert // crc32 digests implement encoding.BinaryMarshaler.
+	digest, err := z.digest.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("%w: digest: %w", errDictzip, err)
+	}
+	z.checkpointDigest = digest
+	if z.contentHash != nil {
+		//nolint:forcetypeassert // sha256 digests implement encoding.BinaryMarshaler.
+		z.checkpointContentHash, err = z.contentHash.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("%w: content hash: %w", errDictzip, err)
+		}
+	}
+
+	if z.checkpointInterval > 0 && len(z.sizes)%z.checkpointInterval == 0 {
+		return z.Checkpoint()
+	}
+	return nil
+}
+
+// Checkpoint writes the state of the Writer at the last chunk boundary to the
+// checkpoint file given to [WithCheckpoint]. Data written since the last
+// full chunk is not included and must be written again after resuming. The
+// checkpoint file is replaced atomically so that a valid checkpoint remains
+// if the process is interrupted.
+//
+// Checkpoint returns an error wrapping [ErrCheckpoint] if checkpoints are not
+// enabled.
+func (z *Writer) Checkpoint() error {
+	if z.closed {
+		return fmt.Errorf("%w: Checkpoint called on closed writer", errDictzip)
+	}
+	if z.checkpointPath == "" {
+		return fmt.Errorf("%w: checkpoints are not enabled", ErrCheckpoint)
+	}
+	if z.rejected != nil {
+		return z.rejected
+	}
+
+	digest := z.checkpointDigest
+	if digest == nil {
+		var err error
+		//nolint:forcetypeassert // crc32 digests implement encoding.BinaryMarshaler.
+		digest, err = crc32.NewIEEE().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("%w: digest: %w", errDictzip, err)
+		}
+	}
+
+	// NOTE: Only full chunks are written to the chunk file before the
+	// Writer is closed.
+	s := checkpointState{
+		Version:   checkpointVersion,
+		Level:     z.level,
+		ChunkSize: z.chunkSize,
+		Interval:  z.checkpointInterval,
+		Offset:    int64(len(z.sizes)) * int64(z.chunkSize),
+		Sizes:     z.sizes,
+		ChunkCRCs: z.chunkCRCs,
+		Digest:    digest,
+
+		ChunkCRCCastagnoli: z.chunkCRCCastagnoli,
+
+		ContentHash: z.checkpointContentHash,
+	}
+	for _, n := range z.sizes {
+		s.Size += int64(n)
+	}
+	b, err := json.Marshal(&s)
+	if err != nil {
+		return fmt.Errorf("%w: encoding checkpoint: %w", errDictzip, err)
+	}
+
+	// The chunks must be on disk before the checkpoint refers to them.
+	if err := z.tmp.Sync(); err != nil {
+		return fmt.Errorf("%w: sync: %w", errDictzip, err)
+	}
+	return writeFileAtomic(z.checkpointPath, b)
+}
+
+// removeCheckpoint removes the checkpoint and chunk files once the Writer has
+// been closed successfully.
+func (z *Writer) removeCheckpoint() error {
+	if z.checkpointPath == "" {
+		return nil
+	}
+	if err := z.tmp.Close(); err != nil {
+		return fmt.Errorf("%w: closing chunk file: %w", errDictzip, err)
+	}
+	for _, path := range []string{z.checkpointPath, z.checkpointPath + checkpointChunksSuffix} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: removing checkpoint: %w", errDictzip, err)
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic writes b to a temporary file and renames it to path.
+func writeFileAtomic(path string, b []byte) error {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("%w: writing checkpoint: %w", errDictzip, err)
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("%w: writing checkpoint: %w", errDictzip, err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("%w: writing checkpoint: %w", errDictzip, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%w: writing checkpoint: %w", errDictzip, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("%w: writing checkpoint: %w", errDictzip, err)
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func checkpointData() []byte {
+	rng := rand.New(rand.NewSource(1))
+	var data []byte
+	for len(data) < 750 {
+		data = append(data, []byte("Hello, checkpoint! ")...)
+		data = append(data, byte(rng.Intn(256)))
+	}
+	return data[:750]
+}
+
+func TestResumeWriter(t *testing.T) {
+	t.Parallel()
+
+	data := checkpointData()
+
+	testCases := map[string]struct {
+		opts []WriterOption
+
+		// compressFrom indicates that data is written using
+		// Writer.CompressFrom rather than Writer.Write.
+		compressFrom bool
+	}{
+		"write": {},
+		"compress from": {
+			compressFrom: true,
+		},
+		"chunk checksums": {
+			opts: []WriterOption{WithChunkChecksums()},
+		},
+		"castagnoli chunk checksums": {
+			opts: []WriterOption{WithCastagnoliChunkChecksums()},
+		},
+		"content hash": {
+			opts:         []WriterOption{WithContentHash()},
+			compressFrom: true,
+		},
+		"store incompressible": {
+			opts:         []WriterOption{WithStoreIncompressible()},
+			compressFrom: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			write := func(z *Writer, p []byte) {
+				t.Helper()
+
+				var err error
+				if tc.compressFrom {
+					_, err = z.CompressFrom(bytes.NewReader(p), int64(len(p)), 2)
+				} else {
+					_, err = z.Write(p)
+				}
+				if err != nil {
+					t.Fatalf("writing: %v", err)
+				}
+			}
+
+			// The archive written without interruption.
+			var want bytes.Buffer
+			z, err := NewWriterLevel(&want, DefaultCompression, 100, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			write(z, data)
+			if err := z.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			// Write part of the data and abandon the writer.
+			path := filepath.Join(t.TempDir(), "checkpoint")
+			opts := append([]WriterOption{WithCheckpoint(path, 2)}, tc.opts...)
+			var lost bytes.Buffer
+			z, err = NewWriterLevel(&lost, DefaultCompression, 100, opts...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			t.Cleanup(func() { _ = z.tmp.Close() })
+			write(z, data[:550])
+
+			var got bytes.Buffer
+			z, off, err := ResumeWriter(&got, path, tc.opts...)
+			if err != nil {
+				t.Fatalf("ResumeWriter: %v", err)
+			}
+			if off != 400 {
+				t.Errorf("ResumeWriter: got offset %d, want 400", off)
+			}
+			write(z, data[off:])
+			if err := z.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			if diff := cmp.Diff(want.Bytes(), got.Bytes()); diff != "" {
+				t.Errorf("archive (-want, +got):\n%s", diff)
+			}
+
+			for _, p := range []string{path, path + checkpointChunksSuffix} {
+				if _, err := os.Stat(p); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Stat(%q): got %v, want %v", p, err, fs.ErrNotExist)
+				}
+			}
+		})
+	}
+}
+
+func TestWriter_Checkpoint(t *testing.T) {
+	t.Parallel()
+
+	data := checkpointData()
+	path := filepath.Join(t.TempDir(), "checkpoint")
+
+	var lost bytes.Buffer
+	z, err := NewWriterLevel(&lost, DefaultCompression, 100, WithCheckpoint(path, 0))
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	t.Cleanup(func() { _ = z.tmp.Close() })
+
+	// Checkpoints are only written on request.
+	if _, err := z.Write(data[:250]); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(%q): got %v, want %v", path, err, fs.ErrNotExist)
+	}
+
+	// Only full chunks are included.
+	if err := z.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	var got bytes.Buffer
+	z, off, err := ResumeWriter(&got, path)
+	if err != nil {
+		t.Fatalf("ResumeWriter: %v", err)
+	}
+	if off != 200 {
+		t.Errorf("ResumeWriter: got offset %d, want 200", off)
+	}
+	if _, err := z.Write(data[off:]); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(got.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+	buf := make([]byte, len(data))
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if diff := cmp.Diff(data, buf); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWithCheckpoint_errors(t *testing.T) {
+	t.Parallel()
+
+	data := checkpointData()
+
+	// newCheckpoint writes a checkpoint after two chunks using the given
+	// options and returns its path.
+	newCheckpoint := func(t *testing.T, opts ...WriterOption) string {
+		t.Helper()
+
+		path := filepath.Join(t.TempDir(), "checkpoint")
+		var lost bytes.Buffer
+		opts = append([]WriterOption{WithCheckpoint(path, 2)}, opts...)
+		z, err := NewWriterLevel(&lost, DefaultCompression, 100, opts...)
+		if err != nil {
+			t.Fatalf("NewWriterLevel: %v", err)
+		}
+		t.Cleanup(func() { _ = z.tmp.Close() })
+		if _, err := z.Write(data[:200]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		return path
+	}
+
+	t.Run("not enabled", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		z, err := NewWriter(&buf)
+		if err != nil {
+			t.Fatalf("NewWriter: %v", err)
+		}
+		defer z.Close()
+		if diff := cmp.Diff(ErrCheckpoint, z.Checkpoint(), cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("Checkpoint (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("shared window", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "checkpoint")
+		var buf bytes.Buffer
+		_, err := NewWriter(&buf, WithCheckpoint(path, 1), WithSharedWindow())
+		if diff := cmp.Diff(ErrCheckpoint, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("NewWriter (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("chunk checksums mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		path := newCheckpoint(t)
+		var buf bytes.Buffer
+		_, _, err := ResumeWriter(&buf, path, WithChunkChecksums())
+		if diff := cmp.Diff(ErrCheckpoint, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("ResumeWriter (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("chunk checksum polynomial mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		path := newCheckpoint(t, WithChunkChecksums())
+		var buf bytes.Buffer
+		_, _, err := ResumeWriter(&buf, path, WithCastagnoliChunkChecksums())
+		if diff := cmp.Diff(ErrCheckpoint, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("ResumeWriter (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("truncated chunk file", func(t *testing.T) {
+		t.Parallel()
+
+		path := newCheckpoint(t)
+		if err := os.Truncate(path+checkpointChunksSuffix, 10); err != nil {
+			t.Fatalf("Truncate: %v", err)
+		}
+		var buf bytes.Buffer
+		_, _, err := ResumeWriter(&buf, path)
+		if diff := cmp.Diff(ErrCheckpoint, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("ResumeWriter (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid checkpoint", func(t *testing.T) {
+		t.Parallel()
+
+		path := newCheckpoint(t)
+		if err := os.WriteFile(path, []byte(`{"version":1,"chunkSize":100,"offset":50}`), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		var buf bytes.Buffer
+		_, _, err := ResumeWriter(&buf, path)
+		if diff := cmp.Diff(ErrCheckpoint, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("ResumeWriter (-want, +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"runtime"
+)
+
+// Checksum returns the checksum of the uncompressed data in z computed using
+// the hash returned by h. Chunks are inflated using up to workers goroutines
+// and written to the hash in order, which is much faster than copying the
+// data from z into the hash for large archives.
+//
+// If workers is less than 1, [runtime.NumCPU] workers are used. Chunks are
+// inflated sequentially if the underlying reader does not implement
+// [io.ReaderAt], if the archive's chunks share the deflate window, or if it
+// is a plain gzip file read with [WithGzipFallback] or a multistream file read
+// with [WithMultistream].
+//
+// Checksum does not change the offset of z.
+func Checksum(z *Reader, h func() hash.Hash, workers int) ([]byte, error) {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	hh := h()
+
+	ra, ok := z.r.(io.ReaderAt)
+	if !ok || z.sharedWindow || z.plainGzip || z.members != nil || workers == 1 {
+		if _, err := io.Copy(hh, io.NewSectionReader(z, 0, math.MaxInt64)); err != nil {
+			return nil, fmt.Errorf("%w: hashing: %w", errDictzip, err)
+		}
+		return hh.Sum(nil), nil
+	}
+
+	size, err := z.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	chunkCount := len(z.sizes)
+	if workers > chunkCount {
+		workers = chunkCount + 1
+	}
+
+	// decompressors holds one flate reader per worker.
+	decompressors := make(chan readCloseResetter, workers)
+	for i := 0; i < workers; i++ {
+		fr, err := z.decompressor(nil)
+		if err != nil {
+			return nil, err
+		}
+		decompressors <- fr
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	type inflateResult struct {
+		data []byte
+		err  error
+	}
+
+	// queue holds result channels in chunk order. Its capacity limits the
+	// number of chunks being inflated at once.
+	queue := make(chan chan inflateResult, workers-1)
+	go func() {
+		defer close(queue)
+		for i := 0; i < chunkCount; i++ {
+			result := make(chan inflateResult, 1)
+			select {
+			case queue <- result:
+			case <-done:
+				return
+			}
+
+			chunkLen := int64(z.chunkSize)
+			if i == chunkCount-1 {
+				chunkLen = size - int64(i)*chunkLen
+			}
+
+			go func(i int, chunkLen int64) {
+				fr := <-decompressors
+				defer func() { decompressors <- fr }()
+				data, err := inflateChunk(ra, z.offsets.at(i), z.offsets.at(i+1), chunkLen, fr)
+				result <- inflateResult{data: data, err: err}
+			}(i, chunkLen)
+		}
+	}()
+
+	for result := range queue {
+		res := <-result
+		if res.err != nil {
+			return nil, res.err
+		}
+		if _, err := hh.Write(res.data); err != nil {
+			return nil, fmt.Errorf("%w: hashing: %w", errDictzip, err)
+		}
+	}
+
+	return hh.Sum(nil), nil
+}
+
+// CRC32 returns a function that creates CRC-32 hashes using the polynomial
+// poly, such as [crc32.IEEE] or [crc32.Castagnoli], for use with [Checksum].
+// The [crc32] package uses hardware accelerated implementations of both
+// polynomials on most platforms, such as amd64 and arm64, so checksum
+// throughput is generally limited by inflating the chunks rather than by
+// hashing.
+//
+// The gzip trailer CRC-32 always uses the IEEE polynomial.
+func CRC32(poly uint32) func() hash.Hash {
+	// NOTE: crc32.New only uses the accelerated implementations if given the
+	// IEEE or Castagnoli tables returned by crc32.MakeTable. Only those two
+	// tables are cached. Other polynomials build a new table, so the table
+	// is made once here rather than for each hash.
+	tab := crc32.MakeTable(poly)
+	return func() hash.Hash {
+		return crc32.New(tab)
+	}
+}
+
+// inflateChunk reads the compressed chunk between the offsets start and end
+// in r and inflates size bytes of uncompressed data using fr.
+func inflateChunk(r io.ReaderAt, start, end, size int64, fr readCloseResetter) ([]byte, error) {
+	if err := fr.Reset(io.NewSectionReader(r, start, end-start), nil); err != nil {
+		return nil, fmt.Errorf("%w: Reset: %w", errDictzip, err)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(fr, data); err != nil {
+		return nil, fmt.Errorf("%w: inflating chunk: %w", errDictzip, err)
+	}
+	return data, nil
+}
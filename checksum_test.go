@@ -0,0 +1,272 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestChecksum(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(data)
+	// Make the data somewhat compressible.
+	for i := range data {
+		data[i] %= 16
+	}
+	want := sha256.Sum256(data)
+
+	write := func(t *testing.T, opts ...WriterOption) []byte {
+		t.Helper()
+
+		var buf bytes.Buffer
+		z, err := NewWriterLevel(&buf, DefaultCompression, 1000, opts...)
+		if err != nil {
+			t.Fatalf("NewWriterLevel: %v", err)
+		}
+		if _, err := z.Write(data[:9500]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if _, err := z.Write(data[9500:]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := z.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	testCases := map[string]struct {
+		data    []byte
+		workers int
+
+		// noReaderAt hides the io.ReaderAt implementation of the underlying
+		// reader.
+		noReaderAt bool
+	}{
+		"sequential": {
+			data:    write(t),
+			workers: 1,
+		},
+		"parallel": {
+			data:    write(t),
+			workers: 4,
+		},
+		"num cpu": {
+			data:    write(t),
+			workers: 0,
+		},
+		"more workers than chunks": {
+			data:    write(t),
+			workers: 100,
+		},
+		"shared window": {
+			data:    write(t, WithSharedWindow()),
+			workers: 4,
+		},
+		"no ReaderAt": {
+			data:       write(t),
+			workers:    4,
+			noReaderAt: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var r io.ReadSeeker = bytes.NewReader(tc.data)
+			if tc.noReaderAt {
+				r = struct{ io.ReadSeeker }{r}
+			}
+			z, err := NewReader(r)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			got, err := Checksum(z, sha256.New, tc.workers)
+			if err != nil {
+				t.Fatalf("Checksum: %v", err)
+			}
+			if diff := cmp.Diff(want[:], got); diff != "" {
+				t.Errorf("Checksum (-want, +got):\n%s", diff)
+			}
+
+			// The offset of z is unchanged.
+			if diff := cmp.Diff(int64(0), z.offset); diff != "" {
+				t.Errorf("offset (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestChecksum_corrupt(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	z, err := NewWriterLevel(&buf, DefaultCompression, 6)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := z.Write([]byte("chunk1chunk2chunk3")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	// Corrupt the second chunk.
+	data := buf.Bytes()
+	for i := r.offsets.at(1); i < r.offsets.at(2); i++ {
+		data[i] = 0xff
+	}
+
+	_, err = Checksum(r, sha256.New, 2)
+	if diff := cmp.Diff(errDictzip, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("Checksum (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCRC32(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("hello world")
+
+	testCases := map[string]struct {
+		poly uint32
+		want uint32
+	}{
+		"IEEE": {
+			poly: crc32.IEEE,
+			want: crc32.ChecksumIEEE(data),
+		},
+		"Castagnoli": {
+			poly: crc32.Castagnoli,
+			want: crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)),
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(mustCompress(t, data)))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			got, err := Checksum(z, CRC32(tc.poly), 1)
+			if err != nil {
+				t.Fatalf("Checksum: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, binary.BigEndian.Uint32(got)); diff != "" {
+				t.Errorf("Checksum (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// benchmarkData returns size bytes of compressible data.
+func benchmarkData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	for i := range data {
+		data[i] = 'a' + data[i]%16
+	}
+	return data
+}
+
+func BenchmarkCRC32(b *testing.B) {
+	data := benchmarkData(16 << 20)
+	for _, poly := range []struct {
+		name string
+		poly uint32
+	}{
+		{"IEEE", crc32.IEEE},
+		{"Castagnoli", crc32.Castagnoli},
+	} {
+		b.Run(poly.name, func(b *testing.B) {
+			h := CRC32(poly.poly)()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				h.Reset()
+				h.Write(data)
+			}
+		})
+	}
+}
+
+func BenchmarkChecksum(b *testing.B) {
+	data := benchmarkData(16 << 20)
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 32768)
+	if err != nil {
+		b.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		b.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatalf("Close: %v", err)
+	}
+
+	for _, poly := range []struct {
+		name string
+		poly uint32
+	}{
+		{"IEEE", crc32.IEEE},
+		{"Castagnoli", crc32.Castagnoli},
+	} {
+		for _, workers := range []int{1, 0} {
+			b.Run(fmt.Sprintf("%s/workers=%d", poly.name, workers), func(b *testing.B) {
+				z, err := NewReader(bytes.NewReader(buf.Bytes()))
+				if err != nil {
+					b.Fatalf("NewReader: %v", err)
+				}
+				defer z.Close()
+
+				b.SetBytes(int64(len(data)))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := Checksum(z, CRC32(poly.poly), workers); err != nil {
+						b.Fatalf("Checksum: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
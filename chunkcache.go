@@ -0,0 +1,79 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"errors"
+	"io"
+)
+
+// WithChunkCache configures the [Reader] to cache the inflated data of the
+// most recently used chunks. Reads of a cached chunk are served from memory
+// without reading the underlying reader or inflating the chunk again. At
+// most maxBytes bytes of data are cached, so maxBytes should be n times the
+// chunk size to cache n chunks.
+//
+// Unlike [WithReadCache], which only serves repeated reads of exactly the
+// same range, the chunk cache serves any read of a cached chunk. This is
+// useful for applications such as dictionary servers where lookups tend to
+// hit the same chunks repeatedly. [Reader.ReadAtBuffer] does not use the
+// chunk cache.
+func WithChunkCache(maxBytes int) ReaderOption {
+	return func(z *Reader) {
+		z.chunkCache = newLRU[int64](maxBytes)
+	}
+}
+
+// readCached reads len(p) bytes of uncompressed data at offset off into p
+// using the chunk cache.
+func (z *Reader) readCached(p []byte, off int64) (int, error) {
+	chunkSize := int64(z.chunkSize)
+	var n int
+	for n < len(p) {
+		pos := off + int64(n)
+		chunkNum := pos / chunkSize
+		data, err := z.cachedChunk(chunkNum)
+		if err != nil {
+			return n, err
+		}
+
+		skip := pos - chunkNum*chunkSize
+		if skip >= int64(len(data)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], data[skip:])
+	}
+	return n, nil
+}
+
+// cachedChunk returns the inflated data of the chunk chunkNum from the chunk
+// cache, inflating and adding it to the cache if it is not cached.
+func (z *Reader) cachedChunk(chunkNum int64) ([]byte, error) {
+	if data, ok := z.chunkCache.get(chunkNum); ok {
+		return data, nil
+	}
+	if chunkNum >= int64(len(z.sizes)) {
+		return nil, io.EOF
+	}
+
+	// NOTE: Reading the last chunk, or the recoverable data of a chunk in
+	// salvage mode, returns io.EOF along with the complete data.
+	data, err := z.readChunk(chunkNum*int64(z.chunkSize), z.chunkSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	z.chunkCache.add(chunkNum, data)
+	return data, nil
+}
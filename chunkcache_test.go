@@ -0,0 +1,149 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithChunkCache(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	var data []byte
+	for i := 0; i < 500; i++ {
+		data = append(data, []byte("Hello, WithChunkCache! ")...)
+		data = append(data, byte(rng.Intn(256)))
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 1000)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var resets int
+	newDecompressor := func(r io.Reader) io.ReadCloser {
+		return &countingDecompressor{
+			readCloseResetter: flate.NewReader(r).(readCloseResetter),
+			resets:            &resets,
+		}
+	}
+
+	// Cache two chunks.
+	z, err := NewReader(bytes.NewReader(buf.Bytes()), WithChunkCache(2000), WithDecompressor(newDecompressor))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	readAt := func(off, n int64) {
+		t.Helper()
+
+		got := make([]byte, n)
+		m, err := z.ReadAt(got, off)
+		want := data[off:]
+		if int64(len(want)) > n {
+			want = want[:n]
+		}
+		if int64(len(want)) < n {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("ReadAt(%d, %d): got err %v, want io.EOF", off, n, err)
+			}
+		} else if err != nil {
+			t.Fatalf("ReadAt(%d, %d): %v", off, n, err)
+		}
+		if diff := cmp.Diff(want, got[:m]); diff != "" {
+			t.Fatalf("ReadAt(%d, %d) (-want, +got):\n%s", off, n, diff)
+		}
+	}
+
+	// Reads within and across the cached chunks do not inflate them again.
+	readAt(1100, 100)
+	readAt(1900, 200)
+	before := resets
+	readAt(1000, 1000)
+	readAt(1500, 600)
+	readAt(2999, 1)
+	if resets != before {
+		t.Errorf("Reset called %d times for cached chunks, want 0", resets-before)
+	}
+
+	// Reading other chunks evicts the least recently used chunk.
+	readAt(3000, 2000)
+	readAt(11000, 2000)
+	before = resets
+	readAt(1000, 10)
+	if resets == before {
+		t.Errorf("Reset not called for evicted chunk")
+	}
+
+	// Reads past the end return io.EOF.
+	readAt(int64(len(data))-10, 100)
+	readAt(0, int64(len(data))+1)
+
+	// Read uses the cache too.
+	if _, err := z.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if diff := cmp.Diff(data, got); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWithChunkCache_reset(t *testing.T) {
+	t.Parallel()
+
+	first := bytes.Repeat([]byte("first "), 100)
+	second := bytes.Repeat([]byte("second "), 100)
+
+	z, err := NewReader(bytes.NewReader(mustCompress(t, first)), WithChunkCache(1<<20))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	got := make([]byte, 10)
+	if _, err := z.ReadAt(got, 0); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+
+	if err := z.Reset(bytes.NewReader(mustCompress(t, second))); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if _, err := z.ReadAt(got, 0); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if diff := cmp.Diff(second[:10], got); diff != "" {
+		t.Errorf("ReadAt after Reset (-want, +got):\n%s", diff)
+	}
+}
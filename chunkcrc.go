@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+var (
+	// ErrChunkChecksum indicates that the uncompressed data of a chunk does
+	// not match its checksum.
+	ErrChunkChecksum = fmt.Errorf("%w: chunk checksum mismatch", errDictzip)
+
+	// ErrNoChunkChecksums indicates that the archive does not include chunk
+	// checksums.
+	ErrNoChunkChecksums = fmt.Errorf("%w: archive has no chunk checksums", errDictzip)
+)
+
+// castagnoliTable is the CRC-32 table for the Castagnoli polynomial.
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// WithChunkChecksums configures the [Writer] to write the CRC-32 (IEEE
+// polynomial) of the uncompressed data of each chunk to an additional EXTRA
+// subfield. The checksums are verified by [Reader.ReadAtVerified].
+//
+// Each checksum uses 4 bytes of the EXTRA field, which is limited to 65535
+// bytes, so fewer chunks may be written than without checksums. Archives
+// written with this option remain compatible with dictzip(1) and gzip(1).
+func WithChunkChecksums() WriterOption {
+	return func(z *Writer) {
+		z.chunkDigest = crc32.NewIEEE()
+		z.chunkCRCs = []uint32{}
+		z.chunkCRCCastagnoli = false
+	}
+}
+
+// WithCastagnoliChunkChecksums is like [WithChunkChecksums] but writes the
+// CRC-32C (Castagnoli polynomial) of each chunk, which some platforms
+// compute faster than the IEEE polynomial.
+//
+// The checksums are written to a different EXTRA subfield than IEEE
+// checksums. Readers that only support IEEE chunk checksums, such as older
+// versions of this package, ignore the subfield and don't verify the chunks.
+func WithCastagnoliChunkChecksums() WriterOption {
+	return func(z *Writer) {
+		z.chunkDigest = crc32.New(castagnoliTable)
+		z.chunkCRCs = []uint32{}
+		z.chunkCRCCastagnoli = true
+	}
+}
+
+// ChunkCRCPolynomial returns the polynomial of the checksums returned by
+// [Header.ChunkCRCs]. It is [crc32.Castagnoli] if the archive was written
+// with [WithCastagnoliChunkChecksums] and [crc32.IEEE] otherwise.
+func (h *Header) ChunkCRCPolynomial() uint32 {
+	if h.chunkCRCCastagnoli {
+		return crc32.Castagnoli
+	}
+	return crc32.IEEE
+}
+
+// chunkCRCTable returns the CRC-32 table for the polynomial of the chunk
+// checksums.
+func (h *Header) chunkCRCTable() *crc32.Table {
+	if h.chunkCRCCastagnoli {
+		return castagnoliTable
+	}
+	return crc32.IEEETable
+}
+
+// ReadAtVerified is like [Reader.ReadAt] but verifies the checksum of every
+// chunk read before returning any data. It returns an error wrapping
+// [ErrChunkChecksum] if a chunk's data does not match its checksum and an
+// error wrapping [ErrNoChunkChecksums] if the archive does not include chunk
+// checksums.
+//
+// ReadAtVerified is intended for integrity-critical data and is slower than
+// ReadAt since whole chunks are inflated. Results are not cached.
+// See [WithChunkChecksums].
+func (z *Reader) ReadAtVerified(p []byte, off int64) (int, error) {
+	z.lock()
+	defer z.unlock()
+
+	if z.members != nil {
+		return z.readMembers(p, off, (*Reader).readAtVerified)
+	}
+	return z.readAtVerified(p, off)
+}
+
+// readAtVerified implements ReadAtVerified. The caller must hold the lock.
+func (z *Reader) readAtVerified(p []byte, off int64) (int, error) {
+	if z.chunkCRCs == nil {
+		return 0, ErrNoChunkChecksums
+	}
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	chunkSize := int64(z.chunkSize)
+	first := off / chunkSize
+	last := (off + int64(len(p)) - 1) / chunkSize
+
+	// Verify all chunks before copying any data to p.
+	chunks := make([][]byte, 0, last-first+1)
+	for c := first; c <= last && c < int64(len(z.chunkCRCs)); c++ {
+		data, err := z.readChunk(c*chunkSize, z.chunkSize)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		if crc32.Checksum(data, z.chunkCRCTable()) != z.chunkCRCs[c] {
+			return 0, fmt.Errorf("%w: chunk %d", ErrChunkChecksum, c)
+		}
+		chunks = append(chunks, data)
+		if int64(len(data)) < chunkSize {
+			// This is the last chunk.
+			break
+		}
+	}
+
+	var n int
+	start := off - first*chunkSize
+	for _, data := range chunks {
+		if start < int64(len(data)) {
+			n += copy(p[n:], data[start:])
+		}
+		start = 0
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+func TestWithChunkChecksums(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("chunk1chunk2chunk3chu")
+
+	testCases := map[string]struct {
+		workers    int
+		opts       []WriterOption
+		castagnoli bool
+	}{
+		"write": {},
+		"compress from": {
+			workers: 2,
+		},
+		"shared window": {
+			opts: []WriterOption{WithSharedWindow()},
+		},
+		"castagnoli": {
+			castagnoli: true,
+		},
+		"castagnoli compress from": {
+			workers:    2,
+			castagnoli: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			opt, poly := WithChunkChecksums(), uint32(crc32.IEEE)
+			if tc.castagnoli {
+				opt, poly = WithCastagnoliChunkChecksums(), crc32.Castagnoli
+			}
+
+			var buf bytes.Buffer
+			w, err := NewWriterLevel(&buf, DefaultCompression, 6, append(tc.opts, opt)...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if tc.workers > 0 {
+				if _, err := w.CompressFrom(bytes.NewReader(data), int64(len(data)), tc.workers); err != nil {
+					t.Fatalf("CompressFrom: %v", err)
+				}
+			} else if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			z, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			var want []uint32
+			for off := 0; off < len(data); off += 6 {
+				end := off + 6
+				if end > len(data) {
+					end = len(data)
+				}
+				want = append(want, crc32.Checksum(data[off:end], crc32.MakeTable(poly)))
+			}
+			if diff := cmp.Diff(want, z.ChunkCRCs()); diff != "" {
+				t.Errorf("ChunkCRCs (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(poly, z.ChunkCRCPolynomial()); diff != "" {
+				t.Errorf("ChunkCRCPolynomial (-want, +got):\n%s", diff)
+			}
+			if err := z.Verify(); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+
+			// The polynomial is marked by the subfield ID.
+			h, _, err := format.Parse(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if diff := cmp.Diff(tc.castagnoli, h.ChunkCRCCastagnoli); diff != "" {
+				t.Errorf("ChunkCRCCastagnoli (-want, +got):\n%s", diff)
+			}
+
+			s, err := NewStreamReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewStreamReader: %v", err)
+			}
+			if _, err := io.Copy(io.Discard, s); err != nil {
+				t.Errorf("StreamReader: %v", err)
+			}
+
+			for _, r := range []struct {
+				off, size int
+			}{
+				{0, 6},
+				{3, 10},
+				{0, len(data)},
+				{19, 2},
+			} {
+				p := make([]byte, r.size)
+				n, err := z.ReadAtVerified(p, int64(r.off))
+				if err != nil && !(errors.Is(err, io.EOF) && n == len(p)) {
+					t.Fatalf("ReadAtVerified(%d, %d): %v", r.off, r.size, err)
+				}
+				if diff := cmp.Diff(data[r.off:r.off+r.size], p); diff != "" {
+					t.Errorf("ReadAtVerified(%d, %d) (-want, +got):\n%s", r.off, r.size, diff)
+				}
+			}
+
+			// Reads past the end of the data are short.
+			n, err := z.ReadAtVerified(make([]byte, 10), 18)
+			if diff := cmp.Diff(io.EOF, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ReadAtVerified (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(3, n); diff != "" {
+				t.Errorf("ReadAtVerified (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReader_ReadAtVerified_mismatch(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		opt WriterOption
+		id  [2]byte
+	}{
+		"ieee": {
+			opt: WithChunkChecksums(),
+			id:  [2]byte{'R', 'C'},
+		},
+		"castagnoli": {
+			opt: WithCastagnoliChunkChecksums(),
+			id:  [2]byte{'R', 'K'},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriterLevel(&buf, DefaultCompression, 6, tc.opt)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := w.Write([]byte("chunk1chunk2")); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			// Corrupt the checksum of the second chunk.
+			b := buf.Bytes()
+			i := bytes.Index(b, []byte{tc.id[0], tc.id[1], 0x8, 0x0})
+			if i < 0 {
+				t.Fatalf("chunk CRC subfield not found")
+			}
+			b[i+8] ^= 0xff
+
+			z, err := NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			p := make([]byte, 6)
+			if _, err := z.ReadAtVerified(p, 0); err != nil {
+				t.Errorf("ReadAtVerified: %v", err)
+			}
+
+			// NOTE: p is not modified if verification fails.
+			p = make([]byte, 8)
+			n, err := z.ReadAtVerified(p, 4)
+			if diff := cmp.Diff(ErrChunkChecksum, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ReadAtVerified (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(0, n); diff != "" {
+				t.Errorf("ReadAtVerified (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(make([]byte, 8), p); diff != "" {
+				t.Errorf("ReadAtVerified (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReader_ReadAtVerified_noChecksums(t *testing.T) {
+	t.Parallel()
+
+	z, err := NewReader(bytes.NewReader(mustCompress(t, []byte("chunk1"))))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	_, err = z.ReadAtVerified(make([]byte, 6), 0)
+	if diff := cmp.Diff(ErrNoChunkChecksums, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("ReadAtVerified (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// ErrChunkIndex indicates that a [ChunkIndex] is invalid.
+var ErrChunkIndex = fmt.Errorf("%w: invalid chunk index", errDictzip)
+
+// chunkIndexMagic identifies the binary encoding of a [ChunkIndex].
+const chunkIndexMagic = "DZCI"
+
+// chunkIndexVersion is the version of the binary encoding of a
+// [ChunkIndex].
+const chunkIndexVersion = 1
+
+const (
+	chunkIndexShared = byte(1 << 0)
+	chunkIndexCRCs   = byte(1 << 1)
+
+	chunkIndexCastagnoli = byte(1 << 2)
+)
+
+// ChunkIndex is the chunk map of a dictzip archive. It includes everything
+// needed to read the archive's chunks so that it can be stored separately
+// from the archive, for example alongside an object storage key, and given
+// to [WithChunkIndex] to create a [Reader] without reading the header.
+//
+// ChunkIndex can be encoded as JSON using the encoding/json package and
+// implements [encoding.BinaryMarshaler] and [encoding.BinaryUnmarshaler] for
+// a compact binary encoding, which is also used by the encoding/gob package.
+type ChunkIndex struct {
+	// HeaderSize is the size of the archive header, which is the offset of
+	// the first chunk.
+	HeaderSize int64 `json:"headerSize"`
+
+	// ChunkSize is the uncompressed chunk size.
+	ChunkSize int `json:"chunkSize"`
+
+	// Sizes are the compressed sizes of each chunk.
+	Sizes []int `json:"sizes"`
+
+	// SharedWindow indicates that chunks share the deflate window.
+	SharedWindow bool `json:"sharedWindow,omitempty"`
+
+	// ChunkCRCs are the CRC-32 checksums of the uncompressed data of each
+	// chunk. It is nil if the archive has no chunk checksums.
+	ChunkCRCs []uint32 `json:"chunkCRCs,omitempty"`
+
+	// ChunkCRCCastagnoli indicates that ChunkCRCs use the Castagnoli
+	// polynomial rather than the IEEE polynomial.
+	ChunkCRCCastagnoli bool `json:"chunkCRCCastagnoli,omitempty"`
+}
+
+// ChunkIndex returns the chunk map of the archive.
+func (z *Reader) ChunkIndex() *ChunkIndex {
+	z.lock()
+	defer z.unlock()
+
+	return &ChunkIndex{
+		HeaderSize:   z.offsets.at(0),
+		ChunkSize:    z.chunkSize,
+		Sizes:        append([]int(nil), z.sizes...),
+		SharedWindow: z.sharedWindow,
+		ChunkCRCs:    append([]uint32(nil), z.chunkCRCs...),
+
+		ChunkCRCCastagnoli: z.chunkCRCs != nil && z.chunkCRCCastagnoli,
+	}
+}
+
+// WithChunkIndex configures the [Reader] to use the chunk map idx rather than
+// reading it from the archive header. Only the chunk map is set in the
+// [Header]. Other header fields such as Name are left empty.
+//
+// idx is not checked against the archive. [NewReader] and [Reader.Reset]
+// return an error wrapping [ErrChunkIndex] if idx is invalid.
+func WithChunkIndex(idx *ChunkIndex) ReaderOption {
+	return func(z *Reader) {
+		z.chunkIndex = idx
+	}
+}
+
+// validate checks that the chunk index is valid.
+func (idx *ChunkIndex) validate() error {
+	// NOTE: The gzip header is at least 10 bytes.
+	if idx.HeaderSize < 10 {
+		return fmt.Errorf("%w: header size %d", ErrChunkIndex, idx.HeaderSize)
+	}
+	if idx.ChunkSize <= 0 || idx.ChunkSize > MaxChunkSize {
+		return fmt.Errorf("%w: chunk size %d", ErrChunkIndex, idx.ChunkSize)
+	}
+	if len(idx.Sizes) > math.MaxUint16 {
+		return fmt.Errorf("%w: %d chunks", ErrChunkIndex, len(idx.Sizes))
+	}
+	for i, size := range idx.Sizes {
+		if size < 0 || size > math.MaxUint16 {
+			return fmt.Errorf("%w: chunk %d: size %d", ErrChunkIndex, i, size)
+		}
+	}
+	if idx.ChunkCRCs != nil && len(idx.ChunkCRCs) != len(idx.Sizes) {
+		return fmt.Errorf("%w: %d chunk CRCs for %d chunks", ErrChunkIndex, len(idx.ChunkCRCs), len(idx.Sizes))
+	}
+	if idx.ChunkCRCCastagnoli && idx.ChunkCRCs == nil {
+		return fmt.Errorf("%w: Castagnoli polynomial without chunk CRCs", ErrChunkIndex)
+	}
+	return nil
+}
+
+// setChunkIndex sets the chunk map from idx.
+func (z *Reader) setChunkIndex(idx *ChunkIndex) error {
+	if err := idx.validate(); err != nil {
+		return err
+	}
+	z.chunkSize = idx.ChunkSize
+	z.sizes = idx.Sizes
+	z.sharedWindow = idx.SharedWindow
+	z.chunkCRCs = idx.ChunkCRCs
+	z.chunkCRCCastagnoli = idx.ChunkCRCCastagnoli
+	z.offsets = chunkOffsets(idx.HeaderSize, idx.Sizes)
+	return nil
+}
+
+// MarshalBinary implements [encoding.BinaryMarshaler]. The chunk index is
+// encoded as follows.
+//
+//   - Magic "DZCI" (4 bytes)
+//   - Version (1 byte)
+//   - Flags (1 byte). Bit 0 indicates a shared window, bit 1 that chunk
+//     CRCs follow the chunk sizes, and bit 2 that they use the Castagnoli
+//     polynomial.
+//   - Header size (uvarint)
+//   - Chunk size (uvarint)
+//   - Chunk count (uvarint)
+//   - Chunk sizes (each uvarint)
+//   - Chunk CRC-32s (each 4 bytes, little endian)
+func (idx *ChunkIndex) MarshalBinary() ([]byte, error) {
+	if err := idx.validate(); err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	if idx.SharedWindow {
+		flags |= chunkIndexShared
+	}
+	if idx.ChunkCRCs != nil {
+		flags |= chunkIndexCRCs
+	}
+	if idx.ChunkCRCCastagnoli {
+		flags |= chunkIndexCastagnoli
+	}
+
+	b := make([]byte, 0, 6+3*binary.MaxVarintLen64+len(idx.Sizes)*3+len(idx.ChunkCRCs)*4)
+	b = append(b, chunkIndexMagic...)
+	b = append(b, chunkIndexVersion, flags)
+	//nolint:gosec // HeaderSize is positive as checked by validate.
+	b = binary.AppendUvarint(b, uint64(idx.HeaderSize))
+	//nolint:gosec // ChunkSize is positive as checked by validate.
+	b = binary.AppendUvarint(b, uint64(idx.ChunkSize))
+	b = binary.AppendUvarint(b, uint64(len(idx.Sizes)))
+	for _, size := range idx.Sizes {
+		//nolint:gosec // sizes are positive as checked by validate.
+		b = binary.AppendUvarint(b, uint64(size))
+	}
+	for _, crc := range idx.ChunkCRCs {
+		b = binary.LittleEndian.AppendUint32(b, crc)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements [encoding.BinaryUnmarshaler]. It returns an
+// error wrapping [ErrChunkIndex] if data is not a valid chunk index.
+func (idx *ChunkIndex) UnmarshalBinary(data []byte) error {
+	if len(data) < 6 || string(data[:4]) != chunkIndexMagic {
+		return fmt.Errorf("%w: bad magic", ErrChunkIndex)
+	}
+	if data[4] != chunkIndexVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrChunkIndex, data[4])
+	}
+	flags := data[5]
+	data = data[6:]
+
+	// uvarint reads a uvarint no larger than limit from data.
+	uvarint := func(name string, limit uint64) (uint64, error) {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, fmt.Errorf("%w: reading %s", ErrChunkIndex, name)
+		}
+		if v > limit {
+			return 0, fmt.Errorf("%w: %s %d", ErrChunkIndex, name, v)
+		}
+		data = data[n:]
+		return v, nil
+	}
+
+	headerSize, err := uvarint("header size", math.MaxInt64)
+	if err != nil {
+		return err
+	}
+	chunkSize, err := uvarint("chunk size", MaxChunkSize)
+	if err != nil {
+		return err
+	}
+	count, err := uvarint("chunk count", math.MaxUint16)
+	if err != nil {
+		return err
+	}
+
+	newIdx := ChunkIndex{
+		//nolint:gosec // headerSize max value is checked above.
+		HeaderSize:   int64(headerSize),
+		ChunkSize:    int(chunkSize),
+		Sizes:        make([]int, count),
+		SharedWindow: flags&chunkIndexShared != 0,
+
+		ChunkCRCCastagnoli: flags&chunkIndexCastagnoli != 0,
+	}
+	for i := range newIdx.Sizes {
+		size, err := uvarint("chunk size", math.MaxUint16)
+		if err != nil {
+			return err
+		}
+		newIdx.Sizes[i] = int(size)
+	}
+	if flags&chunkIndexCRCs != 0 {
+		if len(data) < 4*int(count) {
+			return fmt.Errorf("%w: reading chunk CRCs", ErrChunkIndex)
+		}
+		newIdx.ChunkCRCs = make([]uint32, count)
+		for i := range newIdx.ChunkCRCs {
+			newIdx.ChunkCRCs[i] = binary.LittleEndian.Uint32(data[4*i:])
+		}
+		data = data[4*count:]
+	}
+	if len(data) != 0 {
+		return fmt.Errorf("%w: %d bytes of trailing data", ErrChunkIndex, len(data))
+	}
+
+	if err := newIdx.validate(); err != nil {
+		return err
+	}
+	*idx = newIdx
+	return nil
+}
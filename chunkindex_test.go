@@ -0,0 +1,280 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestChunkIndex(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("chunk1chunk2chunk3chu")
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 6, WithChunkChecksums())
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	w.Name = "test.txt"
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+	idx := z.ChunkIndex()
+
+	testCases := map[string]struct {
+		roundTrip func(*testing.T, *ChunkIndex) *ChunkIndex
+	}{
+		"json": {
+			roundTrip: func(t *testing.T, idx *ChunkIndex) *ChunkIndex {
+				t.Helper()
+				b, err := json.Marshal(idx)
+				if err != nil {
+					t.Fatalf("json.Marshal: %v", err)
+				}
+				var got ChunkIndex
+				if err := json.Unmarshal(b, &got); err != nil {
+					t.Fatalf("json.Unmarshal: %v", err)
+				}
+				return &got
+			},
+		},
+		"binary": {
+			roundTrip: func(t *testing.T, idx *ChunkIndex) *ChunkIndex {
+				t.Helper()
+				b, err := idx.MarshalBinary()
+				if err != nil {
+					t.Fatalf("MarshalBinary: %v", err)
+				}
+				var got ChunkIndex
+				if err := got.UnmarshalBinary(b); err != nil {
+					t.Fatalf("UnmarshalBinary: %v", err)
+				}
+				return &got
+			},
+		},
+		"gob": {
+			roundTrip: func(t *testing.T, idx *ChunkIndex) *ChunkIndex {
+				t.Helper()
+				var b bytes.Buffer
+				if err := gob.NewEncoder(&b).Encode(idx); err != nil {
+					t.Fatalf("Encode: %v", err)
+				}
+				var got ChunkIndex
+				if err := gob.NewDecoder(&b).Decode(&got); err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				return &got
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.roundTrip(t, idx)
+			if diff := cmp.Diff(idx, got); diff != "" {
+				t.Fatalf("ChunkIndex (-want, +got):\n%s", diff)
+			}
+
+			// A Reader created with the index reads the archive without
+			// reading the header.
+			z, err := NewReader(bytes.NewReader(buf.Bytes()), WithChunkIndex(got))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			if diff := cmp.Diff("", z.Name); diff != "" {
+				t.Errorf("Name (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(idx.ChunkCRCs, z.ChunkCRCs()); diff != "" {
+				t.Errorf("ChunkCRCs (-want, +got):\n%s", diff)
+			}
+
+			b := make([]byte, 10)
+			n, err := z.ReadAtVerified(b, 4)
+			if err != nil {
+				t.Fatalf("ReadAtVerified: %v", err)
+			}
+			if diff := cmp.Diff(data[4:14], b[:n]); diff != "" {
+				t.Errorf("ReadAtVerified (-want, +got):\n%s", diff)
+			}
+
+			all, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(data, all); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestChunkIndex_castagnoli(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("chunk1chunk2chunk3chu")
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 6, WithCastagnoliChunkChecksums())
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+	idx := z.ChunkIndex()
+	if !idx.ChunkCRCCastagnoli {
+		t.Fatalf("ChunkCRCCastagnoli: got false, want true")
+	}
+
+	b, err := idx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	var got ChunkIndex
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if diff := cmp.Diff(idx, &got); diff != "" {
+		t.Fatalf("ChunkIndex (-want, +got):\n%s", diff)
+	}
+
+	// The chunks are verified using the Castagnoli polynomial.
+	z, err = NewReader(bytes.NewReader(buf.Bytes()), WithChunkIndex(&got))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+	p := make([]byte, 10)
+	if _, err := z.ReadAtVerified(p, 4); err != nil {
+		t.Fatalf("ReadAtVerified: %v", err)
+	}
+	if diff := cmp.Diff(data[4:14], p); diff != "" {
+		t.Errorf("ReadAtVerified (-want, +got):\n%s", diff)
+	}
+
+	// The polynomial is invalid without checksums.
+	_, err = (&ChunkIndex{
+		HeaderSize:         idx.HeaderSize,
+		ChunkSize:          idx.ChunkSize,
+		Sizes:              idx.Sizes,
+		ChunkCRCCastagnoli: true,
+	}).MarshalBinary()
+	if diff := cmp.Diff(ErrChunkIndex, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("MarshalBinary (-want, +got):\n%s", diff)
+	}
+}
+
+func TestChunkIndex_UnmarshalBinary(t *testing.T) {
+	t.Parallel()
+
+	valid, err := (&ChunkIndex{
+		HeaderSize: 30,
+		ChunkSize:  100,
+		Sizes:      []int{50, 20},
+		ChunkCRCs:  []uint32{1, 2},
+	}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	testCases := map[string]struct {
+		data []byte
+	}{
+		"empty": {
+			data: nil,
+		},
+		"bad magic": {
+			data: append([]byte("DZXX"), valid[4:]...),
+		},
+		"bad version": {
+			data: append([]byte("DZCI\x02"), valid[5:]...),
+		},
+		"truncated sizes": {
+			data: valid[:9],
+		},
+		"truncated CRCs": {
+			data: valid[:len(valid)-1],
+		},
+		"trailing data": {
+			data: append(append([]byte(nil), valid...), 0),
+		},
+		"header size": {
+			// NOTE: The header is at least 10 bytes.
+			data: []byte("DZCI\x01\x00\x05\x64\x00"),
+		},
+		"chunk size": {
+			data: []byte("DZCI\x01\x00\x1e\x80\x80\x04\x00"),
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var idx ChunkIndex
+			err := idx.UnmarshalBinary(tc.data)
+			if diff := cmp.Diff(ErrChunkIndex, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("UnmarshalBinary (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithChunkIndex_invalid(t *testing.T) {
+	t.Parallel()
+
+	data := mustCompress(t, []byte("Hello, index!"))
+	_, err := NewReader(bytes.NewReader(data), WithChunkIndex(&ChunkIndex{
+		HeaderSize: 30,
+		ChunkSize:  100,
+		Sizes:      []int{50},
+		ChunkCRCs:  []uint32{1, 2},
+	}))
+	if diff := cmp.Diff(ErrChunkIndex, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("NewReader (-want, +got):\n%s", diff)
+	}
+}
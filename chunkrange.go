@@ -0,0 +1,138 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrChunkRange indicates that a chunk index passed to [Reader.ChunkRange] is
+// out of range.
+var ErrChunkRange = fmt.Errorf("%w: chunk index out of range", errDictzip)
+
+// ChunkRange maps the chunk with index i to its location in the compressed
+// file and in the uncompressed data. It returns the offset and length of the
+// compressed chunk and the offset and length of its uncompressed data. With
+// [WithMultistream] chunks are numbered across all members in file order, as
+// with [Reader.ChunkSections], and the offsets are those in the file and in
+// the uncompressed data of all members.
+//
+// The last chunk is inflated to determine its uncompressed length the first
+// time it is mapped. See [Reader.LastChunkLen]. ChunkRange returns an error
+// wrapping [ErrChunkRange] if i is not in [0, ChunkCount()).
+func (z *Reader) ChunkRange(i int) (compOff, compLen, uncompOff, uncompLen int64, err error) {
+	z.lock()
+	defer z.unlock()
+
+	if z.members != nil {
+		m, j, err := z.memberChunk(i)
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+		compOff, compLen, uncompOff, uncompLen, err = m.r.ChunkRange(j)
+		return m.Offset + compOff, compLen, m.Start + uncompOff, uncompLen, err
+	}
+	return z.chunkRange(i)
+}
+
+// ChunkCount returns the number of dictzip chunks. With [WithMultistream] it
+// is the number of chunks of all members while [Header.ChunkCount] is the
+// number of chunks of the first member.
+func (z *Reader) ChunkCount() int {
+	z.lock()
+	defer z.unlock()
+
+	if z.members != nil {
+		var n int
+		for _, m := range z.members {
+			n += m.r.Header.ChunkCount()
+		}
+		return n
+	}
+	return len(z.sizes)
+}
+
+// memberChunk returns the member of a multistream file holding the chunk with
+// index i and the index of the chunk in the member. The caller must hold the
+// lock.
+func (z *Reader) memberChunk(i int) (*member, int, error) {
+	j := i
+	var count int
+	for _, m := range z.members {
+		n := m.r.Header.ChunkCount()
+		if j >= 0 && j < n {
+			return m, j, nil
+		}
+		j -= n
+		count += n
+	}
+	return nil, 0, fmt.Errorf("%w: %d not in [0, %d)", ErrChunkRange, i, count)
+}
+
+// ReadChunk decompresses the chunk with index i into buf and returns the
+// number of bytes read. All chunks except the last are [Header.ChunkSize]
+// bytes long. It returns io.ErrShortBuffer if buf is too small for the chunk
+// and an error wrapping [ErrChunkRange] if i is not in [0, ChunkCount()).
+// With [WithMultistream] chunks are numbered across all members as with
+// [Reader.ChunkRange].
+func (z *Reader) ReadChunk(i int, buf []byte) (int, error) {
+	z.lock()
+	defer z.unlock()
+
+	if z.members != nil {
+		m, j, err := z.memberChunk(i)
+		if err != nil {
+			return 0, err
+		}
+		return m.r.ReadChunk(j, buf)
+	}
+
+	_, _, off, n, err := z.chunkRange(i)
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(buf)) < n {
+		return 0, io.ErrShortBuffer
+	}
+
+	read, err := z.readInto(buf[:n], off)
+	if errors.Is(err, io.EOF) && int64(read) == n {
+		err = nil
+	}
+	return read, err
+}
+
+// chunkRange implements ChunkRange. The caller must hold the lock.
+func (z *Reader) chunkRange(i int) (compOff, compLen, uncompOff, uncompLen int64, err error) {
+	if i < 0 || i >= len(z.sizes) {
+		return 0, 0, 0, 0, fmt.Errorf("%w: %d not in [0, %d)", ErrChunkRange, i, len(z.sizes))
+	}
+
+	uncompLen = int64(z.chunkSize)
+	if i == len(z.sizes)-1 {
+		lastLen, err := z.lastChunk()
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+		uncompLen = int64(lastLen)
+	}
+
+	compOff = z.offsets.at(i)
+	compLen = z.offsets.at(i+1) - z.offsets.at(i)
+	uncompOff = int64(i) * int64(z.chunkSize)
+	return compOff, compLen, uncompOff, uncompLen, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReader_ChunkRange(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("chunk1chunk2chu")
+	b := compressStream(t, data, 6)
+	z, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	offsets := z.Offsets()
+	for i := 0; i < z.ChunkCount(); i++ {
+		compOff, compLen, uncompOff, uncompLen, err := z.ChunkRange(i)
+		if err != nil {
+			t.Fatalf("ChunkRange(%d): %v", i, err)
+		}
+		if diff := cmp.Diff(offsets[i], compOff); diff != "" {
+			t.Errorf("ChunkRange(%d) compOff (-want, +got):\n%s", i, diff)
+		}
+		if diff := cmp.Diff(int64(z.Sizes()[i]), compLen); diff != "" {
+			t.Errorf("ChunkRange(%d) compLen (-want, +got):\n%s", i, diff)
+		}
+
+		end := uncompOff + uncompLen
+		if end > int64(len(data)) {
+			t.Fatalf("ChunkRange(%d): uncompressed range [%d, %d) past end of data", i, uncompOff, end)
+		}
+		got := make([]byte, uncompLen)
+		if _, err := z.ReadAt(got, uncompOff); err != nil {
+			t.Fatalf("ReadAt: %v", err)
+		}
+		if diff := cmp.Diff(string(data[uncompOff:end]), string(got)); diff != "" {
+			t.Errorf("ChunkRange(%d) data (-want, +got):\n%s", i, diff)
+		}
+	}
+
+	_, _, uncompOff, uncompLen, err := z.ChunkRange(z.ChunkCount() - 1)
+	if err != nil {
+		t.Fatalf("ChunkRange: %v", err)
+	}
+	if diff := cmp.Diff(int64(len(data)), uncompOff+uncompLen); diff != "" {
+		t.Errorf("end of last chunk (-want, +got):\n%s", diff)
+	}
+
+	for _, i := range []int{-1, z.ChunkCount()} {
+		_, _, _, _, err := z.ChunkRange(i)
+		if diff := cmp.Diff(ErrChunkRange, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("ChunkRange(%d) (-want, +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestReader_ReadChunk(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("chunk1chunk2chu")
+
+	testCases := map[string][]WriterOption{
+		"independent":   nil,
+		"shared window": {WithSharedWindow()},
+	}
+
+	for name, opts := range testCases {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(compressStream(t, data, 6, opts...)))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			buf := make([]byte, z.ChunkSize())
+			for i, want := range []string{"chunk1", "chunk2", "chu"} {
+				n, err := z.ReadChunk(i, buf)
+				if err != nil {
+					t.Fatalf("ReadChunk(%d): %v", i, err)
+				}
+				if diff := cmp.Diff(want, string(buf[:n])); diff != "" {
+					t.Errorf("ReadChunk(%d) (-want, +got):\n%s", i, diff)
+				}
+			}
+
+			_, err = z.ReadChunk(0, buf[:5])
+			if diff := cmp.Diff(io.ErrShortBuffer, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ReadChunk short buffer (-want, +got):\n%s", diff)
+			}
+			// The last chunk only needs a buffer large enough for its data.
+			if _, err := z.ReadChunk(2, buf[:3]); err != nil {
+				t.Errorf("ReadChunk: last chunk: %v", err)
+			}
+			_, err = z.ReadChunk(3, buf)
+			if diff := cmp.Diff(ErrChunkRange, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ReadChunk out of range (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -19,9 +19,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/urfave/cli/v2"
+
+	"github.com/ianlewis/go-dictzip"
 )
 
 const (
@@ -33,6 +36,10 @@ const (
 
 	// ExitCodeUnknownError is the exit code for an unknown error.
 	ExitCodeUnknownError
+
+	// ExitCodeWarning is the exit code when some files were not compressed
+	// because the compressed file would be larger than the input.
+	ExitCodeWarning
 )
 
 // ErrDictzip is a parent error for all dictzip command errors.
@@ -41,6 +48,9 @@ var ErrDictzip = errors.New("dictzip")
 // ErrFlagParse is a flag parsing error.
 var ErrFlagParse = fmt.Errorf("%w: parsing flags", ErrDictzip)
 
+// errNotCompressed indicates that files were left uncompressed.
+var errNotCompressed = fmt.Errorf("%w: not compressed", ErrDictzip)
+
 // ErrUnsupported indicates a feature is unsupported.
 var ErrUnsupported = fmt.Errorf("%w: unsupported", ErrDictzip)
 
@@ -48,7 +58,7 @@ var ErrUnsupported = fmt.Errorf("%w: unsupported", ErrDictzip)
 func init() {
 	// Set the HelpFlag to a random name so that it isn't used. `cli` handles
 	// the flag with the root command such that it takes a command name argument
-	// but we don't use commands.
+	// but the root command takes file paths.
 	//
 	// This is done because `dictzip --help foo` will display a
 	// "command foo not found" error instead of the help.
@@ -94,7 +104,7 @@ func newDictzipApp() *cli.App {
 			},
 			&cli.BoolFlag{
 				Name:               "force",
-				Usage:              "force overwrite of output file",
+				Usage:              "force overwrite of output file and compression of files that would grow",
 				Aliases:            []string{"f"},
 				DisableDefaultText: true,
 			},
@@ -104,6 +114,12 @@ func newDictzipApp() *cli.App {
 				Aliases:            []string{"n"},
 				DisableDefaultText: true,
 			},
+			&cli.BoolFlag{
+				Name:               "restore-name",
+				Usage:              "when decompressing, restore the original filename and timestamp",
+				Aliases:            []string{"N"},
+				DisableDefaultText: true,
+			},
 			&cli.BoolFlag{
 				Name:               "keep",
 				Usage:              "do not delete original file",
@@ -112,16 +128,30 @@ func newDictzipApp() *cli.App {
 			},
 			&cli.BoolFlag{
 				Name:               "list",
-				Usage:              "list compressed file contents",
+				Usage:              "list compressed file contents (with --test, check the CRC-32)",
 				Aliases:            []string{"l"},
 				DisableDefaultText: true,
 			},
+			&cli.BoolFlag{
+				Name:               "gzip-list",
+				Usage:              "list compressed file contents in gzip -lv format",
+				DisableDefaultText: true,
+			},
 			&cli.BoolFlag{
 				Name:               "test",
 				Usage:              "test compressed file integrity",
 				Aliases:            []string{"t"},
 				DisableDefaultText: true,
 			},
+			&cli.IntFlag{
+				Name:  "test-chunks",
+				Usage: "test the header, trailer, and `N` randomly selected chunks of each file (implies --test)",
+			},
+			&cli.BoolFlag{
+				Name:               "mark-verified",
+				Usage:              "with --test, record verified files in the " + verifiedXattr + " extended attribute and skip unchanged verified files",
+				DisableDefaultText: true,
+			},
 			&cli.BoolFlag{
 				Name:               "license",
 				Usage:              "display software license",
@@ -141,6 +171,11 @@ func newDictzipApp() *cli.App {
 				Aliases:            []string{"v"},
 				DisableDefaultText: true,
 			},
+			&cli.BoolFlag{
+				Name:               "no-color",
+				Usage:              "disable colored output (also disabled by NO_COLOR)",
+				DisableDefaultText: true,
+			},
 
 			// NOTE: -D --debug flag is not supported.
 
@@ -157,6 +192,61 @@ func newDictzipApp() *cli.App {
 				DefaultText: "whole file",
 				Value:       -1,
 			},
+			&cli.IntFlag{
+				Name:  "level",
+				Usage: "compression `level` (0-9, -1 for default, -2 for Huffman only)",
+				Value: dictzip.DefaultCompression,
+			},
+			&cli.IntFlag{
+				Name:  "chunk-size",
+				Usage: "uncompressed chunk `size` (1-65535)",
+				Value: dictzip.DefaultChunkSize,
+			},
+			&cli.IntFlag{
+				Name:        "jobs",
+				Usage:       "number of `jobs` (threads) used for compression",
+				Aliases:     []string{"j", "threads"},
+				Value:       runtime.NumCPU(),
+				DefaultText: "number of CPUs",
+			},
+			&cli.BoolFlag{
+				Name:               "chunk-checksums",
+				Usage:              "write a CRC-32 checksum for each chunk",
+				DisableDefaultText: true,
+			},
+			&cli.BoolFlag{
+				Name:               "dictd",
+				Usage:              "write files compatible with dictd (overrides --chunk-size and --chunk-checksums)",
+				DisableDefaultText: true,
+			},
+			&cli.BoolFlag{
+				Name:               "wait",
+				Usage:              "wait for other dictzip processes compressing the same file to finish",
+				DisableDefaultText: true,
+			},
+			&cli.BoolFlag{
+				Name:               "resume",
+				Usage:              "checkpoint compression progress and resume an interrupted compression",
+				DisableDefaultText: true,
+			},
+			&cli.BoolFlag{
+				Name:               "store-incompressible",
+				Usage:              "store incompressible chunks without compression (lowers the default --chunk-size)",
+				DisableDefaultText: true,
+			},
+			&cli.StringFlag{
+				Name:  "index",
+				Usage: "dictd index `file` used with --word",
+			},
+			&cli.StringFlag{
+				Name:  "word",
+				Usage: "write the definition of `headword` found in the --index file, or in the databases in a directory, to stdout",
+			},
+			&cli.StringFlag{
+				Name:   "pprof",
+				Usage:  "write a CPU profile of compression or decompression to `file` and a heap profile to file.heap",
+				Hidden: true,
+			},
 			// TODO(#13): -S --Start <offset>  starting offset for decompression (base64)
 			// TODO(#13): -E --Size <offset>   size for decompression (base64)
 			// TODO(#13): -p --pre <filter>    pre-compression filter
@@ -176,6 +266,69 @@ func newDictzipApp() *cli.App {
 				DisableDefaultText: true,
 			},
 		},
+		Commands: []*cli.Command{
+			{
+				Name:      "inspect",
+				Usage:     "print the layout of a dictzip file",
+				ArgsUsage: "PATH...",
+				Action:    inspectCmd,
+			},
+			{
+				Name:      "stats",
+				Usage:     "print chunk statistics for a dictzip file",
+				ArgsUsage: "PATH...",
+				Action:    statsCmd,
+			},
+			{
+				Name:      "ls-chunks",
+				Usage:     "print the chunk table as tab separated chunk, offset, length, uncompressed offset, and uncompressed length columns",
+				ArgsUsage: "PATH",
+				Flags:     lsChunksFlags(),
+				Action:    lsChunksCmd,
+			},
+			{
+				Name:      "head",
+				Usage:     "print the first bytes or lines of the uncompressed data",
+				ArgsUsage: "PATH...",
+				Flags:     headTailFlags("first"),
+				Action:    headTailCmd(false),
+			},
+			{
+				Name:      "tail",
+				Usage:     "print the last bytes or lines of the uncompressed data",
+				ArgsUsage: "PATH...",
+				Flags:     headTailFlags("last"),
+				Action:    headTailCmd(true),
+			},
+			{
+				Name:      "doctor",
+				Usage:     "diagnose interoperability problems with dictzip files",
+				ArgsUsage: "PATH...",
+				Flags:     doctorFlags(),
+				Action:    doctorCmd,
+			},
+			{
+				Name:      "filter",
+				Usage:     "run a command on each uncompressed chunk and write a new dictzip file",
+				ArgsUsage: "PATH",
+				Flags:     filterFlags(),
+				Action:    filterCmd,
+			},
+			{
+				Name:      "send",
+				Usage:     "send dictzip files to a dictzip recv process, resuming after connection errors",
+				ArgsUsage: "HOST:PORT PATH...",
+				Flags:     sendFlags(),
+				Action:    sendCmd,
+			},
+			{
+				Name:      "recv",
+				Usage:     "receive a dictzip file sent by dictzip send into a directory",
+				ArgsUsage: "[DIR]",
+				Flags:     recvFlags(),
+				Action:    recvCmd,
+			},
+		},
 		ArgsUsage:       "[PATH]...",
 		Copyright:       "Google LLC",
 		HideHelp:        true,
@@ -194,10 +347,18 @@ func newDictzipApp() *cli.App {
 				return printLicense(c)
 			}
 
-			if c.Bool("list") || c.Bool("test") {
+			if c.Bool("list") || c.Bool("gzip-list") {
 				return listCmd(c)
 			}
 
+			if c.Bool("test") || c.IsSet("test-chunks") {
+				return testCmd(c)
+			}
+
+			if c.IsSet("word") {
+				return lookupCmd(c)
+			}
+
 			// If --start or --size are specified --decompress is implied.
 			if c.IsSet("start") || c.IsSet("size") {
 				if err := c.Set("decompress", "true"); err != nil {
@@ -207,11 +368,11 @@ func newDictzipApp() *cli.App {
 
 			// decompress
 			if c.Bool("decompress") {
-				return decompressCmd(c)
+				return withProfile(c, decompressCmd)
 			}
 
 			// compress
-			return compressCmd(c)
+			return withProfile(c, compressCmd)
 		},
 		ExitErrHandler: func(c *cli.Context, err error) {
 			if err == nil {
@@ -224,6 +385,10 @@ func newDictzipApp() *cli.App {
 				cli.OsExiter(ExitCodeFlagParseError)
 				return
 			}
+			if errors.Is(err, errNotCompressed) {
+				cli.OsExiter(ExitCodeWarning)
+				return
+			}
 
 			cli.OsExiter(ExitCodeUnknownError)
 		},
@@ -231,9 +396,67 @@ func newDictzipApp() *cli.App {
 }
 
 func listCmd(c *cli.Context) error {
+	out := newOutput(c, c.App.Writer)
+	if c.Bool("gzip-list") {
+		return gzipListCmd(c, out)
+	}
+	for _, path := range c.Args().Slice() {
+		l := list{
+			path: path,
+			out:  out,
+			// NOTE: --list may be combined with --test to check the CRC-32.
+			verify: c.Bool("test"),
+		}
+		if err := l.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gzipListCmd lists files in the same format as gzip -lv.
+func gzipListCmd(c *cli.Context, out *output) error {
+	g := gzipList{out: out}
+	g.printHeader()
 	for _, path := range c.Args().Slice() {
 		l := list{
 			path: path,
+			out:  out,
+		}
+		e, err := l.read()
+		if err != nil {
+			return err
+		}
+		g.print(path, e)
+	}
+	g.printTotals()
+	return nil
+}
+
+func lookupCmd(c *cli.Context) error {
+	for _, path := range c.Args().Slice() {
+		fInfo, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("%w: stat %q: %w", ErrDictzip, path, err)
+		}
+
+		// If given a directory, look up the word in every database found in
+		// the directory.
+		if fInfo.IsDir() {
+			if err := lookupDirCmd(c, path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !c.IsSet("index") {
+			return fmt.Errorf("%w: --word requires --index", ErrFlagParse)
+		}
+		l := lookup{
+			path:      path,
+			indexPath: c.String("index"),
+			word:      c.String("word"),
+			w:         c.App.Writer,
 		}
 		if err := l.Run(); err != nil {
 			return err
@@ -242,19 +465,122 @@ func listCmd(c *cli.Context) error {
 	return nil
 }
 
+// lookupDirCmd looks up the word in the databases found in dir. It is an
+// error only if the word is not found in any database.
+func lookupDirCmd(c *cli.Context, dir string) error {
+	dbs, err := discoverDatabases(dir)
+	if err != nil {
+		return err
+	}
+
+	var found bool
+	for _, db := range dbs {
+		l := lookup{
+			path:      db.dictPath,
+			indexPath: db.indexPath,
+			word:      c.String("word"),
+			w:         c.App.Writer,
+		}
+		err := l.Run()
+		if errors.Is(err, errWordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("%w: %q", errWordNotFound, c.String("word"))
+	}
+	return nil
+}
+
+func testCmd(c *cli.Context) error {
+	if c.IsSet("test-chunks") && c.Int("test-chunks") <= 0 {
+		return fmt.Errorf("%w: --test-chunks must be positive: %d", ErrFlagParse, c.Int("test-chunks"))
+	}
+	out := newOutput(c, c.App.Writer)
+	for _, path := range c.Args().Slice() {
+		t := test{
+			path:   path,
+			out:    out,
+			chunks: c.Int("test-chunks"),
+
+			markVerified: c.Bool("mark-verified"),
+		}
+		if err := t.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func inspectCmd(c *cli.Context) error {
+	for _, path := range c.Args().Slice() {
+		i := inspect{
+			path: path,
+			w:    c.App.Writer,
+		}
+		if err := i.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func compressCmd(c *cli.Context) error {
+	// NOTE: Verbose output is diagnostic and is written to stderr as is
+	// done by gzip(1).
+	out := newOutput(c, c.App.ErrWriter)
+	chunkSize := c.Int("chunk-size")
+	if c.Bool("dictd") {
+		chunkSize = dictzip.DictdChunkSize
+	}
+	if c.Bool("store-incompressible") && chunkSize > dictzip.MaxStoredChunkSize {
+		// NOTE: Stored chunks larger than MaxStoredChunkSize don't fit in
+		// the 16-bit chunk size field so the default chunk size is lowered.
+		if c.IsSet("chunk-size") {
+			return fmt.Errorf("%w: --chunk-size must be at most %d with --store-incompressible",
+				ErrFlagParse, dictzip.MaxStoredChunkSize)
+		}
+		chunkSize = dictzip.MaxStoredChunkSize
+	}
+	// NOTE: As with gzip, the remaining files are compressed if a file is
+	// left uncompressed and the exit status reflects that afterwards.
+	var skipped int
 	for _, path := range c.Args().Slice() {
 		c := compress{
-			path:    path,
-			force:   c.Bool("force"),
-			noName:  c.Bool("no-name"),
-			keep:    c.Bool("keep"),
-			verbose: c.Bool("verbose"),
+			path:      path,
+			force:     c.Bool("force"),
+			noName:    c.Bool("no-name"),
+			keep:      c.Bool("keep"),
+			verbose:   c.Bool("verbose"),
+			level:     c.Int("level"),
+			chunkSize: chunkSize,
+			levelSet:  setOnCommandLine(c, "level"),
+			jobs:      c.Int("jobs"),
+			store:     c.Bool("store-incompressible"),
+			checksums: c.Bool("chunk-checksums"),
+			dictd:     c.Bool("dictd"),
+			wait:      c.Bool("wait"),
+			resume:    c.Bool("resume"),
+			create:    createFile,
+			out:       out,
 		}
-		if err := c.Run(); err != nil {
+		err := c.Run()
+		if errors.Is(err, errNotCompressed) {
+			skipped++
+			continue
+		}
+		if err != nil {
 			return err
 		}
 	}
+	if skipped > 0 {
+		return fmt.Errorf("%w: %d of %d files", errNotCompressed, skipped, c.NArg())
+	}
 	return nil
 }
 
@@ -266,15 +592,22 @@ func decompressCmd(c *cli.Context) error {
 		}
 	}
 
+	// NOTE: Verbose output is written to stderr so that it is not mixed with
+	// decompressed data written to stdout.
+	out := newOutput(c, c.App.ErrWriter)
 	for _, path := range c.Args().Slice() {
 		d := decompress{
 			path:    path,
 			force:   c.Bool("force"),
 			keep:    c.Bool("keep"),
 			stdout:  c.Bool("stdout"),
+			w:       c.App.Writer,
 			verbose: c.Bool("verbose"),
 			start:   c.Int64("start"),
 			size:    c.Int64("size"),
+			out:     out,
+
+			restoreName: c.Bool("restore-name"),
 		}
 		if err := d.Run(); err != nil {
 			return err
@@ -282,3 +615,17 @@ func decompressCmd(c *cli.Context) error {
 	}
 	return nil
 }
+
+func statsCmd(c *cli.Context) error {
+	out := newOutput(c, c.App.Writer)
+	for _, path := range c.Args().Slice() {
+		s := stats{
+			path: path,
+			out:  out,
+		}
+		if err := s.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,890 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ianlewis/go-dictzip"
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// runApp runs the dictzip command with the given arguments and returns the
+// data written to stdout and stderr.
+func runApp(t *testing.T, args ...string) (string, string) {
+	t.Helper()
+
+	stdout, stderr, err := runAppErr(args...)
+	if err != nil {
+		t.Fatalf("dictzip %s: %v", strings.Join(args, " "), err)
+	}
+	return stdout, stderr
+}
+
+// runAppErr runs the dictzip command with the given arguments and returns the
+// data written to stdout and stderr and the error returned by the command.
+func runAppErr(args ...string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	app := newDictzipApp()
+	app.Writer = &stdout
+	app.ErrWriter = &stderr
+	app.ExitErrHandler = func(_ *cli.Context, _ error) {}
+	err := app.Run(append([]string{"dictzip"}, args...))
+	return stdout.String(), stderr.String(), err
+}
+
+func TestApp_stdout(t *testing.T) {
+	t.Parallel()
+
+	data := strings.Repeat("dictzip stdout test\n", 200)
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	// Verbose output for compression is written to stderr.
+	stdout, stderr := runApp(t, "--keep", "--verbose", "--chunk-size", "1000", path)
+	if diff := cmp.Diff("", stdout); diff != "" {
+		t.Errorf("compress stdout (-want, +got):\n%s", diff)
+	}
+	if !strings.Contains(stderr, "4000 bytes total") {
+		t.Errorf("compress stderr: missing verbose output: %q", stderr)
+	}
+
+	// Only decompressed data is written to stdout.
+	stdout, stderr = runApp(t, "--decompress", "--stdout", "--verbose", path+".dz")
+	if diff := cmp.Diff(data, stdout); diff != "" {
+		t.Errorf("decompress stdout (-want, +got):\n%s", diff)
+	}
+	if !strings.Contains(stderr, "4000 bytes total") {
+		t.Errorf("decompress stderr: missing verbose output: %q", stderr)
+	}
+
+	stdout, stderr = runApp(t, "--decompress", "--stdout", "--start", "20", "--size", "19", path+".dz")
+	if diff := cmp.Diff("dictzip stdout test", stdout); diff != "" {
+		t.Errorf("decompress range stdout (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("", stderr); diff != "" {
+		t.Errorf("decompress range stderr (-want, +got):\n%s", diff)
+	}
+
+	// The archive is kept when writing to stdout.
+	if _, err := os.Stat(path + ".dz"); err != nil {
+		t.Errorf("Stat: %v", err)
+	}
+}
+
+func TestApp_decompressRange(t *testing.T) {
+	t.Parallel()
+
+	var data strings.Builder
+	for i := 0; data.Len() < 5000; i++ {
+		fmt.Fprintf(&data, "line %d\n", i)
+	}
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte(data.String()), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	runApp(t, "--chunk-size", "1000", path)
+	path += ".dz"
+
+	// Corrupt the first chunk. Ranges in later chunks are read without
+	// inflating the data before them.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	z, err := dictzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	offsets := z.Offsets()
+	for i := offsets[0]; i < offsets[1]; i++ {
+		b[i] = 0xff
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	stdout, _ := runApp(t, "--stdout", "--start", "3000", "--size", "1500", path)
+	if diff := cmp.Diff(data.String()[3000:4500], stdout); diff != "" {
+		t.Errorf("decompress range (-want, +got):\n%s", diff)
+	}
+
+	if _, _, err := runAppErr("--stdout", "--start", "500", "--size", "10", path); err == nil {
+		t.Errorf("decompress corrupt range: expected error")
+	}
+}
+
+func TestApp_restoreName(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "original.txt")
+	if err := os.WriteFile(path, []byte("Hello World!\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	modTime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	runApp(t, "--force", path)
+
+	archive := filepath.Join(dir, "renamed.dz")
+	if err := os.Rename(path+".dz", archive); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	runApp(t, "-d", "--restore-name", archive)
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if diff := cmp.Diff("Hello World!\n", string(b)); diff != "" {
+		t.Errorf("restored data (-want, +got):\n%s", diff)
+	}
+	fInfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if !fInfo.ModTime().Equal(modTime) {
+		t.Errorf("restored modification time: want %v, got %v", modTime, fInfo.ModTime())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "renamed")); err == nil {
+		t.Errorf("default output file was created")
+	}
+}
+
+func TestApp_restoreNameUnsafe(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	z, err := dictzip.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	z.Name = "../aux.txt"
+	if _, err := z.Write([]byte("Hello World!\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "sub")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	archive := filepath.Join(dir, "test.txt.dz")
+	if err := os.WriteFile(archive, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	_, stderr := runApp(t, "-d", "--restore-name", archive)
+	if !strings.Contains(stderr, "not restoring name") {
+		t.Errorf("restore unsafe name: expected warning, got %q", stderr)
+	}
+
+	// The output is written to the default path.
+	b, err := os.ReadFile(filepath.Join(dir, "test.txt"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if diff := cmp.Diff("Hello World!\n", string(b)); diff != "" {
+		t.Errorf("restored data (-want, +got):\n%s", diff)
+	}
+	for _, path := range []string{filepath.Join(filepath.Dir(dir), "aux.txt"), filepath.Join(dir, "aux.txt")} {
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("unsafe name %q was restored", path)
+		}
+	}
+}
+
+func TestApp_lsChunks(t *testing.T) {
+	t.Parallel()
+
+	var data strings.Builder
+	for i := 0; data.Len() < 5000; i++ {
+		fmt.Fprintf(&data, "line %d\n", i)
+	}
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte(data.String()), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	runApp(t, "--chunk-size", "1000", path)
+	path += ".dz"
+
+	stdout, _ := runApp(t, "ls-chunks", "--header", path)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if diff := cmp.Diff("chunk\toffset\tlength\tuncompressed_offset\tuncompressed_length", lines[0]); diff != "" {
+		t.Errorf("ls-chunks header (-want, +got):\n%s", diff)
+	}
+
+	// Each chunk's compressed range inflates to its uncompressed range.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if diff := cmp.Diff((data.Len()+999)/1000+1, len(lines)); diff != "" {
+		t.Fatalf("ls-chunks lines (-want, +got):\n%s", diff)
+	}
+	for i, line := range lines[1:] {
+		var chunk, off, length, uncompOff, uncompLen int
+		if _, err := fmt.Sscanf(line, "%d\t%d\t%d\t%d\t%d", &chunk, &off, &length, &uncompOff, &uncompLen); err != nil {
+			t.Fatalf("ls-chunks line %q: %v", line, err)
+		}
+		if chunk != i {
+			t.Errorf("ls-chunks line %q: want chunk %d", line, i)
+		}
+		got, err := io.ReadAll(flate.NewReader(bytes.NewReader(b[off : off+length])))
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("inflating chunk %d: %v", i, err)
+		}
+		if diff := cmp.Diff(data.String()[uncompOff:uncompOff+uncompLen], string(got)); diff != "" {
+			t.Errorf("chunk %d (-want, +got):\n%s", i, diff)
+		}
+	}
+
+	if _, _, err := runAppErr("ls-chunks", path, path); !errors.Is(err, ErrFlagParse) {
+		t.Errorf("ls-chunks with two files: want ErrFlagParse, got %v", err)
+	}
+}
+
+func TestApp_inspect(t *testing.T) {
+	t.Parallel()
+
+	stdout, stderr := runApp(t, "inspect", "../../internal/testdata/test.txt.dz")
+	if !strings.HasPrefix(stdout, "../../internal/testdata/test.txt.dz: ") {
+		t.Errorf("inspect stdout: unexpected output: %q", stdout)
+	}
+	if diff := cmp.Diff("", stderr); diff != "" {
+		t.Errorf("inspect stderr (-want, +got):\n%s", diff)
+	}
+}
+
+func TestApp_listCRC(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(strings.Repeat("Hello, World!\n", 100))
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	_, _ = runApp(t, "--keep", path)
+	crc := fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+
+	stdout, _ := runApp(t, "--list", path+".dz")
+	if !strings.Contains(stdout, " "+crc+" ") {
+		t.Errorf("list: missing CRC-32 %s: %q", crc, stdout)
+	}
+
+	stdout, _ = runApp(t, "--list", "--test", path+".dz")
+	if !strings.Contains(stdout, " "+crc+"  OK ") {
+		t.Errorf("list: missing CRC-32 check: %q", stdout)
+	}
+}
+
+func TestApp_gzipList(t *testing.T) {
+	t.Parallel()
+
+	// NOTE: The golden files are the output of gzip -lv for the files in
+	// testdata/gziplist with their modification time set to modTime in UTC.
+	modTime := time.Date(2024, time.November, 17, 12, 34, 56, 0, time.UTC)
+
+	testCases := map[string]struct {
+		files  []string
+		golden string
+	}{
+		"single": {
+			files:  []string{"tiny.txt.gz"},
+			golden: "single.golden",
+		},
+		"totals": {
+			files:  []string{"words.txt.gz", "tiny.txt.gz", "random.bin.gz", "words.dict.dz"},
+			golden: "all.golden",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			var paths []string
+			for _, name := range tc.files {
+				b, err := os.ReadFile(filepath.Join("testdata", "gziplist", name))
+				if err != nil {
+					t.Fatalf("ReadFile: %v", err)
+				}
+				path := filepath.Join(dir, name)
+				if err := os.WriteFile(path, b, 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+				if err := os.Chtimes(path, modTime, modTime); err != nil {
+					t.Fatalf("Chtimes: %v", err)
+				}
+				paths = append(paths, path)
+			}
+
+			golden, err := os.ReadFile(filepath.Join("testdata", "gziplist", tc.golden))
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			// The time is printed in the local time zone.
+			want := strings.ReplaceAll(string(golden),
+				modTime.Format("Jan _2 15:04"), modTime.Local().Format("Jan _2 15:04"))
+
+			stdout, _ := runApp(t, append([]string{"--gzip-list"}, paths...)...)
+			got := strings.ReplaceAll(stdout, dir+string(filepath.Separator), "")
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("gzip-list (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGzipName(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"file.txt.gz":  "file.txt",
+		"dir/file.gz":  "dir/file",
+		"FILE.GZ":      "FILE",
+		"file.Z":       "file",
+		"file-gz":      "file",
+		"file_z":       "file",
+		"file.tgz":     "file.tar",
+		"file.taz":     "file.tar",
+		"file.gz.gz":   "file.gz",
+		"file.dict.dz": "file.dict.dz",
+		".gz":          ".gz",
+		"dir/.gz":      "dir/.gz",
+		"uncompressed": "uncompressed",
+	}
+
+	for path, want := range testCases {
+		path, want := path, want
+		t.Run(path, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(want, gzipName(path)); diff != "" {
+				t.Errorf("gzipName(%q) (-want, +got):\n%s", path, diff)
+			}
+		})
+	}
+}
+
+func TestApp_testBadTrailer(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(strings.Repeat("dictzip bad trailer test\n", 200))
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	runApp(t, "--chunk-size", "1000", path)
+	path += ".dz"
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	// Corrupt the CRC-32 in the trailer.
+	b[len(b)-8] ^= 0xff
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	_, _, err = runAppErr("--test", path)
+	if diff := cmp.Diff(dictzip.ErrTrailer, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("test (-want, +got):\n%s", diff)
+	}
+}
+
+func TestApp_testChunks(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		n    string
+		want string
+		err  error
+	}{
+		"sample": {
+			n:    "3",
+			want: "(3 of 5 chunks tested)",
+		},
+		"all": {
+			n:    "10",
+			want: "(5 of 5 chunks tested)",
+		},
+		"zero": {
+			n:   "0",
+			err: ErrFlagParse,
+		},
+		"negative": {
+			n:   "-1",
+			err: ErrFlagParse,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data := []byte(strings.Repeat("dictzip test chunks test\n", 200))
+			path := filepath.Join(t.TempDir(), "test.txt")
+			if err := os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			runApp(t, "--chunk-size", "1000", path)
+
+			stdout, _, err := runAppErr("--test-chunks", tc.n, path+".dz")
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("test (-want, +got):\n%s", diff)
+			}
+			if !strings.Contains(stdout, tc.want) {
+				t.Errorf("test stdout: got %q, want %q", stdout, tc.want)
+			}
+		})
+	}
+}
+
+func TestApp_testChunksCorrupt(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(strings.Repeat("dictzip corrupt chunk test\n", 200))
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	runApp(t, "--chunk-size", "1000", "--chunk-checksums", path)
+	path += ".dz"
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	h, off, err := format.Parse(b)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// Corrupt the middle of the third chunk.
+	for _, size := range h.Sizes[:2] {
+		off += size
+	}
+	b[off+h.Sizes[2]/2] ^= 0xff
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	// NOTE: All chunks are sampled so the corrupt chunk is tested.
+	stdout, _, err := runAppErr("--test-chunks", fmt.Sprint(len(h.Sizes)), path)
+	if diff := cmp.Diff(ErrDictzip, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("test (-want, +got):\n%s", diff)
+	}
+	if !strings.Contains(stdout, "FAILED") {
+		t.Errorf("test stdout: missing FAILED: %q", stdout)
+	}
+}
+
+func TestSampleChunks(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		n, total int
+		want     int
+	}{
+		"sample":    {n: 3, total: 10, want: 3},
+		"all":       {n: 10, total: 10, want: 10},
+		"too many":  {n: 20, total: 10, want: 10},
+		"no chunks": {n: 3, total: 0, want: 0},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := sampleChunks(tc.n, tc.total)
+			if diff := cmp.Diff(tc.want, len(got)); diff != "" {
+				t.Errorf("sampleChunks length (-want, +got):\n%s", diff)
+			}
+			seen := map[int]bool{}
+			for _, i := range got {
+				if i < 0 || i >= tc.total {
+					t.Errorf("sampleChunks: chunk %d out of range", i)
+				}
+				if seen[i] {
+					t.Errorf("sampleChunks: chunk %d sampled twice", i)
+				}
+				seen[i] = true
+			}
+		})
+	}
+}
+
+func TestApp_markVerified(t *testing.T) {
+	t.Parallel()
+
+	data := strings.Repeat("dictzip mark verified test\n", 200)
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	runApp(t, "--chunk-size", "1000", path)
+	path += ".dz"
+
+	// Check that the file system supports user extended attributes.
+	if err := setXattr(path, verifiedXattr, nil); err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+
+	stdout, _ := runApp(t, "--test", "--mark-verified", path)
+	if diff := cmp.Diff(path+": OK\n", stdout); diff != "" {
+		t.Errorf("first test (-want, +got):\n%s", diff)
+	}
+	if _, ok := verifiedAt(path); !ok {
+		t.Fatalf("verifiedAt: file not marked as verified")
+	}
+
+	stdout, _ = runApp(t, "--test", "--mark-verified", path)
+	if !strings.HasPrefix(stdout, path+": OK (verified ") {
+		t.Errorf("second test: file not skipped: %q", stdout)
+	}
+
+	// Modifying the file invalidates the mark.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	if _, err := f.Write([]byte{0}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, ok := verifiedAt(path); ok {
+		t.Errorf("verifiedAt: modified file marked as verified")
+	}
+}
+
+func TestApp_threads(t *testing.T) {
+	t.Parallel()
+
+	data := strings.Repeat("dictzip threads test\n", 500)
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	_, stderr := runApp(t, "--keep", "--verbose", "--threads", "2", "--chunk-size", "1000", path)
+	if !strings.Contains(stderr, "throughput") {
+		t.Errorf("compress stderr: missing thread output: %q", stderr)
+	}
+
+	stdout, _ := runApp(t, "--decompress", "--stdout", path+".dz")
+	if diff := cmp.Diff(data, stdout); diff != "" {
+		t.Errorf("decompress stdout (-want, +got):\n%s", diff)
+	}
+}
+
+func TestApp_headTail(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	for i := 1; i <= 1000; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	data := strings.Join(lines, "\n")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.txt")
+	if err := os.WriteFile(path, []byte(data+"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	runApp(t, "--chunk-size", "1000", path)
+	path += ".dz"
+
+	// noNewline has no newline at the end of the data.
+	noNewline := filepath.Join(dir, "nonewline.txt")
+	if err := os.WriteFile(noNewline, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	runApp(t, "--chunk-size", "1000", noNewline)
+	noNewline += ".dz"
+
+	testCases := map[string]struct {
+		args []string
+		want string
+	}{
+		"head": {
+			args: []string{"head", path},
+			want: strings.Join(lines[:10], "\n") + "\n",
+		},
+		"head lines": {
+			args: []string{"head", "-n", "3", path},
+			want: "line 1\nline 2\nline 3\n",
+		},
+		"head bytes": {
+			args: []string{"head", "-c", "8", path},
+			want: "line 1\nl",
+		},
+		"head all": {
+			args: []string{"head", "-n", "2000", noNewline},
+			want: data,
+		},
+		"tail": {
+			args: []string{"tail", path},
+			want: strings.Join(lines[990:], "\n") + "\n",
+		},
+		"tail lines": {
+			args: []string{"tail", "-n", "2", path},
+			want: "line 999\nline 1000\n",
+		},
+		"tail no newline": {
+			args: []string{"tail", "-n", "2", noNewline},
+			want: "line 999\nline 1000",
+		},
+		"tail spanning chunks": {
+			args: []string{"tail", "-n", "300", path},
+			want: strings.Join(lines[700:], "\n") + "\n",
+		},
+		"tail bytes": {
+			args: []string{"tail", "-c", "5", path},
+			want: "1000\n",
+		},
+		"tail zero": {
+			args: []string{"tail", "-n", "0", path},
+			want: "",
+		},
+		"multiple": {
+			args: []string{"head", "-n", "1", path, noNewline},
+			want: "==> " + path + " <==\nline 1\n\n==> " + noNewline + " <==\nline 1\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stdout, _ := runApp(t, tc.args...)
+			if diff := cmp.Diff(tc.want, stdout); diff != "" {
+				t.Errorf("stdout (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// failWriter is an io.Writer that always fails.
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestApp_notCompressed(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	data := strings.Repeat("dictzip skip test\n", 200)
+	files := map[string]string{
+		"empty.txt": "",
+		"small.txt": "hi",
+		"large.txt": data,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	empty, small, large := filepath.Join(dir, "empty.txt"), filepath.Join(dir, "small.txt"), filepath.Join(dir, "large.txt")
+
+	// Files that would grow are left uncompressed with a warning and the
+	// remaining files are compressed.
+	_, stderr, err := runAppErr("--keep", empty, small, large)
+	if diff := cmp.Diff(errNotCompressed, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("compress (-want, +got):\n%s", diff)
+	}
+	for _, path := range []string{empty, small} {
+		if !strings.Contains(stderr, path+": not compressed") {
+			t.Errorf("compress stderr: missing warning for %q: %q", path, stderr)
+		}
+		if _, err := os.Stat(path + ".dz"); !os.IsNotExist(err) {
+			t.Errorf("Stat(%q): got %v, want not exist", path+".dz", err)
+		}
+	}
+	stdout, _ := runApp(t, "--decompress", "--stdout", large+".dz")
+	if diff := cmp.Diff(data, stdout); diff != "" {
+		t.Errorf("decompress stdout (-want, +got):\n%s", diff)
+	}
+
+	// With --force they are compressed.
+	runApp(t, "--keep", "--force", empty, small)
+	for _, path := range []string{empty, small} {
+		stdout, _ := runApp(t, "--decompress", "--stdout", path+".dz")
+		if diff := cmp.Diff(files[filepath.Base(path)], stdout); diff != "" {
+			t.Errorf("decompress stdout (-want, +got):\n%s", diff)
+		}
+	}
+}
+
+func TestApp_resume(t *testing.T) {
+	t.Parallel()
+
+	data := strings.Repeat("dictzip resume test\n", 500)
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	// Simulate an interrupted compression by checkpointing part of the data
+	// and failing to write the archive.
+	checkpoint := path + ".dz" + checkpointSuffix
+	z, err := dictzip.NewWriterLevel(failWriter{}, dictzip.DefaultCompression, 1000,
+		dictzip.WithCheckpoint(checkpoint, 2))
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := z.Write([]byte(data[:5500])); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := z.Close(); err == nil {
+		t.Fatalf("Close: expected error")
+	}
+
+	// An explicit --level must match the checkpoint.
+	_, _, err = runAppErr("--keep", "--resume", "--chunk-size", "1000", "--level", "1", path)
+	if diff := cmp.Diff(ErrDictzip, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("compress --level (-want, +got):\n%s", diff)
+	}
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Errorf("Stat(%q): %v", checkpoint, err)
+	}
+
+	_, stderr := runApp(t, "--keep", "--verbose", "--resume", "--chunk-size", "1000", path)
+	if !strings.Contains(stderr, "resuming at offset 4000") {
+		t.Errorf("compress stderr: missing resume output: %q", stderr)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("Stat(%q): got %v, want not exist", checkpoint, err)
+	}
+
+	stdout, _ := runApp(t, "--decompress", "--stdout", path+".dz")
+	if diff := cmp.Diff(data, stdout); diff != "" {
+		t.Errorf("decompress stdout (-want, +got):\n%s", diff)
+	}
+
+	// Without a checkpoint, --resume compresses from the start.
+	runApp(t, "--force", "--resume", "--chunk-size", "1000", path)
+	stdout, _ = runApp(t, "--decompress", "--stdout", path+".dz")
+	if diff := cmp.Diff(data, stdout); diff != "" {
+		t.Errorf("decompress stdout (-want, +got):\n%s", diff)
+	}
+}
+
+func TestApp_pprof(t *testing.T) {
+	// NOTE: Only one CPU profile may be running at a time so the test is not
+	// run in parallel.
+
+	data := strings.Repeat("dictzip pprof test\n", 500)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.txt")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	profile := filepath.Join(dir, "cpu.pprof")
+	runApp(t, "--pprof", profile, path)
+	for _, p := range []string{profile, profile + heapProfileSuffix} {
+		fInfo, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if fInfo.Size() == 0 {
+			t.Errorf("%s: profile is empty", p)
+		}
+	}
+
+	runApp(t, "-d", "--pprof", profile, path+".dz")
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if diff := cmp.Diff(data, string(got)); diff != "" {
+		t.Errorf("data (-want, +got):\n%s", diff)
+	}
+}
+
+func TestApp_storeIncompressible(t *testing.T) {
+	t.Parallel()
+
+	// NOTE: Random data is incompressible so chunks are stored.
+	data := make([]byte, 3*dictzip.DefaultChunkSize)
+	//nolint:gosec // a weak random number generator is fine for test data.
+	rand.New(rand.NewSource(1)).Read(data)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "random.bin")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	_, _, err := runAppErr("--store-incompressible", "--chunk-size", "65535", "--keep", "--force", path)
+	if diff := cmp.Diff(ErrFlagParse, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("explicit chunk size (-want, +got):\n%s", diff)
+	}
+
+	// The default chunk size is lowered so that stored chunks fit.
+	runApp(t, "--store-incompressible", "--keep", "--force", path)
+
+	f, err := os.Open(path + ".dz")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	z, err := dictzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+	if diff := cmp.Diff(dictzip.MaxStoredChunkSize, z.ChunkSize()); diff != "" {
+		t.Errorf("ChunkSize (-want, +got):\n%s", diff)
+	}
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Errorf("ReadAll: data does not match")
+	}
+}
@@ -15,8 +15,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
@@ -25,16 +27,49 @@ import (
 )
 
 type compress struct {
-	path    string
-	force   bool
-	noName  bool
-	keep    bool
-	verbose bool
+	path      string
+	force     bool
+	noName    bool
+	keep      bool
+	verbose   bool
+	level     int
+	levelSet  bool
+	chunkSize int
+	jobs      int
+	store     bool
+	checksums bool
+	dictd     bool
+	wait      bool
+	resume    bool
+
+	// create creates the target file with the given open flags.
+	create func(path string, flags int) (io.WriteCloser, error)
+
+	out *output
 }
 
-func (c *compress) Run() error {
+// checkpointSuffix is appended to the target path to get the path of the
+// checkpoint file written with --resume.
+const checkpointSuffix = ".checkpoint"
+
+// checkpointInterval is the number of chunks between checkpoints.
+const checkpointInterval = 1024
+
+func (c *compress) Run() (err error) {
 	newPath := c.path + ".dz"
 
+	// Prevent concurrent compression to the same target file.
+	lock, err := acquireLock(newPath, c.wait)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// NOTE: this sets the returned error in the deferred func.
+		if relErr := lock.Release(); err == nil {
+			err = relErr
+		}
+	}()
+
 	from, err := os.Open(c.path)
 	if err != nil {
 		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
@@ -53,40 +88,55 @@ func (c *compress) Run() error {
 		fName = filepath.Base(from.Name())
 	}
 
-	flags := os.O_CREATE | os.O_WRONLY
-	if !c.force {
-		// Do not overwrite existing files unless --force is specified.
+	// Do not overwrite existing files unless --force is specified. This is
+	// checked before compressing so that we fail early.
+	symlink, err := checkTarget(newPath, c.force)
+	if err != nil {
+		return err
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if !c.force || symlink {
+		// O_EXCL guarantees that a file created since the check, or
+		// the target of a symlink, is not overwritten.
 		flags |= os.O_EXCL
 	}
 
-	dst, err := os.OpenFile(newPath, flags, 0o644)
-	if err != nil {
-		return fmt.Errorf("%w: opening target file: %w", ErrDictzip, err)
-	}
+	// NOTE: The target file is only created once compressed data is written
+	// so that it is not left behind if the source can't be read.
+	dst := &lazyFile{path: newPath, flags: flags, symlink: symlink, create: c.create}
 	defer dst.Close()
 
-	uncompressedSize, sizes, err := c.compress(dst, from, fName, modTime)
+	uncompressedSize, sizes, stats, err := c.compress(dst, from, fName, modTime)
 	if err != nil {
+		// Remove the partially written target file.
+		if dst.f != nil {
+			_ = dst.Close()
+			_ = os.Remove(newPath)
+		}
 		return err
 	}
 
 	if c.verbose {
-		var compressedSize int64
-		for _, size := range sizes {
-			compressedSize += int64(size)
-		}
+		c.out.printChunks(sizes, uncompressedSize, c.chunkSize)
+		c.out.printWorkers(stats)
+	}
 
-		remaining := uncompressedSize
-		for i, size := range sizes {
-			chunkSize := int64(dictzip.DefaultChunkSize)
-			if remaining < chunkSize {
-				chunkSize = remaining
-			}
-			remaining -= chunkSize
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("%w: closing target file: %w", ErrDictzip, err)
+	}
 
-			fmt.Printf("chunk %d: %d -> %d (%.2f%%) of %d total\n", i+1, chunkSize, size,
-				(1-float64(size)/float64(chunkSize))*100, uncompressedSize)
+	if dst.n > uncompressedSize {
+		// As with gzip, files that would grow are left uncompressed unless
+		// --force is specified.
+		if !c.force {
+			c.out.warn("%s: not compressed, compressed file would be larger than input (%d > %d bytes)",
+				c.path, dst.n, uncompressedSize)
+			if err := os.Remove(newPath); err != nil {
+				return fmt.Errorf("%w: removing target file: %w", ErrDictzip, err)
+			}
+			return errNotCompressed
 		}
+		c.out.warn("%s: compressed file is larger than input (%d > %d bytes)", c.path, dst.n, uncompressedSize)
 	}
 
 	if !c.keep {
@@ -101,10 +151,21 @@ func (c *compress) Run() error {
 
 func (c *compress) compress(
 	dst io.Writer, src *os.File, name string, modTime time.Time,
-) (n int64, sizes []int, err error) {
-	z, err := dictzip.NewWriter(dst)
+) (n int64, sizes []int, stats []dictzip.WorkerStats, err error) {
+	// NOTE: File modification times that can't be stored in the header are
+	// clamped so that such files can still be compressed.
+	opts := []dictzip.WriterOption{dictzip.WithClampModTime()}
+	if c.store {
+		opts = append(opts, dictzip.WithStoreIncompressible())
+	}
+	if c.checksums {
+		opts = append(opts, dictzip.WithChunkChecksums())
+	}
+	if c.dictd {
+		opts = append(opts, dictzip.WithDictdCompatible())
+	}
+	z, off, err := c.newWriter(dst, opts)
 	if err != nil {
-		err = fmt.Errorf("%w: creating writer: %w", ErrDictzip, err)
 		return
 	}
 	z.ModTime = modTime
@@ -119,12 +180,134 @@ func (c *compress) compress(
 			return
 		}
 		sizes = z.Sizes()
+		stats = z.WorkerStats()
 	}()
 
-	n, err = io.Copy(z, src)
+	fInfo, err := src.Stat()
+	if err != nil {
+		err = fmt.Errorf("%w: stat %q: %w", ErrDictzip, src.Name(), err)
+		return
+	}
+
+	if off > fInfo.Size() {
+		err = fmt.Errorf("%w: checkpoint offset %d is beyond the end of %q", ErrDictzip, off, src.Name())
+		return
+	}
+	n, err = z.CompressFrom(io.NewSectionReader(src, off, fInfo.Size()-off), fInfo.Size()-off, c.jobs)
+	n += off
 	if err != nil {
 		err = fmt.Errorf("%w: decompressing file %q: %w", ErrDictzip, src.Name(), err)
 		return
 	}
 	return
 }
+
+// newWriter creates the writer for dst. With --resume, progress is
+// checkpointed and an existing checkpoint is resumed. newWriter returns the
+// offset of the input to compress from.
+func (c *compress) newWriter(dst io.Writer, opts []dictzip.WriterOption) (*dictzip.Writer, int64, error) {
+	if !c.resume {
+		z, err := dictzip.NewWriterLevel(dst, c.level, c.chunkSize, opts...)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: creating writer: %w", ErrDictzip, err)
+		}
+		return z, 0, nil
+	}
+
+	checkpoint := c.path + ".dz" + checkpointSuffix
+	_, err := os.Stat(checkpoint)
+	if errors.Is(err, fs.ErrNotExist) {
+		opts = append(opts, dictzip.WithCheckpoint(checkpoint, checkpointInterval))
+		z, err := dictzip.NewWriterLevel(dst, c.level, c.chunkSize, opts...)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: creating writer: %w", ErrDictzip, err)
+		}
+		return z, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: stat %q: %w", ErrDictzip, checkpoint, err)
+	}
+
+	z, off, err := dictzip.ResumeWriter(dst, checkpoint, opts...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: resuming: %w", ErrDictzip, err)
+	}
+	// NOTE: The chunk size is read from the checkpoint.
+	if z.ChunkSize() != c.chunkSize {
+		return nil, 0, fmt.Errorf("%w: resuming: checkpoint chunk size %d does not match %d",
+			ErrDictzip, z.ChunkSize(), c.chunkSize)
+	}
+	// NOTE: The level is also read from the checkpoint. An explicit
+	// --level that differs is an error rather than being ignored.
+	if c.levelSet && z.Level() != c.level {
+		return nil, 0, fmt.Errorf("%w: resuming: checkpoint compression level %d does not match --level %d",
+			ErrDictzip, z.Level(), c.level)
+	}
+	if c.verbose {
+		_ = must(fmt.Fprintf(c.out.w, "%s: resuming at offset %d\n", c.path, off))
+	}
+	return z, off, nil
+}
+
+// lazyFile is an [io.WriteCloser] that creates the file at path with the
+// given open flags when it is first written to.
+type lazyFile struct {
+	path  string
+	flags int
+
+	// symlink indicates that path is a symlink which is removed before the
+	// file is created.
+	symlink bool
+
+	// create creates the file.
+	create func(path string, flags int) (io.WriteCloser, error)
+
+	// f is the file. It is nil until the first write.
+	f io.WriteCloser
+
+	// n is the number of bytes written.
+	n int64
+}
+
+// Write implements [io.Writer.Write].
+func (l *lazyFile) Write(p []byte) (int, error) {
+	if l.f == nil {
+		if l.symlink {
+			if err := removeSymlink(l.path); err != nil {
+				return 0, err
+			}
+			l.symlink = false
+		}
+		f, err := l.create(l.path, l.flags)
+		if err != nil {
+			return 0, fmt.Errorf("%w: opening target file: %w", ErrDictzip, err)
+		}
+		l.f = f
+	}
+	n, err := l.f.Write(p)
+	l.n += int64(n)
+	//nolint:wrapcheck // error does not need to be wrapped
+	return n, err
+}
+
+// createFile creates the file at path with the given open flags.
+func createFile(path string, flags int) (io.WriteCloser, error) {
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		//nolint:wrapcheck // error does not need to be wrapped
+		return nil, err
+	}
+	return f, nil
+}
+
+// Close closes the file if it was created. It is safe to call Close more than
+// once.
+func (l *lazyFile) Close() error {
+	if l.f == nil {
+		return nil
+	}
+	f := l.f
+	l.f = nil
+	//nolint:wrapcheck // error does not need to be wrapped
+	return f.Close()
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+func TestCompress_unreadableSource(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		// setup creates the source at path.
+		setup func(t *testing.T, path string)
+	}{
+		"missing": {
+			setup: func(*testing.T, string) {},
+		},
+		"directory": {
+			setup: func(t *testing.T, path string) {
+				t.Helper()
+				if err := os.Mkdir(path, 0o755); err != nil {
+					t.Fatalf("Mkdir: %v", err)
+				}
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "source")
+			tc.setup(t, path)
+
+			_, _, err := runAppErr(path)
+			if diff := cmp.Diff(ErrDictzip, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("compress (-want, +got):\n%s", diff)
+			}
+			if _, err := os.Lstat(path + ".dz"); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Lstat(%q): got %v, want %v", path+".dz", err, os.ErrNotExist)
+			}
+		})
+	}
+}
+
+// errShortWrite is returned by shortFile after its limit is reached.
+var errShortWrite = errors.New("short write")
+
+// shortFile is an io.WriteCloser that fails once n bytes have been written.
+type shortFile struct {
+	io.WriteCloser
+	n int
+}
+
+func (f *shortFile) Write(p []byte) (int, error) {
+	if len(p) <= f.n {
+		n, err := f.WriteCloser.Write(p)
+		f.n -= n
+		return n, err
+	}
+	n, err := f.WriteCloser.Write(p[:f.n])
+	f.n -= n
+	if err == nil {
+		err = errShortWrite
+	}
+	return n, err
+}
+
+func TestCompress_writeFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "source")
+	data := make([]byte, 200000)
+	rand.New(rand.NewSource(1)).Read(data)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	// The target file is created and part of the archive is written before
+	// writing fails.
+	var created bool
+	c := compress{
+		path:      path,
+		level:     dictzip.DefaultCompression,
+		chunkSize: 1000,
+		jobs:      2,
+		create: func(path string, flags int) (io.WriteCloser, error) {
+			f, err := createFile(path, flags)
+			if err != nil {
+				return nil, err
+			}
+			created = true
+			return &shortFile{WriteCloser: f, n: 5000}, nil
+		},
+		out: &output{w: io.Discard, errW: io.Discard},
+	}
+	err := c.Run()
+	if diff := cmp.Diff(errShortWrite, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("Run (-want, +got):\n%s", diff)
+	}
+	if !created {
+		t.Fatalf("target file was not created")
+	}
+
+	// The partially written target is removed and the source is kept.
+	if _, err := os.Lstat(path + ".dz"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Lstat(%q): got %v, want %v", path+".dz", err, os.ErrNotExist)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if diff := cmp.Diff(data, got); diff != "" {
+		t.Errorf("source (-want, +got):\n%s", diff)
+	}
+}
@@ -15,11 +15,14 @@
 package main
 
 import (
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/ianlewis/go-dictzip"
 )
@@ -29,12 +32,21 @@ type decompress struct {
 	force   bool
 	keep    bool
 	stdout  bool
+	w       io.Writer
 	verbose bool
 	start   int64
 	size    int64
+	out     *output
+
+	// restoreName indicates that the output file is named after the name
+	// stored in the archive and given the stored modification time.
+	restoreName bool
 }
 
-var errTruncate = fmt.Errorf("%w: cannot truncate filename", ErrDictzip)
+var (
+	errTruncate = fmt.Errorf("%w: cannot truncate filename", ErrDictzip)
+	errNotGzip  = fmt.Errorf("%w: not in gzip format", ErrDictzip)
+)
 
 func (d *decompress) Run() error {
 	newPath := strings.TrimRight(d.path, filepath.Ext(d.path))
@@ -48,45 +60,51 @@ func (d *decompress) Run() error {
 	}
 	defer from.Close()
 
-	flags := os.O_CREATE | os.O_WRONLY
+	var modTime time.Time
+	if d.restoreName && !d.stdout {
+		newPath, modTime = d.restoredName(from, newPath)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
 	if !d.force {
 		// Do not overwrite existing files unless --force is specified.
 		flags |= os.O_EXCL
 	}
 
-	var dst io.WriteCloser
-
-	if d.stdout {
-		dst = os.Stdout
-	} else {
-		dst, err = os.OpenFile(newPath, flags, 0o644)
+	// dst is d.w if writing to stdout.
+	dst := d.w
+	if !d.stdout {
+		symlink, err := checkTarget(newPath, d.force)
+		if err != nil {
+			return err
+		}
+		if symlink {
+			if err := removeSymlink(newPath); err != nil {
+				return err
+			}
+			// NOTE: Don't follow a symlink created since it was removed.
+			flags |= os.O_EXCL
+		}
+		f, err := os.OpenFile(newPath, flags, 0o644)
 		if err != nil {
 			return fmt.Errorf("%w: opening target file: %w", ErrDictzip, err)
 		}
-		defer dst.Close()
+		defer f.Close()
+		dst = f
 	}
 
-	uncompressedSize, sizes, err := d.decompress(dst, from)
+	uncompressedSize, sizes, chunkSize, err := d.decompress(dst, from)
 	if err != nil {
 		return err
 	}
 
 	if d.verbose {
-		var compressedSize int64
-		for _, size := range sizes {
-			compressedSize += int64(size)
-		}
-
-		remaining := uncompressedSize
-		for i, size := range sizes {
-			chunkSize := int64(dictzip.DefaultChunkSize)
-			if remaining < chunkSize {
-				chunkSize = remaining
-			}
-			remaining -= chunkSize
+		d.out.printChunks(sizes, uncompressedSize, chunkSize)
+	}
 
-			fmt.Printf("chunk %d: %d -> %d (%.2f%%) of %d total\n", i+1, size, chunkSize,
-				(1-float64(size)/float64(chunkSize))*100, uncompressedSize)
+	if !modTime.IsZero() {
+		if err := os.Chtimes(newPath, modTime, modTime); err != nil {
+			return fmt.Errorf("%w: setting modification time: %w", ErrDictzip, err)
 		}
 	}
 
@@ -100,13 +118,58 @@ func (d *decompress) Run() error {
 	return nil
 }
 
-func (d *decompress) decompress(dst io.Writer, src *os.File) (n int64, sizes []int, err error) {
+// restoredName returns the path of the output file named after the NAME
+// header field of src, and the modification time stored in the MTIME header
+// field. defaultPath is returned if the archive has no name or the name is
+// unsafe.
+func (d *decompress) restoredName(src *os.File, defaultPath string) (string, time.Time) {
+	z, err := dictzip.NewReader(src, dictzip.WithGzipFallback())
+	if err != nil {
+		// NOTE: Errors reading the archive are reported when decompressing.
+		return defaultPath, time.Time{}
+	}
+	defer z.Close()
+
+	name, err := z.SafeName()
+	if err != nil {
+		if z.Name != "" {
+			d.out.warn("%s: not restoring name: %v", d.path, err)
+		}
+		return defaultPath, z.ModTime
+	}
+
+	newPath := filepath.Join(filepath.Dir(d.path), name)
+	if newPath == d.path {
+		d.out.warn("%s: not restoring name: name is the same as the archive", d.path)
+		return defaultPath, z.ModTime
+	}
+	return newPath, z.ModTime
+}
+
+func (d *decompress) decompress(dst io.Writer, src *os.File) (n int64, sizes []int, chunkSize int, err error) {
+	format, err := dictzip.DetectFormat(src)
+	if err != nil {
+		err = fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+		return
+	}
+	switch format {
+	case dictzip.FormatDictzip:
+	case dictzip.FormatGzip:
+		// Plain gzip files are decompressed sequentially.
+		n, err = d.decompressGzip(dst, src)
+		return
+	case dictzip.FormatUnknown:
+		err = fmt.Errorf("%w: %q", errNotGzip, src.Name())
+		return
+	}
+
 	z, err := dictzip.NewReader(src)
 	if err != nil {
 		err = fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
 		return
 	}
 	sizes = z.Sizes()
+	chunkSize = z.ChunkSize()
 	defer func() {
 		// NOTE: this sets the returned error in the deferred func.
 		clsErr := z.Close()
@@ -120,18 +183,42 @@ func (d *decompress) decompress(dst io.Writer, src *os.File) (n int64, sizes []i
 }
 
 func (d *decompress) seekCopy(dst io.Writer, src *dictzip.Reader) (int64, error) {
-	if _, err := src.Seek(d.start, io.SeekStart); err != nil {
-		return 0, fmt.Errorf("%w: Seek: %w", ErrDictzip, err)
+	var err error
+	var n int64
+	if d.start == 0 && d.size < 0 {
+		// NOTE: The trailer is verified when all data is read sequentially.
+		n, err = io.Copy(dst, src)
+	} else {
+		n, err = src.CopyRange(dst, d.start, d.size)
+	}
+
+	if err != nil {
+		return n, fmt.Errorf("%w: decompressing: %w", ErrDictzip, err)
+	}
+
+	return n, nil
+}
+
+// decompressGzip decompresses a plain gzip file. Random access is not
+// supported so data before the start offset is read and discarded.
+func (d *decompress) decompressGzip(dst io.Writer, src *os.File) (int64, error) {
+	z, err := gzip.NewReader(src)
+	if err != nil {
+		return 0, fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
+
+	// NOTE: Like Seek, skipping past the end of the data is not an error.
+	if _, err := io.CopyN(io.Discard, z, d.start); err != nil && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("%w: decompressing: %w", ErrDictzip, err)
 	}
 
-	var err error
 	var n int64
 	if d.size >= 0 {
-		n, err = io.CopyN(dst, src, d.size)
+		n, err = io.CopyN(dst, z, d.size)
 	} else {
-		n, err = io.Copy(dst, src)
+		n, err = io.Copy(dst, z)
 	}
-
 	if err != nil {
 		return n, fmt.Errorf("%w: decompressing: %w", ErrDictzip, err)
 	}
@@ -0,0 +1,215 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// optsEnv is the environment variable holding default options.
+const optsEnv = "DICTZIP_OPTS"
+
+// commandLineFlagsKey is the [cli.App] Metadata key holding the names of
+// the flags given on the command line. See [setOnCommandLine].
+const commandLineFlagsKey = "commandLineFlags"
+
+// defaultFlags are flags that may be given in the config file or in the
+// DICTZIP_OPTS environment variable. The value indicates whether the flag
+// takes a value. Flags that change the mode of operation are not allowed.
+var defaultFlags = map[string]bool{
+	"level":                true,
+	"chunk-size":           true,
+	"jobs":                 true,
+	"j":                    true,
+	"threads":              true,
+	"no-name":              false,
+	"n":                    false,
+	"restore-name":         false,
+	"N":                    false,
+	"keep":                 false,
+	"k":                    false,
+	"verbose":              false,
+	"v":                    false,
+	"no-color":             false,
+	"store-incompressible": false,
+	"wait":                 false,
+	"chunk-checksums":      false,
+	"dictd":                false,
+	"mark-verified":        false,
+	"resume":               false,
+}
+
+// configPath returns the path to the optional config file.
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("%w: config dir: %w", ErrDictzip, err)
+	}
+	return filepath.Join(dir, "dictzip", "config"), nil
+}
+
+// defaultArgs returns default command line arguments read from the config
+// file and the DICTZIP_OPTS environment variable. These should be inserted
+// before the arguments given on the command line so that command line
+// arguments take precedence. Similarly, the environment variable takes
+// precedence over the config file.
+//
+// The config file contains options separated by whitespace. Lines starting
+// with '#' are ignored.
+func defaultArgs() ([]string, error) {
+	var args []string
+
+	// NOTE: If the user config directory can't be determined we simply don't
+	// read the config file.
+	path, err := configPath()
+	if err == nil {
+		var fileArgs []string
+		fileArgs, err = readConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = validateDefaultArgs(path, fileArgs); err != nil {
+			return nil, err
+		}
+		args = append(args, fileArgs...)
+	}
+
+	envArgs := strings.Fields(os.Getenv(optsEnv))
+	if err = validateDefaultArgs(optsEnv, envArgs); err != nil {
+		return nil, err
+	}
+	args = append(args, envArgs...)
+
+	return args, nil
+}
+
+// withDefaults returns the arguments to run app with given the command line
+// arguments args, starting with the program name, and the default arguments
+// returned by [defaultArgs]. The defaults are inserted before the command
+// line arguments so that they can be overridden. The names of the flags in
+// args are recorded in the app Metadata so that flags given on the command
+// line can be told apart from defaults.
+func withDefaults(app *cli.App, args, defaults []string) []string {
+	if app.Metadata == nil {
+		app.Metadata = map[string]interface{}{}
+	}
+	app.Metadata[commandLineFlagsKey] = flagNames(args[1:])
+
+	newArgs := append([]string{args[0]}, defaults...)
+	return append(newArgs, args[1:]...)
+}
+
+// flagNames returns the names of the flags in args.
+func flagNames(args []string) map[string]bool {
+	names := map[string]bool{}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		names[name] = true
+	}
+	return names
+}
+
+// setOnCommandLine returns true if the flag name, or one of its aliases, was
+// given on the command line rather than only as a default from the config
+// file or DICTZIP_OPTS.
+func setOnCommandLine(c *cli.Context, name string) bool {
+	names, ok := c.App.Metadata[commandLineFlagsKey].(map[string]bool)
+	if !ok {
+		// NOTE: Defaults are only used if args are from withDefaults.
+		return c.IsSet(name)
+	}
+	aliases := []string{name}
+	for _, f := range c.App.Flags {
+		for _, n := range f.Names() {
+			if n == name {
+				aliases = f.Names()
+			}
+		}
+	}
+	for _, alias := range aliases {
+		if names[alias] {
+			return true
+		}
+	}
+	return false
+}
+
+// readConfig reads options from the config file at path. A missing config
+// file is not an error.
+func readConfig(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: reading config: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	var args []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		args = append(args, strings.Fields(line)...)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("%w: reading config: %w", ErrDictzip, err)
+	}
+
+	return args, nil
+}
+
+// validateDefaultArgs checks that args read from source only include flags
+// allowed as defaults.
+func validateDefaultArgs(source string, args []string) error {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("%w: %s: unexpected argument %q", ErrFlagParse, source, arg)
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		takesValue, ok := defaultFlags[name]
+		if !ok {
+			return fmt.Errorf("%w: %s: option not allowed: %q", ErrFlagParse, source, arg)
+		}
+
+		if takesValue && !hasValue {
+			// The value is the next argument.
+			i++
+			if i >= len(args) {
+				return fmt.Errorf("%w: %s: missing value for %q", ErrFlagParse, source, arg)
+			}
+		}
+	}
+	return nil
+}
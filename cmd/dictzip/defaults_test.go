@@ -0,0 +1,310 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+// runAppDefaults is like runAppErr but runs the command with the given
+// default arguments as if they were read from the config file or
+// DICTZIP_OPTS.
+func runAppDefaults(defaults []string, args ...string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	app := newDictzipApp()
+	app.Writer = &stdout
+	app.ErrWriter = &stderr
+	app.ExitErrHandler = func(_ *cli.Context, _ error) {}
+	err := app.Run(withDefaults(app, append([]string{"dictzip"}, args...), defaults))
+	return stdout.String(), stderr.String(), err
+}
+
+func TestReadConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		config *string
+		want   []string
+	}{
+		"missing": {},
+		"empty": {
+			config: strPtr(""),
+		},
+		"options": {
+			config: strPtr("--level 9\n--keep   --chunk-size=1000\n"),
+			want:   []string{"--level", "9", "--keep", "--chunk-size=1000"},
+		},
+		"comments": {
+			config: strPtr("# defaults\n  # indented comment\n--keep\n\n-v\n"),
+			want:   []string{"--keep", "-v"},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "config")
+			if tc.config != nil {
+				if err := os.WriteFile(path, []byte(*tc.config), 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			}
+			got, err := readConfig(path)
+			if err != nil {
+				t.Fatalf("readConfig: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("readConfig (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestValidateDefaultArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		args []string
+		err  error
+	}{
+		"none": {},
+		"allowed": {
+			args: []string{"--level", "9", "-k", "--chunk-size=1000", "-j", "4", "--dictd"},
+		},
+		"missing value": {
+			args: []string{"--keep", "--level"},
+			err:  ErrFlagParse,
+		},
+		"mode flag": {
+			args: []string{"--decompress"},
+			err:  ErrFlagParse,
+		},
+		"output flag": {
+			args: []string{"-c"},
+			err:  ErrFlagParse,
+		},
+		"force": {
+			args: []string{"--force"},
+			err:  ErrFlagParse,
+		},
+		"argument": {
+			args: []string{"--keep", "file.txt"},
+			err:  ErrFlagParse,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateDefaultArgs(optsEnv, tc.args)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("validateDefaultArgs (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // t.Setenv can't be used with t.Parallel.
+func TestDefaultArgs(t *testing.T) {
+	testCases := map[string]struct {
+		config string
+		env    string
+		want   []string
+		err    error
+	}{
+		"none": {},
+		"config": {
+			config: "--level 1\n",
+			want:   []string{"--level", "1"},
+		},
+		"env": {
+			env:  "--keep -v",
+			want: []string{"--keep", "-v"},
+		},
+		"env after config": {
+			config: "--level 1 --keep\n",
+			env:    "--level 9",
+			want:   []string{"--level", "1", "--keep", "--level", "9"},
+		},
+		"invalid config": {
+			config: "--stdout\n",
+			err:    ErrFlagParse,
+		},
+		"invalid env": {
+			env: "--test",
+			err: ErrFlagParse,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", dir)
+			t.Setenv("HOME", dir)
+			t.Setenv(optsEnv, tc.env)
+			if tc.config != "" {
+				path, err := configPath()
+				if err != nil {
+					t.Fatalf("configPath: %v", err)
+				}
+				if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+					t.Fatalf("MkdirAll: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(tc.config), 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			}
+
+			got, err := defaultArgs()
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("defaultArgs (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("defaultArgs (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApp_defaults(t *testing.T) {
+	t.Parallel()
+
+	data := strings.Repeat("dictzip defaults test\n", 500)
+
+	testCases := map[string]struct {
+		defaults      []string
+		args          []string
+		wantChunkSize int
+	}{
+		"default": {
+			defaults:      []string{"--chunk-size", "1000"},
+			wantChunkSize: 1000,
+		},
+		"command line takes precedence": {
+			defaults:      []string{"--chunk-size", "1000"},
+			args:          []string{"--chunk-size", "2000"},
+			wantChunkSize: 2000,
+		},
+		"last default takes precedence": {
+			defaults:      []string{"--chunk-size", "1000", "--chunk-size=3000"},
+			wantChunkSize: 3000,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "test.txt")
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			if _, _, err := runAppDefaults(tc.defaults, append(tc.args, path)...); err != nil {
+				t.Fatalf("dictzip: %v", err)
+			}
+
+			b, err := os.ReadFile(path + ".dz")
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			got, z := readDictzip(t, b)
+			if diff := cmp.Diff(data, got); diff != "" {
+				t.Errorf("data (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantChunkSize, z.ChunkSize()); diff != "" {
+				t.Errorf("ChunkSize (-want, +got):\n%s", diff)
+			}
+			// NOTE: --keep was not given so the input is removed.
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("Stat(%q): got %v, want not exist", path, err)
+			}
+		})
+	}
+}
+
+func TestApp_defaultsResumeLevel(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		defaults []string
+		args     []string
+		err      error
+	}{
+		"default level": {
+			defaults: []string{"--level", "1"},
+		},
+		"command line level": {
+			args: []string{"--level", "1"},
+			err:  ErrDictzip,
+		},
+		"command line level with default": {
+			defaults: []string{"--level", "1"},
+			args:     []string{"--level=1"},
+			err:      ErrDictzip,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data := strings.Repeat("dictzip resume test\n", 500)
+			path := filepath.Join(t.TempDir(), "test.txt")
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			checkpoint := path + ".dz" + checkpointSuffix
+			z, err := dictzip.NewWriterLevel(failWriter{}, dictzip.DefaultCompression, 1000,
+				dictzip.WithCheckpoint(checkpoint, 2))
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := z.Write([]byte(data[:5500])); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := z.Close(); err == nil {
+				t.Fatalf("Close: expected error")
+			}
+
+			// A --level given only as a default does not conflict with the
+			// level of the checkpoint.
+			args := append([]string{"--keep", "--resume", "--chunk-size", "1000"}, tc.args...)
+			_, _, err = runAppDefaults(tc.defaults, append(args, path)...)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("dictzip (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
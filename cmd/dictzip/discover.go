@@ -0,0 +1,72 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// database is a dictd database made up of an index file and a dictzip
+// archive.
+type database struct {
+	// name is the database name, which is the file name without extensions.
+	name string
+
+	// indexPath is the path to the dictd .index file.
+	indexPath string
+
+	// dictPath is the path to the dictzip .dict.dz file.
+	dictPath string
+}
+
+// discoverDatabases returns the databases in the directory dir, such as
+// /usr/share/dictd. A database is a NAME.index file with a matching
+// NAME.dict.dz file. Index files without a matching archive are ignored.
+// Databases are returned sorted by name.
+func discoverDatabases(dir string) ([]database, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading directory: %w", ErrDictzip, err)
+	}
+
+	var dbs []database
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".index")
+		if !ok || name == "" || e.IsDir() {
+			continue
+		}
+
+		dictPath := filepath.Join(dir, name+".dict.dz")
+		fInfo, err := os.Stat(dictPath)
+		if err != nil || fInfo.IsDir() {
+			continue
+		}
+
+		dbs = append(dbs, database{
+			name:      name,
+			indexPath: filepath.Join(dir, e.Name()),
+			dictPath:  dictPath,
+		})
+	}
+
+	if len(dbs) == 0 {
+		return nil, fmt.Errorf("%w: no databases found in %q", ErrDictzip, dir)
+	}
+
+	return dbs, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// newDatabaseDir creates a directory of dictd databases, an orphaned index
+// file without a matching archive, and other files that are not databases.
+// The orphaned index defines "apple" and "orphan".
+func newDatabaseDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	newDatabase(t, dir, "fruit", []definition{
+		{"apple", "apple: a round fruit\n"},
+		{"bank", "bank: a place where fruit is kept\n"},
+	})
+	newDatabase(t, dir, "geo", []definition{
+		{"bank", "bank: the land alongside a river\n"},
+		{"delta", "delta: land at the mouth of a river\n"},
+	})
+	_, dictPath := newDatabase(t, dir, "orphan", []definition{
+		{"apple", "apple: a computer company\n"},
+		{"orphan", "orphan: an index without a dictionary\n"},
+	})
+	if err := os.Remove(dictPath); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	// An archive without an index, an index whose archive is a directory,
+	// a directory named like an index, and an index with an empty name.
+	if err := os.WriteFile(filepath.Join(dir, "noindex.dict.dz"), nil, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dir.index"), nil, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "dir.dict.dz"), 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.index"), 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub.dict.dz"), nil, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".index"), nil, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".dict.dz"), nil, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return dir
+}
+
+func TestDiscoverDatabases(t *testing.T) {
+	t.Parallel()
+
+	t.Run("mixed", func(t *testing.T) {
+		t.Parallel()
+
+		dir := newDatabaseDir(t)
+		dbs, err := discoverDatabases(dir)
+		if err != nil {
+			t.Fatalf("discoverDatabases: %v", err)
+		}
+
+		want := []database{
+			{
+				name:      "fruit",
+				indexPath: filepath.Join(dir, "fruit.index"),
+				dictPath:  filepath.Join(dir, "fruit.dict.dz"),
+			},
+			{
+				name:      "geo",
+				indexPath: filepath.Join(dir, "geo.index"),
+				dictPath:  filepath.Join(dir, "geo.dict.dz"),
+			},
+		}
+		if diff := cmp.Diff(want, dbs, cmp.AllowUnexported(database{})); diff != "" {
+			t.Errorf("discoverDatabases (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("orphaned index only", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "orphan.index"), nil, 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		_, err := discoverDatabases(dir)
+		if diff := cmp.Diff(ErrDictzip, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("discoverDatabases (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := discoverDatabases(filepath.Join(t.TempDir(), "missing"))
+		if diff := cmp.Diff(ErrDictzip, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("discoverDatabases (-want, +got):\n%s", diff)
+		}
+	})
+}
+
+func TestApp_lookupDir(t *testing.T) {
+	t.Parallel()
+
+	dir := newDatabaseDir(t)
+
+	testCases := map[string]struct {
+		word string
+		want string
+		err  error
+	}{
+		"one database": {
+			word: "delta",
+			want: "delta: land at the mouth of a river\n",
+		},
+		"all databases": {
+			word: "bank",
+			want: "bank: a place where fruit is kept\nbank: the land alongside a river\n",
+		},
+		"orphaned index ignored": {
+			word: "apple",
+			want: "apple: a round fruit\n",
+		},
+		"only in orphaned index": {
+			word: "orphan",
+			err:  errWordNotFound,
+		},
+		"missing": {
+			word: "durian",
+			err:  errWordNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stdout, _, err := runAppErr("--word", tc.word, dir)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("lookup (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want, stdout); diff != "" {
+				t.Errorf("lookup stdout (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ianlewis/go-dictzip"
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// maxNameLen is the longest NAME that can be used as a file name on most
+// file systems.
+const maxNameLen = 255
+
+// errDoctor indicates that doctor found problems that were not fixed.
+var errDoctor = fmt.Errorf("%w: problems found", ErrDictzip)
+
+// severity is the severity of a problem found by doctor.
+type severity int
+
+const (
+	// severityWarning indicates that some clients may not read the archive
+	// correctly.
+	severityWarning severity = iota
+
+	// severityError indicates that common clients such as dictzip(1) and
+	// dictd(8) can not read the archive.
+	severityError
+)
+
+// finding is a problem found by doctor.
+type finding struct {
+	severity severity
+	problem  string
+	fix      string
+
+	// rewrite indicates that the problem is fixed by --rewrite.
+	rewrite bool
+}
+
+// doctor diagnoses common interoperability problems with dictzip files and
+// optionally rewrites them to fix the problems.
+type doctor struct {
+	path    string
+	rewrite bool
+	out     *output
+}
+
+func (d *doctor) Run() error {
+	findings, err := d.diagnose()
+	if err != nil {
+		return err
+	}
+
+	if len(findings) == 0 {
+		_ = must(fmt.Fprintf(d.out.w, "%s: %s (no problems found)\n", d.path, d.out.ok("OK")))
+		return nil
+	}
+
+	var rewrite bool
+	for _, f := range findings {
+		label := "warning"
+		if f.severity == severityError {
+			label = d.out.fail("error")
+		}
+		_ = must(fmt.Fprintf(d.out.w, "%s: %s: %s\n", d.path, label, f.problem))
+		_ = must(fmt.Fprintf(d.out.w, "  fix: %s\n", f.fix))
+		rewrite = rewrite || f.rewrite
+	}
+
+	if d.rewrite && rewrite {
+		if err := d.rewriteFile(); err != nil {
+			return err
+		}
+		_ = must(fmt.Fprintf(d.out.w, "%s: %s\n", d.path, d.out.ok("rewritten")))
+	}
+
+	var errs int
+	for _, f := range findings {
+		if f.severity == severityError && !(d.rewrite && f.rewrite) {
+			errs++
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("%w: %s: %d errors", errDoctor, d.path, errs)
+	}
+	return nil
+}
+
+// diagnose checks the header of the file for interoperability problems.
+func (d *doctor) diagnose() ([]finding, error) {
+	f, err := os.Open(d.path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	h, _, err := readRawHeader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []finding
+	if len(h.Subfields) > 0 && h.Subfields[0] != [2]byte{format.RASI1, format.RASI2} {
+		findings = append(findings, finding{
+			severity: severityError,
+			problem:  fmt.Sprintf("the first EXTRA subfield is %q rather than the RA subfield", h.Subfields[0][:]),
+			fix:      "rewrite the archive with the RA subfield first (--rewrite)",
+			rewrite:  true,
+		})
+	}
+	if h.SharedWindow {
+		findings = append(findings, finding{
+			severity: severityError,
+			problem:  "chunks share the deflate window, which dictzip(1) and dictd(8) do not support",
+			fix:      "recompress without a shared window (--rewrite)",
+			rewrite:  true,
+		})
+	}
+	if h.HeaderCRC {
+		findings = append(findings, finding{
+			severity: severityWarning,
+			problem:  "the header has a FHCRC header CRC-16, which some dictzip readers do not skip",
+			fix:      "rewrite the archive without FHCRC (--rewrite)",
+			rewrite:  true,
+		})
+	}
+	if n := len(h.Subfields); n > 1 {
+		findings = append(findings, finding{
+			severity: severityWarning,
+			problem:  fmt.Sprintf("the header has %d EXTRA subfields but some dict clients only read a single subfield", n),
+			fix:      "recompress without additional EXTRA subfields or options such as --chunk-checksums",
+		})
+	}
+	if strings.ContainsAny(h.Name, `/\`) {
+		findings = append(findings, finding{
+			severity: severityWarning,
+			problem:  fmt.Sprintf("NAME %q includes directories", h.Name),
+			fix:      "rewrite the archive with only the base name (--rewrite)",
+			rewrite:  true,
+		})
+	}
+	if len(h.Name) > maxNameLen {
+		findings = append(findings, finding{
+			severity: severityWarning,
+			problem:  fmt.Sprintf("NAME is %d bytes, longer than most file systems allow (%d)", len(h.Name), maxNameLen),
+			fix:      fmt.Sprintf("rewrite the archive with NAME shortened to %d bytes (--rewrite)", maxNameLen),
+			rewrite:  true,
+		})
+	}
+	if h.ChunkSize != dictzip.DictdChunkSize {
+		findings = append(findings, finding{
+			severity: severityWarning,
+			problem: fmt.Sprintf("the chunk size is %d rather than %d as written by dictzip(1), which older dict clients assume",
+				h.ChunkSize, dictzip.DictdChunkSize),
+			fix:     "recompress with --dictd (--rewrite)",
+			rewrite: true,
+		})
+	}
+	return findings, nil
+}
+
+// rewriteFile recompresses the file so that it is compatible with dictd. The
+// file is replaced once the new archive has been written.
+func (d *doctor) rewriteFile() (err error) {
+	src, err := os.Open(d.path)
+	if err != nil {
+		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer src.Close()
+
+	fInfo, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: stat %q: %w", ErrDictzip, d.path, err)
+	}
+	z, err := dictzip.NewReader(src)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
+	size, err := z.Size()
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+
+	dst, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("%w: creating temp file: %w", ErrDictzip, err)
+	}
+	defer func() {
+		// NOTE: this removes the temp file if the rewrite fails.
+		if err != nil {
+			_ = dst.Close()
+			_ = os.Remove(dst.Name())
+		}
+	}()
+
+	w, err := dictzip.NewWriterLevel(dst, dictzip.BestCompression, dictzip.DictdChunkSize,
+		dictzip.WithDictdCompatible(), dictzip.WithClampModTime())
+	if err != nil {
+		return fmt.Errorf("%w: creating writer: %w", ErrDictzip, err)
+	}
+	// NOTE: The Reader removes directories from the name.
+	w.Name = truncateName(z.Name, maxNameLen)
+	w.ModTime = z.ModTime
+	w.Comment = z.Comment
+	w.OS = z.OS
+	w.Extra = z.Extra
+	if _, err := w.CompressFrom(z, size, runtime.NumCPU()); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("%w: compressing: %w", ErrDictzip, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("%w: compressing: %w", ErrDictzip, err)
+	}
+
+	if err := dst.Chmod(fInfo.Mode().Perm()); err != nil {
+		return fmt.Errorf("%w: chmod: %w", ErrDictzip, err)
+	}
+	if err := dst.Sync(); err != nil {
+		return fmt.Errorf("%w: sync: %w", ErrDictzip, err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("%w: closing temp file: %w", ErrDictzip, err)
+	}
+	if err := os.Rename(dst.Name(), d.path); err != nil {
+		return fmt.Errorf("%w: replacing file: %w", ErrDictzip, err)
+	}
+	return nil
+}
+
+// truncateName returns name shortened to at most n bytes without splitting
+// a UTF-8 sequence.
+func truncateName(name string, n int) string {
+	if len(name) <= n {
+		return name
+	}
+	name = name[:n]
+	for len(name) > 0 && !utf8.ValidString(name) {
+		name = name[:len(name)-1]
+	}
+	return name
+}
+
+// readRawHeader reads the dictzip header at the start of f as it is stored
+// and returns it along with its length.
+func readRawHeader(f *os.File) (*format.Header, []byte, error) {
+	buf := make([]byte, maxTransferHeader)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	h, headerLen, err := format.Parse(buf[:n])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	return h, buf[:headerLen], nil
+}
+
+func doctorCmd(c *cli.Context) error {
+	out := newOutput(c, c.App.Writer)
+	var errs []error
+	for _, path := range c.Args().Slice() {
+		d := doctor{
+			path:    path,
+			rewrite: c.Bool("rewrite"),
+			out:     out,
+		}
+		if err := d.Run(); err != nil {
+			// NOTE: Other files are still diagnosed if problems are found.
+			if !errors.Is(err, errDoctor) {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// doctorFlags returns the flags for the doctor command.
+func doctorFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:               "rewrite",
+			Usage:              "recompress files with problems so that they are compatible with dictzip(1) and dictd(8)",
+			DisableDefaultText: true,
+		},
+	}
+}
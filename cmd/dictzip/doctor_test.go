@@ -0,0 +1,217 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/go-dictzip"
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// newDoctorFile compresses data with the given flags and returns the path to
+// the compressed file.
+func newDoctorFile(t *testing.T, data string, flags ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	runApp(t, append(flags, path)...)
+	return path + ".dz"
+}
+
+// breakHeader rewrites the header of the file at path so that an unknown
+// subfield is written before the RA subfield, NAME includes directories, and
+// the header includes FHCRC.
+func breakHeader(t *testing.T, path string) {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	h, headerLen, err := format.Parse(b)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	h.Name = "dir/" + h.Name
+	hdr, err := format.Append(nil, h)
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// FLG is at offset 3 and XLEN at offset 10.
+	xlen := int(binary.LittleEndian.Uint16(hdr[10:]))
+	broken := append([]byte{}, hdr[:10]...)
+	broken[3] |= 0x02 // FHCRC
+	broken = binary.LittleEndian.AppendUint16(broken, uint16(xlen+4))
+	broken = append(broken, 'X', 'X', 0, 0)
+	broken = append(broken, hdr[12:]...)
+	// NOTE: format.Parse computes the CRC-16 from the header fields
+	// following the fixed length header.
+	broken = binary.LittleEndian.AppendUint16(broken, uint16(crc32.ChecksumIEEE(broken[10:])))
+	broken = append(broken, b[headerLen:]...)
+
+	if err := os.WriteFile(path, broken, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func TestApp_doctor(t *testing.T) {
+	t.Parallel()
+
+	data := strings.Repeat("dictzip doctor test\n", 5000)
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+
+		path := newDoctorFile(t, data, "--dictd")
+		stdout, _ := runApp(t, "doctor", path)
+		if want := path + ": OK (no problems found)\n"; stdout != want {
+			t.Errorf("doctor (-want, +got):\n%s", cmp.Diff(want, stdout))
+		}
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		t.Parallel()
+
+		path := newDoctorFile(t, data, "--chunk-size", "1000", "--chunk-checksums")
+		stdout, _ := runApp(t, "doctor", path)
+		for _, want := range []string{
+			"warning: the header has 2 EXTRA subfields",
+			"warning: the chunk size is 1000 rather than 58315",
+		} {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("doctor: output %q does not contain %q", stdout, want)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		path := newDoctorFile(t, data, "--dictd")
+		breakHeader(t, path)
+
+		stdout, _, err := runAppErr("doctor", path)
+		if !errors.Is(err, errDoctor) {
+			t.Errorf("doctor: unexpected error (-want, +got):\n%s", cmp.Diff(errDoctor, err))
+		}
+		for _, want := range []string{
+			`error: the first EXTRA subfield is "XX" rather than the RA subfield`,
+			"warning: the header has a FHCRC header CRC-16",
+			`warning: NAME "dir/test.txt" includes directories`,
+		} {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("doctor: output %q does not contain %q", stdout, want)
+			}
+		}
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		t.Parallel()
+
+		// NOTE: The command does not support writing a shared window.
+		path := filepath.Join(t.TempDir(), "test.txt.dz")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		w, err := dictzip.NewWriterLevel(f, dictzip.DefaultCompression, 1000, dictzip.WithSharedWindow())
+		if err != nil {
+			t.Fatalf("NewWriterLevel: %v", err)
+		}
+		w.Name = "test.txt"
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		breakHeader(t, path)
+
+		stdout, _ := runApp(t, "doctor", "--rewrite", path)
+		if want := path + ": rewritten\n"; !strings.HasSuffix(stdout, want) {
+			t.Errorf("doctor: output %q does not end with %q", stdout, want)
+		}
+
+		// NOTE: EXTRA subfields other than the dictzip subfields are
+		// preserved.
+		stdout, _ = runApp(t, "doctor", path)
+		if got, want := strings.Count(stdout, ": warning: "), 1; got != want {
+			t.Errorf("doctor: got %d warnings, want %d: %q", got, want, stdout)
+		}
+		if want := "warning: the header has 2 EXTRA subfields"; !strings.Contains(stdout, want) {
+			t.Errorf("doctor: output %q does not contain %q", stdout, want)
+		}
+
+		f, err = os.Open(path)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		defer f.Close()
+		z, err := dictzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("NewReader: %v", err)
+		}
+		if got, want := z.Name, "test.txt"; got != want {
+			t.Errorf("Name: got %q, want %q", got, want)
+		}
+		got := make([]byte, len(data))
+		if _, err := z.ReadAt(got, 0); err != nil {
+			t.Fatalf("ReadAt: %v", err)
+		}
+		if diff := cmp.Diff(data, string(got)); diff != "" {
+			t.Errorf("data (-want, +got):\n%s", diff)
+		}
+	})
+}
+
+func TestTruncateName(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		name string
+		n    int
+		want string
+	}{
+		"short": {name: "test.txt", n: 10, want: "test.txt"},
+		"long":  {name: "test.txt", n: 4, want: "test"},
+		"utf-8": {name: "tëst", n: 2, want: "t"},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := truncateName(tc.name, tc.n); got != tc.want {
+				t.Errorf("truncateName(%q, %d): got %q, want %q", tc.name, tc.n, got, tc.want)
+			}
+		})
+	}
+}
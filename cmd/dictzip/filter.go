@@ -0,0 +1,232 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+// Environment variables set for the filter command.
+const (
+	// filterChunkEnv holds the index of the chunk being filtered.
+	filterChunkEnv = "DICTZIP_CHUNK"
+
+	// filterOffsetEnv holds the uncompressed offset of the chunk being
+	// filtered.
+	filterOffsetEnv = "DICTZIP_OFFSET"
+)
+
+// errFilter indicates that the filter command failed.
+var errFilter = fmt.Errorf("%w: filter command failed", ErrDictzip)
+
+// filter runs a command on each uncompressed chunk of a dictzip file and
+// writes the output to a new dictzip file with the same chunk size and
+// metadata.
+type filter struct {
+	path    string
+	command string
+	output  string
+	level   int
+	out     *output
+}
+
+func (f *filter) Run() (err error) {
+	src, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer src.Close()
+
+	z, err := dictzip.NewReader(src)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
+
+	if f.output == "" {
+		return f.filter(f.out.w, z)
+	}
+
+	fInfo, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: stat %q: %w", ErrDictzip, f.path, err)
+	}
+	// NOTE: The output is written to a temp file so that an existing file
+	// is only replaced if the filter succeeds. This also allows the output
+	// to be the input file.
+	dst, err := os.CreateTemp(filepath.Dir(f.output), filepath.Base(f.output)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("%w: creating temp file: %w", ErrDictzip, err)
+	}
+	defer func() {
+		// NOTE: this removes the temp file if the filter fails.
+		if err != nil {
+			_ = dst.Close()
+			_ = os.Remove(dst.Name())
+		}
+	}()
+
+	if err := f.filter(dst, z); err != nil {
+		return err
+	}
+	if err := dst.Chmod(fInfo.Mode().Perm()); err != nil {
+		return fmt.Errorf("%w: chmod: %w", ErrDictzip, err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("%w: closing temp file: %w", ErrDictzip, err)
+	}
+	if err := os.Rename(dst.Name(), f.output); err != nil {
+		return fmt.Errorf("%w: replacing file: %w", ErrDictzip, err)
+	}
+	return nil
+}
+
+// filter runs the command on each chunk of z and writes the output to dst.
+// Chunk boundaries are preserved as long as the command output for each
+// chunk is the same length as the chunk.
+func (f *filter) filter(dst io.Writer, z *dictzip.Reader) error {
+	var opts []dictzip.WriterOption
+	if z.ChunkCRCs() != nil {
+		if z.ChunkCRCPolynomial() == crc32.Castagnoli {
+			opts = append(opts, dictzip.WithCastagnoliChunkChecksums())
+		} else {
+			opts = append(opts, dictzip.WithChunkChecksums())
+		}
+	}
+	if z.SharedWindow() {
+		opts = append(opts, dictzip.WithSharedWindow())
+	}
+	w, err := dictzip.NewWriterLevel(dst, f.level, z.ChunkSize(), opts...)
+	if err != nil {
+		return fmt.Errorf("%w: creating writer: %w", ErrDictzip, err)
+	}
+	w.Name = z.Name
+	w.ModTime = z.ModTime
+	w.Comment = z.Comment
+	w.OS = z.OS
+	w.Extra = z.Extra
+	w.Subfields = z.Subfields
+
+	size, err := z.Size()
+	if err != nil {
+		_ = w.Close()
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+
+	chunk := make([]byte, z.ChunkSize())
+	var shifted int64 = -1
+	for i, off := 0, int64(0); off < size; i++ {
+		n := int64(len(chunk))
+		if size-off < n {
+			n = size - off
+		}
+		if _, err := z.ReadAt(chunk[:n], off); err != nil && !errors.Is(err, io.EOF) {
+			_ = w.Close()
+			return fmt.Errorf("%w: reading chunk %d: %w", ErrDictzip, i, err)
+		}
+
+		filtered, err := f.run(chunk[:n], i, off)
+		if err != nil {
+			_ = w.Close()
+			return err
+		}
+		if shifted < 0 && int64(len(filtered)) != n {
+			shifted = int64(i)
+		}
+		if _, err := w.Write(filtered); err != nil {
+			_ = w.Close()
+			return fmt.Errorf("%w: compressing: %w", ErrDictzip, err)
+		}
+		off += n
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("%w: compressing: %w", ErrDictzip, err)
+	}
+	if shifted >= 0 {
+		f.out.warn("%s: output of chunk %d changed its length, chunk boundaries are not preserved from this chunk",
+			f.path, shifted)
+	}
+	return nil
+}
+
+// run runs the command with the chunk data at off as input and returns its
+// output. The command's standard error is passed through.
+func (f *filter) run(data []byte, chunk int, off int64) ([]byte, error) {
+	//nolint:gosec // Running a user given command is intended.
+	cmd := exec.Command("sh", "-c", f.command)
+	cmd.Env = append(os.Environ(),
+		filterChunkEnv+"="+strconv.Itoa(chunk),
+		filterOffsetEnv+"="+strconv.FormatInt(off, 10),
+	)
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = f.out.errW
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: chunk %d: %w", errFilter, chunk, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func filterCmd(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("%w: filter requires exactly one file", ErrFlagParse)
+	}
+	if c.String("exec") == "" {
+		return fmt.Errorf("%w: filter requires --exec", ErrFlagParse)
+	}
+	f := filter{
+		path:    c.Args().First(),
+		command: c.String("exec"),
+		output:  c.String("output"),
+		level:   c.Int("level"),
+		out:     newOutput(c, c.App.Writer),
+	}
+	return f.Run()
+}
+
+// filterFlags returns the flags for the filter command.
+func filterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "exec",
+			Usage:   "shell `COMMAND` run for each uncompressed chunk, reading the chunk from stdin and writing the new data to stdout",
+			Aliases: []string{"e"},
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Usage:   "write the new dictzip file to `PATH` instead of stdout",
+			Aliases: []string{"o"},
+		},
+		&cli.IntFlag{
+			Name:  "level",
+			Usage: "compression level",
+			Value: dictzip.DefaultCompression,
+		},
+	}
+}
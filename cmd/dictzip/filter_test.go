@@ -0,0 +1,158 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip"
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// readDictzip returns the uncompressed data and the reader of the dictzip
+// file b.
+func readDictzip(t *testing.T, b []byte) (string, *dictzip.Reader) {
+	t.Helper()
+
+	z, err := dictzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	data, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	return string(data), z
+}
+
+func TestApp_filter(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("tr"); err != nil {
+		t.Skipf("tr not found: %v", err)
+	}
+
+	data := strings.Repeat("dictzip filter test\n", 200)
+	path := newDoctorFile(t, data, "--chunk-size", "1000", "--chunk-checksums")
+
+	t.Run("stdout", func(t *testing.T) {
+		t.Parallel()
+
+		stdout, stderr := runApp(t, "filter", "--exec", "tr a-z A-Z", path)
+		if diff := cmp.Diff("", stderr); diff != "" {
+			t.Errorf("filter stderr (-want, +got):\n%s", diff)
+		}
+
+		got, z := readDictzip(t, []byte(stdout))
+		if diff := cmp.Diff(strings.ToUpper(data), got); diff != "" {
+			t.Errorf("data (-want, +got):\n%s", diff)
+		}
+		if diff := cmp.Diff(1000, z.ChunkSize()); diff != "" {
+			t.Errorf("ChunkSize (-want, +got):\n%s", diff)
+		}
+		if diff := cmp.Diff("test.txt", z.Name); diff != "" {
+			t.Errorf("Name (-want, +got):\n%s", diff)
+		}
+		if z.ChunkCRCs() == nil {
+			t.Errorf("ChunkCRCs: chunk checksums not preserved")
+		}
+	})
+
+	t.Run("output", func(t *testing.T) {
+		t.Parallel()
+
+		output := filepath.Join(t.TempDir(), "out.txt.dz")
+		stdout, _ := runApp(t, "filter", "--exec", `echo "$DICTZIP_CHUNK"`, "--output", output, path)
+		if diff := cmp.Diff("", stdout); diff != "" {
+			t.Errorf("filter stdout (-want, +got):\n%s", diff)
+		}
+
+		b, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		got, _ := readDictzip(t, b)
+		if diff := cmp.Diff("0\n1\n2\n3\n", got); diff != "" {
+			t.Errorf("data (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("boundaries not preserved", func(t *testing.T) {
+		t.Parallel()
+
+		_, stderr := runApp(t, "filter", "--exec", "head -c 10", path)
+		if !strings.Contains(stderr, "chunk boundaries are not preserved") {
+			t.Errorf("filter stderr: missing warning: %q", stderr)
+		}
+	})
+
+	t.Run("command fails", func(t *testing.T) {
+		t.Parallel()
+
+		output := filepath.Join(t.TempDir(), "out.txt.dz")
+		_, _, err := runAppErr("filter", "--exec", "exit 3", "--output", output, path)
+		if diff := cmp.Diff(errFilter, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("filter (-want, +got):\n%s", diff)
+		}
+		if _, err := os.Stat(output); err == nil {
+			t.Errorf("Stat: output written for failed filter")
+		}
+	})
+}
+
+func TestApp_filterSubfieldOrder(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skipf("cat not found: %v", err)
+	}
+
+	subfields := [][2]byte{{'X', 'Y'}, {format.RASI1, format.RASI2}}
+	var buf bytes.Buffer
+	w, err := dictzip.NewWriterLevel(&buf, dictzip.DefaultCompression, 1000)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	w.Extra = []byte{'X', 'Y', 0x1, 0x0, 0xab}
+	w.Subfields = subfields
+	if _, err := w.Write([]byte(strings.Repeat("subfield order\n", 200))); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "test.txt.dz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	stdout, _ := runApp(t, "filter", "--exec", "cat", path)
+	h, _, err := format.Parse([]byte(stdout))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if diff := cmp.Diff(subfields, h.Subfields); diff != "" {
+		t.Errorf("Subfields (-want, +got):\n%s", diff)
+	}
+}
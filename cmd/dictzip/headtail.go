@@ -0,0 +1,225 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+// defaultLines is the default number of lines printed by head and tail.
+const defaultLines = 10
+
+// headTail prints the first or last bytes or lines of the uncompressed data
+// of a dictzip file. Only the chunks holding the data printed are inflated.
+type headTail struct {
+	path string
+
+	// tail indicates that the end of the data is printed.
+	tail bool
+
+	// bytes is the number of bytes to print or -1 if lines are printed.
+	bytes int64
+
+	// lines is the number of lines to print.
+	lines int
+
+	// header indicates that the file name is printed before the data.
+	header bool
+
+	w io.Writer
+}
+
+func (h *headTail) Run() error {
+	f, err := os.Open(h.path)
+	if err != nil {
+		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	z, err := dictzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
+
+	size, err := z.Size()
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+
+	var start, end int64
+	switch {
+	case h.bytes >= 0 && h.tail:
+		start, end = size-h.bytes, size
+		if start < 0 {
+			start = 0
+		}
+	case h.bytes >= 0:
+		start, end = 0, h.bytes
+		if end > size {
+			end = size
+		}
+	case h.tail:
+		start, end, err = tailLines(z, size, h.lines)
+	default:
+		start, end, err = headLines(z, size, h.lines)
+	}
+	if err != nil {
+		return err
+	}
+
+	if h.header {
+		_ = must(fmt.Fprintf(h.w, "==> %s <==\n", h.path))
+	}
+	if _, err := io.Copy(h.w, z.Section(start, end-start)); err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	return nil
+}
+
+// headLines returns the range of the first n lines of the data.
+func headLines(z *dictzip.Reader, size int64, n int) (int64, int64, error) {
+	if n == 0 {
+		return 0, 0, nil
+	}
+
+	buf := make([]byte, z.ChunkSize())
+	for off := int64(0); off < size; off += int64(len(buf)) {
+		b, err := readBlock(z, buf, off, size)
+		if err != nil {
+			return 0, 0, err
+		}
+		for i, c := range b {
+			if c != '\n' {
+				continue
+			}
+			n--
+			if n == 0 {
+				return 0, off + int64(i) + 1, nil
+			}
+		}
+	}
+	return 0, size, nil
+}
+
+// tailLines returns the range of the last n lines of the data. As with
+// tail(1), a newline at the end of the data ends the last line.
+func tailLines(z *dictzip.Reader, size int64, n int) (int64, int64, error) {
+	if n == 0 || size == 0 {
+		return size, size, nil
+	}
+
+	buf := make([]byte, z.ChunkSize())
+	last, err := readBlock(z, buf[:1], size-1, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	searchEnd := size
+	if last[0] == '\n' {
+		searchEnd--
+	}
+
+	for end := searchEnd; end > 0; {
+		off := end - int64(len(buf))
+		if off < 0 {
+			off = 0
+		}
+		b, err := readBlock(z, buf[:end-off], off, size)
+		if err != nil {
+			return 0, 0, err
+		}
+		for i := bytes.LastIndexByte(b, '\n'); i >= 0; i = bytes.LastIndexByte(b[:i], '\n') {
+			n--
+			if n == 0 {
+				return off + int64(i) + 1, size, nil
+			}
+		}
+		end = off
+	}
+	return 0, size, nil
+}
+
+// readBlock reads up to len(buf) bytes of the data at off.
+func readBlock(z *dictzip.Reader, buf []byte, off, size int64) ([]byte, error) {
+	if rem := size - off; int64(len(buf)) > rem {
+		buf = buf[:rem]
+	}
+	n, err := z.ReadAt(buf, off)
+	if err != nil && !(errors.Is(err, io.EOF) && n == len(buf)) {
+		return nil, fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	return buf[:n], nil
+}
+
+// headTailCmd returns the action for the head or tail command.
+func headTailCmd(tail bool) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		n := c.Int64("bytes")
+		if c.IsSet("bytes") && n < 0 {
+			return fmt.Errorf("%w: invalid --bytes: %d", ErrFlagParse, n)
+		}
+		if !c.IsSet("bytes") {
+			n = -1
+		}
+		lines := c.Int("lines")
+		if lines < 0 {
+			return fmt.Errorf("%w: invalid --lines: %d", ErrFlagParse, lines)
+		}
+
+		paths := c.Args().Slice()
+		for i, path := range paths {
+			if i > 0 {
+				_ = must(fmt.Fprintln(c.App.Writer))
+			}
+			h := headTail{
+				path:   path,
+				tail:   tail,
+				bytes:  n,
+				lines:  lines,
+				header: len(paths) > 1,
+				w:      c.App.Writer,
+			}
+			if err := h.Run(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// headTailFlags returns the flags for the head and tail commands.
+func headTailFlags(which string) []cli.Flag {
+	return []cli.Flag{
+		&cli.Int64Flag{
+			Name:    "bytes",
+			Usage:   "print the " + which + " `N` bytes",
+			Aliases: []string{"c"},
+		},
+		&cli.IntFlag{
+			Name:    "lines",
+			Usage:   "print the " + which + " `N` lines",
+			Aliases: []string{"n"},
+			Value:   defaultLines,
+		},
+	}
+}
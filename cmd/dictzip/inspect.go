@@ -0,0 +1,87 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rodaine/table"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+type inspect struct {
+	path string
+	w    io.Writer
+}
+
+func (i *inspect) Run() error {
+	f, err := os.Open(i.path)
+	if err != nil {
+		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	d, err := dictzip.Describe(f)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+
+	z, err := dictzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
+	p, err := z.Provenance()
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+
+	_ = must(fmt.Fprintf(i.w, "%s: %d bytes (producer: %s)\n\n", i.path, d.Size, p.Producer))
+
+	fields := table.New("offset", "len", "field", "value").WithWriter(i.w)
+	for _, field := range d.Header {
+		fields.AddRow(field.Offset, field.Len, field.Name, field.Value)
+
+		// EXTRA sub-fields immediately follow XLEN.
+		if field.Name != "XLEN" {
+			continue
+		}
+		for _, sub := range d.Subfields {
+			id := string(sub.ID[:])
+			fields.AddRow(sub.Offset, 4+sub.Len, id, fmt.Sprintf("LEN %d", sub.Len))
+			for _, subField := range sub.Fields {
+				fields.AddRow(subField.Offset, subField.Len, id+"."+subField.Name, subField.Value)
+			}
+		}
+	}
+	for _, field := range d.Trailer {
+		fields.AddRow(field.Offset, field.Len, field.Name, field.Value)
+	}
+	fields.Print()
+
+	if len(d.Chunks) > 0 {
+		_ = must(fmt.Fprintln(i.w))
+		chunks := table.New("chunk", "entry", "offset", "len", "uncompressed offset").WithWriter(i.w)
+		for _, chunk := range d.Chunks {
+			chunks.AddRow(chunk.Index, chunk.EntryOffset, chunk.Offset, chunk.Len, chunk.UncompressedOffset)
+		}
+		chunks.Print()
+	}
+
+	return nil
+}
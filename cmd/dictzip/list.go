@@ -15,56 +15,310 @@
 package main
 
 import (
+	"compress/gzip"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
-
-	"github.com/rodaine/table"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/ianlewis/go-dictzip"
 )
 
+// gzipOffWidth is the width of size columns in gzip -l output. It is the
+// number of digits in the maximum 64-bit off_t value.
+const gzipOffWidth = 19
+
 type list struct {
 	path string
+	out  *output
+
+	// verify indicates that the CRC-32 of the uncompressed data is checked
+	// against the CRC-32 in the gzip trailer.
+	verify bool
+}
+
+// listEntry is the information listed for a single file.
+type listEntry struct {
+	// format is "dzip" for dictzip files and "gzip" for plain gzip files.
+	format string
+
+	modTime time.Time
+	name    string
+
+	// fileModTime is the modification time of the compressed file which is
+	// shown by gzip -l rather than the header MTIME.
+	fileModTime time.Time
+
+	// chunks and chunkSize are only set for dictzip files.
+	chunks    int
+	chunkSize int
+
+	compressed   int64
+	uncompressed int64
+
+	// overhead is the size of the gzip header and trailer.
+	overhead int64
+
+	// crc is the CRC-32 from the gzip trailer.
+	crc uint32
+
+	// dataCRC is the CRC-32 of the uncompressed data.
+	dataCRC uint32
 }
 
 func (l *list) Run() error {
+	e, err := l.read()
+	if err != nil {
+		return err
+	}
+
+	headers := []interface{}{"type", "date", "time", "chunks", "size", "compressed", "uncompressed", "ratio", "crc"}
+	if l.verify {
+		headers = append(headers, "check")
+	}
+	headers = append(headers, "name")
+	tbl := l.out.table(headers...)
+
+	var chunks, chunkSize interface{} = "", ""
+	if e.format == "dzip" {
+		chunks, chunkSize = e.chunks, e.chunkSize
+	}
+	row := []interface{}{
+		e.format,
+		e.modTime.Format("2006-01-02"),
+		e.modTime.Format("15:04:05"),
+		chunks,
+		chunkSize,
+		fmt.Sprintf("%d", e.compressed),
+		fmt.Sprintf("%d", e.uncompressed),
+		l.out.ratio(e.compressed, e.uncompressed),
+		fmt.Sprintf("%08x", e.crc),
+	}
+	if l.verify {
+		check := l.out.ok("OK")
+		if e.crc != e.dataCRC {
+			check = l.out.fail("MISMATCH")
+		}
+		row = append(row, check)
+	}
+	row = append(row, e.name)
+	tbl.AddRow(row...)
+	tbl.Print()
+
+	if l.verify && e.crc != e.dataCRC {
+		return fmt.Errorf("%w: %s: CRC-32 mismatch: trailer %08x, data %08x", ErrDictzip, l.path, e.crc, e.dataCRC)
+	}
+
+	return nil
+}
+
+// read reads the list information from the file.
+func (l *list) read() (*listEntry, error) {
 	f, err := os.Open(l.path)
 	if err != nil {
-		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+		return nil, fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
 	}
 	defer f.Close()
 
+	fInfo, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("%w: stat: %w", ErrDictzip, err)
+	}
+	compressed := fInfo.Size()
+
+	format, err := dictzip.DetectFormat(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	switch format {
+	case dictzip.FormatDictzip:
+	case dictzip.FormatGzip:
+		return l.readGzip(f, fInfo)
+	case dictzip.FormatUnknown:
+		return nil, fmt.Errorf("%w: %q", errNotGzip, l.path)
+	}
+
+	d, err := dictzip.Describe(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	var headerSize int64
+	for _, field := range d.Header {
+		if end := field.Offset + field.Len; end > headerSize {
+			headerSize = end
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("%w: seek: %w", ErrDictzip, err)
+	}
 	z, err := dictzip.NewReader(f)
 	if err != nil {
-		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+		return nil, fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
 	}
 	defer z.Close()
 
-	fInfo, err := f.Stat()
+	h := crc32.NewIEEE()
+	uncompressed, err := io.Copy(h, z)
 	if err != nil {
-		return fmt.Errorf("%w: stat: %w", ErrDictzip, err)
+		return nil, fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
 	}
 
+	crc, err := readCRC(f, compressed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listEntry{
+		format:       "dzip",
+		modTime:      z.ModTime,
+		name:         z.Name,
+		fileModTime:  fInfo.ModTime(),
+		chunks:       len(z.Sizes()),
+		chunkSize:    z.ChunkSize(),
+		compressed:   compressed,
+		uncompressed: uncompressed,
+		overhead:     headerSize + 8,
+		crc:          crc,
+		dataCRC:      h.Sum32(),
+	}, nil
+}
+
+// readGzip reads the list information from a plain gzip file.
+func (l *list) readGzip(f *os.File, fInfo os.FileInfo) (*listEntry, error) {
 	compressed := fInfo.Size()
-	uncompressed, err := io.Copy(io.Discard, z)
+	z, err := gzip.NewReader(f)
 	if err != nil {
-		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
-	}
-
-	tbl := table.New("type", "date", "time", "chunks", "size", "compressed", "uncompressed", "ratio", "name")
-	tbl.AddRow(
-		"dzip",
-		z.ModTime.Format("2006-01-02"),
-		z.ModTime.Format("15:04:05"),
-		len(z.Sizes()),
-		z.ChunkSize(),
-		fmt.Sprintf("%d", compressed),
-		fmt.Sprintf("%d", uncompressed),
-		fmt.Sprintf("%.1f%%", (1-float64(compressed)/float64(uncompressed))*100),
-		z.Name,
-	)
-	tbl.Print()
+		return nil, fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
 
-	return nil
+	// NOTE: gzip.Reader also verifies the CRC-32 and returns an error if it
+	// does not match.
+	h := crc32.NewIEEE()
+	uncompressed, err := io.Copy(h, z)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+
+	crc, err := readCRC(f, compressed)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: The header CRC16 is not exposed by gzip.Header so it is not
+	// included here.
+	headerSize := int64(10)
+	if z.Extra != nil {
+		headerSize += 2 + int64(len(z.Extra))
+	}
+	if z.Name != "" {
+		headerSize += int64(len([]rune(z.Name))) + 1
+	}
+	if z.Comment != "" {
+		headerSize += int64(len([]rune(z.Comment))) + 1
+	}
+
+	return &listEntry{
+		format:       "gzip",
+		modTime:      z.ModTime,
+		name:         z.Name,
+		fileModTime:  fInfo.ModTime(),
+		compressed:   compressed,
+		uncompressed: uncompressed,
+		overhead:     headerSize + 8,
+		crc:          crc,
+		dataCRC:      h.Sum32(),
+	}, nil
+}
+
+// readCRC reads the CRC-32 from the gzip trailer of f which has the given
+// size.
+func readCRC(f *os.File, size int64) (uint32, error) {
+	buf := make([]byte, 4)
+	if _, err := f.ReadAt(buf, size-8); err != nil {
+		return 0, fmt.Errorf("%w: reading trailer: %w", ErrDictzip, err)
+	}
+	return binary.LittleEndian.Uint32(buf), nil
+}
+
+// gzipList prints list output in the same format as gzip -lv.
+type gzipList struct {
+	out *output
+
+	// totals is the sum of all entries listed.
+	totals listEntry
+	count  int
+}
+
+// printHeader prints the gzip -lv column headers.
+func (g *gzipList) printHeader() {
+	_ = must(fmt.Fprintf(g.out.w, "method  crc     date  time  %*s %*s  ratio uncompressed_name\n",
+		gzipOffWidth, "compressed", gzipOffWidth, "uncompressed"))
+}
+
+// print prints the entry for the file at path.
+func (g *gzipList) print(path string, e *listEntry) {
+	g.totals.compressed += e.compressed
+	g.totals.uncompressed += e.uncompressed
+	// NOTE: gzip computes the ratio of the totals using the header and
+	// trailer size of the last file.
+	g.totals.overhead = e.overhead
+	g.count++
+
+	// NOTE: Like gzip, the name and time printed are the uncompressed file
+	// name and the modification time of the file rather than those from the
+	// header.
+	_ = must(fmt.Fprintf(g.out.w, "defla %08x %s %*d %*d %s %s\n",
+		e.crc, e.fileModTime.Format("Jan _2 15:04"),
+		gzipOffWidth, e.compressed, gzipOffWidth, e.uncompressed,
+		gzipRatio(e), gzipName(path)))
+}
+
+// gzipSuffixes are the suffixes removed by gzip to get the uncompressed file
+// name and their replacements. Suffixes are compared case-insensitively.
+var gzipSuffixes = [][2]string{
+	{".gz", ""},
+	{".z", ""},
+	{".taz", ".tar"},
+	{".tgz", ".tar"},
+	{"-gz", ""},
+	{"-z", ""},
+	{"_z", ""},
+}
+
+// gzipName returns the uncompressed file name for path as shown by gzip -l.
+// Names without a gzip suffix, such as dictzip .dz files, are unchanged.
+func gzipName(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	for _, s := range gzipSuffixes {
+		if strings.HasSuffix(base, s[0]) && len(base) > len(s[0]) {
+			return path[:len(path)-len(s[0])] + s[1]
+		}
+	}
+	return path
+}
+
+// printTotals prints the totals line if more than one file was listed.
+func (g *gzipList) printTotals() {
+	if g.count < 2 {
+		return
+	}
+	_ = must(fmt.Fprintf(g.out.w, "%28s%*d %*d %s (totals)\n",
+		"", gzipOffWidth, g.totals.compressed, gzipOffWidth, g.totals.uncompressed,
+		gzipRatio(&g.totals)))
+}
+
+// gzipRatio formats the compression ratio as gzip does. The ratio excludes the
+// gzip header and trailer.
+func gzipRatio(e *listEntry) string {
+	var r float64
+	if e.uncompressed > 0 {
+		r = 100 * float64(e.uncompressed-(e.compressed-e.overhead)) / float64(e.uncompressed)
+	}
+	return fmt.Sprintf("%5.1f%%", r)
 }
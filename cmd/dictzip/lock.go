@@ -0,0 +1,74 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// errLocked indicates that another dictzip process holds the lock on a file.
+var errLocked = fmt.Errorf("%w: file is locked by another process", ErrDictzip)
+
+// lockPollInterval is how often the lock file is checked when waiting.
+const lockPollInterval = 100 * time.Millisecond
+
+// lockFile is a lock held on a path using a companion lock file.
+type lockFile struct {
+	path string
+
+	// f is the open lock file. On Unix systems it holds the lock.
+	f *os.File
+}
+
+// acquireLock locks path using the lock file path + ".lock". If the lock is
+// held by another process, acquireLock returns an error wrapping errLocked
+// or, if wait is true, waits until the lock is released.
+//
+// On Unix systems the lock file is locked with flock(2) so that the lock is
+// released by the kernel if the process is killed. Otherwise the lock file is
+// created exclusively and contains the process ID of the process holding the
+// lock. A lock file left behind by a process that is no longer running is
+// removed.
+func acquireLock(path string, wait bool) (*lockFile, error) {
+	lockPath := path + ".lock"
+	for {
+		l, err := tryLock(lockPath)
+		if err == nil {
+			return l, nil
+		}
+		if !errors.Is(err, errLocked) {
+			return nil, err
+		}
+		if !wait {
+			return nil, fmt.Errorf("%w: %s (use --wait to wait)", errLocked, path)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// writePID writes the process ID to the lock file f.
+func writePID(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("%w: writing lock file: %w", ErrDictzip, err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return fmt.Errorf("%w: writing lock file: %w", ErrDictzip, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// tryLock locks lockPath by exclusively creating the lock file. It returns an
+// error wrapping errLocked if the lock file exists and the process that
+// created it is still running.
+func tryLock(lockPath string) (*lockFile, error) {
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			err = writePID(f)
+			if clsErr := f.Close(); err == nil {
+				err = clsErr
+			}
+			if err != nil {
+				_ = os.Remove(lockPath)
+				return nil, err
+			}
+			return &lockFile{path: lockPath}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("%w: creating lock file: %w", ErrDictzip, err)
+		}
+		if !staleLock(lockPath) {
+			return nil, errLocked
+		}
+		if err := os.Remove(lockPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: removing stale lock file: %w", ErrDictzip, err)
+		}
+	}
+}
+
+// staleLock reports whether the lock file at lockPath was left behind by a
+// process that is no longer running. Lock files that can't be read, or that
+// are still being written, are not stale.
+func staleLock(lockPath string) bool {
+	b, err := os.ReadFile(lockPath)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return false
+	}
+	// NOTE: FindProcess returns an error if the process doesn't exist on
+	// Windows.
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return true
+	}
+	_ = p.Release()
+	return false
+}
+
+// Release releases the lock by removing the lock file.
+func (l *lockFile) Release() error {
+	if err := os.Remove(l.path); err != nil {
+		return fmt.Errorf("%w: removing lock file: %w", ErrDictzip, err)
+	}
+	return nil
+}
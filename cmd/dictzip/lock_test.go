@@ -0,0 +1,95 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestAcquireLock(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "test.txt.dz")
+	l, err := acquireLock(path, false)
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+
+	_, err = acquireLock(path, false)
+	if diff := cmp.Diff(errLocked, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("acquireLock (-want, +got):\n%s", diff)
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, err := os.Stat(path + ".lock"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("lock file not removed: %v", err)
+	}
+}
+
+func TestAcquireLock_wait(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "test.txt.dz")
+	l, err := acquireLock(path, false)
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+
+	released := make(chan error, 1)
+	go func() {
+		time.Sleep(2 * lockPollInterval)
+		released <- l.Release()
+	}()
+
+	l2, err := acquireLock(path, true)
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	if err := <-released; err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := l2.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+}
+
+func TestAcquireLock_stale(t *testing.T) {
+	t.Parallel()
+
+	// NOTE: A lock file left behind by a process that was killed contains
+	// the process ID of a process that is no longer running.
+	path := filepath.Join(t.TempDir(), "test.txt.dz")
+	if err := os.WriteFile(path+".lock", []byte("2147483646\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	l, err := acquireLock(path, false)
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// tryLock locks the lock file at lockPath with flock(2). It returns an error
+// wrapping errLocked if the file is locked by another process.
+func tryLock(lockPath string) (*lockFile, error) {
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("%w: creating lock file: %w", ErrDictzip, err)
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			_ = f.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, errLocked
+			}
+			return nil, fmt.Errorf("%w: locking %s: %w", ErrDictzip, lockPath, err)
+		}
+
+		// NOTE: The previous holder removes the lock file when releasing the
+		// lock so the file locked may no longer be the one at lockPath.
+		fInfo, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("%w: stat %q: %w", ErrDictzip, lockPath, err)
+		}
+		pInfo, err := os.Stat(lockPath)
+		if err != nil || !os.SameFile(fInfo, pInfo) {
+			_ = f.Close()
+			continue
+		}
+
+		if err := writePID(f); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return &lockFile{path: lockPath, f: f}, nil
+	}
+}
+
+// Release releases the lock by removing and closing the lock file.
+func (l *lockFile) Release() error {
+	// NOTE: The file is removed before it is unlocked so that another process
+	// does not lock a file that is then removed.
+	err := os.Remove(l.path)
+	if clsErr := l.f.Close(); err == nil {
+		err = clsErr
+	}
+	if err != nil {
+		return fmt.Errorf("%w: removing lock file: %w", ErrDictzip, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ianlewis/go-dictzip"
+	"github.com/ianlewis/go-dictzip/index"
+)
+
+var errWordNotFound = fmt.Errorf("%w: word not found", ErrDictzip)
+
+// lookup writes the definitions for a headword found in a dictd index to
+// stdout.
+type lookup struct {
+	path      string
+	indexPath string
+	word      string
+	w         io.Writer
+}
+
+func (l *lookup) Run() error {
+	idxFile, err := os.Open(l.indexPath)
+	if err != nil {
+		return fmt.Errorf("%w: opening index: %w", ErrDictzip, err)
+	}
+	defer idxFile.Close()
+
+	idx, err := index.Read(idxFile)
+	if err != nil {
+		return fmt.Errorf("%w: reading index: %w", ErrDictzip, err)
+	}
+
+	entries := idx.Lookup(l.word)
+	if len(entries) == 0 {
+		return fmt.Errorf("%w: %q", errWordNotFound, l.word)
+	}
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	z, err := dictzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
+
+	for _, e := range entries {
+		if _, err := io.Copy(l.w, z.Section(e.Offset, e.Size)); err != nil {
+			return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+		}
+	}
+
+	return nil
+}
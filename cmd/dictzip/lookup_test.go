@@ -0,0 +1,133 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip"
+	"github.com/ianlewis/go-dictzip/index"
+)
+
+// definition is a headword and its definition.
+type definition struct {
+	word, text string
+}
+
+// newDatabase writes a dictd database with the given definitions to
+// dir/name.index and dir/name.dict.dz and returns their paths. The
+// definitions are written in order using a small chunk size so that they
+// span several chunks.
+func newDatabase(t *testing.T, dir, name string, defs []definition) (string, string) {
+	t.Helper()
+
+	var data bytes.Buffer
+	var idx strings.Builder
+	for _, d := range defs {
+		off, err := index.EncodeOffset(int64(data.Len()))
+		if err != nil {
+			t.Fatalf("EncodeOffset: %v", err)
+		}
+		size, err := index.EncodeOffset(int64(len(d.text)))
+		if err != nil {
+			t.Fatalf("EncodeOffset: %v", err)
+		}
+		fmt.Fprintf(&idx, "%s\t%s\t%s\n", d.word, off, size)
+		data.WriteString(d.text)
+	}
+
+	var buf bytes.Buffer
+	z, err := dictzip.NewWriterLevel(&buf, dictzip.DefaultCompression, 16)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := z.Write(data.Bytes()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	indexPath := filepath.Join(dir, name+".index")
+	dictPath := filepath.Join(dir, name+".dict.dz")
+	if err := os.WriteFile(indexPath, []byte(idx.String()), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(dictPath, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return indexPath, dictPath
+}
+
+func TestApp_lookup(t *testing.T) {
+	t.Parallel()
+
+	indexPath, dictPath := newDatabase(t, t.TempDir(), "test", []definition{
+		{"apple", "apple: a round fruit\n"},
+		{"bank", "bank: the land alongside a river\n"},
+		{"cherry", "cherry: a small stone fruit\n"},
+		{"bank", "bank: a place where money is kept\n"},
+	})
+
+	testCases := map[string]struct {
+		args []string
+		want string
+		err  error
+	}{
+		"found": {
+			args: []string{"--index", indexPath, "--word", "cherry", dictPath},
+			want: "cherry: a small stone fruit\n",
+		},
+		"case insensitive": {
+			args: []string{"--index", indexPath, "--word", "Apple", dictPath},
+			want: "apple: a round fruit\n",
+		},
+		"multiple entries": {
+			args: []string{"--index", indexPath, "--word", "bank", dictPath},
+			want: "bank: the land alongside a river\nbank: a place where money is kept\n",
+		},
+		"missing": {
+			args: []string{"--index", indexPath, "--word", "durian", dictPath},
+			err:  errWordNotFound,
+		},
+		"no index": {
+			args: []string{"--word", "apple", dictPath},
+			err:  ErrFlagParse,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stdout, _, err := runAppErr(tc.args...)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("lookup (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want, stdout); diff != "" {
+				t.Errorf("lookup stdout (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+// lsChunksColumns are the names of the columns printed by ls-chunks.
+var lsChunksColumns = []string{"chunk", "offset", "length", "uncompressed_offset", "uncompressed_length"}
+
+// lsChunks prints the chunk table of a dictzip file as tab separated values
+// so that it can be processed by shell pipelines.
+type lsChunks struct {
+	path   string
+	header bool
+	w      io.Writer
+}
+
+func (l *lsChunks) Run() error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	z, err := dictzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
+
+	if l.header {
+		_ = must(fmt.Fprintln(l.w, strings.Join(lsChunksColumns, "\t")))
+	}
+
+	for i := 0; i < z.ChunkCount(); i++ {
+		compOff, compLen, uncompOff, uncompLen, err := z.ChunkRange(i)
+		if err != nil {
+			return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+		}
+		_ = must(fmt.Fprintf(l.w, "%d\t%d\t%d\t%d\t%d\n", i, compOff, compLen, uncompOff, uncompLen))
+	}
+
+	return nil
+}
+
+func lsChunksCmd(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("%w: ls-chunks requires exactly one file", ErrFlagParse)
+	}
+	l := lsChunks{
+		path:   c.Args().First(),
+		header: c.Bool("header"),
+		w:      c.App.Writer,
+	}
+	return l.Run()
+}
+
+// lsChunksFlags returns the flags for the ls-chunks command.
+func lsChunksFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:               "header",
+			Usage:              "print a header line with the column names",
+			DisableDefaultText: true,
+		},
+	}
+}
@@ -16,6 +16,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/urfave/cli/v2"
@@ -28,7 +29,16 @@ func main() {
 	// return errors if command line flags are incorrect etc. In this case neither
 	// Action nor ExitErrHandler are called.
 	app := newDictzipApp()
-	if err := app.Run(os.Args); err != nil {
+
+	// Default options from the config file and environment are inserted
+	// before the command line arguments so that they can be overridden.
+	defaults, err := defaultArgs()
+	if err != nil {
+		_ = must(fmt.Fprintf(os.Stderr, "%s: %v\n", app.Name, err))
+		cli.OsExiter(ExitCodeFlagParseError)
+		return
+	}
+	if err = app.Run(withDefaults(app, os.Args, defaults)); err != nil {
 		cli.OsExiter(ExitCodeUnknownError)
 	}
 }
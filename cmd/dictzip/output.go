@@ -0,0 +1,165 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rodaine/table"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+// ANSI escape sequences used for colored output.
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+)
+
+// ansiEscape matches ANSI SGR escape sequences.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// output formats human readable output for the list, test, and verbose modes.
+type output struct {
+	w io.Writer
+
+	// errW is the writer for warnings.
+	errW io.Writer
+
+	// name is the program name used as a prefix for warnings.
+	name string
+
+	// color indicates that ANSI colors should be used.
+	color bool
+}
+
+// newOutput returns an output writing to w. Colors are enabled only if w is
+// a terminal, the --no-color flag is not set, and the NO_COLOR environment
+// variable is empty.
+// See: https://no-color.org/
+func newOutput(c *cli.Context, w io.Writer) *output {
+	return &output{
+		w:     w,
+		errW:  c.App.ErrWriter,
+		name:  c.App.Name,
+		color: !c.Bool("no-color") && os.Getenv("NO_COLOR") == "" && isTerminal(w),
+	}
+}
+
+// isTerminal returns true if w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fInfo, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fInfo.Mode()&os.ModeCharDevice != 0
+}
+
+// displayWidth returns the width of s when displayed excluding any ANSI
+// escape sequences.
+func displayWidth(s string) int {
+	return utf8.RuneCountInString(ansiEscape.ReplaceAllString(s, ""))
+}
+
+// style wraps s in the given ANSI escape sequence if colors are enabled.
+func (o *output) style(code, s string) string {
+	if !o.color {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// table returns a new table with aligned columns that writes to the output.
+func (o *output) table(headers ...interface{}) table.Table {
+	tbl := table.New(headers...).WithWriter(o.w).WithWidthFunc(displayWidth)
+	if o.color {
+		tbl = tbl.WithHeaderFormatter(func(format string, vals ...interface{}) string {
+			return o.style(ansiBold, strings.TrimSuffix(fmt.Sprintf(format, vals...), "\n")) + "\n"
+		})
+	}
+	return tbl
+}
+
+// ratio formats a compression ratio given the compressed and uncompressed
+// sizes. Ratios where the compressed data is larger are shown in red.
+func (o *output) ratio(compressed, uncompressed int64) string {
+	r := (1 - float64(compressed)/float64(uncompressed)) * 100
+	s := fmt.Sprintf("%.1f%%", r)
+	if r < 0 {
+		return o.style(ansiRed, s)
+	}
+	return o.style(ansiGreen, s)
+}
+
+// ok formats a success message.
+func (o *output) ok(s string) string {
+	return o.style(ansiGreen, s)
+}
+
+// fail formats a failure message.
+func (o *output) fail(s string) string {
+	return o.style(ansiRed, s)
+}
+
+// warn prints a warning message to the error writer.
+func (o *output) warn(format string, a ...interface{}) {
+	_ = must(fmt.Fprintf(o.errW, "%s: warning: %s\n", o.name, fmt.Sprintf(format, a...)))
+}
+
+// printChunks prints a table of compressed chunk sizes for the given
+// uncompressed size and chunk size.
+func (o *output) printChunks(sizes []int, uncompressedSize int64, chunkSize int) {
+	tbl := o.table("chunk", "uncompressed", "compressed", "ratio")
+	remaining := uncompressedSize
+	for i, size := range sizes {
+		chunkLen := int64(chunkSize)
+		if remaining < chunkLen {
+			chunkLen = remaining
+		}
+		remaining -= chunkLen
+
+		tbl.AddRow(i+1, chunkLen, size, o.ratio(int64(size), chunkLen))
+	}
+	tbl.Print()
+	_ = must(fmt.Fprintf(o.w, "%d bytes total\n", uncompressedSize))
+}
+
+// printWorkers prints a table of the chunks compressed by each worker thread
+// and its throughput. Nothing is printed if chunks were not compressed in
+// parallel.
+func (o *output) printWorkers(stats []dictzip.WorkerStats) {
+	if len(stats) == 0 {
+		return
+	}
+	tbl := o.table("thread", "chunks", "uncompressed", "time", "throughput")
+	for i, s := range stats {
+		tbl.AddRow(i+1, s.Chunks, s.Bytes, s.Duration.Round(time.Microsecond),
+			fmt.Sprintf("%.1f MB/s", s.Throughput()/1e6))
+	}
+	tbl.Print()
+}
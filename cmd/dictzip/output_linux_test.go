@@ -0,0 +1,136 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/urfave/cli/v2"
+)
+
+// openTerminal opens a pseudo-terminal and returns its controller and the
+// terminal. The test is skipped if a pseudo-terminal can't be opened.
+func openTerminal(t *testing.T) (*os.File, *os.File) {
+	t.Helper()
+
+	ptmx, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("opening pseudo-terminal: %v", err)
+	}
+	t.Cleanup(func() { _ = ptmx.Close() })
+
+	ioctl := func(req uintptr, arg unsafe.Pointer) error {
+		//nolint:gosec // ioctl arguments are pointers to local variables.
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, ptmx.Fd(), req, uintptr(arg))
+		if errno != 0 {
+			return errno
+		}
+		return nil
+	}
+	var unlock int32
+	if err := ioctl(syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		t.Skipf("unlocking pseudo-terminal: %v", err)
+	}
+	var n uint32
+	if err := ioctl(syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		t.Skipf("getting pseudo-terminal number: %v", err)
+	}
+
+	tty, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("opening pseudo-terminal: %v", err)
+	}
+	return ptmx, tty
+}
+
+// runAppTerminal runs the dictzip command with stdout written to a terminal
+// and returns the data written to stdout.
+func runAppTerminal(t *testing.T, args ...string) string {
+	t.Helper()
+
+	ptmx, tty := openTerminal(t)
+	out := make(chan []byte)
+	go func() {
+		// NOTE: Reads return an error once the terminal is closed.
+		b, _ := io.ReadAll(ptmx)
+		out <- b
+	}()
+
+	app := newDictzipApp()
+	app.Writer = tty
+	app.ErrWriter = &bytes.Buffer{}
+	app.ExitErrHandler = func(_ *cli.Context, _ error) {}
+	err := app.Run(append([]string{"dictzip"}, args...))
+	_ = tty.Close()
+	stdout := string(<-out)
+	if err != nil {
+		t.Fatalf("dictzip %s: %v", strings.Join(args, " "), err)
+	}
+	return stdout
+}
+
+//nolint:paralleltest // t.Setenv can't be used with t.Parallel.
+func TestApp_noColorTerminal(t *testing.T) {
+	path := newColorTestFile(t)
+
+	testCases := map[string]struct {
+		args    []string
+		noColor string
+		color   bool
+	}{
+		"list": {
+			args:  []string{"--list", path},
+			color: true,
+		},
+		"test": {
+			args:  []string{"--test", path},
+			color: true,
+		},
+		"list no color flag": {
+			args: []string{"--list", "--no-color", path},
+		},
+		"test no color flag": {
+			args: []string{"--test", "--no-color", path},
+		},
+		"list NO_COLOR": {
+			args:    []string{"--list", path},
+			noColor: "1",
+		},
+		"test NO_COLOR": {
+			args:    []string{"--test", path},
+			noColor: "1",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tc.noColor)
+
+			stdout := runAppTerminal(t, tc.args...)
+			if got := strings.Contains(stdout, "\x1b["); got != tc.color {
+				t.Errorf("dictzip %s: got ANSI escapes %v, want %v: %q", strings.Join(tc.args, " "), got, tc.color, stdout)
+			}
+		})
+	}
+}
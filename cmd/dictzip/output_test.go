@@ -0,0 +1,51 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newColorTestFile returns the path of an archive whose list and test
+// output includes colors when written to a terminal.
+func newColorTestFile(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "color.txt")
+	if err := os.WriteFile(path, []byte(strings.Repeat("dictzip color test\n", 200)), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	runApp(t, path)
+	return path + ".dz"
+}
+
+func TestApp_noColorNonTerminal(t *testing.T) {
+	t.Parallel()
+
+	path := newColorTestFile(t)
+	for _, args := range [][]string{
+		{"--list", path},
+		{"--test", path},
+		{"--list", "--no-color", path},
+	} {
+		stdout, _ := runApp(t, args...)
+		if strings.Contains(stdout, "\x1b[") {
+			t.Errorf("dictzip %s: output includes ANSI escapes: %q", strings.Join(args, " "), stdout)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/urfave/cli/v2"
+)
+
+// heapProfileSuffix is appended to the --pprof path to get the path of the
+// heap profile.
+const heapProfileSuffix = ".heap"
+
+// withProfile runs action while writing a CPU profile to the file given by
+// --pprof. A heap profile is written to the file with heapProfileSuffix
+// appended once action returns. The profiles can be read with
+// "go tool pprof".
+func withProfile(c *cli.Context, action cli.ActionFunc) (err error) {
+	path := c.String("pprof")
+	if path == "" {
+		return action(c)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("%w: creating profile: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+	if err := pprof.StartCPUProfile(f); err != nil {
+		return fmt.Errorf("%w: starting CPU profile: %w", ErrDictzip, err)
+	}
+
+	err = action(c)
+
+	pprof.StopCPUProfile()
+	if cErr := f.Close(); cErr != nil && err == nil {
+		err = fmt.Errorf("%w: writing CPU profile: %w", ErrDictzip, cErr)
+	}
+	if hErr := writeHeapProfile(path + heapProfileSuffix); hErr != nil && err == nil {
+		err = hErr
+	}
+	return err
+}
+
+// writeHeapProfile writes a heap profile to path.
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("%w: creating heap profile: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	// NOTE: A GC is run so that the profile is up to date.
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("%w: writing heap profile: %w", ErrDictzip, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%w: writing heap profile: %w", ErrDictzip, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+type stats struct {
+	path string
+	out  *output
+}
+
+func (s *stats) Run() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	z, err := dictzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
+
+	st, err := dictzip.Stats(z)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+
+	_ = must(fmt.Fprintf(s.out.w, "%s: %d chunks of %d bytes\n\n", s.path, st.Chunks, st.ChunkSize))
+
+	summary := s.out.table("stat", "value")
+	summary.AddRow("uncompressed", st.Size)
+	summary.AddRow("compressed", fmt.Sprintf("%d (%s)", st.CompressedSize, s.out.ratio(st.CompressedSize, st.Size)))
+	summary.AddRow("min chunk", st.MinChunkLen)
+	summary.AddRow("max chunk", st.MaxChunkLen)
+	summary.AddRow("avg chunk", fmt.Sprintf("%.1f", st.AvgChunkLen))
+	summary.AddRow("final data", st.FinalDataLen)
+	summary.Print()
+
+	_ = must(fmt.Fprintln(s.out.w))
+
+	// NOTE: Buckets are shown as the compressed size relative to the
+	// uncompressed size.
+	dist := s.out.table("compressed size", "chunks")
+	for i, count := range st.Distribution {
+		bucket := fmt.Sprintf("%d-%d%%", i*10, (i+1)*10)
+		if i == len(st.Distribution)-1 {
+			bucket = fmt.Sprintf("%d%%+", i*10)
+		}
+		dist.AddRow(bucket, count)
+	}
+	dist.Print()
+
+	return nil
+}
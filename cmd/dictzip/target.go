@@ -0,0 +1,90 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+var (
+	// errTargetExists indicates that the target file exists and --force was
+	// not given.
+	errTargetExists = fmt.Errorf("%w: %w", ErrDictzip, fs.ErrExist)
+
+	// errTargetNotRegular indicates that the target path exists but is not
+	// a regular file or a symlink and so is never replaced.
+	errTargetNotRegular = fmt.Errorf("%w: target is not a regular file", ErrDictzip)
+)
+
+// checkTarget checks whether the file at path may be written to. If path
+// does not exist it may be created. Otherwise, it may only be replaced if
+// force is true and it is a regular file or a symlink. Directories and special
+// files such as devices and named pipes are never replaced.
+//
+// If path is a symlink, including a dangling symlink, the symlink itself is
+// replaced rather than writing to the file it points to and the returned
+// value is true. The caller must remove the symlink before creating the file.
+func checkTarget(path string, force bool) (bool, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%w: stat target file: %w", ErrDictzip, err)
+	}
+
+	mode := fi.Mode()
+	switch {
+	case mode.IsDir():
+		return false, fmt.Errorf("%w: %s is a directory", errTargetNotRegular, path)
+	case mode&fs.ModeSymlink != 0:
+		if !force {
+			return false, fmt.Errorf("%w: %s is a symlink (use --force to replace it)", errTargetExists, path)
+		}
+		return true, nil
+	case !mode.IsRegular():
+		return false, fmt.Errorf("%w: %s is a %s file", errTargetNotRegular, path, fileType(mode))
+	case !force:
+		return false, fmt.Errorf("%w: %s (use --force to overwrite it)", errTargetExists, path)
+	}
+	return false, nil
+}
+
+// removeSymlink removes the symlink at path replaced by the target file.
+func removeSymlink(path string) error {
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("%w: removing target symlink: %w", ErrDictzip, err)
+	}
+	return nil
+}
+
+// fileType returns a description of the type of a special file.
+func fileType(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "device"
+	default:
+		return "special"
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestApp_target(t *testing.T) {
+	t.Parallel()
+
+	data := strings.Repeat("dictzip target test\n", 200)
+
+	testCases := map[string]struct {
+		// decompress indicates that the target is written by decompression.
+		decompress bool
+		force      bool
+
+		// setup creates the target at path in dir.
+		setup func(t *testing.T, dir, path string)
+
+		err error
+
+		// link is the path of a file which must not be written to via a
+		// symlink.
+		link string
+	}{
+		"directory": {
+			setup: func(t *testing.T, _, path string) {
+				if err := os.Mkdir(path, 0o700); err != nil {
+					t.Fatalf("Mkdir: %v", err)
+				}
+			},
+			err: errTargetNotRegular,
+		},
+		"directory force": {
+			force: true,
+			setup: func(t *testing.T, _, path string) {
+				if err := os.Mkdir(path, 0o700); err != nil {
+					t.Fatalf("Mkdir: %v", err)
+				}
+			},
+			err: errTargetNotRegular,
+		},
+		"regular": {
+			setup: func(t *testing.T, _, path string) {
+				if err := os.WriteFile(path, nil, 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			},
+			err: errTargetExists,
+		},
+		"regular force": {
+			force: true,
+			setup: func(t *testing.T, _, path string) {
+				if err := os.WriteFile(path, []byte(strings.Repeat("x", 10000)), 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			},
+		},
+		"dangling symlink": {
+			setup: func(t *testing.T, dir, path string) {
+				symlink(t, filepath.Join(dir, "missing"), path)
+			},
+			err: errTargetExists,
+		},
+		"dangling symlink force": {
+			force: true,
+			setup: func(t *testing.T, dir, path string) {
+				symlink(t, filepath.Join(dir, "missing"), path)
+			},
+			link: "missing",
+		},
+		"symlink force": {
+			force: true,
+			setup: func(t *testing.T, dir, path string) {
+				other := filepath.Join(dir, "other")
+				if err := os.WriteFile(other, nil, 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+				symlink(t, other, path)
+			},
+			link: "other",
+		},
+		"decompress directory force": {
+			decompress: true,
+			force:      true,
+			setup: func(t *testing.T, _, path string) {
+				if err := os.Mkdir(path, 0o700); err != nil {
+					t.Fatalf("Mkdir: %v", err)
+				}
+			},
+			err: errTargetNotRegular,
+		},
+		"decompress regular": {
+			decompress: true,
+			setup: func(t *testing.T, _, path string) {
+				if err := os.WriteFile(path, nil, 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			},
+			err: errTargetExists,
+		},
+		"decompress regular force": {
+			decompress: true,
+			force:      true,
+			setup: func(t *testing.T, _, path string) {
+				// NOTE: The existing file is longer than the data and must
+				// be truncated.
+				if err := os.WriteFile(path, []byte(strings.Repeat("x", 10000)), 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			},
+		},
+		"decompress dangling symlink force": {
+			decompress: true,
+			force:      true,
+			setup: func(t *testing.T, dir, path string) {
+				symlink(t, filepath.Join(dir, "missing"), path)
+			},
+			link: "missing",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, "test.txt")
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+
+			args := []string{"--keep"}
+			target := path + ".dz"
+			src := path
+			if tc.decompress {
+				// NOTE: The source file is removed after compression.
+				runApp(t, "--chunk-size", "1000", path)
+				args = append(args, "--decompress")
+				target, src = path, path+".dz"
+			}
+			if tc.force {
+				args = append(args, "--force")
+			}
+			tc.setup(t, dir, target)
+
+			_, _, err := runAppErr(append(args, "--chunk-size", "1000", src)...)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("dictzip (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+
+			fi, err := os.Lstat(target)
+			if err != nil {
+				t.Fatalf("Lstat: %v", err)
+			}
+			if !fi.Mode().IsRegular() {
+				t.Errorf("target mode: got %v, want regular file", fi.Mode())
+			}
+			if tc.decompress {
+				got, err := os.ReadFile(target)
+				if err != nil {
+					t.Fatalf("ReadFile: %v", err)
+				}
+				if diff := cmp.Diff(data, string(got)); diff != "" {
+					t.Errorf("target (-want, +got):\n%s", diff)
+				}
+			}
+			if tc.link != "" {
+				fi, err := os.Stat(filepath.Join(dir, tc.link))
+				if err == nil && fi.Size() != 0 {
+					t.Errorf("symlink target %q was written to", tc.link)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckTarget_special(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("no /dev/null device file")
+	}
+
+	_, err := checkTarget(os.DevNull, true)
+	if diff := cmp.Diff(errTargetNotRegular, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("checkTarget (-want, +got):\n%s", diff)
+	}
+}
+
+// symlink creates a symlink at path pointing to target or skips the test if
+// symlinks are not supported.
+func symlink(t *testing.T, target, path string) {
+	t.Helper()
+
+	if err := os.Symlink(target, path); err != nil {
+		t.Skipf("Symlink: %v", err)
+	}
+}
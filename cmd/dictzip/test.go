@@ -0,0 +1,182 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+type test struct {
+	path string
+	out  *output
+
+	// chunks is the number of randomly selected chunks to test. All data is
+	// tested if chunks is zero.
+	chunks int
+
+	// sampled and total are the number of chunks tested and the total number
+	// of chunks when sampling.
+	sampled, total int
+
+	// markVerified indicates that fully tested files are marked as verified
+	// using an extended attribute and that marked files which have not
+	// changed are not tested again.
+	markVerified bool
+}
+
+// Run tests the integrity of the compressed file by decompressing it fully or
+// by decompressing a sample of its chunks.
+func (t *test) Run() error {
+	// NOTE: Only full tests are recorded so sampling always tests the file.
+	record := t.markVerified && t.chunks == 0
+	if record {
+		if at, ok := verifiedAt(t.path); ok {
+			_ = must(fmt.Fprintf(t.out.w, "%s: %s (verified %s)\n",
+				t.path, t.out.ok("OK"), at.Format(time.RFC3339)))
+			return nil
+		}
+	}
+
+	if err := t.test(); err != nil {
+		_ = must(fmt.Fprintf(t.out.w, "%s: %s\n", t.path, t.out.fail("FAILED")))
+		return err
+	}
+	if t.chunks > 0 && t.total > 0 {
+		_ = must(fmt.Fprintf(t.out.w, "%s: %s (%d of %d chunks tested)\n",
+			t.path, t.out.ok("OK"), t.sampled, t.total))
+		return nil
+	}
+	_ = must(fmt.Fprintf(t.out.w, "%s: %s\n", t.path, t.out.ok("OK")))
+
+	if record {
+		// NOTE: Failing to record the result does not fail the test.
+		if err := markVerified(t.path, time.Now()); err != nil {
+			t.out.warn("%s: not marked as verified: %v", t.path, err)
+		}
+	}
+	return nil
+}
+
+func (t *test) test() error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	format, err := dictzip.DetectFormat(f)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+
+	var r io.ReadCloser
+	switch format {
+	case dictzip.FormatDictzip:
+		if t.chunks > 0 {
+			return t.testChunks(f)
+		}
+		return t.verify(f)
+	case dictzip.FormatGzip:
+		r, err = gzip.NewReader(f)
+	case dictzip.FormatUnknown:
+		return fmt.Errorf("%w: %q", errNotGzip, t.path)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer r.Close()
+
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+
+	return nil
+}
+
+// verify verifies all chunks of the dictzip file f against the trailer and
+// the chunk checksums.
+func (t *test) verify(f *os.File) error {
+	z, err := dictzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
+
+	if err := z.Verify(); err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	return nil
+}
+
+// testChunks tests the header, the trailer, and t.chunks randomly selected
+// chunks of the dictzip file f. Chunks are verified using their checksums if
+// the archive includes chunk checksums.
+func (t *test) testChunks(f *os.File) error {
+	z, err := dictzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+	defer z.Close()
+
+	// NOTE: Size checks the trailer ISIZE against the chunks.
+	size, err := z.Size()
+	if err != nil {
+		return fmt.Errorf("%w: reading archive: %w", ErrDictzip, err)
+	}
+
+	chunkSize := int64(z.ChunkSize())
+	t.total = len(z.Sizes())
+	chunks := sampleChunks(t.chunks, t.total)
+	t.sampled = len(chunks)
+
+	buf := make([]byte, chunkSize)
+	for _, i := range chunks {
+		off := int64(i) * chunkSize
+		p := buf
+		if remaining := size - off; remaining < chunkSize {
+			p = buf[:remaining]
+		}
+		readAt := z.ReadAt
+		if z.ChunkCRCs() != nil {
+			readAt = z.ReadAtVerified
+		}
+		n, err := readAt(p, off)
+		if n < len(p) {
+			if err == nil || errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("%w: reading chunk %d: %w", ErrDictzip, i, err)
+		}
+	}
+
+	return nil
+}
+
+// sampleChunks returns the indexes of n distinct randomly selected chunks out
+// of total chunks, or of all chunks in random order if n is at least total.
+func sampleChunks(n, total int) []int {
+	if n > total {
+		n = total
+	}
+	return rand.Perm(total)[:n]
+}
@@ -0,0 +1,710 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ianlewis/go-dictzip"
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// The send and recv commands copy a dictzip file over a TCP connection. The
+// protocol is:
+//
+//  1. The sender sends an offer: the magic string, the file name, the file
+//     size, and the dictzip header.
+//  2. The receiver replies with the offset to send from. This is the end of
+//     the chunks already received and verified in a previous connection.
+//  3. The sender sends the rest of the file and the receiver verifies each
+//     chunk against the chunk table as it arrives.
+//  4. The receiver verifies the complete file and replies with the size
+//     received.
+//
+// Replies are a status byte followed by an 8 byte value, or a length
+// prefixed error message if the status is not OK. Integers are big-endian.
+
+// transferMagic starts each offer.
+const transferMagic = "DZXFER1\n"
+
+// defaultTransferAddr is the default address recv listens on. Transfers
+// are not authenticated so only local connections are accepted by default.
+const defaultTransferAddr = "127.0.0.1:7272"
+
+const (
+	// maxTransferName is the maximum length of the file name in an offer.
+	maxTransferName = 255
+
+	// maxTransferHeader is the maximum size of the dictzip header in an
+	// offer.
+	maxTransferHeader = 1 << 17
+)
+
+const (
+	// transferTrailerSize is the size of the gzip trailer following the
+	// chunks.
+	transferTrailerSize = 8
+
+	// transferFinalBlockSize is the size of the empty final deflate block
+	// that may precede the trailer, as written by [dictzip.Writer].
+	transferFinalBlockSize = 2
+)
+
+const (
+	statusOK    = byte(0)
+	statusError = byte(1)
+)
+
+// partSuffix is appended to the target path to get the path of the file
+// holding the data received so far.
+const partSuffix = ".part"
+
+var (
+	// errConnection indicates that the connection failed. The sender
+	// reconnects after connection errors.
+	errConnection = fmt.Errorf("%w: connection failed", ErrDictzip)
+
+	// errTransferRejected indicates that the receiver rejected the transfer.
+	errTransferRejected = fmt.Errorf("%w: transfer rejected", ErrDictzip)
+
+	// errTransfer indicates that the data received is invalid.
+	errTransfer = fmt.Errorf("%w: invalid transfer", ErrDictzip)
+)
+
+// offer is sent by the sender to start a transfer.
+type offer struct {
+	name   string
+	size   int64
+	header []byte
+}
+
+// writeOffer writes the offer o to w.
+func writeOffer(w io.Writer, o *offer) error {
+	b := []byte(transferMagic)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(o.name)))
+	b = append(b, o.name...)
+	b = binary.BigEndian.AppendUint64(b, uint64(o.size))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(o.header)))
+	b = append(b, o.header...)
+	if _, err := w.Write(b); err != nil {
+		return fmt.Errorf("%w: %w", errConnection, err)
+	}
+	return nil
+}
+
+// readOffer reads an offer from r.
+func readOffer(r io.Reader) (*offer, error) {
+	head := make([]byte, len(transferMagic)+2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, fmt.Errorf("%w: reading offer: %w", errConnection, err)
+	}
+	if string(head[:len(transferMagic)]) != transferMagic {
+		return nil, fmt.Errorf("%w: not a dictzip transfer", errTransfer)
+	}
+	nameLen := int(binary.BigEndian.Uint16(head[len(transferMagic):]))
+	if nameLen > maxTransferName {
+		return nil, fmt.Errorf("%w: name too long: %d bytes", errTransfer, nameLen)
+	}
+
+	buf := make([]byte, nameLen+12)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, fmt.Errorf("%w: reading offer: %w", errConnection, err)
+	}
+	o := &offer{
+		name: string(buf[:nameLen]),
+		//nolint:gosec // the size is validated against the header.
+		size: int64(binary.BigEndian.Uint64(buf[nameLen:])),
+	}
+	headerLen := binary.BigEndian.Uint32(buf[nameLen+8:])
+	if headerLen > maxTransferHeader {
+		return nil, fmt.Errorf("%w: header too large: %d bytes", errTransfer, headerLen)
+	}
+	o.header = make([]byte, headerLen)
+	if _, err := io.ReadFull(r, o.header); err != nil {
+		return nil, fmt.Errorf("%w: reading offer: %w", errConnection, err)
+	}
+	return o, nil
+}
+
+// writeStatus writes a reply to w. If err is not nil, the reply is an error
+// with err's message. Otherwise, it is OK with the value val.
+func writeStatus(w io.Writer, val int64, err error) error {
+	var b []byte
+	if err != nil {
+		msg := err.Error()
+		if len(msg) > 1024 {
+			msg = msg[:1024]
+		}
+		b = append(b, statusError)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(msg)))
+		b = append(b, msg...)
+	} else {
+		b = append(b, statusOK)
+		b = binary.BigEndian.AppendUint64(b, uint64(val))
+	}
+	if _, err := w.Write(b); err != nil {
+		return fmt.Errorf("%w: %w", errConnection, err)
+	}
+	return nil
+}
+
+// readStatus reads a reply from r and returns its value. An error wrapping
+// errTransferRejected is returned if the reply is an error.
+func readStatus(r io.Reader) (int64, error) {
+	buf := make([]byte, 9)
+	if _, err := io.ReadFull(r, buf[:1]); err != nil {
+		return 0, fmt.Errorf("%w: reading reply: %w", errConnection, err)
+	}
+	if buf[0] != statusOK {
+		if _, err := io.ReadFull(r, buf[:2]); err != nil {
+			return 0, fmt.Errorf("%w: reading reply: %w", errConnection, err)
+		}
+		msg := make([]byte, binary.BigEndian.Uint16(buf[:2]))
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return 0, fmt.Errorf("%w: reading reply: %w", errConnection, err)
+		}
+		return 0, fmt.Errorf("%w: %s", errTransferRejected, msg)
+	}
+	if _, err := io.ReadFull(r, buf[1:]); err != nil {
+		return 0, fmt.Errorf("%w: reading reply: %w", errConnection, err)
+	}
+	//nolint:gosec // the value is validated by the caller.
+	return int64(binary.BigEndian.Uint64(buf[1:])), nil
+}
+
+// send sends a dictzip file to a receiver. The transfer is resumed from the
+// last verified chunk after connection errors.
+type send struct {
+	addr string
+	path string
+
+	// retries is the number of times to reconnect after connection errors
+	// and retryDelay is the time to wait before reconnecting.
+	retries    int
+	retryDelay time.Duration
+
+	// dial connects to the receiver.
+	dial func(addr string) (net.Conn, error)
+
+	verbose bool
+	out     *output
+}
+
+func (s *send) Run() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	fInfo, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: stat %q: %w", ErrDictzip, s.path, err)
+	}
+	o := &offer{
+		name: filepath.Base(s.path),
+		size: fInfo.Size(),
+	}
+
+	// Read the header to send the chunk table with the offer.
+	if _, o.header, err = readRawHeader(f); err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		var sent int64
+		sent, err = s.send(f, o)
+		if err == nil {
+			if s.verbose {
+				_ = must(fmt.Fprintf(s.out.w, "%s: sent %d of %d bytes\n", s.path, sent, o.size))
+			}
+			return nil
+		}
+		if !errors.Is(err, errConnection) || attempt >= s.retries {
+			return err
+		}
+		s.out.warn("%s: %v: reconnecting", s.path, err)
+		time.Sleep(s.retryDelay)
+	}
+}
+
+// send sends the file f described by o over a new connection and returns the
+// number of bytes sent.
+func (s *send) send(f *os.File, o *offer) (int64, error) {
+	conn, err := s.dial(s.addr)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", errConnection, err)
+	}
+	defer conn.Close()
+
+	if err := writeOffer(conn, o); err != nil {
+		return 0, err
+	}
+	off, err := readStatus(conn)
+	if err != nil {
+		return 0, err
+	}
+	if off < 0 || off > o.size {
+		return 0, fmt.Errorf("%w: invalid offset %d", errTransfer, off)
+	}
+
+	n, err := io.Copy(conn, io.NewSectionReader(f, off, o.size-off))
+	if err != nil {
+		// The receiver may have rejected the data and closed the
+		// connection.
+		if _, rErr := readStatus(conn); errors.Is(rErr, errTransferRejected) {
+			return n, rErr
+		}
+		return n, fmt.Errorf("%w: %w", errConnection, err)
+	}
+	size, err := readStatus(conn)
+	if err != nil {
+		return n, err
+	}
+	if size != o.size {
+		return n, fmt.Errorf("%w: receiver has %d of %d bytes", errTransfer, size, o.size)
+	}
+	return n, nil
+}
+
+// recv receives a dictzip file from a sender. Connections are accepted until
+// a file has been received completely.
+type recv struct {
+	listen string
+	dir    string
+	force  bool
+
+	// ln is the listener. A listener for the listen address is created if
+	// it is nil.
+	ln net.Listener
+
+	verbose bool
+	out     *output
+}
+
+func (r *recv) Run() error {
+	if r.ln == nil {
+		ln, err := net.Listen("tcp", r.listen)
+		if err != nil {
+			return fmt.Errorf("%w: listen: %w", ErrDictzip, err)
+		}
+		r.ln = ln
+	}
+	defer r.ln.Close()
+
+	if r.verbose {
+		_ = must(fmt.Fprintf(r.out.w, "listening on %s\n", r.ln.Addr()))
+	}
+
+	for {
+		conn, err := r.ln.Accept()
+		if err != nil {
+			return fmt.Errorf("%w: accept: %w", ErrDictzip, err)
+		}
+		done, err := r.handle(conn)
+		_ = conn.Close()
+		if done {
+			return err
+		}
+		if err != nil {
+			r.out.warn("%s: %v", conn.RemoteAddr(), err)
+		}
+	}
+}
+
+// handle receives data over conn. It returns true if the transfer is done,
+// either because the file was received or because it failed in a way that
+// reconnecting can not fix.
+func (r *recv) handle(conn net.Conn) (bool, error) {
+	o, err := readOffer(conn)
+	if err != nil {
+		// NOTE: Malformed offers are ignored so that stray connections do
+		// not stop the receiver.
+		return false, err
+	}
+
+	// NOTE: Rejected offers do not stop the receiver so that a bad offer
+	// can not end a transfer that is in progress.
+	t, err := r.newTransfer(o)
+	if err != nil {
+		_ = writeStatus(conn, 0, err)
+		return false, err
+	}
+	defer t.part.Close()
+
+	off, err := t.resume()
+	if err != nil {
+		_ = writeStatus(conn, 0, err)
+		return true, err
+	}
+	if r.verbose {
+		_ = must(fmt.Fprintf(r.out.w, "%s: receiving %s from offset %d\n", conn.RemoteAddr(), t.path, off))
+	}
+	if err := writeStatus(conn, off, nil); err != nil {
+		return false, err
+	}
+
+	if err := t.receive(conn); err != nil {
+		if errors.Is(err, errConnection) {
+			return false, err
+		}
+		_ = writeStatus(conn, 0, err)
+		return true, err
+	}
+	if err := t.finish(); err != nil {
+		_ = writeStatus(conn, 0, err)
+		return true, err
+	}
+	if r.verbose {
+		_ = must(fmt.Fprintf(r.out.w, "%s: received %d bytes\n", t.path, o.size))
+	}
+
+	// NOTE: The file has been received even if the reply can not be sent.
+	_ = writeStatus(conn, o.size, nil)
+	return true, nil
+}
+
+// transfer is a file being received.
+type transfer struct {
+	offer  *offer
+	header *format.Header
+
+	// path is the target path and part is the file holding the data
+	// received so far.
+	path string
+	part *os.File
+
+	// symlink indicates that path is a symlink which is replaced.
+	symlink bool
+
+	// offsets are the offsets of each chunk and the end of the chunks.
+	offsets []int64
+
+	// next is the index of the next chunk to receive.
+	next int
+
+	buf []byte
+	fr  io.ReadCloser
+}
+
+// newTransfer validates the offer o and opens the part file.
+func (r *recv) newTransfer(o *offer) (*transfer, error) {
+	name := filepath.Base(o.name)
+	if name != o.name || name == "." || name == ".." || name == "" {
+		return nil, fmt.Errorf("%w: invalid file name %q", errTransfer, o.name)
+	}
+
+	h, headerLen, err := format.Parse(o.header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errTransfer, err)
+	}
+	if headerLen != len(o.header) {
+		return nil, fmt.Errorf("%w: header is %d bytes, got %d", errTransfer, headerLen, len(o.header))
+	}
+	offsets := make([]int64, len(h.Sizes)+1)
+	offsets[0] = int64(headerLen)
+	for i, size := range h.Sizes {
+		offsets[i+1] = offsets[i] + int64(size)
+	}
+	// NOTE: The chunks are followed by the 8 byte trailer, optionally
+	// preceded by the empty final deflate block.
+	switch o.size - offsets[len(h.Sizes)] {
+	case transferTrailerSize, transferFinalBlockSize + transferTrailerSize:
+	default:
+		return nil, fmt.Errorf("%w: size %d does not match the chunks ending at %d",
+			errTransfer, o.size, offsets[len(h.Sizes)])
+	}
+
+	path := filepath.Join(r.dir, name)
+	symlink, err := checkTarget(path, r.force)
+	if err != nil {
+		return nil, err
+	}
+
+	part, err := os.OpenFile(path+partSuffix, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("%w: opening %s: %w", ErrDictzip, path+partSuffix, err)
+	}
+	return &transfer{
+		offer:   o,
+		header:  h,
+		path:    path,
+		part:    part,
+		symlink: symlink,
+		offsets: offsets,
+		buf:     make([]byte, h.ChunkSize),
+		fr:      flate.NewReader(nil),
+	}, nil
+}
+
+// resume verifies the data already received and returns the offset to
+// receive from. Data received for a different file or after the last
+// verified chunk is discarded.
+func (t *transfer) resume() (int64, error) {
+	fInfo, err := t.part.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("%w: stat %s: %w", ErrDictzip, t.part.Name(), err)
+	}
+
+	headerLen := t.offsets[0]
+	have := make([]byte, headerLen)
+	if fInfo.Size() >= headerLen {
+		if _, err := t.part.ReadAt(have, 0); err != nil {
+			return 0, fmt.Errorf("%w: reading %s: %w", ErrDictzip, t.part.Name(), err)
+		}
+	}
+	if fInfo.Size() < headerLen || !bytes.Equal(have, t.offer.header) {
+		// Start over with the header from the offer.
+		if err := t.part.Truncate(0); err != nil {
+			return 0, fmt.Errorf("%w: truncating %s: %w", ErrDictzip, t.part.Name(), err)
+		}
+		if _, err := t.part.WriteAt(t.offer.header, 0); err != nil {
+			return 0, fmt.Errorf("%w: writing %s: %w", ErrDictzip, t.part.Name(), err)
+		}
+		return t.seek(0)
+	}
+
+	// Keep the chunks that were received completely and verify.
+	chunk := make([]byte, 0, dictzip.MaxChunkSize)
+	for t.next < len(t.header.Sizes) && t.offsets[t.next+1] <= fInfo.Size() {
+		chunk = chunk[:t.header.Sizes[t.next]]
+		if _, err := t.part.ReadAt(chunk, t.offsets[t.next]); err != nil {
+			return 0, fmt.Errorf("%w: reading %s: %w", ErrDictzip, t.part.Name(), err)
+		}
+		if err := t.verify(t.next, chunk); err != nil {
+			break
+		}
+		t.next++
+	}
+	return t.seek(t.next)
+}
+
+// seek discards the data after chunk i and returns its offset.
+func (t *transfer) seek(i int) (int64, error) {
+	t.next = i
+	off := t.offsets[i]
+	if err := t.part.Truncate(off); err != nil {
+		return 0, fmt.Errorf("%w: truncating %s: %w", ErrDictzip, t.part.Name(), err)
+	}
+	if _, err := t.part.Seek(off, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("%w: seek: %w", ErrDictzip, err)
+	}
+	return off, nil
+}
+
+// verify checks that chunk i inflates to a full chunk, or to at most a full
+// chunk for the last chunk, and matches its checksum if the archive has
+// chunk checksums. Chunks sharing the deflate window can not be inflated on
+// their own and are verified when the file is complete.
+func (t *transfer) verify(i int, chunk []byte) error {
+	if t.header.SharedWindow {
+		return nil
+	}
+
+	//nolint:forcetypeassert // flate readers implement flate.Resetter.
+	if err := t.fr.(flate.Resetter).Reset(bytes.NewReader(chunk), nil); err != nil {
+		return fmt.Errorf("%w: chunk %d: %w", errTransfer, i, err)
+	}
+	// NOTE: Chunks end with a sync marker rather than a final block so
+	// reading to the end of the chunk returns io.ErrUnexpectedEOF.
+	n, err := io.ReadFull(t.fr, t.buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: chunk %d: %w", errTransfer, i, err)
+	}
+	if n < len(t.buf) && i != len(t.header.Sizes)-1 {
+		return fmt.Errorf("%w: chunk %d: inflated to %d bytes, want %d", errTransfer, i, n, len(t.buf))
+	}
+	if t.header.ChunkCRCs != nil {
+		tab := crc32.IEEETable
+		if t.header.ChunkCRCCastagnoli {
+			tab = crc32.MakeTable(crc32.Castagnoli)
+		}
+		if crc := crc32.Checksum(t.buf[:n], tab); crc != t.header.ChunkCRCs[i] {
+			return fmt.Errorf("%w: chunk %d: CRC-32 %08x, want %08x", errTransfer, i, crc, t.header.ChunkCRCs[i])
+		}
+	}
+	return nil
+}
+
+// receive receives the rest of the file from r. Each chunk is verified
+// before it is written so that only verified chunks are kept if the
+// connection fails.
+func (t *transfer) receive(r io.Reader) error {
+	chunk := make([]byte, 0, dictzip.MaxChunkSize)
+	for ; t.next < len(t.header.Sizes); t.next++ {
+		chunk = chunk[:t.header.Sizes[t.next]]
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return fmt.Errorf("%w: %w", errConnection, err)
+		}
+		if err := t.verify(t.next, chunk); err != nil {
+			return err
+		}
+		if _, err := t.part.Write(chunk); err != nil {
+			return fmt.Errorf("%w: writing %s: %w", ErrDictzip, t.part.Name(), err)
+		}
+	}
+
+	// NOTE: newTransfer checks that the size of the data following the
+	// chunks fits in the buffer.
+	var buf [transferFinalBlockSize + transferTrailerSize]byte
+	trailer := buf[:t.offer.size-t.offsets[len(t.header.Sizes)]]
+	if _, err := io.ReadFull(r, trailer); err != nil {
+		return fmt.Errorf("%w: %w", errConnection, err)
+	}
+	if _, err := t.part.Write(trailer); err != nil {
+		return fmt.Errorf("%w: writing %s: %w", ErrDictzip, t.part.Name(), err)
+	}
+	return nil
+}
+
+// finish verifies the complete file against its trailer and moves it to the
+// target path. The data received is discarded if it is invalid.
+func (t *transfer) finish() error {
+	if err := t.verifyFile(); err != nil {
+		_ = t.part.Close()
+		_ = os.Remove(t.part.Name())
+		return err
+	}
+	if err := t.part.Sync(); err != nil {
+		return fmt.Errorf("%w: sync: %w", ErrDictzip, err)
+	}
+	if err := t.part.Close(); err != nil {
+		return fmt.Errorf("%w: closing %s: %w", ErrDictzip, t.part.Name(), err)
+	}
+	if t.symlink {
+		if err := removeSymlink(t.path); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(t.part.Name(), t.path); err != nil {
+		return fmt.Errorf("%w: renaming %s: %w", ErrDictzip, t.part.Name(), err)
+	}
+	return nil
+}
+
+// verifyFile checks the ISIZE and CRC-32 in the trailer of the received file.
+func (t *transfer) verifyFile() error {
+	z, err := dictzip.NewReader(t.part)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errTransfer, err)
+	}
+	defer z.Close()
+
+	// NOTE: Size checks the trailer ISIZE against the chunks.
+	if _, err := z.Size(); err != nil {
+		return fmt.Errorf("%w: %w", errTransfer, err)
+	}
+	sum, err := dictzip.Checksum(z, dictzip.CRC32(crc32.IEEE), 0)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errTransfer, err)
+	}
+	crc, err := readCRC(t.part, t.offer.size)
+	if err != nil {
+		return err
+	}
+	if got := binary.BigEndian.Uint32(sum); got != crc {
+		return fmt.Errorf("%w: CRC-32 %08x, want %08x", errTransfer, got, crc)
+	}
+	return nil
+}
+
+func sendCmd(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return fmt.Errorf("%w: send requires an address and at least one file", ErrFlagParse)
+	}
+	retries := c.Int("retries")
+	if retries < 0 {
+		return fmt.Errorf("%w: invalid --retries: %d", ErrFlagParse, retries)
+	}
+
+	out := newOutput(c, c.App.ErrWriter)
+	addr := c.Args().First()
+	for _, path := range c.Args().Tail() {
+		s := send{
+			addr:       addr,
+			path:       path,
+			retries:    retries,
+			retryDelay: c.Duration("retry-delay"),
+			dial: func(addr string) (net.Conn, error) {
+				return net.Dial("tcp", addr)
+			},
+			verbose: c.Bool("verbose"),
+			out:     out,
+		}
+		if err := s.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recvCmd(c *cli.Context) error {
+	if c.NArg() > 1 {
+		return fmt.Errorf("%w: recv accepts at most one directory", ErrFlagParse)
+	}
+	dir := c.Args().First()
+	if dir == "" {
+		dir = "."
+	}
+
+	r := recv{
+		listen:  c.String("listen"),
+		dir:     dir,
+		force:   c.Bool("force"),
+		verbose: c.Bool("verbose"),
+		out:     newOutput(c, c.App.ErrWriter),
+	}
+	return r.Run()
+}
+
+// sendFlags returns the flags for the send command.
+func sendFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:  "retries",
+			Usage: "reconnect up to `N` times if the connection fails",
+			Value: 5,
+		},
+		&cli.DurationFlag{
+			Name:  "retry-delay",
+			Usage: "wait `DURATION` before reconnecting",
+			Value: time.Second,
+		},
+	}
+}
+
+// recvFlags returns the flags for the recv command.
+func recvFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "listen",
+			Usage:   "listen on `ADDRESS`",
+			Aliases: []string{"l"},
+			Value:   defaultTransferAddr,
+		},
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// failingConn is a net.Conn that fails after n bytes are written.
+type failingConn struct {
+	net.Conn
+	n int
+}
+
+func (c *failingConn) Write(p []byte) (int, error) {
+	if len(p) > c.n {
+		n, _ := c.Conn.Write(p[:c.n])
+		c.n = 0
+		_ = c.Conn.Close()
+		return n, net.ErrClosed
+	}
+	c.n -= len(p)
+	//nolint:wrapcheck // error does not need to be wrapped
+	return c.Conn.Write(p)
+}
+
+// newTransferFile writes a compressed file for transfer tests and returns its
+// path.
+func newTransferFile(t *testing.T) string {
+	t.Helper()
+
+	rng := rand.New(rand.NewSource(1))
+	var b strings.Builder
+	for b.Len() < 20000 {
+		_ = must(fmt.Fprintf(&b, "line %d %d\n", b.Len(), rng.Intn(1000)))
+	}
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	runApp(t, "--chunk-size", "1000", "--chunk-checksums", path)
+	return path + ".dz"
+}
+
+// startRecv starts a receiver writing to dir and returns its address and a
+// channel receiving the error it returns.
+func startRecv(t *testing.T, dir string, force bool) (string, <-chan error) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	r := &recv{
+		dir:   dir,
+		force: force,
+		ln:    ln,
+		out:   &output{w: io.Discard, errW: io.Discard},
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- r.Run() }()
+	return ln.Addr().String(), errc
+}
+
+func newSend(addr, path string, w io.Writer) *send {
+	return &send{
+		addr:    addr,
+		path:    path,
+		retries: 3,
+		dial: func(addr string) (net.Conn, error) {
+			return net.Dial("tcp", addr)
+		},
+		verbose: true,
+		out:     &output{w: w, errW: w},
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		// part returns the data already received given the file and the
+		// number of bytes that should be sent given the size of the data
+		// following the header, or -1 if fewer bytes should be sent.
+		part func(b []byte, body int) ([]byte, int)
+
+		// failAfter is the number of bytes after which the first
+		// connection fails. The connection does not fail if zero.
+		failAfter int
+	}{
+		"new": {},
+		"reconnect": {
+			failAfter: 5000,
+		},
+		"resume": {
+			part: func(b []byte, _ int) ([]byte, int) {
+				return b[:5000], -1
+			},
+		},
+		"resume corrupt part": {
+			part: func(b []byte, _ int) ([]byte, int) {
+				p := append([]byte{}, b[:5000]...)
+				for i := 2000; i < 5000; i++ {
+					p[i] = 0xff
+				}
+				return p, -1
+			},
+		},
+		"resume other file": {
+			part: func(_ []byte, body int) ([]byte, int) {
+				return bytes.Repeat([]byte{0x1f}, 100), body
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := newTransferFile(t)
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+
+			// NOTE: The receiver writes the header from the offer.
+			_, headerLen, err := format.Parse(want)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			body := len(want) - headerLen
+
+			dir := t.TempDir()
+			wantSent := body
+			if tc.failAfter > 0 {
+				wantSent = -1
+			}
+			if tc.part != nil {
+				var part []byte
+				part, wantSent = tc.part(want, body)
+				if err := os.WriteFile(filepath.Join(dir, filepath.Base(path)+partSuffix), part, 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			}
+
+			addr, errc := startRecv(t, dir, false)
+			var out bytes.Buffer
+			s := newSend(addr, path, &out)
+			if tc.failAfter > 0 {
+				failed := false
+				s.dial = func(addr string) (net.Conn, error) {
+					conn, err := net.Dial("tcp", addr)
+					if err != nil || failed {
+						return conn, err
+					}
+					failed = true
+					return &failingConn{Conn: conn, n: tc.failAfter}, nil
+				}
+			}
+			if err := s.Run(); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("recv: %v", err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(dir, filepath.Base(path)))
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if !bytes.Equal(want, got) {
+				t.Errorf("received file does not match")
+			}
+			if _, err := os.Stat(filepath.Join(dir, filepath.Base(path)+partSuffix)); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("part file: got %v, want %v", err, os.ErrNotExist)
+			}
+
+			if tc.failAfter > 0 && !strings.Contains(out.String(), "reconnecting") {
+				t.Errorf("send output: missing reconnect: %q", out.String())
+			}
+			if wantSent >= 0 {
+				if msg := fmt.Sprintf("sent %d of %d bytes", wantSent, len(want)); !strings.Contains(out.String(), msg) {
+					t.Errorf("send output: got %q, want %q", out.String(), msg)
+				}
+			} else if strings.Contains(out.String(), fmt.Sprintf("sent %d of", body)) {
+				t.Errorf("send output: whole file sent: %q", out.String())
+			}
+		})
+	}
+}
+
+func TestTransfer_errors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("corrupt chunk", func(t *testing.T) {
+		t.Parallel()
+
+		path := newTransferFile(t)
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		for i := len(b) / 2; i < len(b)/2+100; i++ {
+			b[i] ^= 0xff
+		}
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+
+		dir := t.TempDir()
+		addr, errc := startRecv(t, dir, false)
+		err = newSend(addr, path, io.Discard).Run()
+		if diff := cmp.Diff(errTransferRejected, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("send (-want, +got):\n%s", diff)
+		}
+		if diff := cmp.Diff(errTransfer, <-errc, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("recv (-want, +got):\n%s", diff)
+		}
+		if _, err := os.Stat(filepath.Join(dir, filepath.Base(path))); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("target file: got %v, want %v", err, os.ErrNotExist)
+		}
+	})
+
+	t.Run("target exists", func(t *testing.T) {
+		t.Parallel()
+
+		path := newTransferFile(t)
+		dir := t.TempDir()
+		target := filepath.Join(dir, filepath.Base(path))
+		if err := os.WriteFile(target, []byte("existing"), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+
+		addr, errc := startRecv(t, dir, false)
+		err := newSend(addr, path, io.Discard).Run()
+		if diff := cmp.Diff(errTransferRejected, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("send (-want, +got):\n%s", diff)
+		}
+
+		// The receiver keeps accepting transfers after rejecting an offer.
+		if err := os.Remove(target); err != nil {
+			t.Fatalf("Remove: %v", err)
+		}
+		if err := newSend(addr, path, io.Discard).Run(); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+	})
+
+	t.Run("not dictzip", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "test.txt")
+		if err := os.WriteFile(path, []byte("not a dictzip file"), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		err := newSend("127.0.0.1:0", path, io.Discard).Run()
+		if diff := cmp.Diff(ErrDictzip, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("send (-want, +got):\n%s", diff)
+		}
+	})
+}
+
+func TestRecv_badOffer(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]func(chunksEnd int64) int64{
+		"huge size": func(int64) int64 {
+			return math.MaxInt64
+		},
+		"size too small": func(chunksEnd int64) int64 {
+			return chunksEnd + 7
+		},
+		"size too large": func(chunksEnd int64) int64 {
+			return chunksEnd + 11
+		},
+	}
+
+	for name, size := range testCases {
+		size := size
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := newTransferFile(t)
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			h, headerLen, err := format.Parse(b)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			chunksEnd := int64(headerLen)
+			for _, n := range h.Sizes {
+				chunksEnd += int64(n)
+			}
+
+			dir := t.TempDir()
+			addr, errc := startRecv(t, dir, false)
+
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("Dial: %v", err)
+			}
+			o := &offer{
+				name:   filepath.Base(path),
+				size:   size(chunksEnd),
+				header: b[:headerLen],
+			}
+			if err := writeOffer(conn, o); err != nil {
+				t.Fatalf("writeOffer: %v", err)
+			}
+			_, err = readStatus(conn)
+			if diff := cmp.Diff(errTransferRejected, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("readStatus (-want, +got):\n%s", diff)
+			}
+			_ = conn.Close()
+
+			// The offer is rejected without stopping the receiver.
+			if err := newSend(addr, path, io.Discard).Run(); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("recv: %v", err)
+			}
+		})
+	}
+}
+
+func TestDefaultTransferAddr(t *testing.T) {
+	t.Parallel()
+
+	// Transfers are not authenticated so recv listens on loopback by
+	// default.
+	host, _, err := net.SplitHostPort(defaultTransferAddr)
+	if err != nil {
+		t.Fatalf("SplitHostPort: %v", err)
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		t.Errorf("defaultTransferAddr: %q is not a loopback address", defaultTransferAddr)
+	}
+}
+
+func TestRecv_strayConnection(t *testing.T) {
+	t.Parallel()
+
+	path := newTransferFile(t)
+	dir := t.TempDir()
+	addr, errc := startRecv(t, dir, false)
+
+	// Connections that are not transfers do not stop the receiver.
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	_ = must(conn.Write([]byte("GET / HTTP/1.0\r\n\r\n")))
+	_ = conn.Close()
+
+	if err := newSend(addr, path, io.Discard).Run(); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// verifiedXattr is the extended attribute recording that a file has been
+// verified by --test.
+const verifiedXattr = "user.dictzip.verified"
+
+// fingerprint returns a hash identifying the contents of the file f. It is
+// calculated from the file size, the modification time, and the gzip
+// trailer so that the whole file does not need to be read.
+func fingerprint(f *os.File) (string, error) {
+	fInfo, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("%w: stat %q: %w", ErrDictzip, f.Name(), err)
+	}
+
+	var trailer []byte
+	if size := fInfo.Size(); size >= 8 {
+		trailer = make([]byte, 8)
+		if _, err := f.ReadAt(trailer, size-8); err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("%w: reading trailer: %w", ErrDictzip, err)
+		}
+	}
+
+	h := sha256.New()
+	_ = must(fmt.Fprintf(h, "%d %d %x", fInfo.Size(), fInfo.ModTime().UnixNano(), trailer))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifiedAt returns the time that the file at path was verified if it is
+// recorded and the file has not changed since.
+func verifiedAt(path string) (time.Time, bool) {
+	value, err := getXattr(path, verifiedXattr)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// The value is the fingerprint followed by the verification time in
+	// seconds since the Unix epoch.
+	hash, ts, ok := strings.Cut(string(value), " ")
+	if !ok {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	current, err := fingerprint(f)
+	if err != nil || current != hash {
+		return time.Time{}, false
+	}
+
+	return time.Unix(sec, 0), true
+}
+
+// markVerified records that the file at path was verified at the given
+// time.
+func markVerified(path string, now time.Time) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: opening file: %w", ErrDictzip, err)
+	}
+	defer f.Close()
+
+	hash, err := fingerprint(f)
+	if err != nil {
+		return err
+	}
+	return setXattr(path, verifiedXattr, []byte(fmt.Sprintf("%s %d", hash, now.Unix())))
+}
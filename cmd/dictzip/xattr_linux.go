@@ -0,0 +1,53 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// getXattr returns the value of the extended attribute name of the file at
+// path.
+func getXattr(path, name string) ([]byte, error) {
+	// NOTE: Retry if the attribute grows between getting the size and the
+	// value.
+	for {
+		size, err := syscall.Getxattr(path, name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: getxattr %q: %w", ErrDictzip, path, err)
+		}
+		buf := make([]byte, size)
+		n, err := syscall.Getxattr(path, name, buf)
+		if errors.Is(err, syscall.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: getxattr %q: %w", ErrDictzip, path, err)
+		}
+		return buf[:n], nil
+	}
+}
+
+// setXattr sets the extended attribute name of the file at path to value.
+func setXattr(path, name string, value []byte) error {
+	if err := syscall.Setxattr(path, name, value, 0); err != nil {
+		return fmt.Errorf("%w: setxattr %q: %w", ErrDictzip, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// getXattr returns the value of the extended attribute name of the file at
+// path. Extended attributes are only supported on Linux.
+func getXattr(path, _ string) ([]byte, error) {
+	return nil, fmt.Errorf("%w: getxattr %q: extended attributes", ErrUnsupported, path)
+}
+
+// setXattr sets the extended attribute name of the file at path to value.
+// Extended attributes are only supported on Linux.
+func setXattr(path, _ string, _ []byte) error {
+	return fmt.Errorf("%w: setxattr %q: extended attributes", ErrUnsupported, path)
+}
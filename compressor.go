@@ -0,0 +1,60 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"compress/flate"
+	"io"
+)
+
+// Compressor is a deflate compressor such as [flate.Writer]. Flush must
+// complete the current block and write a sync marker as done by
+// [flate.Writer.Flush], and Reset must discard the compressor's state so
+// that it writes a new deflate stream to w.
+type Compressor interface {
+	io.WriteCloser
+
+	// Flush writes any pending data and a sync marker.
+	Flush() error
+
+	// Reset discards the compressor's state and makes it equivalent to a
+	// new compressor writing to w.
+	Reset(w io.Writer)
+}
+
+// WithCompressor configures the [Writer] to use deflate compressors created
+// by newCompressor rather than [flate.NewWriter]. This allows other
+// compatible deflate implementations, or instrumented ones, to be used
+// without depending on them by default. For example:
+//
+//	z, err := dictzip.NewWriterLevel(w, level, chunkSize, dictzip.WithCompressor(
+//		func(w io.Writer, level int) (dictzip.Compressor, error) {
+//			return flate.NewWriter(w, level)
+//		},
+//	))
+//
+// The compressors are created with the level given to [NewWriterLevel]. The
+// decompressor used by the [Reader] is set by [WithDecompressor].
+func WithCompressor(newCompressor func(w io.Writer, level int) (Compressor, error)) WriterOption {
+	return func(z *Writer) {
+		z.newCompressor = newCompressor
+	}
+}
+
+// newFlateCompressor creates a new [flate.Writer].
+func newFlateCompressor(w io.Writer, level int) (Compressor, error) {
+	//nolint:wrapcheck // errors are wrapped by the caller.
+	return flate.NewWriter(w, level)
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// countingCompressor counts the number of chunks flushed.
+type countingCompressor struct {
+	*flate.Writer
+	flushes *atomic.Int64
+}
+
+func (c *countingCompressor) Flush() error {
+	c.flushes.Add(1)
+	//nolint:wrapcheck // error does not need to be wrapped
+	return c.Writer.Flush()
+}
+
+func TestWithCompressor(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("compress me with a custom compressor\n"), 1000)
+
+	testCases := map[string]struct {
+		compressFrom bool
+	}{
+		"write": {
+			compressFrom: false,
+		},
+		"compress from": {
+			compressFrom: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var flushes atomic.Int64
+			newCompressor := func(w io.Writer, level int) (Compressor, error) {
+				fw, err := flate.NewWriter(w, level)
+				if err != nil {
+					return nil, err
+				}
+				return &countingCompressor{Writer: fw, flushes: &flushes}, nil
+			}
+
+			var buf bytes.Buffer
+			z, err := NewWriterLevel(&buf, BestSpeed, 1000, WithCompressor(newCompressor))
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if tc.compressFrom {
+				_, err = z.CompressFrom(bytes.NewReader(data), int64(len(data)), 4)
+			} else {
+				_, err = z.Write(data)
+			}
+			if err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if err := z.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			if diff := cmp.Diff(int64(z.ChunkCount()), flushes.Load()); diff != "" {
+				t.Errorf("flushes (-want, +got):\n%s", diff)
+			}
+			verifyGzip(t, &buf, [][]byte{data})
+		})
+	}
+}
+
+func TestWithCompressor_error(t *testing.T) {
+	t.Parallel()
+
+	errCompressor := errors.New("compressor")
+	newCompressor := func(io.Writer, int) (Compressor, error) {
+		return nil, errCompressor
+	}
+
+	_, err := NewWriterLevel(io.Discard, DefaultCompression, 1000, WithCompressor(newCompressor))
+	if diff := cmp.Diff(errCompressor, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("NewWriterLevel (-want, +got):\n%s", diff)
+	}
+}
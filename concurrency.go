@@ -0,0 +1,188 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// WithConcurrency configures the [Reader] to inflate up to n chunks in
+// parallel when a call to [Reader.Read] or [Reader.ReadAt] spans several
+// chunks. The compressed data of the chunks is read from the underlying
+// reader in a single request before the chunks are inflated.
+//
+// Chunks are inflated sequentially if n is less than 2 and when parallel
+// inflation is not possible, that is if the chunks share the deflate window
+// (see [WithSharedWindow]), or the archive is read in salvage mode, is
+// partially downloaded, or [WithMemoryLimit] is given.
+func WithConcurrency(n int) ReaderOption {
+	return func(z *Reader) {
+		z.concurrency = n
+	}
+}
+
+// parallel reports whether a read of size bytes at off is done by
+// readParallel.
+func (z *Reader) parallel(off int64, size int) bool {
+	if z.concurrency < 2 || z.sharedWindow || z.salvage || z.downloaded >= 0 || z.memoryLimit > 0 {
+		return false
+	}
+	chunkSize := int64(z.chunkSize)
+	return size > 0 && off/chunkSize != (off+int64(size)-1)/chunkSize
+}
+
+// readParallel reads len(p) bytes of uncompressed data at offset off into p
+// inflating the chunks in parallel.
+func (z *Reader) readParallel(p []byte, off int64) (int, error) {
+	chunkSize := int64(z.chunkSize)
+	chunkCount := int64(len(z.sizes))
+	first := off / chunkSize
+	last := (off + int64(len(p)) - 1) / chunkSize
+	if last >= chunkCount {
+		last = chunkCount - 1
+	}
+	if first > last {
+		return 0, io.EOF
+	}
+
+	// NOTE: The last chunk of the archive is followed by the final deflate
+	// block so it is read sequentially using readChunk which reads it from
+	// the underlying reader.
+	end := last + 1
+	if last == chunkCount-1 {
+		end = last
+	}
+
+	var compressed []byte
+	if end > first {
+		compressed = make([]byte, z.offsets.at(int(end))-z.offsets.at(int(first)))
+		if err := z.readCompressed(compressed, z.offsets.at(int(first))); err != nil {
+			return 0, err
+		}
+	}
+
+	// span returns the range of p holding data from chunk i.
+	span := func(i int64) (int64, int64) {
+		lo, hi := i*chunkSize, (i+1)*chunkSize
+		if lo < off {
+			lo = off
+		}
+		if pEnd := off + int64(len(p)); hi > pEnd {
+			hi = pEnd
+		}
+		return lo - off, hi - off
+	}
+
+	// NOTE: Each worker reuses a decompressor which is kept for later reads.
+	workers := z.concurrency
+	if n := int(end - first); workers > n {
+		workers = n
+	}
+	for len(z.inflaters) < workers {
+		fr, err := z.decompressor(bytes.NewReader(nil))
+		if err != nil {
+			return 0, err
+		}
+		z.inflaters = append(z.inflaters, fr)
+	}
+
+	errs := make([]error, end-first)
+	chunks := make(chan int64, end-first)
+	for i := first; i < end; i++ {
+		chunks <- i
+	}
+	close(chunks)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(fr readCloseResetter) {
+			defer wg.Done()
+			for i := range chunks {
+				data := compressed[z.offsets.at(int(i))-z.offsets.at(int(first)) : z.offsets.at(int(i+1))-z.offsets.at(int(first))]
+				lo, hi := span(i)
+				errs[i-first] = inflateInto(fr, data, p[lo:hi], off+lo-i*chunkSize)
+			}
+		}(z.inflaters[w])
+	}
+
+	// Read the last chunk of the archive while the other chunks are
+	// inflated.
+	n := len(p)
+	var err error
+	if end == last {
+		lo, _ := span(last)
+		var buf []byte
+		buf, err = z.readChunk(off+lo, len(p)-int(lo))
+		n = int(lo) + copy(p[lo:], buf)
+	}
+	wg.Wait()
+
+	for i, chunkErr := range errs {
+		if chunkErr != nil {
+			lo, _ := span(first + int64(i))
+			return int(lo), chunkErr
+		}
+	}
+	if n < len(p) && err == nil {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// readCompressed reads len(p) bytes of compressed data at offset off of the
+// underlying reader into p. A single positional read is used if the
+// underlying reader implements [io.ReaderAt], such as when [WithPread] is
+// given. Otherwise, the underlying reader is seeked to off.
+func (z *Reader) readCompressed(p []byte, off int64) error {
+	if ra, ok := z.r.(io.ReaderAt); ok {
+		n, err := ra.ReadAt(p, off)
+		if n < len(p) {
+			if err == nil || errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("%w: reading chunks: %w", errDictzip, err)
+		}
+		return nil
+	}
+
+	if _, err := z.r.Seek(off, io.SeekStart); err != nil {
+		return fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+	if _, err := io.ReadFull(z.r, p); err != nil {
+		z.resetState()
+		return fmt.Errorf("%w: reading chunks: %w", errDictzip, err)
+	}
+	return nil
+}
+
+// inflateInto inflates the compressed chunk data into p using fr after
+// discarding the first skip bytes of uncompressed data.
+func inflateInto(fr readCloseResetter, data, p []byte, skip int64) error {
+	if err := fr.Reset(bytes.NewReader(data), nil); err != nil {
+		return fmt.Errorf("%w: Reset: %w", errDictzip, err)
+	}
+	if _, err := io.CopyN(io.Discard, fr, skip); err != nil {
+		return fmt.Errorf("%w: inflating chunk: %w", errDictzip, err)
+	}
+	if _, err := io.ReadFull(fr, p); err != nil {
+		return fmt.Errorf("%w: inflating chunk: %w", errDictzip, err)
+	}
+	return nil
+}
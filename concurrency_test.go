@@ -0,0 +1,192 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithConcurrency(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	var data []byte
+	for i := 0; i < 2000; i++ {
+		data = append(data, []byte("Hello, WithConcurrency! ")...)
+		data = append(data, byte(rng.Intn(256)))
+	}
+
+	testCases := map[string]struct {
+		chunkSize   int
+		concurrency int
+	}{
+		"sequential": {
+			chunkSize:   1000,
+			concurrency: 1,
+		},
+		"two": {
+			chunkSize:   1000,
+			concurrency: 2,
+		},
+		"many": {
+			chunkSize:   333,
+			concurrency: 16,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriterLevel(&buf, DefaultCompression, tc.chunkSize)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			z, err := NewReader(bytes.NewReader(buf.Bytes()), WithConcurrency(tc.concurrency))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			got, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(data, got); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+
+			for i := 0; i < 100; i++ {
+				off := rng.Intn(len(data))
+				n := rng.Intn(len(data) - off + 100)
+				p := make([]byte, n)
+				m, err := z.ReadAt(p, int64(off))
+				want := data[off:]
+				if len(want) > n {
+					want = want[:n]
+				}
+				if m < n && !errors.Is(err, io.EOF) {
+					t.Fatalf("ReadAt(%d, %d): got %d bytes and error %v, want io.EOF", off, n, m, err)
+				}
+				if m == n && err != nil && !errors.Is(err, io.EOF) {
+					t.Fatalf("ReadAt(%d, %d): %v", off, n, err)
+				}
+				if diff := cmp.Diff(want, p[:m]); diff != "" {
+					t.Fatalf("ReadAt(%d, %d) (-want, +got):\n%s", off, n, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestWithConcurrency_corrupt(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("Hello, WithConcurrency!\n"), 1000)
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 1000)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	offsets := z.Offsets()
+	z.Close()
+
+	// Corrupt the third chunk.
+	archive := buf.Bytes()
+	for i := offsets[2]; i < offsets[3]; i++ {
+		archive[i] = 0xff
+	}
+
+	z, err = NewReader(bytes.NewReader(archive), WithConcurrency(4))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	p := make([]byte, 5000)
+	n, err := z.ReadAt(p, 0)
+	if err == nil {
+		t.Fatalf("ReadAt: expected error")
+	}
+	// The chunks before the corrupt chunk are read.
+	if diff := cmp.Diff(2000, n); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(data[:n], p[:n]); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+}
+
+func BenchmarkReaderReadAt_concurrency(b *testing.B) {
+	data := benchmarkData(4 << 20)
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 32768)
+	if err != nil {
+		b.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		b.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatalf("Close: %v", err)
+	}
+
+	for _, concurrency := range []int{1, 4} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			z, err := NewReader(bytes.NewReader(buf.Bytes()), WithConcurrency(concurrency))
+			if err != nil {
+				b.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			p := make([]byte, 1<<20)
+			b.SetBytes(int64(len(p)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := z.ReadAt(p, 100); err != nil {
+					b.Fatalf("ReadAt: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip_test
+
+import (
+	"testing"
+	"testing/quick"
+
+	"github.com/ianlewis/go-dictzip"
+	"github.com/ianlewis/go-dictzip/dztest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		ropts []dictzip.ReaderOption
+		wopts []dictzip.WriterOption
+	}{
+		"default": {},
+		"shared window": {
+			wopts: []dictzip.WriterOption{dictzip.WithSharedWindow()},
+		},
+		"store incompressible": {
+			wopts: []dictzip.WriterOption{dictzip.WithStoreIncompressible()},
+		},
+		"chunk checksums": {
+			wopts: []dictzip.WriterOption{dictzip.WithChunkChecksums()},
+		},
+		"memory buffer": {
+			wopts: []dictzip.WriterOption{dictzip.WithMemoryBuffer()},
+		},
+		"read cache": {
+			ropts: []dictzip.ReaderOption{dictzip.WithReadCache(1 << 20)},
+		},
+		"chunk cache": {
+			ropts: []dictzip.ReaderOption{dictzip.WithChunkCache(1 << 20)},
+		},
+		"prefetch": {
+			ropts: []dictzip.ReaderOption{dictzip.WithPrefetch(4)},
+		},
+		"concurrency": {
+			ropts: []dictzip.ReaderOption{dictzip.WithConcurrency(4)},
+		},
+		"memory limit": {
+			ropts: []dictzip.ReaderOption{dictzip.WithMemoryLimit(1 << 16)},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dztest.RunConformance(t, dztest.DefaultBackend(tc.ropts, tc.wopts))
+		})
+	}
+}
+
+func TestRoundTripProperty(t *testing.T) {
+	t.Parallel()
+
+	if err := quick.Check(dztest.RoundTripProperty(dztest.DefaultBackend(nil, nil)), nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte("Hello World!"), uint16(5), uint16(0), byte(0))
+	f.Add([]byte("abcdefghijklmnopqrstuvwxyz"), uint16(3), uint16(40), byte(0xff))
+
+	f.Fuzz(func(t *testing.T, data []byte, chunkSize, corruptOff uint16, corrupt byte) {
+		// NOTE: The RA subfield holds at most about 32K chunks.
+		size := int(chunkSize%256) + 1
+		if len(data) > 1<<16 || len(data)/size > 30000 {
+			return
+		}
+		b := dztest.NewArchiveBuilder(size)
+		for off := 0; off < len(data); off += size {
+			end := off + size
+			if end > len(data) {
+				end = len(data)
+			}
+			b.AddChunk(data[off:end])
+		}
+		archive := b.Build()
+		if corrupt != 0 {
+			archive[int(corruptOff)%len(archive)] ^= corrupt
+		}
+
+		// NOTE: Corrupt archives must not cause a panic but may fail to be
+		// read in any way.
+		err := dztest.CheckReader(dztest.DefaultBackend(nil, nil), archive, data)
+		if corrupt == 0 && err != nil {
+			t.Errorf("CheckReader: %v", err)
+		}
+	})
+}
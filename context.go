@@ -0,0 +1,81 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ContextReader is implemented by underlying readers which support
+// cancellation of reads, such as adapters for remote storage that make a
+// request for each read. [Reader.ReadAtContext] calls ReadContext rather than
+// Read so that in-flight requests are cancelled along with the context.
+type ContextReader interface {
+	ReadContext(ctx context.Context, p []byte) (int, error)
+}
+
+// ReadAtContext is like [Reader.ReadAt] but stops reading from the
+// underlying reader when ctx is done. If the underlying reader implements
+// [ContextReader], ctx is passed to its ReadContext method. Otherwise, ctx is
+// only checked between reads from the underlying reader.
+//
+// An error wrapping ctx.Err() is returned if ctx is done before the read
+// completes.
+func (z *Reader) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	z.lock()
+	defer z.unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%w: %w", errDictzip, err)
+	}
+
+	r := z.r
+	z.r = &contextReadSeeker{ctx: ctx, r: r}
+	defer func() {
+		z.r = r
+	}()
+
+	return z.readAt(p, off)
+}
+
+// contextReadSeeker is an [io.ReadSeeker] that reads from r using ctx.
+type contextReadSeeker struct {
+	ctx context.Context
+	r   io.ReadSeeker
+}
+
+// Read implements [io.Reader.Read].
+func (c *contextReadSeeker) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%w: %w", errDictzip, err)
+	}
+	if cr, ok := c.r.(ContextReader); ok {
+		//nolint:wrapcheck // errors are wrapped by the Reader.
+		return cr.ReadContext(c.ctx, p)
+	}
+	//nolint:wrapcheck // errors are wrapped by the Reader.
+	return c.r.Read(p)
+}
+
+// Seek implements [io.Seeker.Seek].
+func (c *contextReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%w: %w", errDictzip, err)
+	}
+	//nolint:wrapcheck // errors are wrapped by the Reader.
+	return c.r.Seek(offset, whence)
+}
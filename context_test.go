@@ -0,0 +1,170 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// testContextReader is an io.ReadSeeker implementing ContextReader. It calls
+// onRead for each ReadContext call.
+type testContextReader struct {
+	io.ReadSeeker
+	onRead func(ctx context.Context)
+}
+
+func (r *testContextReader) ReadContext(ctx context.Context, p []byte) (int, error) {
+	r.onRead(ctx)
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	//nolint:wrapcheck // error does not need to be wrapped
+	return r.ReadSeeker.Read(p)
+}
+
+type ctxKey struct{}
+
+func TestReader_ReadAtContext(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("Hello, context!")
+	r := &testContextReader{ReadSeeker: bytes.NewReader(mustCompress(t, data))}
+	var ctxs []context.Context
+	r.onRead = func(ctx context.Context) {
+		ctxs = append(ctxs, ctx)
+	}
+
+	z, err := NewReader(r)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	// NOTE: NewReader does not use a context.
+	if diff := cmp.Diff(0, len(ctxs)); diff != "" {
+		t.Errorf("ReadContext calls (-want, +got):\n%s", diff)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "lookup")
+	buf := make([]byte, 7)
+	n, err := z.ReadAtContext(ctx, buf, 7)
+	if err != nil {
+		t.Fatalf("ReadAtContext: %v", err)
+	}
+	if diff := cmp.Diff("context", string(buf[:n])); diff != "" {
+		t.Errorf("ReadAtContext (-want, +got):\n%s", diff)
+	}
+
+	if len(ctxs) == 0 {
+		t.Fatalf("ReadContext was not called")
+	}
+	for _, got := range ctxs {
+		if diff := cmp.Diff("lookup", got.Value(ctxKey{})); diff != "" {
+			t.Errorf("context value (-want, +got):\n%s", diff)
+		}
+	}
+
+	// ReadAt is not affected by the previous context.
+	ctxs = nil
+	n, err = z.ReadAt(buf, 0)
+	if err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if diff := cmp.Diff("Hello, ", string(buf[:n])); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(0, len(ctxs)); diff != "" {
+		t.Errorf("ReadContext calls (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReader_ReadAtContext_cancel(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		// contextReader indicates that the underlying reader implements
+		// ContextReader.
+		contextReader bool
+
+		// cancelBefore indicates that the context is cancelled before
+		// calling ReadAtContext.
+		cancelBefore bool
+	}{
+		"cancelled": {
+			contextReader: true,
+			cancelBefore:  true,
+		},
+		"in-flight": {
+			contextReader: true,
+		},
+		"no context reader": {
+			cancelBefore: true,
+		},
+	}
+
+	data := []byte("Hello, context!")
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var r io.ReadSeeker = bytes.NewReader(mustCompress(t, data))
+			if tc.contextReader {
+				r = &testContextReader{
+					ReadSeeker: r,
+					onRead: func(context.Context) {
+						// Cancel the context while the request is in
+						// flight.
+						cancel()
+					},
+				}
+			}
+
+			z, err := NewReader(r)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			if tc.cancelBefore {
+				cancel()
+			}
+
+			buf := make([]byte, 7)
+			_, err = z.ReadAtContext(ctx, buf, 7)
+			if diff := cmp.Diff(context.Canceled, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ReadAtContext (-want, +got):\n%s", diff)
+			}
+
+			// The Reader can be used after the cancelled read.
+			n, err := z.ReadAt(buf, 7)
+			if err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if diff := cmp.Diff("context", string(buf[:n])); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
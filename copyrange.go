@@ -0,0 +1,74 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// copyRangeBufSize is the size of the buffer used by CopyRange for plain gzip
+// files, which have no chunks.
+const copyRangeBufSize = 32 * 1024
+
+// CopyRange writes n bytes of uncompressed data starting at offset off to dst
+// and returns the number of bytes written. If n is negative, the data from off
+// to the end is written. The data is read a chunk at a time, so that at most
+// one chunk is held in memory, and does not change the offset of z.
+//
+// As with [io.CopyN], CopyRange returns io.EOF if the data ends before n
+// bytes are written.
+func (z *Reader) CopyRange(dst io.Writer, off, n int64) (int64, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+
+	bufSize := int64(z.ChunkSize())
+	if bufSize == 0 {
+		bufSize = copyRangeBufSize
+	}
+	buf := make([]byte, bufSize)
+
+	var written int64
+	for n < 0 || written < n {
+		size := bufSize - off%bufSize
+		if n >= 0 && n-written < size {
+			size = n - written
+		}
+
+		// NOTE: Reads are aligned to chunk boundaries so that each chunk is
+		// inflated once.
+		read, err := z.ReadAt(buf[:size], off)
+		if read > 0 {
+			w, wErr := dst.Write(buf[:read])
+			written += int64(w)
+			off += int64(w)
+			if wErr != nil {
+				return written, fmt.Errorf("%w: writing: %w", errDictzip, wErr)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			if n < 0 {
+				return written, nil
+			}
+			return written, io.EOF
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
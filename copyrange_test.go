@@ -0,0 +1,114 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// maxWriter records the size of the largest write.
+type maxWriter struct {
+	bytes.Buffer
+	max int
+}
+
+func (w *maxWriter) Write(p []byte) (int, error) {
+	if len(p) > w.max {
+		w.max = len(p)
+	}
+	//nolint:wrapcheck // error does not need to be wrapped
+	return w.Buffer.Write(p)
+}
+
+func TestReader_CopyRange(t *testing.T) {
+	t.Parallel()
+
+	var text bytes.Buffer
+	for i := 0; text.Len() < 5000; i++ {
+		fmt.Fprintf(&text, "line %d\n", i)
+	}
+	data := text.Bytes()
+	const chunkSize = 1000
+
+	testCases := map[string]struct {
+		off, n int64
+		want   []byte
+		err    error
+	}{
+		"all": {
+			n:    -1,
+			want: data,
+		},
+		"middle": {
+			off:  1500,
+			n:    2000,
+			want: data[1500:3500],
+		},
+		"to end": {
+			off:  4321,
+			n:    -1,
+			want: data[4321:],
+		},
+		"empty": {
+			off: 100,
+		},
+		"past end": {
+			off:  int64(len(data)) - 10,
+			n:    20,
+			want: data[len(data)-10:],
+			err:  io.EOF,
+		},
+		"negative offset": {
+			off: -1,
+			n:   -1,
+			err: ErrNegativeOffset,
+		},
+	}
+
+	b := compressStream(t, data, chunkSize)
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			var w maxWriter
+			n, err := z.CopyRange(&w, tc.off, tc.n)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("CopyRange (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(int64(len(tc.want)), n); diff != "" {
+				t.Errorf("CopyRange n (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(string(tc.want), w.String()); diff != "" {
+				t.Errorf("CopyRange data (-want, +got):\n%s", diff)
+			}
+			if w.max > chunkSize {
+				t.Errorf("CopyRange: write of %d bytes, want at most %d", w.max, chunkSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import "crypto/sha256"
+
+// DedupStats are statistics about chunks with identical uncompressed data
+// reported by [Writer.DedupStats].
+type DedupStats struct {
+	// Chunks is the number of chunks written.
+	Chunks int
+
+	// Duplicates is the number of chunks whose uncompressed data is identical
+	// to an earlier chunk.
+	Duplicates int
+
+	// DuplicateBytes is the uncompressed size of the duplicate chunks.
+	DuplicateBytes int64
+
+	// DuplicateCompressedBytes is the compressed size of the duplicate
+	// chunks. It is the size that could be saved by a format supporting
+	// references to earlier chunks.
+	DuplicateCompressedBytes int64
+}
+
+// WithDedup configures the [Writer] to detect chunks with identical
+// uncompressed data by hashing each chunk with SHA-256. The duplicates are
+// reported by [Writer.DedupStats] and [Writer.DuplicateOf].
+//
+// The RA subfield only stores the size of each chunk and chunk offsets are
+// calculated from the sizes of the preceding chunks, so chunks can not refer
+// to the compressed data of an earlier chunk. Duplicate chunks are therefore
+// still written, and the statistics can be used to decide whether highly
+// repetitive data would be better stored by other means.
+func WithDedup() WriterOption {
+	return func(z *Writer) {
+		z.dedupHash = sha256.New()
+		z.dedupSeen = map[[sha256.Size]byte]int{}
+		z.duplicateOf = map[int]int{}
+	}
+}
+
+// DedupStats returns statistics about duplicate chunks written so far. It
+// returns the zero value unless [WithDedup] was given.
+func (z *Writer) DedupStats() DedupStats {
+	return z.dedupStats
+}
+
+// DuplicateOf returns the index of the first chunk with identical
+// uncompressed data to the chunk at index and whether the chunk at index is a
+// duplicate. It always returns false unless [WithDedup] was given.
+func (z *Writer) DuplicateOf(index int) (int, bool) {
+	first, ok := z.duplicateOf[index]
+	return first, ok
+}
+
+// dedupChunk records the last chunk written given the hash of its
+// uncompressed data. n is its uncompressed size.
+func (z *Writer) dedupChunk(sum [sha256.Size]byte, n int) {
+	index := len(z.sizes) - 1
+	z.dedupStats.Chunks++
+	if first, ok := z.dedupSeen[sum]; ok {
+		z.duplicateOf[index] = first
+		z.dedupStats.Duplicates++
+		z.dedupStats.DuplicateBytes += int64(n)
+		z.dedupStats.DuplicateCompressedBytes += int64(z.sizes[index])
+		return
+	}
+	z.dedupSeen[sum] = index
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithDedup(t *testing.T) {
+	t.Parallel()
+
+	// Chunks 0, 2, and 3 are identical as are chunks 1 and 4. The last
+	// partial chunk is unique.
+	a := bytes.Repeat([]byte("a"), 100)
+	b := bytes.Repeat([]byte("b"), 100)
+	data := bytes.Join([][]byte{a, b, a, a, b, []byte("abc")}, nil)
+
+	testCases := map[string]struct {
+		write func(z *Writer) error
+	}{
+		"Write": {
+			write: func(z *Writer) error {
+				_, err := z.Write(data)
+				return err
+			},
+		},
+		"CompressFrom": {
+			write: func(z *Writer) error {
+				_, err := z.CompressFrom(bytes.NewReader(data), int64(len(data)), 2)
+				return err
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			z, err := NewWriterLevel(&buf, DefaultCompression, 100, WithDedup())
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if err := tc.write(z); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if err := z.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			sizes := z.Sizes()
+			want := DedupStats{
+				Chunks:                   6,
+				Duplicates:               3,
+				DuplicateBytes:           300,
+				DuplicateCompressedBytes: int64(sizes[2] + sizes[3] + sizes[4]),
+			}
+			if diff := cmp.Diff(want, z.DedupStats()); diff != "" {
+				t.Errorf("DedupStats (-want, +got):\n%s", diff)
+			}
+
+			got := map[int]int{}
+			for i := range sizes {
+				if first, ok := z.DuplicateOf(i); ok {
+					got[i] = first
+				}
+			}
+			if diff := cmp.Diff(map[int]int{2: 0, 3: 0, 4: 1}, got); diff != "" {
+				t.Errorf("DuplicateOf (-want, +got):\n%s", diff)
+			}
+
+			// Duplicate chunks are still written.
+			r, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer r.Close()
+			out := make([]byte, len(data))
+			if _, err := r.ReadAt(out, 0); err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if diff := cmp.Diff(data, out); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriter_DedupStats_disabled(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	z, err := NewWriterLevel(&buf, DefaultCompression, 10)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := z.Write(bytes.Repeat([]byte("a"), 100)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if diff := cmp.Diff(DedupStats{}, z.DedupStats()); diff != "" {
+		t.Errorf("DedupStats (-want, +got):\n%s", diff)
+	}
+	if _, ok := z.DuplicateOf(1); ok {
+		t.Errorf("DuplicateOf: got true, want false")
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Field describes a single field in a dictzip file.
+type Field struct {
+	// Name is the name of the field as given in RFC 1952 or dictzip(1)
+	// (e.g. "ID1", "MTIME", "CHLEN").
+	Name string
+
+	// Offset is the byte offset of the field in the file.
+	Offset int64
+
+	// Len is the length of the field in bytes.
+	Len int64
+
+	// Value is a human readable representation of the field's value.
+	Value string
+}
+
+// SubfieldDescription describes a sub-field of the EXTRA header field.
+type SubfieldDescription struct {
+	// ID is the sub-field ID (SI1 and SI2).
+	ID [2]byte
+
+	// Offset is the byte offset of SI1 in the file.
+	Offset int64
+
+	// Len is the value of the sub-field's LEN field. It does not include the
+	// SI1, SI2, and LEN fields themselves.
+	Len int
+
+	// Fields are the fields that make up the sub-field. Fields are only
+	// described for known sub-fields.
+	Fields []Field
+}
+
+// ChunkDescription describes a single compressed dictzip chunk.
+type ChunkDescription struct {
+	// Index is the zero-based index of the chunk.
+	Index int
+
+	// EntryOffset is the byte offset of the chunk's size entry in the RA
+	// sub-field.
+	EntryOffset int64
+
+	// Offset is the byte offset of the start of the compressed chunk.
+	Offset int64
+
+	// Len is the length of the compressed chunk.
+	Len int64
+
+	// UncompressedOffset is the offset of the chunk's data in the uncompressed
+	// data.
+	UncompressedOffset int64
+}
+
+// Description is a structured breakdown of the layout of a dictzip file. It
+// is intended for debugging and diagnosing interoperability issues.
+type Description struct {
+	// Header lists the gzip header fields in file order. The EXTRA
+	// sub-fields are described separately in Subfields.
+	Header []Field
+
+	// Subfields lists the EXTRA sub-fields in file order.
+	Subfields []SubfieldDescription
+
+	// Chunks lists the compressed chunks in file order.
+	Chunks []ChunkDescription
+
+	// Trailer lists the fields following the compressed chunks. This
+	// includes the final deflate data, CRC32, and ISIZE fields.
+	Trailer []Field
+
+	// Size is the total size of the file.
+	Size int64
+}
+
+// osNames are human readable names for OS header values.
+var osNames = map[byte]string{
+	OSFAT:       "FAT",
+	OSAmiga:     "Amiga",
+	OSVMS:       "VMS",
+	OSUnix:      "Unix",
+	OSVM:        "VM/CMS",
+	OSAtari:     "Atari TOS",
+	OSHPFS:      "HPFS",
+	OSMacintosh: "Macintosh",
+	OSZSystem:   "Z-System",
+	OSCPM:       "CP/M",
+	OSTOPS20:    "TOPS-20",
+	OSNTFS:      "NTFS",
+	OSQDOS:      "QDOS",
+	OSAcorn:     "Acorn RISCOS",
+	OSUnknown:   "unknown",
+}
+
+// Describe reads the dictzip file from r and returns a description of every
+// header field, EXTRA sub-field, chunk, and trailer field along with their
+// byte offsets in the file.
+//
+// Describe will call Seek on the given reader. The data must be a valid
+// dictzip file.
+func Describe(r io.ReadSeeker) (*Description, error) {
+	z, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+
+	// NOTE: The header has already been validated by NewReader.
+	head := make([]byte, z.offsets.at(0))
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, headerErr(fmt.Errorf("reading header: %w", err))
+	}
+
+	d := Description{
+		Size: size,
+	}
+
+	flg := head[3]
+	mtime := binary.LittleEndian.Uint32(head[4:8])
+	mtimeStr := "not set"
+	if mtime > 0 {
+		mtimeStr = time.Unix(int64(mtime), 0).UTC().Format(time.RFC3339)
+	}
+	d.Header = append(d.Header,
+		Field{Name: "ID1", Offset: 0, Len: 1, Value: fmt.Sprintf("0x%02x", head[0])},
+		Field{Name: "ID2", Offset: 1, Len: 1, Value: fmt.Sprintf("0x%02x", head[1])},
+		Field{Name: "CM", Offset: 2, Len: 1, Value: fmt.Sprintf("%d (deflate)", head[2])},
+		Field{Name: "FLG", Offset: 3, Len: 1, Value: fmt.Sprintf("0x%02x (%s)", flg, flgString(flg))},
+		Field{Name: "MTIME", Offset: 4, Len: 4, Value: fmt.Sprintf("%d (%s)", mtime, mtimeStr)},
+		Field{Name: "XFL", Offset: 8, Len: 1, Value: fmt.Sprintf("0x%02x", head[8])},
+		Field{Name: "OS", Offset: 9, Len: 1, Value: fmt.Sprintf("%d (%s)", head[9], osName(head[9]))},
+	)
+
+	xlen := int64(binary.LittleEndian.Uint16(head[10:12]))
+	d.Header = append(d.Header, Field{Name: "XLEN", Offset: 10, Len: 2, Value: fmt.Sprintf("%d", xlen)})
+
+	var chunkEntries []int64
+	pos := int64(12)
+	for pos < 12+xlen {
+		sub := SubfieldDescription{
+			ID:     [2]byte{head[pos], head[pos+1]},
+			Offset: pos,
+			Len:    int(binary.LittleEndian.Uint16(head[pos+2 : pos+4])),
+		}
+		data := pos + 4
+		if sub.ID == [2]byte{hdrDictzipSI1, hdrDictzipSI2} {
+			chcnt := int64(binary.LittleEndian.Uint16(head[data+4 : data+6]))
+			sub.Fields = []Field{
+				{Name: "VER", Offset: data, Len: 2, Value: fmt.Sprintf("%d", binary.LittleEndian.Uint16(head[data:data+2]))},
+				{Name: "CHLEN", Offset: data + 2, Len: 2, Value: fmt.Sprintf("%d", binary.LittleEndian.Uint16(head[data+2:data+4]))},
+				{Name: "CHCNT", Offset: data + 4, Len: 2, Value: fmt.Sprintf("%d", chcnt)},
+			}
+			for i := int64(0); i < chcnt; i++ {
+				chunkEntries = append(chunkEntries, data+6+i*2)
+			}
+		}
+		d.Subfields = append(d.Subfields, sub)
+		pos = data + int64(sub.Len)
+	}
+
+	if flg&flgNAME != 0 {
+		n := int64(utf8.RuneCountInString(z.RawName())) + 1
+		d.Header = append(d.Header, Field{Name: "NAME", Offset: pos, Len: n, Value: z.RawName()})
+		pos += n
+	}
+	if flg&flgCOMMENT != 0 {
+		n := int64(utf8.RuneCountInString(z.Comment)) + 1
+		d.Header = append(d.Header, Field{Name: "COMMENT", Offset: pos, Len: n, Value: z.Comment})
+		pos += n
+	}
+	if flg&flgCRC != 0 {
+		d.Header = append(d.Header, Field{
+			Name:   "CRC16",
+			Offset: pos,
+			Len:    2,
+			Value:  fmt.Sprintf("0x%04x", binary.LittleEndian.Uint16(head[pos:pos+2])),
+		})
+	}
+
+	for i, chunkLen := range z.sizes {
+		d.Chunks = append(d.Chunks, ChunkDescription{
+			Index:              i,
+			EntryOffset:        chunkEntries[i],
+			Offset:             z.offsets.at(i),
+			Len:                int64(chunkLen),
+			UncompressedOffset: int64(i) * int64(z.chunkSize),
+		})
+	}
+
+	trailer, err := describeTrailer(r, z.offsets.at(z.offsets.len()-1), size)
+	if err != nil {
+		return nil, err
+	}
+	d.Trailer = trailer
+
+	return &d, nil
+}
+
+// describeTrailer describes the data following the last chunk that ends at
+// offset end in a file of the given size.
+func describeTrailer(r io.ReadSeeker, end, size int64) ([]Field, error) {
+	var fields []Field
+
+	trailerStart := size - 8
+	if trailerStart < end {
+		// The trailer has been truncated.
+		return fields, nil
+	}
+
+	if trailerStart > end {
+		fields = append(fields, Field{
+			Name:   "DEFLATE",
+			Offset: end,
+			Len:    trailerStart - end,
+			Value:  "final deflate data",
+		})
+	}
+
+	if _, err := r.Seek(trailerStart, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, fmt.Errorf("%w: reading trailer: %w", errDictzip, err)
+	}
+
+	fields = append(fields,
+		Field{
+			Name:   "CRC32",
+			Offset: trailerStart,
+			Len:    4,
+			Value:  fmt.Sprintf("0x%08x", binary.LittleEndian.Uint32(buf[0:4])),
+		},
+		Field{
+			Name:   "ISIZE",
+			Offset: trailerStart + 4,
+			Len:    4,
+			Value:  fmt.Sprintf("%d", binary.LittleEndian.Uint32(buf[4:8])),
+		},
+	)
+
+	return fields, nil
+}
+
+// flgString returns the names of the flags set in flg.
+func flgString(flg byte) string {
+	var names []string
+	for _, f := range []struct {
+		bit  byte
+		name string
+	}{
+		{1 << 0, "FTEXT"},
+		{flgCRC, "FHCRC"},
+		{flgEXTRA, "FEXTRA"},
+		{flgNAME, "FNAME"},
+		{flgCOMMENT, "FCOMMENT"},
+	} {
+		if flg&f.bit != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// osName returns a human readable name for the OS header value.
+func osName(os byte) string {
+	if name, ok := osNames[os]; ok {
+		return name
+	}
+	return "unknown"
+}
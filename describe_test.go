@@ -0,0 +1,82 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("internal/testdata/hello.txt.dz")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	d, err := Describe(f)
+	if err != nil {
+		t.Fatalf("Describe: %v", err)
+	}
+
+	want := &Description{
+		Header: []Field{
+			{Name: "ID1", Offset: 0, Len: 1, Value: "0x1f"},
+			{Name: "ID2", Offset: 1, Len: 1, Value: "0x8b"},
+			{Name: "CM", Offset: 2, Len: 1, Value: "8 (deflate)"},
+			{Name: "FLG", Offset: 3, Len: 1, Value: "0x0c (FEXTRA|FNAME)"},
+			{Name: "MTIME", Offset: 4, Len: 4, Value: "1730955206 (2024-11-07T04:53:26Z)"},
+			{Name: "XFL", Offset: 8, Len: 1, Value: "0x02"},
+			{Name: "OS", Offset: 9, Len: 1, Value: "3 (Unix)"},
+			{Name: "XLEN", Offset: 10, Len: 2, Value: "12"},
+			{Name: "NAME", Offset: 24, Len: 9, Value: "test.txt"},
+		},
+		Subfields: []SubfieldDescription{
+			{
+				ID:     [2]byte{'R', 'A'},
+				Offset: 12,
+				Len:    8,
+				Fields: []Field{
+					{Name: "VER", Offset: 16, Len: 2, Value: "1"},
+					{Name: "CHLEN", Offset: 18, Len: 2, Value: "58315"},
+					{Name: "CHCNT", Offset: 20, Len: 2, Value: "1"},
+				},
+			},
+		},
+		Chunks: []ChunkDescription{
+			{
+				Index:              0,
+				EntryOffset:        22,
+				Offset:             33,
+				Len:                25,
+				UncompressedOffset: 0,
+			},
+		},
+		Trailer: []Field{
+			{Name: "DEFLATE", Offset: 58, Len: 2, Value: "final deflate data"},
+			{Name: "CRC32", Offset: 60, Len: 4, Value: "0x5c6e7a05"},
+			{Name: "ISIZE", Offset: 64, Len: 4, Value: "23"},
+		},
+		Size: 68,
+	}
+
+	if diff := cmp.Diff(want, d); diff != "" {
+		t.Errorf("Describe (-want, +got):\n%s", diff)
+	}
+}
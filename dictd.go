@@ -0,0 +1,56 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+// DictdChunkSize is the chunk size used by dictzip(1) from the dictd
+// distribution. It is smaller than [MaxChunkSize] so that the compressed data
+// of a chunk always fits in the 16-bit chunk size field of the RA subfield,
+// even if the data is incompressible.
+const DictdChunkSize = 58315
+
+// WithDictdCompatible configures the [Writer] to write archives that match
+// those written by dictzip(1) and expected by dictd(8) and other dict
+// clients. The chunk size is set to [DictdChunkSize] regardless of the chunk
+// size given to [NewWriterLevel], the RA subfield is the only subfield
+// written by the Writer, and options which are incompatible with dictd are
+// ignored. These are [WithSharedWindow], [WithChunkChecksums],
+// [WithWriteUTF8], [WithManifest], [WithContentHash], [WithExtraFields], and
+// [WithReservedChunkEntries].
+//
+// The Extra field is still written as-is and should not be set if the archive
+// is to be read by clients that only support a single subfield.
+func WithDictdCompatible() WriterOption {
+	return func(z *Writer) {
+		z.dictdCompatible = true
+	}
+}
+
+// applyDictdCompatible overrides settings that are not compatible with
+// dictd. It is called after all options have been applied.
+func (z *Writer) applyDictdCompatible() {
+	if !z.dictdCompatible {
+		return
+	}
+	z.chunkSize = DictdChunkSize
+	z.sharedWindow = false
+	z.chunkDigest = nil
+	z.chunkCRCs = nil
+	z.chunkCRCCastagnoli = false
+	z.writeUTF8 = false
+	z.manifest = nil
+	z.contentHash = nil
+	z.extraFields = nil
+	z.reservedChunks = 0
+}
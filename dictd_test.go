@@ -0,0 +1,122 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+func TestWithDictdCompatible(t *testing.T) {
+	t.Parallel()
+
+	// NOTE: Random data is incompressible so compressed chunks are larger
+	// than the uncompressed data.
+	data := make([]byte, 2*DictdChunkSize+100)
+	//nolint:gosec // a weak random number generator is fine for test data.
+	rand.New(rand.NewSource(1)).Read(data)
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, BestCompression, 100,
+		WithSharedWindow(),
+		WithChunkChecksums(),
+		WithWriteUTF8(),
+		WithDictdCompatible(),
+	)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	w.Name = "dict.txt"
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	h, _, err := format.Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if diff := cmp.Diff(DictdChunkSize, h.ChunkSize); diff != "" {
+		t.Errorf("ChunkSize (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(3, len(h.Sizes)); diff != "" {
+		t.Errorf("chunk count (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([][2]byte{{format.RASI1, format.RASI2}}, h.Subfields); diff != "" {
+		t.Errorf("Subfields (-want, +got):\n%s", diff)
+	}
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	got := make([]byte, len(data))
+	if _, err := z.ReadAt(got, 0); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Errorf("ReadAt: data does not match")
+	}
+}
+
+func TestWithDictdCompatible_ignoredOptions(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]WriterOption{
+		"manifest":       WithManifest(Manifest{ManifestSource: "test"}),
+		"content hash":   WithContentHash(),
+		"extra fields":   WithExtraFields(ExtraField{ID: [2]byte{'X', 'Y'}, Data: []byte("data")}),
+		"reserved chunk": WithReservedChunkEntries(8),
+	}
+
+	for name, opt := range testCases {
+		opt := opt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, opt, WithDictdCompatible())
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			if _, err := w.Write([]byte("Hello World!")); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			h, _, err := format.Parse(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if diff := cmp.Diff([][2]byte{{format.RASI1, format.RASI2}}, h.Subfields); diff != "" {
+				t.Errorf("Subfields (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(0, h.ReservedChunks); diff != "" {
+				t.Errorf("ReservedChunks (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -20,5 +20,6 @@
 // See: https://datatracker.ietf.org/doc/html/rfc1952
 //
 // Unless otherwise informed clients should not assume implementations in this
-// package are safe for parallel execution.
+// package are safe for parallel execution. A [Reader] created with the
+// [WithLocking] option is safe for concurrent use by multiple goroutines.
 package dictzip
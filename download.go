@@ -0,0 +1,71 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import "fmt"
+
+// ErrNotDownloaded indicates that the data read is in chunks that are not
+// within the downloaded prefix of a partially downloaded archive.
+// See [WithDownloaded].
+var ErrNotDownloaded = fmt.Errorf("%w: data not downloaded", errDictzip)
+
+// WithDownloaded configures the [Reader] to read an archive of which only
+// the first n bytes have been downloaded, as is the case for applications
+// that download archives progressively. The header must be within the
+// downloaded prefix.
+//
+// Reads of chunks that are wholly within the prefix succeed. Reads of other
+// chunks return an error wrapping [ErrNotDownloaded] after the data from the
+// downloaded chunks, if any, has been read. [Reader.Size] returns an error
+// wrapping ErrNotDownloaded until all chunks have been downloaded. The
+// downloaded size may be updated using [Reader.SetDownloaded].
+func WithDownloaded(n int64) ReaderOption {
+	return func(z *Reader) {
+		z.downloaded = n
+	}
+}
+
+// SetDownloaded sets the number of bytes of the archive that have been
+// downloaded. A negative n indicates that the whole archive is available.
+// See [WithDownloaded].
+func (z *Reader) SetDownloaded(n int64) {
+	z.lock()
+	defer z.unlock()
+
+	z.downloaded = n
+}
+
+// downloadedChunks returns the number of chunks within the downloaded prefix
+// of the archive.
+func (z *Reader) downloadedChunks() int {
+	if z.downloaded < 0 {
+		return len(z.sizes)
+	}
+	var chunks int
+	for chunks < len(z.sizes) && z.offsets.at(chunks+1) <= z.downloaded {
+		chunks++
+	}
+	return chunks
+}
+
+// downloadedData returns the size of the uncompressed data that can be read
+// from the downloaded chunks or -1 if all chunks have been downloaded.
+func (z *Reader) downloadedData() int64 {
+	chunks := z.downloadedChunks()
+	if chunks == len(z.sizes) {
+		return -1
+	}
+	return int64(chunks) * int64(z.chunkSize)
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestWithDownloaded(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("chunk 00;chunk 01;chunk 02;chunk 03;chunk 04;")
+
+	testCases := map[string]struct {
+		wOpts []WriterOption
+		opts  []ReaderOption
+	}{
+		"default": {},
+		"prefetch": {
+			opts: []ReaderOption{WithPrefetch(4)},
+		},
+		"shared window": {
+			wOpts: []WriterOption{WithSharedWindow()},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriterLevel(&buf, DefaultCompression, 9, tc.wOpts...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			archive := buf.Bytes()
+
+			idx, err := NewReader(bytes.NewReader(archive))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer idx.Close()
+			offsets := idx.Offsets()
+
+			// Only the header and the first two chunks are downloaded.
+			path := filepath.Join(t.TempDir(), "test.dz")
+			downloaded := offsets[2]
+			if err := os.WriteFile(path, archive[:downloaded], 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer f.Close()
+
+			z, err := NewReader(f, append(tc.opts, WithDownloaded(downloaded))...)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			// Reads within the downloaded chunks succeed.
+			p := make([]byte, 9)
+			n, err := z.ReadAt(p, 9)
+			if err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if diff := cmp.Diff("chunk 01;", string(p[:n])); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+
+			// Reads spanning chunks that are not downloaded return the
+			// downloaded data.
+			n, err = z.ReadAt(p, 13)
+			if diff := cmp.Diff(ErrNotDownloaded, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("k 01;", string(p[:n])); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+
+			n, err = z.ReadAt(p, 27)
+			if diff := cmp.Diff(ErrNotDownloaded, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(0, n); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+
+			_, err = z.Size()
+			if diff := cmp.Diff(ErrNotDownloaded, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Size (-want, +got):\n%s", diff)
+			}
+
+			// All chunks are downloaded but the trailer is not.
+			if err := os.WriteFile(path, archive[:offsets[len(offsets)-1]], 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			z.SetDownloaded(offsets[len(offsets)-1])
+
+			n, err = z.ReadAt(p, 36)
+			if err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if diff := cmp.Diff("chunk 04;", string(p[:n])); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+
+			size, err := z.Size()
+			if err != nil {
+				t.Fatalf("Size: %v", err)
+			}
+			if diff := cmp.Diff(int64(len(data)), size); diff != "" {
+				t.Errorf("Size (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dztest
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// finalBlock is an empty final deflate block which ends the deflate stream
+// following the last chunk.
+var finalBlock = []byte{0x03, 0x00}
+
+// ArchiveBuilder builds dictzip archives chunk by chunk. Unlike
+// [dictzip.Writer], it does not check that the chunks and header fields are
+// consistent so it can be used to build deliberately malformed archives, for
+// example chunks that don't match the chunk size or the sizes in the header,
+// as well as valid ones.
+//
+// The zero value builds an empty archive with a chunk size of zero. Use
+// [NewArchiveBuilder] to set the chunk size.
+type ArchiveBuilder struct {
+	header format.Header
+
+	// chunks is the compressed data of the chunks.
+	chunks bytes.Buffer
+
+	// sizes are the sizes of the compressed chunks.
+	sizes []int
+
+	// crc and size are the CRC-32 and size of the uncompressed data.
+	crc  uint32
+	size uint32
+
+	// trailer overrides the trailer if it is not nil.
+	trailer []byte
+}
+
+// NewArchiveBuilder returns a new ArchiveBuilder for an archive with the
+// given chunk size (CHLEN).
+func NewArchiveBuilder(chunkSize int) *ArchiveBuilder {
+	return &ArchiveBuilder{
+		header: format.Header{ChunkSize: chunkSize},
+	}
+}
+
+// AddChunk compresses p as the next chunk. Chunks are independently
+// compressed with [flate.DefaultCompression] and end in a sync flush, as
+// written by [dictzip.Writer]. p is not checked against the chunk size.
+func (b *ArchiveBuilder) AddChunk(p []byte) {
+	var buf bytes.Buffer
+	// NOTE: flate.NewWriter only returns an error for invalid levels.
+	fw, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	_, _ = fw.Write(p)
+	_ = fw.Flush()
+	b.AddRawChunk(buf.Bytes(), p)
+}
+
+// AddRawChunk adds the deflate data compressed as the next chunk. The
+// uncompressed data is only used to compute the gzip trailer and may differ
+// from the data compressed, or be nil, to build an archive with a bad
+// trailer.
+func (b *ArchiveBuilder) AddRawChunk(compressed, uncompressed []byte) {
+	b.chunks.Write(compressed)
+	b.sizes = append(b.sizes, len(compressed))
+	b.crc = crc32.Update(b.crc, crc32.IEEETable, uncompressed)
+	//nolint:gosec // ISIZE is the size modulo 2^32.
+	b.size += uint32(len(uncompressed))
+}
+
+// SetHeaderFields sets the header fields of the archive. The chunk size of
+// h is used if it is not zero. The chunk sizes of h are used if they are not
+// nil, allowing the sizes in the RA subfield to differ from the chunks
+// added. Otherwise, the sizes of the chunks added are used.
+func (b *ArchiveBuilder) SetHeaderFields(h format.Header) {
+	if h.ChunkSize == 0 {
+		h.ChunkSize = b.header.ChunkSize
+	}
+	b.header = h
+}
+
+// SetTrailer sets the CRC-32 and ISIZE fields of the gzip trailer rather
+// than computing them from the uncompressed data of the chunks.
+func (b *ArchiveBuilder) SetTrailer(crc, size uint32) {
+	b.trailer = binary.LittleEndian.AppendUint32(nil, crc)
+	b.trailer = binary.LittleEndian.AppendUint32(b.trailer, size)
+}
+
+// Build returns the archive. Build panics if the header can't be encoded by
+// [format.Append], such as if there are too many chunks or the chunk size is
+// too large for the RA subfield.
+func (b *ArchiveBuilder) Build() []byte {
+	h := b.header
+	if h.Sizes == nil {
+		h.Sizes = b.sizes
+	}
+	archive, err := format.Append(nil, &h)
+	if err != nil {
+		panic(fmt.Sprintf("dztest: building header: %v", err))
+	}
+
+	archive = append(archive, b.chunks.Bytes()...)
+	archive = append(archive, finalBlock...)
+	if b.trailer != nil {
+		return append(archive, b.trailer...)
+	}
+	archive = binary.LittleEndian.AppendUint32(archive, b.crc)
+	return binary.LittleEndian.AppendUint32(archive, b.size)
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dztest
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip"
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+func TestArchiveBuilder(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("Hello World! Hello again.")
+
+	// build returns an archive of data in chunks of 10 bytes.
+	build := func(f func(b *ArchiveBuilder)) []byte {
+		b := NewArchiveBuilder(10)
+		b.SetHeaderFields(format.Header{Name: "hello.txt", OS: dictzip.OSUnix})
+		for off := 0; off < len(data); off += 10 {
+			end := off + 10
+			if end > len(data) {
+				end = len(data)
+			}
+			b.AddChunk(data[off:end])
+		}
+		if f != nil {
+			f(b)
+		}
+		return b.Build()
+	}
+
+	testCases := map[string]struct {
+		archive []byte
+		err     error
+	}{
+		"valid": {
+			archive: build(nil),
+		},
+		"bad trailer": {
+			archive: build(func(b *ArchiveBuilder) {
+				b.SetTrailer(0, uint32(len(data)))
+			}),
+			err: dictzip.ErrTrailer,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := dictzip.NewReader(bytes.NewReader(tc.archive))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			got, err := io.ReadAll(z)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("ReadAll (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(data, got); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("hello.txt", z.Name); diff != "" {
+				t.Errorf("Name (-want, +got):\n%s", diff)
+			}
+			if err := CheckReader(DefaultBackend(nil, nil), tc.archive, data); err != nil {
+				t.Errorf("CheckReader: %v", err)
+			}
+		})
+	}
+}
+
+func TestArchiveBuilder_chunkSize(t *testing.T) {
+	t.Parallel()
+
+	// Random access fails if the chunks don't match the chunk size.
+	data := bytes.Repeat([]byte("abcdefghij"), 10)
+	b := NewArchiveBuilder(12)
+	for off := 0; off < len(data); off += 10 {
+		b.AddChunk(data[off : off+10])
+	}
+	if err := CheckReader(DefaultBackend(nil, nil), b.Build(), data); err == nil {
+		t.Errorf("CheckReader: expected error")
+	}
+}
+
+func TestArchiveBuilder_rawChunk(t *testing.T) {
+	t.Parallel()
+
+	// Chunk sizes in the header that don't match the chunks are written as
+	// given.
+	b := NewArchiveBuilder(100)
+	b.AddRawChunk([]byte{0x01, 0x00, 0x00, 0xff, 0xff}, nil)
+	b.SetHeaderFields(format.Header{Sizes: []int{7}})
+
+	h, _, err := format.Parse(b.Build())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if diff := cmp.Diff(100, h.ChunkSize); diff != "" {
+		t.Errorf("ChunkSize (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{7}, h.Sizes); diff != "" {
+		t.Errorf("Sizes (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dztest provides a conformance test harness for implementations of
+// the [dictzip.Reader] and [dictzip.Writer] contracts, such as readers and
+// writers using other deflate implementations or storage backends.
+//
+// [RunConformance] runs the conformance tests using constructors given in a
+// [Backend]. [Golden] archives written by dictzip(1) and [RoundTrip] helpers
+// suitable for property-based testing with [testing/quick] are also provided
+// for use in other tests. [ArchiveBuilder] builds valid and deliberately
+// malformed archives chunk by chunk for test corpora and fuzzers.
+package dztest
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+var (
+	// ErrMismatch indicates that the data read from an archive does not
+	// match the expected data.
+	ErrMismatch = errors.New("dztest: data mismatch")
+
+	// errBackend indicates that a backend constructor failed.
+	errBackend = errors.New("dztest: backend")
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+// Reader is the contract that readers are tested against. It is implemented
+// by [dictzip.Reader].
+type Reader interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// Backend holds the constructors for the implementation being tested.
+type Backend struct {
+	// NewReader returns a new Reader reading the archive from r. It is
+	// required.
+	NewReader func(r io.ReadSeeker) (Reader, error)
+
+	// NewWriter returns a new writer writing an archive with the given
+	// chunk size to w. The archive must be readable by [dictzip.NewReader].
+	// If nil, only reader tests are run.
+	NewWriter func(w io.Writer, chunkSize int) (io.WriteCloser, error)
+}
+
+// DefaultBackend returns a Backend using [dictzip.NewReader] and
+// [dictzip.NewWriterLevel] with the given options.
+func DefaultBackend(ropts []dictzip.ReaderOption, wopts []dictzip.WriterOption) Backend {
+	return Backend{
+		NewReader: func(r io.ReadSeeker) (Reader, error) {
+			return dictzip.NewReader(r, ropts...)
+		},
+		NewWriter: func(w io.Writer, chunkSize int) (io.WriteCloser, error) {
+			return dictzip.NewWriterLevel(w, dictzip.DefaultCompression, chunkSize, wopts...)
+		},
+	}
+}
+
+// GoldenArchive is a golden dictzip archive and its uncompressed data.
+type GoldenArchive struct {
+	// Name is the file name of the archive.
+	Name string
+
+	// Archive is the compressed archive.
+	Archive []byte
+
+	// Data is the uncompressed data.
+	Data []byte
+}
+
+// Golden returns the golden archives. The archives were written by
+// dictzip(1).
+func Golden() ([]GoldenArchive, error) {
+	entries, err := testdata.ReadDir("testdata")
+	if err != nil {
+		return nil, fmt.Errorf("dztest: reading golden archives: %w", err)
+	}
+
+	var golden []GoldenArchive
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".dz") {
+			continue
+		}
+		archive, err := testdata.ReadFile(path.Join("testdata", e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("dztest: reading golden archive: %w", err)
+		}
+		data, err := testdata.ReadFile(path.Join("testdata", strings.TrimSuffix(e.Name(), ".dz")))
+		if err != nil {
+			return nil, fmt.Errorf("dztest: reading golden data: %w", err)
+		}
+		golden = append(golden, GoldenArchive{
+			Name:    e.Name(),
+			Archive: archive,
+			Data:    data,
+		})
+	}
+	return golden, nil
+}
+
+// chunkSizes are the chunk sizes used for round trip tests. The smallest
+// chunk size is chosen so that the chunk list of the samples still fits in the
+// EXTRA field.
+var chunkSizes = []int{7, 512, 4096, 16384}
+
+// samples returns named sample data used for round trip tests.
+func samples() map[string][]byte {
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, 50000)
+	_, _ = rng.Read(random)
+
+	return map[string][]byte{
+		"empty":  {},
+		"short":  []byte("Hello, dztest!"),
+		"text":   bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog.\n"), 1000),
+		"zeros":  make([]byte, 50000),
+		"random": random,
+	}
+}
+
+// RunConformance runs the conformance tests for b as subtests of t. Readers
+// are tested against the golden archives and, if b.NewWriter is set, archives
+// written by b and the [dictzip.Writer] are read by both b and the
+// [dictzip.Reader].
+func RunConformance(t *testing.T, b Backend) {
+	t.Helper()
+
+	golden, err := Golden()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("golden", func(t *testing.T) {
+		for _, g := range golden {
+			g := g
+			t.Run(g.Name, func(t *testing.T) {
+				if err := CheckReader(b, g.Archive, g.Data); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	})
+
+	defaultBackend := DefaultBackend(nil, nil)
+	for name, data := range samples() {
+		data := data
+		for _, chunkSize := range chunkSizes {
+			chunkSize := chunkSize
+			t.Run(fmt.Sprintf("read/%s/%d", name, chunkSize), func(t *testing.T) {
+				// Archives written by the dictzip package are read by b.
+				archive, err := write(defaultBackend, data, chunkSize)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := CheckReader(b, archive, data); err != nil {
+					t.Error(err)
+				}
+			})
+
+			if b.NewWriter == nil {
+				continue
+			}
+			t.Run(fmt.Sprintf("write/%s/%d", name, chunkSize), func(t *testing.T) {
+				// Archives written by b are read by b and the dictzip
+				// package.
+				archive, err := write(b, data, chunkSize)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := CheckReader(b, archive, data); err != nil {
+					t.Error(err)
+				}
+				if err := CheckReader(defaultBackend, archive, data); err != nil {
+					t.Errorf("dictzip.Reader: %v", err)
+				}
+			})
+		}
+	}
+}
+
+// RoundTrip writes data to an archive with the given chunk size using
+// b.NewWriter and checks that it is read correctly using b.NewReader. An error
+// wrapping [ErrMismatch] is returned if the data read does not match.
+func RoundTrip(b Backend, data []byte, chunkSize int) error {
+	archive, err := write(b, data, chunkSize)
+	if err != nil {
+		return err
+	}
+	return CheckReader(b, archive, data)
+}
+
+// RoundTripProperty returns a property for use with [testing/quick.Check]
+// which reports whether data round trips through b with a chunk size derived
+// from chunkSize.
+func RoundTripProperty(b Backend) func(data []byte, chunkSize uint16) bool {
+	return func(data []byte, chunkSize uint16) bool {
+		// NOTE: Chunk sizes are kept small so that inputs generated by
+		// testing/quick span multiple chunks.
+		return RoundTrip(b, data, int(chunkSize%1024)+1) == nil
+	}
+}
+
+// CheckReader checks that the archive is read as data by a Reader created by
+// b.NewReader using Read, ReadAt, and Seek. An error wrapping [ErrMismatch] is
+// returned if the data read does not match.
+func CheckReader(b Backend, archive, data []byte) error {
+	z, err := b.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return fmt.Errorf("%w: NewReader: %w", errBackend, err)
+	}
+	defer z.Close()
+
+	// Read the whole archive sequentially.
+	got, err := io.ReadAll(z)
+	if err != nil {
+		return fmt.Errorf("%w: Read: %w", errBackend, err)
+	}
+	if !bytes.Equal(data, got) {
+		return fmt.Errorf("%w: Read: got %d bytes, want %d bytes", ErrMismatch, len(got), len(data))
+	}
+
+	// Read ranges at random offsets, including ranges spanning chunks.
+	rng := rand.New(rand.NewSource(int64(len(data))))
+	for i := 0; i < 20 && len(data) > 0; i++ {
+		off := rng.Intn(len(data))
+		n := rng.Intn(len(data)-off) + 1
+		buf := make([]byte, n)
+		if _, err := z.ReadAt(buf, int64(off)); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: ReadAt(%d, %d): %w", errBackend, off, n, err)
+		}
+		if !bytes.Equal(data[off:off+n], buf) {
+			return fmt.Errorf("%w: ReadAt(%d, %d)", ErrMismatch, off, n)
+		}
+	}
+
+	// Reads past the end return fewer bytes and io.EOF.
+	buf := make([]byte, 10)
+	n, err := z.ReadAt(buf, int64(len(data)))
+	if n != 0 || !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: ReadAt past end: got (%d, %v), want (0, EOF)", ErrMismatch, n, err)
+	}
+
+	// Seek back to the middle and read the rest.
+	mid := int64(len(data) / 2)
+	if _, err := z.Seek(mid, io.SeekStart); err != nil {
+		return fmt.Errorf("%w: Seek: %w", errBackend, err)
+	}
+	got, err = io.ReadAll(z)
+	if err != nil {
+		return fmt.Errorf("%w: Read after Seek: %w", errBackend, err)
+	}
+	if !bytes.Equal(data[mid:], got) {
+		return fmt.Errorf("%w: Read after Seek(%d)", ErrMismatch, mid)
+	}
+
+	return nil
+}
+
+// write writes data to an archive with the given chunk size using b.
+func write(b Backend, data []byte, chunkSize int) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := b.NewWriter(&buf, chunkSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: NewWriter: %w", errBackend, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("%w: Write: %w", errBackend, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("%w: Close: %w", errBackend, err)
+	}
+	return buf.Bytes(), nil
+}
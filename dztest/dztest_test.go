@@ -0,0 +1,129 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dztest
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip"
+)
+
+// truncatingReader is a Reader which drops the last byte of the data.
+type truncatingReader struct {
+	*dictzip.Reader
+	size int64
+}
+
+func (r *truncatingReader) Read(p []byte) (int, error) {
+	off, err := r.Reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if off >= r.size-1 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.size-1-off {
+		p = p[:r.size-1-off]
+	}
+	return r.Reader.Read(p)
+}
+
+func TestGolden(t *testing.T) {
+	t.Parallel()
+
+	golden, err := Golden()
+	if err != nil {
+		t.Fatalf("Golden: %v", err)
+	}
+
+	var names []string
+	for _, g := range golden {
+		names = append(names, g.Name)
+		if len(g.Data) == 0 {
+			t.Errorf("%s: no data", g.Name)
+		}
+	}
+	if diff := cmp.Diff([]string{"hello.txt.dz", "test.txt.dz"}, names); diff != "" {
+		t.Errorf("names (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	broken := Backend{
+		NewReader: func(r io.ReadSeeker) (Reader, error) {
+			z, err := dictzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			size, err := z.Size()
+			if err != nil {
+				return nil, err
+			}
+			return &truncatingReader{Reader: z, size: size}, nil
+		},
+		NewWriter: DefaultBackend(nil, nil).NewWriter,
+	}
+
+	testCases := map[string]struct {
+		backend Backend
+		err     error
+	}{
+		"default": {
+			backend: DefaultBackend(nil, nil),
+		},
+		"broken": {
+			backend: broken,
+			err:     ErrMismatch,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := RoundTrip(tc.backend, []byte("Hello, RoundTrip!"), 4)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("RoundTrip (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestErrMismatch(t *testing.T) {
+	t.Parallel()
+
+	err := CheckReader(DefaultBackend(nil, nil), mustArchive(t, "Hello!"), []byte("Hello?"))
+	if !errors.Is(err, ErrMismatch) {
+		t.Errorf("CheckReader: got %v, want %v", err, ErrMismatch)
+	}
+}
+
+func mustArchive(t *testing.T, s string) []byte {
+	t.Helper()
+
+	archive, err := write(DefaultBackend(nil, nil), []byte(s), 4)
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	return archive
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+// WithExactSize configures the [Reader] to calculate the size of the
+// uncompressed data returned by [Reader.Size] by inflating the last chunk
+// rather than from the ISIZE field of the gzip trailer. The last chunk is only
+// inflated once and the result is cached as for [Reader.LastChunkLen].
+//
+// This allows the size, and seeking relative to the end with [ReadSeeker], to
+// be correct for archives where ISIZE can not be trusted, such as archives
+// followed by additional gzip members or archives whose trailer was
+// modified.
+func WithExactSize() ReaderOption {
+	return func(z *Reader) {
+		z.exactSize = true
+	}
+}
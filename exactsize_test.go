@@ -0,0 +1,121 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithExactSize(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("chunk1chunk2chu")
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 6)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// badISIZE has an ISIZE that doesn't match the chunk sizes.
+	badISIZE := append([]byte(nil), buf.Bytes()...)
+	binary.LittleEndian.PutUint32(badISIZE[len(badISIZE)-4:], 100)
+
+	// multiMember is followed by another gzip member whose trailer is read
+	// as the trailer of the archive.
+	multiMember := append([]byte(nil), buf.Bytes()...)
+	var member bytes.Buffer
+	gw := gzip.NewWriter(&member)
+	if _, err := gw.Write([]byte("another member")); err != nil {
+		t.Fatalf("gzip Write: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("gzip Close: %v", err)
+	}
+	multiMember = append(multiMember, member.Bytes()...)
+
+	testCases := map[string][]byte{
+		"valid":        buf.Bytes(),
+		"bad ISIZE":    badISIZE,
+		"multi-member": multiMember,
+	}
+
+	for name, archive := range testCases {
+		archive := archive
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var resets int
+			newDecompressor := func(r io.Reader) io.ReadCloser {
+				return &countingDecompressor{
+					readCloseResetter: flate.NewReader(r).(readCloseResetter),
+					resets:            &resets,
+				}
+			}
+
+			z, err := NewReader(bytes.NewReader(archive), WithExactSize(), WithDecompressor(newDecompressor))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			want := -1
+			for i := 0; i < 2; i++ {
+				size, err := z.Size()
+				if err != nil {
+					t.Fatalf("Size: %v", err)
+				}
+				if diff := cmp.Diff(int64(len(data)), size); diff != "" {
+					t.Errorf("Size (-want, +got):\n%s", diff)
+				}
+
+				// The last chunk is only inflated by the first call.
+				if want < 0 {
+					want = resets
+				}
+				if diff := cmp.Diff(want, resets); diff != "" {
+					t.Errorf("resets (-want, +got):\n%s", diff)
+				}
+			}
+
+			s, err := NewReadSeeker(z)
+			if err != nil {
+				t.Fatalf("NewReadSeeker: %v", err)
+			}
+			if _, err := s.Seek(-3, io.SeekEnd); err != nil {
+				t.Fatalf("Seek: %v", err)
+			}
+			got, err := io.ReadAll(s)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff("chu", string(got)); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
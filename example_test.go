@@ -16,6 +16,7 @@ package dictzip_test
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/ianlewis/go-dictzip"
@@ -43,3 +44,49 @@ func Example() {
 
 	// Output:Hello World!
 }
+
+func ExampleReader_Section() {
+	f, err := os.Open("internal/testdata/hello.txt.dz")
+	if err != nil {
+		panic(err)
+	}
+
+	r, err := dictzip.NewReader(f)
+	if err != nil {
+		panic(err)
+	}
+
+	// Read the range given by the offset and length of a dictd index entry.
+	if _, err := io.Copy(os.Stdout, r.Section(5, 12)); err != nil {
+		panic(err)
+	}
+
+	// Output: Hello World!
+}
+
+func ExampleReader_Size() {
+	f, err := os.Open("internal/testdata/hello.txt.dz")
+	if err != nil {
+		panic(err)
+	}
+
+	r, err := dictzip.NewReader(f)
+	if err != nil {
+		panic(err)
+	}
+
+	// The size is read from the trailer so the buffer can be allocated
+	// without decompressing the data first.
+	size, err := r.Size()
+	if err != nil {
+		panic(err)
+	}
+	buf := make([]byte, size)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%d bytes: %q\n", size, buf)
+
+	// Output: 23 bytes: "     Hello World!     \n"
+}
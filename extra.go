@@ -0,0 +1,120 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// ErrExtraField indicates that an EXTRA subfield given to [WithExtraFields]
+// is invalid.
+var ErrExtraField = fmt.Errorf("%w: invalid EXTRA subfield", errDictzip)
+
+// reservedSubfields are the IDs of the EXTRA subfields written by the
+// [Writer] itself.
+var reservedSubfields = [][2]byte{
+	{format.RASI1, format.RASI2},
+	{format.WindowSI1, format.WindowSI2},
+	{format.ChunkCRCSI1, format.ChunkCRCSI2},
+	{format.ChunkCRC32CSI1, format.ChunkCRC32CSI2},
+	{format.UTF8NameSI1, format.UTF8NameSI2},
+	{format.UTF8CommentSI1, format.UTF8CommentSI2},
+	{format.ManifestSI1, format.ManifestSI2},
+}
+
+// ExtraField is a gzip EXTRA subfield.
+// See RFC 1952 Section 2.3.1.1.
+type ExtraField struct {
+	// ID is the subfield ID (SI1 and SI2).
+	ID [2]byte
+
+	// Data is the subfield data.
+	Data []byte
+}
+
+// WithExtraFields configures the [Writer] to write the given EXTRA
+// subfields. The subfields are written in the given order following the RA
+// subfield, which is always written first as expected by dictzip(1), and
+// any other subfields written by the Writer. [Header.Extra] is written after
+// them.
+//
+// [NewWriter] and [NewWriterLevel] return an error wrapping [ErrExtraField]
+// if a subfield ID is given more than once, if a subfield ID is reserved for
+// subfields written by the Writer, such as the RA subfield, if SI2 is zero,
+// which is reserved by RFC 1952, or if the data is longer than 65535 bytes.
+func WithExtraFields(fields ...ExtraField) WriterOption {
+	return func(z *Writer) {
+		z.extraFields = append(z.extraFields, fields...)
+	}
+}
+
+// ExtraFields parses the Extra field into subfields in the order they
+// appear. It returns an error wrapping [ErrSubfieldLength] if Extra is
+// malformed.
+func (h *Header) ExtraFields() ([]ExtraField, error) {
+	var fields []ExtraField
+	extra := h.Extra
+	for len(extra) > 0 {
+		if len(extra) < 4 {
+			return nil, fmt.Errorf("%w: subfield header: %d bytes remaining", ErrSubfieldLength, len(extra))
+		}
+		subLen := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if subLen > len(extra)-4 {
+			return nil, fmt.Errorf("%w: subfield %q: LEN %d, %d bytes remaining",
+				ErrSubfieldLength, extra[:2], subLen, len(extra)-4)
+		}
+		fields = append(fields, ExtraField{
+			ID:   [2]byte{extra[0], extra[1]},
+			Data: extra[4 : 4+subLen],
+		})
+		extra = extra[4+subLen:]
+	}
+	return fields, nil
+}
+
+// validateExtraFields checks the subfields given to WithExtraFields.
+func validateExtraFields(fields []ExtraField) error {
+	seen := make(map[[2]byte]bool, len(fields))
+	for _, f := range fields {
+		for _, id := range reservedSubfields {
+			if f.ID == id {
+				return fmt.Errorf("%w: %q: reserved subfield ID", ErrExtraField, f.ID[:])
+			}
+		}
+		if f.ID[1] == 0 {
+			return fmt.Errorf("%w: %q: SI2 must not be zero", ErrExtraField, f.ID[:])
+		}
+		if seen[f.ID] {
+			return fmt.Errorf("%w: %q: duplicate subfield ID", ErrExtraField, f.ID[:])
+		}
+		seen[f.ID] = true
+		if len(f.Data) > math.MaxUint16 {
+			return fmt.Errorf("%w: %q: LEN %d exceeds %d", ErrExtraField, f.ID[:], len(f.Data), math.MaxUint16)
+		}
+	}
+	return nil
+}
+
+// appendExtraFields appends the subfields to extra.
+func appendExtraFields(extra []byte, fields []ExtraField) []byte {
+	for _, f := range fields {
+		extra = appendSubfield(extra, f.ID[0], f.ID[1], string(f.Data))
+	}
+	return extra
+}
@@ -0,0 +1,215 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+func TestWithExtraFields(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf,
+		WithExtraFields(
+			ExtraField{ID: [2]byte{'Z', 'Z'}, Data: []byte("last")},
+			ExtraField{ID: [2]byte{'A', 'A'}, Data: []byte("first")},
+		),
+		WithExtraFields(ExtraField{ID: [2]byte{'M', 'M'}}),
+		WithChunkChecksums(),
+	)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	w.Extra = []byte{'X', 'X', 0x1, 0x0, 0xab}
+	if _, err := w.Write([]byte("Hello, extra!")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// The RA subfield is first and the subfields are in the given order.
+	h, _, err := format.Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	wantIDs := [][2]byte{
+		{format.RASI1, format.RASI2},
+		{format.ChunkCRCSI1, format.ChunkCRCSI2},
+		{'Z', 'Z'},
+		{'A', 'A'},
+		{'M', 'M'},
+		{'X', 'X'},
+	}
+	if diff := cmp.Diff(wantIDs, h.Subfields); diff != "" {
+		t.Errorf("Subfields (-want, +got):\n%s", diff)
+	}
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	got, err := z.ExtraFields()
+	if err != nil {
+		t.Fatalf("ExtraFields: %v", err)
+	}
+	want := []ExtraField{
+		{ID: [2]byte{'Z', 'Z'}, Data: []byte("last")},
+		{ID: [2]byte{'A', 'A'}, Data: []byte("first")},
+		{ID: [2]byte{'M', 'M'}, Data: []byte{}},
+		{ID: [2]byte{'X', 'X'}, Data: []byte{0xab}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ExtraFields (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWithExtraFields_invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string][]ExtraField{
+		"duplicate": {
+			{ID: [2]byte{'A', 'A'}},
+			{ID: [2]byte{'B', 'B'}},
+			{ID: [2]byte{'A', 'A'}},
+		},
+		"RA": {
+			{ID: [2]byte{'R', 'A'}},
+		},
+		"UTF-8 name": {
+			{ID: [2]byte{'U', 'N'}},
+		},
+		"zero SI2": {
+			{ID: [2]byte{'A', 0}},
+		},
+		"too long": {
+			{ID: [2]byte{'A', 'A'}, Data: make([]byte, 65536)},
+		},
+	}
+
+	for name, fields := range testCases {
+		fields := fields
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			_, err := NewWriter(&buf, WithExtraFields(fields...))
+			if diff := cmp.Diff(ErrExtraField, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("NewWriter (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(0, buf.Len()); diff != "" {
+				t.Errorf("output length (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHeader_ExtraFields(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		extra   []byte
+		want    []ExtraField
+		wantErr error
+	}{
+		"empty": {},
+		"fields": {
+			extra: []byte{'A', 'B', 0x2, 0x0, 0x1, 0x2, 'C', 'D', 0x0, 0x0},
+			want: []ExtraField{
+				{ID: [2]byte{'A', 'B'}, Data: []byte{0x1, 0x2}},
+				{ID: [2]byte{'C', 'D'}, Data: []byte{}},
+			},
+		},
+		"short header": {
+			extra:   []byte{'A', 'B', 0x2},
+			wantErr: ErrSubfieldLength,
+		},
+		"short data": {
+			extra:   []byte{'A', 'B', 0x2, 0x0, 0x1},
+			wantErr: ErrSubfieldLength,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := Header{Extra: tc.extra}
+			got, err := h.ExtraFields()
+			if diff := cmp.Diff(tc.wantErr, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ExtraFields error (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ExtraFields (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHeader_Subfields(t *testing.T) {
+	t.Parallel()
+
+	ra := [2]byte{format.RASI1, format.RASI2}
+	subfields := [][2]byte{{'X', 'Y'}, ra, {'Z', 'W'}}
+	extra := []byte{'X', 'Y', 0x1, 0x0, 0xab, 'Z', 'W', 0x0, 0x0}
+
+	// write writes data with the given header fields and returns the
+	// archive.
+	write := func(h Header) []byte {
+		var buf bytes.Buffer
+		w, err := NewWriterLevel(&buf, DefaultCompression, 1000)
+		if err != nil {
+			t.Fatalf("NewWriterLevel: %v", err)
+		}
+		w.Extra = h.Extra
+		w.Subfields = h.Subfields
+		if _, err := w.Write(bytes.Repeat([]byte("subfield order\n"), 200)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	archive := write(Header{Extra: extra, Subfields: subfields})
+	z, err := NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+	if diff := cmp.Diff(subfields, z.Subfields); diff != "" {
+		t.Errorf("Subfields (-want, +got):\n%s", diff)
+	}
+
+	// Recompressing with the header of the Reader preserves the order.
+	h, _, err := format.Parse(write(z.Header))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if diff := cmp.Diff(subfields, h.Subfields); diff != "" {
+		t.Errorf("rewritten Subfields (-want, +got):\n%s", diff)
+	}
+}
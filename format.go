@@ -0,0 +1,83 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+// Format is a compressed file format detected by [DetectFormat].
+type Format int
+
+const (
+	// FormatUnknown indicates that the data is neither a gzip nor a dictzip
+	// file.
+	FormatUnknown Format = iota
+
+	// FormatGzip indicates that the data is a gzip file that does not include
+	// the dictzip random access EXTRA sub-field.
+	FormatGzip
+
+	// FormatDictzip indicates that the data is a dictzip file.
+	FormatDictzip
+)
+
+// String returns a short name for the format.
+func (f Format) String() string {
+	switch f {
+	case FormatGzip:
+		return "gzip"
+	case FormatDictzip:
+		return "dictzip"
+	case FormatUnknown:
+		return "unknown"
+	default:
+		return "unknown"
+	}
+}
+
+// DetectFormat reads the header of the data in r and reports whether it is a
+// dictzip file, a regular gzip file, or neither. Only header data is read.
+//
+// An error is returned only if reading from r fails for reasons other than
+// reaching the end of the data.
+func DetectFormat(r io.ReaderAt) (Format, error) {
+	head := make([]byte, 3)
+	n, err := r.ReadAt(head, 0)
+	if n < len(head) {
+		if err == nil || errors.Is(err, io.EOF) {
+			return FormatUnknown, nil
+		}
+		//nolint:wrapcheck // error is returned from the user's reader.
+		return FormatUnknown, err
+	}
+	if head[0] != hdrGzipID1 || head[1] != hdrGzipID2 || head[2] != hdrDeflateCM {
+		return FormatUnknown, nil
+	}
+
+	z := Reader{
+		r: io.NewSectionReader(r, 0, math.MaxInt64),
+	}
+	if _, _, _, err := z.readHeader(); err != nil {
+		if errors.Is(err, ErrHeader) {
+			return FormatGzip, nil
+		}
+		return FormatUnknown, err
+	}
+
+	return FormatDictzip, nil
+}
@@ -0,0 +1,919 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package format implements encoding and decoding of dictzip file headers.
+//
+// The functions in this package operate on byte slices and perform no I/O
+// so that the exact parsing logic used by the dictzip [Reader] and [Writer]
+// can be reused by alternative readers, such as readers over memory mapped
+// files, and by fuzzers.
+//
+// [Reader]: https://pkg.go.dev/github.com/ianlewis/go-dictzip#Reader
+// [Writer]: https://pkg.go.dev/github.com/ianlewis/go-dictzip#Writer
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// errDictzip is the base error for all errors in this package.
+	errDictzip = errors.New("dictzip")
+
+	// ErrHeader indicates an error with gzip header data.
+	ErrHeader = fmt.Errorf("%w: invalid header", errDictzip)
+
+	// ErrSubfieldLength indicates that an EXTRA subfield's length exceeds the
+	// size of the EXTRA area given by XLEN.
+	ErrSubfieldLength = fmt.Errorf("%w: subfield length exceeds EXTRA area", ErrHeader)
+)
+
+//nolint:godot // diagram
+/*
++---+---+---+---+---+---+---+---+---+---+
+|ID1|ID2|CM |FLG|     MTIME     |XFL|OS |
++---+---+---+---+---+---+---+---+---+---+
+*/
+const (
+	// ID1 is the gzip header value for ID1.
+	ID1 byte = 0x1f
+
+	// ID2 is the gzip header value for ID2.
+	ID2 byte = 0x8b
+
+	// CMDeflate is the deflate CM (Compression method).
+	CMDeflate byte = 0x08
+)
+
+// FLG (Flags).
+// bit 0 : FTEXT (ignored).
+// bit 1 : FHCRC.
+// bit 2 : FEXTRA (required for dictzip).
+// bit 3 : FNAME.
+// bit 4 : FCOMMENT.
+// bit 5 : reserved (ignored unless strict).
+// bit 6 : reserved (ignored unless strict).
+// bit 7 : reserved	(ignored unless strict).
+const (
+	flgCRC      = byte(1 << 1)
+	flgEXTRA    = byte(1 << 2)
+	flgNAME     = byte(1 << 3)
+	flgCOMMENT  = byte(1 << 4)
+	flgReserved = byte(0xe0)
+)
+
+// XFL values defined for the deflate compression method.
+const (
+	xflSlowest = byte(0x2)
+	xflFastest = byte(0x4)
+)
+
+const (
+	// RASI1 is the dictzip random access subfield ID value SI1.
+	RASI1 = byte('R')
+
+	// RASI2 is the dictzip random access subfield ID value SI2.
+	RASI2 = byte('A')
+
+	// WindowSI1 is the shared window subfield ID value SI1.
+	WindowSI1 = byte('R')
+
+	// WindowSI2 is the shared window subfield ID value SI2.
+	WindowSI2 = byte('W')
+
+	// ChunkCRCSI1 is the per-chunk CRC-32 subfield ID value SI1. The
+	// subfield data is the CRC-32 (IEEE polynomial) of the uncompressed data
+	// of each chunk (each 4 bytes).
+	ChunkCRCSI1 = byte('R')
+
+	// ChunkCRCSI2 is the per-chunk CRC-32 subfield ID value SI2.
+	ChunkCRCSI2 = byte('C')
+
+	// ChunkCRC32CSI1 is the per-chunk CRC-32C subfield ID value SI1. The
+	// subfield data is the CRC-32 (Castagnoli polynomial) of the
+	// uncompressed data of each chunk (each 4 bytes). It uses a separate ID
+	// from the IEEE checksums so that readers which only know the chunk CRC
+	// subfield don't check the data against the wrong polynomial.
+	ChunkCRC32CSI1 = byte('R')
+
+	// ChunkCRC32CSI2 is the per-chunk CRC-32C subfield ID value SI2.
+	ChunkCRC32CSI2 = byte('K')
+
+	// UTF8NameSI1 is the UTF-8 NAME subfield ID value SI1. The subfield data
+	// is the UTF-8 encoded file name.
+	UTF8NameSI1 = byte('U')
+
+	// UTF8NameSI2 is the UTF-8 NAME subfield ID value SI2.
+	UTF8NameSI2 = byte('N')
+
+	// UTF8CommentSI1 is the UTF-8 COMMENT subfield ID value SI1. The subfield
+	// data is the UTF-8 encoded comment.
+	UTF8CommentSI1 = byte('U')
+
+	// UTF8CommentSI2 is the UTF-8 COMMENT subfield ID value SI2.
+	UTF8CommentSI2 = byte('C')
+
+	// ManifestSI1 is the metadata manifest subfield ID value SI1. The
+	// subfield data is a version byte followed by key/value entries, each a
+	// 1 byte key length, the key, a 2 byte little-endian value length, and
+	// the value.
+	ManifestSI1 = byte('D')
+
+	// ManifestSI2 is the metadata manifest subfield ID value SI2.
+	ManifestSI2 = byte('M')
+)
+
+// RAVersion is the version of the random access subfield data that is read
+// and written.
+const RAVersion uint16 = 1
+
+// RAVersion2 is the version of the extended random access subfield data
+// which uses 32-bit little-endian CHLEN, CHCNT, and chunk size fields rather
+// than 16-bit fields so that chunks may be larger than 65535 bytes. It is
+// read but not written.
+const RAVersion2 uint16 = 2
+
+// maxRA2Size is the maximum CHLEN and chunk size read from a version 2 RA
+// subfield so that sizes fit in an int on all platforms.
+const maxRA2Size = math.MaxInt32
+
+// MaxStringLen is the maximum length of the NAME and COMMENT fields,
+// including the zero byte terminator.
+const MaxStringLen = 512
+
+// Header is a decoded dictzip file header.
+type Header struct {
+	// ModTime is the MTIME modification time field. It is the zero value if
+	// MTIME is not set.
+	ModTime time.Time
+
+	// XFL is the XFL (extra flags) header field.
+	XFL byte
+
+	// OS is the OS header field.
+	OS byte
+
+	// Extra includes all EXTRA subfields, including the SI1, SI2, and LEN
+	// fields, except the dictzip RA and shared window subfields.
+	Extra []byte
+
+	// Subfields are the IDs of the EXTRA subfields in the order they appear.
+	// It is set by [Parse]. If it is not nil, [Append] writes the subfields
+	// in this order so that parsed headers are written with their original
+	// layout.
+	Subfields [][2]byte
+
+	// Name is the NAME header field.
+	Name string
+
+	// Comment is the COMMENT header field.
+	Comment string
+
+	// UTF8Strings indicates that Name and Comment are encoded as UTF-8
+	// rather than ISO 8859-1 (Latin-1). [Append] returns an error wrapping
+	// [ErrHeader] if they are not valid UTF-8 or include a zero byte. It is
+	// set by [ParseWithOptions] with [ParseOptions.UTF8Strings] if a field
+	// was decoded as UTF-8.
+	UTF8Strings bool
+
+	// HeaderCRC indicates that the header includes the FHCRC CRC-16 field.
+	// It is set by [Parse] and ignored by [Append].
+	HeaderCRC bool
+
+	// ChunkSize is the size of uncompressed dictzip chunks (CHLEN).
+	ChunkSize int
+
+	// Sizes are the sizes of the compressed chunks.
+	Sizes []int
+
+	// ReservedChunks is the number of zero chunk size entries following
+	// Sizes in the RA subfield. The entries reserve space so that chunks
+	// can be appended without moving the compressed data.
+	ReservedChunks int
+
+	// SharedWindow indicates that the chunks share the deflate window.
+	SharedWindow bool
+
+	// ChunkCRCs are the CRC-32 checksums of the uncompressed data of each
+	// chunk. It is nil if the header does not include chunk checksums.
+	ChunkCRCs []uint32
+
+	// ChunkCRCCastagnoli indicates that ChunkCRCs use the Castagnoli
+	// polynomial rather than the IEEE polynomial. The checksums are then
+	// written to the chunk CRC-32C subfield rather than the chunk CRC
+	// subfield.
+	ChunkCRCCastagnoli bool
+
+	// DiscardedExtra is the number of bytes of malformed EXTRA data
+	// discarded when parsing with [ParseOptions.TolerantExtra]. It is set
+	// by [ParseWithOptions] and ignored by [Append].
+	DiscardedExtra int
+
+	// Gzip indicates that the header is a plain gzip header without the RA
+	// subfield, in which case ChunkSize is zero and Sizes is nil. It is set
+	// by [ParseWithOptions] with [ParseOptions.AllowGzip] and ignored by
+	// [Append].
+	Gzip bool
+
+	// Warnings describe anomalies found in the header that were accepted
+	// rather than rejected. See [ParseOptions.StrictHeader] and
+	// [ParseOptions.LenientHeader]. It is set by [ParseWithOptions] and
+	// ignored by [Append].
+	Warnings []string
+}
+
+// ParseOptions are options for [ParseWithOptions].
+type ParseOptions struct {
+	// TolerantExtra indicates that malformed EXTRA subfields other than the
+	// RA subfield are skipped rather than returning an error. A subfield
+	// whose length exceeds the EXTRA area is discarded along with any data
+	// following it in the EXTRA area.
+	TolerantExtra bool
+
+	// AllowGzip indicates that plain gzip headers without the RA subfield,
+	// or without an EXTRA field, are parsed rather than returning an error.
+	// See [Header.Gzip].
+	AllowGzip bool
+
+	// StrictRFC1952 indicates that headers that do not strictly conform to
+	// RFC 1952 are rejected. The reserved FLG bits must be zero, XFL must be
+	// zero or one of the values defined for deflate, and subfield IDs with
+	// SI2 set to zero, which are reserved, are not allowed.
+	StrictRFC1952 bool
+
+	// StrictHeader indicates that header anomalies are rejected rather than
+	// recorded in [Header.Warnings]. The anomalies are reserved FLG bits
+	// being set, an unsupported RA version, and a zero CHLEN.
+	StrictHeader bool
+
+	// LenientHeader indicates that header anomalies that are rejected by
+	// default are recorded in [Header.Warnings] instead. An RA subfield with
+	// an unsupported version is read as version 1 and a zero CHLEN is
+	// accepted even if there are chunks, in which case the caller must
+	// determine the chunk size. LenientHeader is ignored if StrictHeader is
+	// set.
+	LenientHeader bool
+
+	// UTF8Strings indicates that the NAME and COMMENT fields are decoded as
+	// UTF-8 if they are valid UTF-8 and include non-ASCII characters, as is
+	// written by some tools. Other values are decoded as ISO 8859-1
+	// (Latin-1). See [Header.UTF8Strings].
+	UTF8Strings bool
+}
+
+// Parse decodes the dictzip header at the start of b. It returns the header
+// and the length of the header in bytes, which is the offset of the first
+// compressed chunk.
+//
+// If b is too short to contain the full header the returned error wraps both
+// [ErrHeader] and [io.ErrUnexpectedEOF]. Callers reading the header from a
+// stream can read more data and call Parse again.
+func Parse(b []byte) (*Header, int, error) {
+	return ParseWithOptions(b, ParseOptions{})
+}
+
+// ParseWithOptions is like [Parse] but allows parsing options to be given.
+func ParseWithOptions(b []byte, opts ParseOptions) (*Header, int, error) {
+	p := parser{b: b}
+
+	head, err := p.next(10, "reading header")
+	if err != nil {
+		return nil, p.off, err
+	}
+	if head[0] != ID1 || head[1] != ID2 {
+		return nil, p.off, fmt.Errorf("%w: ID1,ID2: %x", ErrHeader, head[0:2])
+	}
+	if head[2] != CMDeflate {
+		return nil, p.off, fmt.Errorf("%w: CM: %x", ErrHeader, head[2])
+	}
+	flg := head[3]
+	if (opts.StrictRFC1952 || opts.StrictHeader) && flg&flgReserved != 0 {
+		return nil, p.off, fmt.Errorf("%w: reserved FLG bits set: %#x", ErrHeader, flg&flgReserved)
+	}
+	if opts.StrictRFC1952 {
+		if xfl := head[8]; xfl != 0 && xfl != xflSlowest && xfl != xflFastest {
+			return nil, p.off, fmt.Errorf("%w: unknown XFL: %#x", ErrHeader, xfl)
+		}
+	}
+
+	h := &Header{
+		XFL:       head[8],
+		OS:        head[9],
+		HeaderCRC: flg&flgCRC != 0,
+	}
+	if flg&flgReserved != 0 {
+		h.Warnings = append(h.Warnings, fmt.Sprintf("reserved FLG bits set: %#x", flg&flgReserved))
+	}
+
+	// NOTE: The zero value for MTIME means that the modified time is not set.
+	if mtime := binary.LittleEndian.Uint32(head[4:8]); mtime > 0 {
+		h.ModTime = time.Unix(int64(mtime), 0)
+	}
+
+	switch {
+	case flg&flgEXTRA != 0:
+		if err := p.extra(h, opts); err != nil {
+			return nil, p.off, err
+		}
+	case opts.AllowGzip:
+		h.Gzip = true
+	default:
+		return nil, p.off, fmt.Errorf("%w: no EXTRA field", ErrHeader)
+	}
+
+	if flg&flgNAME != 0 {
+		if h.Name, err = p.string(h, opts); err != nil {
+			return nil, p.off, err
+		}
+	}
+
+	if flg&flgCOMMENT != 0 {
+		if h.Comment, err = p.string(h, opts); err != nil {
+			return nil, p.off, err
+		}
+	}
+
+	if h.HeaderCRC {
+		// NOTE: The CRC-16 is the two lowest order bytes of the CRC-32 of
+		// the header fields following the fixed length header up to the
+		// CRC-16 itself.
+		//nolint:gosec // we intentionally take the two lowest order bytes.
+		want := uint16(crc32.ChecksumIEEE(b[10:p.off]))
+		buf, err := p.next(2, "CRC-16")
+		if err != nil {
+			return nil, p.off, err
+		}
+		if binary.LittleEndian.Uint16(buf) != want {
+			return nil, p.off, fmt.Errorf("%w: bad CRC-16 digest", ErrHeader)
+		}
+	}
+
+	return h, p.off, nil
+}
+
+// ChunkTable returns the offset in b of the chunk sizes in the RA subfield
+// of the dictzip header at the start of b, and the size in bytes of each
+// entry, which is 2 for version 1 and 4 for version 2 RA subfields. It
+// returns an error wrapping [ErrHeader] if b does not start with a header
+// that includes the RA subfield.
+func ChunkTable(b []byte) (int, int, error) {
+	p := parser{b: b}
+
+	head, err := p.next(10, "reading header")
+	if err != nil {
+		return 0, 0, err
+	}
+	if head[0] != ID1 || head[1] != ID2 {
+		return 0, 0, fmt.Errorf("%w: ID1,ID2: %x", ErrHeader, head[0:2])
+	}
+	if head[3]&flgEXTRA == 0 {
+		return 0, 0, fmt.Errorf("%w: no EXTRA field", ErrHeader)
+	}
+
+	buf, err := p.next(2, "EXTRA XLEN")
+	if err != nil {
+		return 0, 0, err
+	}
+	end := p.off + int(binary.LittleEndian.Uint16(buf))
+	for p.off+4 <= end {
+		sub, err := p.next(4, "subfield header")
+		if err != nil {
+			return 0, 0, err
+		}
+		subLen := int(binary.LittleEndian.Uint16(sub[2:4]))
+		if sub[0] != RASI1 || sub[1] != RASI2 {
+			if _, err := p.next(subLen, "reading EXTRA"); err != nil {
+				return 0, 0, err
+			}
+			continue
+		}
+
+		ver, err := p.next(2, "VER")
+		if err != nil {
+			return 0, 0, err
+		}
+		// The chunk sizes follow the VER, CHLEN, and CHCNT fields.
+		switch binary.LittleEndian.Uint16(ver) {
+		case RAVersion:
+			return p.off + 4, 2, nil
+		case RAVersion2:
+			return p.off + 8, 4, nil
+		default:
+			return 0, 0, fmt.Errorf("%w: unsupported version: %d", ErrHeader, binary.LittleEndian.Uint16(ver))
+		}
+	}
+
+	return 0, 0, fmt.Errorf("%w: no RA EXTRA field", ErrHeader)
+}
+
+// parser reads header fields from b.
+type parser struct {
+	b   []byte
+	off int
+
+	// subfield indicates that b is subfield data rather than the header.
+	// Subfield data that is too short is malformed rather than incomplete.
+	subfield bool
+}
+
+// next returns the next n bytes of the header. The field is used in the
+// error if there are fewer than n bytes remaining.
+func (p *parser) next(n int, field string) ([]byte, error) {
+	if len(p.b)-p.off < n {
+		p.off = len(p.b)
+		switch {
+		case p.subfield:
+			return nil, fmt.Errorf("%w: %s: subfield too short", ErrHeader, field)
+		case len(p.b) == 0:
+			return nil, fmt.Errorf("%w: %s: %w", ErrHeader, field, io.EOF)
+		default:
+			return nil, fmt.Errorf("%w: %s: %w", ErrHeader, field, io.ErrUnexpectedEOF)
+		}
+	}
+	buf := p.b[p.off : p.off+n]
+	p.off += n
+	return buf, nil
+}
+
+// extra parses the EXTRA field.
+func (p *parser) extra(h *Header, opts ParseOptions) error {
+	buf, err := p.next(2, "EXTRA XLEN")
+	if err != nil {
+		return err
+	}
+	xlen := int(binary.LittleEndian.Uint16(buf))
+
+	extra, err := p.next(xlen, "reading EXTRA")
+	if err != nil {
+		return err
+	}
+
+	// NOTE: The EXTRA field could could contain multiple sub-fields.
+	var foundRAField bool
+	for len(extra) > 0 {
+		// Read SI1, SI2, and LEN
+		if len(extra) < 4 {
+			if opts.TolerantExtra {
+				h.DiscardedExtra = len(extra)
+				break
+			}
+			return fmt.Errorf("%w: subfield header: %d bytes remaining", ErrSubfieldLength, len(extra))
+		}
+		si1, si2 := extra[0], extra[1]
+		if opts.StrictRFC1952 && si2 == 0 {
+			return fmt.Errorf("%w: reserved subfield ID %q", ErrHeader, extra[:2])
+		}
+		subLen := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if subLen > len(extra)-4 {
+			if opts.TolerantExtra {
+				h.DiscardedExtra = len(extra)
+				break
+			}
+			return fmt.Errorf("%w: subfield %q: LEN %d, %d bytes remaining",
+				ErrSubfieldLength, extra[:2], subLen, len(extra)-4)
+		}
+		sub, data := extra[:4+subLen], extra[4:4+subLen]
+		extra = extra[4+subLen:]
+
+		h.Subfields = append(h.Subfields, [2]byte{si1, si2})
+
+		switch {
+		case si1 == RASI1 && si2 == RASI2:
+			// This is the dictzip 'R'andom 'A'ccess data field.
+			if err := parseRA(h, data, opts); err != nil {
+				return err
+			}
+			foundRAField = true
+		case si1 == WindowSI1 && si2 == WindowSI2:
+			// This is the shared 'W'indow field.
+			h.SharedWindow = true
+		case si1 == ChunkCRCSI1 && si2 == ChunkCRCSI2,
+			si1 == ChunkCRC32CSI1 && si2 == ChunkCRC32CSI2:
+			// This is the chunk 'C'RC or CRC-32'K' (Castagnoli) field.
+			castagnoli := si2 == ChunkCRC32CSI2
+			if h.ChunkCRCs != nil && h.ChunkCRCCastagnoli != castagnoli {
+				return fmt.Errorf("%w: both chunk CRC and CRC-32C subfields", ErrHeader)
+			}
+			h.ChunkCRCCastagnoli = castagnoli
+			if len(data)%4 != 0 {
+				return fmt.Errorf("%w: chunk CRC subfield length: %d", ErrHeader, len(data))
+			}
+			h.ChunkCRCs = make([]uint32, 0, len(data)/4)
+			for i := 0; i < len(data); i += 4 {
+				h.ChunkCRCs = append(h.ChunkCRCs, binary.LittleEndian.Uint32(data[i:i+4]))
+			}
+		default:
+			// Append the non-RA extra data field.
+			h.Extra = append(h.Extra, sub...)
+		}
+	}
+
+	if !foundRAField {
+		if !opts.AllowGzip {
+			return fmt.Errorf("%w: no RA EXTRA field", ErrHeader)
+		}
+		h.Gzip = true
+	}
+
+	if h.ChunkCRCs != nil && len(h.ChunkCRCs) != len(h.Sizes) {
+		return fmt.Errorf("%w: %d chunk CRCs for %d chunks", ErrHeader, len(h.ChunkCRCs), len(h.Sizes))
+	}
+
+	return nil
+}
+
+// parseRA parses the dictzip uncompressed chunk size, compressed chunk sizes,
+// and the number of reserved chunk entries from the RA subfield data into h.
+func parseRA(h *Header, data []byte, opts ParseOptions) error {
+	p := parser{b: data, subfield: true}
+	lenient := opts.LenientHeader && !opts.StrictHeader
+
+	buf, err := p.next(2, "VER")
+	if err != nil {
+		return err
+	}
+	// fieldLen is the size of the CHLEN, CHCNT, and chunk size fields.
+	fieldLen := 2
+	switch ver := binary.LittleEndian.Uint16(buf); {
+	case ver == RAVersion:
+	case ver == RAVersion2:
+		fieldLen = 4
+	case lenient:
+		h.Warnings = append(h.Warnings, fmt.Sprintf("unsupported RA version %d read as version %d", ver, RAVersion))
+	default:
+		return fmt.Errorf("%w: unsupported version: %d", ErrHeader, ver)
+	}
+	field := func(name string) (int, error) {
+		buf, err := p.next(fieldLen, name)
+		if err != nil {
+			return 0, err
+		}
+		if fieldLen == 2 {
+			return int(binary.LittleEndian.Uint16(buf)), nil
+		}
+		v := binary.LittleEndian.Uint32(buf)
+		if v > maxRA2Size {
+			return 0, fmt.Errorf("%w: %s exceeded: %d", ErrHeader, name, v)
+		}
+		return int(v), nil
+	}
+
+	chlen, err := field("CHLEN")
+	if err != nil {
+		return err
+	}
+
+	chcnt, err := field("CHCNT")
+	if err != nil {
+		return err
+	}
+
+	// NOTE: A zero CHLEN is accepted by default for version 1 archives
+	// without chunks since their data can be read without the chunk size.
+	if chlen == 0 {
+		switch {
+		case opts.StrictHeader || !lenient && (fieldLen == 4 || chcnt > 0):
+			return fmt.Errorf("%w: CHLEN is zero", ErrHeader)
+		default:
+			h.Warnings = append(h.Warnings, "CHLEN is zero")
+		}
+	}
+
+	var sizes []int
+	for i := 0; i < chcnt; i++ {
+		size, err := field("chunk sizes")
+		if err != nil {
+			return err
+		}
+		sizes = append(sizes, size)
+	}
+
+	// NOTE: Zero bytes following the chunk sizes are reserved entries.
+	// Other trailing data is ignored.
+	var reserved int
+	if rest := data[p.off:]; len(rest) >= fieldLen && len(bytes.Trim(rest, "\x00")) == 0 {
+		reserved = len(rest) / fieldLen
+	}
+
+	h.ChunkSize, h.Sizes, h.ReservedChunks = chlen, sizes, reserved
+	return nil
+}
+
+// string parses a zero byte terminated ISO 8859-1, Latin-1 string. If
+// opts.UTF8Strings is set, non-ASCII UTF-8 strings are decoded as UTF-8 and
+// h.UTF8Strings is set.
+func (p *parser) string(h *Header, opts ParseOptions) (string, error) {
+	for i := p.off; i < len(p.b); i++ {
+		if i-p.off >= MaxStringLen {
+			return "", fmt.Errorf("%w: string header len exceeded", ErrHeader)
+		}
+		if p.b[i] == 0 {
+			if b := p.b[p.off:i]; opts.UTF8Strings && !isASCII(b) && utf8.Valid(b) {
+				p.off = i + 1
+				h.UTF8Strings = true
+				return string(b), nil
+			}
+
+			// Strings are ISO 8859-1, Latin-1 (RFC 1952, section 2.3.1).
+			var s strings.Builder
+			for _, v := range p.b[p.off:i] {
+				s.WriteRune(rune(v))
+			}
+			p.off = i + 1
+			return s.String(), nil
+		}
+	}
+	if len(p.b)-p.off >= MaxStringLen {
+		return "", fmt.Errorf("%w: string header len exceeded", ErrHeader)
+	}
+	_, err := p.next(len(p.b)-p.off+1, "string header")
+	return "", err
+}
+
+// Append appends the encoded header h to dst and returns the extended
+// buffer. The RA subfield is written first followed by the shared window
+// subfield, if h.SharedWindow is set, the chunk CRC or CRC-32C subfield, if
+// h.ChunkCRCs is not nil, and then h.Extra.
+//
+// If h.Subfields is not nil, the subfields are instead written in the order
+// given by h.Subfields. The dictzip subfields are written at the position of
+// their IDs and other IDs are matched in order with the subfields in
+// h.Extra. Subfields not given in h.Subfields are written after the others
+// in the default order.
+func Append(dst []byte, h *Header) ([]byte, error) {
+	return appendHeader(dst, h, true)
+}
+
+// AppendGzip appends h encoded as a plain gzip header, without the dictzip
+// subfields, to dst and returns the extended buffer. Only h.Extra is written
+// to the EXTRA field and the field is omitted if h.Extra is empty.
+func AppendGzip(dst []byte, h *Header) ([]byte, error) {
+	return appendHeader(dst, h, false)
+}
+
+// appendHeader appends the encoded header h to dst. The dictzip subfields
+// are written only if dictzip is true.
+func appendHeader(dst []byte, h *Header, dictzip bool) ([]byte, error) {
+	var flg byte
+	if dictzip || len(h.Extra) > 0 {
+		flg |= flgEXTRA
+	}
+	if h.Name != "" {
+		flg |= flgNAME
+	}
+	if h.Comment != "" {
+		flg |= flgCOMMENT
+	}
+	var mtime uint32
+	if h.ModTime.After(time.Unix(0, 0)) {
+		// Section 2.3.1, the zero value for MTIME means that the
+		// modified time is not set.
+		// NOTE: since this is a uint32 timestamp, it should work until 2106-02-07.
+		//nolint:gosec // We will allow overflow of modtime. It is not a security issue.
+		mtime = uint32(h.ModTime.Unix())
+	}
+
+	dst = append(dst, ID1, ID2, CMDeflate, flg)
+	dst = binary.LittleEndian.AppendUint32(dst, mtime)
+	dst = append(dst, h.XFL, h.OS)
+
+	var err error
+	switch {
+	case dictzip:
+		dst, err = appendExtra(dst, h)
+		if err != nil {
+			return nil, err
+		}
+	case len(h.Extra) > 0:
+		if len(h.Extra) > math.MaxUint16 {
+			return nil, fmt.Errorf("%w: XLEN exceeded: %v", ErrHeader, len(h.Extra))
+		}
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(len(h.Extra)))
+		dst = append(dst, h.Extra...)
+	}
+
+	if h.Name != "" {
+		if dst, err = appendString(dst, h.Name, h.UTF8Strings); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.Comment != "" {
+		if dst, err = appendString(dst, h.Comment, h.UTF8Strings); err != nil {
+			return nil, err
+		}
+	}
+
+	return dst, nil
+}
+
+// appendExtra appends the EXTRA field to dst.
+func appendExtra(dst []byte, h *Header) ([]byte, error) {
+	// The extra header is written as follows unless the order is given by
+	// h.Subfields. The RA random access dictzip field is written first.
+	// - RA subfield
+	//   - SI1 (1 byte) - gzip
+	//   - SI2 (1 byte) - gzip
+	//   - LEN (2 bytes) - gzip
+	//   - VER (2 bytes) - dictzip
+	//   - CHLEN (2 bytes) - dictzip
+	//   - CHCNT (2 bytes) - dictzip
+	//   - Chunk sizes (each 2 bytes).
+	//   - Reserved chunk entries (each 2 zero bytes).
+	// - RW shared window subfield (only if h.SharedWindow is set).
+	//   - SI1 (1 byte) - gzip
+	//   - SI2 (1 byte) - gzip
+	//   - LEN (2 bytes) - gzip (always zero)
+	// - RC chunk CRC subfield (only if h.ChunkCRCs is not nil). The RK
+	//   chunk CRC-32C subfield if h.ChunkCRCCastagnoli is set.
+	//   - SI1 (1 byte) - gzip
+	//   - SI2 (1 byte) - gzip
+	//   - LEN (2 bytes) - gzip
+	//   - Chunk CRC-32s (each 4 bytes).
+	// - User-specified h.Extra data.
+
+	// CHLEN
+	chlen := h.ChunkSize
+	if chlen > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: CHLEN exceeded: %v", ErrHeader, chlen)
+	}
+
+	// CHCNT
+	chcnt := len(h.Sizes)
+	if chcnt > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: CHCNT exceeded: %v", ErrHeader, chcnt)
+	}
+
+	if h.ReservedChunks < 0 {
+		return nil, fmt.Errorf("%w: negative reserved chunks: %v", ErrHeader, h.ReservedChunks)
+	}
+
+	// LEN field (includes VER, CHLEN, CHCNT, chunk sizes, reserved chunk
+	// entries)
+	raLen := 6 + (chcnt * 2) + (h.ReservedChunks * 2)
+
+	// RW subfield length (includes SI1, SI2, LEN)
+	var rwLen int
+	if h.SharedWindow {
+		rwLen = 4
+	}
+
+	// RC subfield LEN (the chunk CRC-32s)
+	var rcLen int
+	if h.ChunkCRCs != nil {
+		if len(h.ChunkCRCs) != chcnt {
+			return nil, fmt.Errorf("%w: %d chunk CRCs for %d chunks", ErrHeader, len(h.ChunkCRCs), chcnt)
+		}
+		rcLen = 4 * chcnt
+		// NOTE: The XLEN check below means rcLen fits in LEN.
+	}
+
+	// XLEN (includes SI1, SI2, LEN, RA subfield, RW subfield, RC subfield,
+	// user-specified extra subfields)
+	xlen := 4 + raLen + rwLen + len(h.Extra)
+	if h.ChunkCRCs != nil {
+		xlen += 4 + rcLen
+	}
+	if xlen > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: XLEN exceeded: %v", ErrHeader, xlen)
+	}
+
+	//nolint:gosec // xlen max value is checked above.
+	dst = binary.LittleEndian.AppendUint16(dst, uint16(xlen))
+
+	// The RA subfield.
+	ra := make([]byte, 0, 4+raLen)
+	ra = append(ra, RASI1, RASI2)
+	//nolint:gosec // raLen max value is checked above.
+	ra = binary.LittleEndian.AppendUint16(ra, uint16(raLen)) // LEN
+	ra = binary.LittleEndian.AppendUint16(ra, RAVersion)     // VER
+	//nolint:gosec // chlen max value is checked above.
+	ra = binary.LittleEndian.AppendUint16(ra, uint16(chlen))
+	// NOTE: chcnt max value is checked above. gosec doesn't seem to care about this.
+	ra = binary.LittleEndian.AppendUint16(ra, uint16(chcnt))
+
+	for _, chSize := range h.Sizes {
+		if chSize > math.MaxUint16 {
+			return nil, fmt.Errorf("%w: chunk size exceeded: %v", ErrHeader, chSize)
+		}
+		//nolint:gosec // chSize max value is checked above.
+		ra = binary.LittleEndian.AppendUint16(ra, uint16(chSize))
+	}
+	ra = append(ra, make([]byte, 2*h.ReservedChunks)...)
+
+	// The RW subfield. LEN is zero.
+	var rw []byte
+	if h.SharedWindow {
+		rw = []byte{WindowSI1, WindowSI2, 0, 0}
+	}
+
+	// The RC subfield.
+	var rc []byte
+	if h.ChunkCRCs != nil {
+		rc = make([]byte, 0, 4+rcLen)
+		if h.ChunkCRCCastagnoli {
+			rc = append(rc, ChunkCRC32CSI1, ChunkCRC32CSI2)
+		} else {
+			rc = append(rc, ChunkCRCSI1, ChunkCRCSI2)
+		}
+		//nolint:gosec // rcLen max value is checked above.
+		rc = binary.LittleEndian.AppendUint16(rc, uint16(rcLen))
+		for _, crc := range h.ChunkCRCs {
+			rc = binary.LittleEndian.AppendUint32(rc, crc)
+		}
+	}
+
+	// Write the subfields in the order given by h.Subfields.
+	extra := h.Extra
+	for _, id := range h.Subfields {
+		switch {
+		case id == [2]byte{RASI1, RASI2}:
+			dst, ra = append(dst, ra...), nil
+		case id == [2]byte{WindowSI1, WindowSI2}:
+			dst, rw = append(dst, rw...), nil
+		case id == [2]byte{ChunkCRCSI1, ChunkCRCSI2}, id == [2]byte{ChunkCRC32CSI1, ChunkCRC32CSI2}:
+			dst, rc = append(dst, rc...), nil
+		default:
+			n := subfieldLen(extra)
+			if n == 0 || extra[0] != id[0] || extra[1] != id[1] {
+				// NOTE: Subfields removed from h.Extra are skipped.
+				continue
+			}
+			dst, extra = append(dst, extra[:n]...), extra[n:]
+		}
+	}
+
+	// Write the remaining subfields in the default order followed by the
+	// user specified extra data.
+	dst = append(dst, ra...)
+	dst = append(dst, rw...)
+	dst = append(dst, rc...)
+	return append(dst, extra...), nil
+}
+
+// subfieldLen returns the length of the EXTRA subfield, including the SI1,
+// SI2, and LEN fields, at the start of extra. It returns zero if extra does
+// not start with a well-formed subfield.
+func subfieldLen(extra []byte) int {
+	if len(extra) < 4 {
+		return 0
+	}
+	n := 4 + int(binary.LittleEndian.Uint16(extra[2:4]))
+	if n > len(extra) {
+		return 0
+	}
+	return n
+}
+
+// appendString appends a string header value to dst. The string is encoded
+// in ISO 8859-1, Latin-1, or UTF-8 if utf8Strings is set, and terminated
+// with a zero byte.
+func appendString(dst []byte, s string, utf8Strings bool) ([]byte, error) {
+	if utf8Strings {
+		if !utf8.ValidString(s) || strings.IndexByte(s, 0) >= 0 {
+			return nil, fmt.Errorf("%w: invalid UTF-8 header string", ErrHeader)
+		}
+		dst = append(dst, s...)
+		return append(dst, byte(0)), nil
+	}
+
+	// Strings are ISO 8859-1, Latin-1 (RFC 1952, section 2.3.1).
+	for _, r := range s {
+		if r == 0 || r > 0xff {
+			return nil, fmt.Errorf("%w: non-Latin-1 header string", ErrHeader)
+		}
+		dst = append(dst, byte(r))
+	}
+	// strings are terminated by a zero byte.
+	return append(dst, byte(0)), nil
+}
+
+// isASCII returns true if b only includes ASCII characters.
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
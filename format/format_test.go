@@ -0,0 +1,981 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		data   []byte
+		opts   ParseOptions
+		header *Header
+		n      int
+		err    error
+	}{
+		"minimal": {
+			data: []byte{
+				ID1, ID2, CMDeflate,
+				flgEXTRA,               // FLG
+				0x00, 0x00, 0x00, 0x00, // MTIME
+				0x2, // XFL
+				0x3, // OS
+
+				0xa, 0x0, // XLEN
+				RASI1, RASI2,
+				0x6, 0x0, // LEN
+				0x1, 0x0, // VER
+				0xcb, 0xe3, // CHLEN
+				0x0, 0x0, // CHCNT
+
+				0x3, 0x0, // Deflate data.
+			},
+			header: &Header{
+				XFL:       0x2,
+				OS:        0x3,
+				Subfields: [][2]byte{{RASI1, RASI2}},
+				ChunkSize: 58315,
+			},
+			n: 22,
+		},
+		"all fields": {
+			data: []byte{
+				ID1, ID2, CMDeflate,
+				flgEXTRA | flgNAME | flgCOMMENT | flgCRC, // FLG
+				0x5c, 0x8b, 0x3e, 0x16,                   // MTIME
+				0x0,  // XFL
+				0xff, // OS
+
+				0x17, 0x0, // XLEN
+				RASI1, RASI2,
+				0xa, 0x0, // LEN
+				0x1, 0x0, // VER
+				0x0, 0x1, // CHLEN
+				0x2, 0x0, // CHCNT
+				0x10, 0x0, // size
+				0x20, 0x0, // size
+				WindowSI1, WindowSI2, 0x0, 0x0,
+				'A', 'Z', 0x1, 0x0, 0xab,
+
+				'n', 0xe9, 0x0, // NAME
+				'c', 0x0, // COMMENT
+
+				0xd4, 0x21, // CRC16
+			},
+			header: &Header{
+				ModTime:      time.Unix(0x163e8b5c, 0),
+				OS:           0xff,
+				Extra:        []byte{'A', 'Z', 0x1, 0x0, 0xab},
+				Subfields:    [][2]byte{{RASI1, RASI2}, {WindowSI1, WindowSI2}, {'A', 'Z'}},
+				Name:         "né",
+				Comment:      "c",
+				HeaderCRC:    true,
+				ChunkSize:    256,
+				Sizes:        []int{16, 32},
+				SharedWindow: true,
+			},
+			n: 42,
+		},
+		"version 2": {
+			data: []byte{
+				ID1, ID2, CMDeflate,
+				flgEXTRA,               // FLG
+				0x00, 0x00, 0x00, 0x00, // MTIME
+				0x0, // XFL
+				0x3, // OS
+
+				0x16, 0x0, // XLEN
+				RASI1, RASI2,
+				0x12, 0x0, // LEN
+				0x2, 0x0, // VER
+				0x0, 0x0, 0x10, 0x0, // CHLEN
+				0x2, 0x0, 0x0, 0x0, // CHCNT
+				0x10, 0x0, 0x1, 0x0, // size
+				0x20, 0x0, 0x0, 0x0, // size
+			},
+			header: &Header{
+				OS:        0x3,
+				Subfields: [][2]byte{{RASI1, RASI2}},
+				ChunkSize: 1 << 20,
+				Sizes:     []int{0x10010, 0x20},
+			},
+			n: 34,
+		},
+		"version 2 zero CHLEN": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xe, 0x0, // XLEN
+				RASI1, RASI2,
+				0xa, 0x0, // LEN
+				0x2, 0x0, // VER
+				0x0, 0x0, 0x0, 0x0, // CHLEN
+				0x0, 0x0, 0x0, 0x0, // CHCNT
+			},
+			n:   26,
+			err: ErrHeader,
+		},
+		"version 2 size exceeded": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0x12, 0x0, // XLEN
+				RASI1, RASI2,
+				0xe, 0x0, // LEN
+				0x2, 0x0, // VER
+				0x0, 0x0, 0x1, 0x0, // CHLEN
+				0x1, 0x0, 0x0, 0x0, // CHCNT
+				0xff, 0xff, 0xff, 0xff, // size
+			},
+			n:   30,
+			err: ErrHeader,
+		},
+		"unsupported version": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2,
+				0x6, 0x0, // LEN
+				0x3, 0x0, // VER
+				0xcb, 0xe3, // CHLEN
+				0x0, 0x0, // CHCNT
+			},
+			n:   22,
+			err: ErrHeader,
+		},
+		"lenient unsupported version": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xc, 0x0, // XLEN
+				RASI1, RASI2,
+				0x8, 0x0, // LEN
+				0x3, 0x0, // VER
+				0x0, 0x1, // CHLEN
+				0x1, 0x0, // CHCNT
+				0x20, 0x0, // size
+			},
+			opts: ParseOptions{LenientHeader: true},
+			header: &Header{
+				Subfields: [][2]byte{{RASI1, RASI2}},
+				ChunkSize: 256,
+				Sizes:     []int{0x20},
+				Warnings:  []string{"unsupported RA version 3 read as version 1"},
+			},
+			n: 24,
+		},
+		"strict and lenient unsupported version": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2,
+				0x6, 0x0, // LEN
+				0x3, 0x0, // VER
+				0x0, 0x1, // CHLEN
+				0x0, 0x0, // CHCNT
+			},
+			opts: ParseOptions{StrictHeader: true, LenientHeader: true},
+			n:    22,
+			err:  ErrHeader,
+		},
+		"zero CHLEN": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xc, 0x0, // XLEN
+				RASI1, RASI2,
+				0x8, 0x0, // LEN
+				0x1, 0x0, // VER
+				0x0, 0x0, // CHLEN
+				0x1, 0x0, // CHCNT
+				0x20, 0x0, // size
+			},
+			n:   24,
+			err: ErrHeader,
+		},
+		"zero CHLEN no chunks": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2,
+				0x6, 0x0, // LEN
+				0x1, 0x0, // VER
+				0x0, 0x0, // CHLEN
+				0x0, 0x0, // CHCNT
+			},
+			header: &Header{
+				Subfields: [][2]byte{{RASI1, RASI2}},
+				Warnings:  []string{"CHLEN is zero"},
+			},
+			n: 22,
+		},
+		"strict header zero CHLEN no chunks": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2,
+				0x6, 0x0, // LEN
+				0x1, 0x0, // VER
+				0x0, 0x0, // CHLEN
+				0x0, 0x0, // CHCNT
+			},
+			opts: ParseOptions{StrictHeader: true},
+			n:    22,
+			err:  ErrHeader,
+		},
+		"lenient zero CHLEN": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xc, 0x0, // XLEN
+				RASI1, RASI2,
+				0x8, 0x0, // LEN
+				0x1, 0x0, // VER
+				0x0, 0x0, // CHLEN
+				0x1, 0x0, // CHCNT
+				0x20, 0x0, // size
+			},
+			opts: ParseOptions{LenientHeader: true},
+			header: &Header{
+				Subfields: [][2]byte{{RASI1, RASI2}},
+				Sizes:     []int{0x20},
+				Warnings:  []string{"CHLEN is zero"},
+			},
+			n: 24,
+		},
+		"bad id": {
+			data: []byte{ID1, 0x00, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0},
+			n:    10,
+			err:  ErrHeader,
+		},
+		"bad cm": {
+			data: []byte{ID1, ID2, 0x07, flgEXTRA, 0, 0, 0, 0, 0, 0},
+			n:    10,
+			err:  ErrHeader,
+		},
+		"no extra": {
+			data: []byte{ID1, ID2, CMDeflate, 0, 0, 0, 0, 0, 0, 0},
+			n:    10,
+			err:  ErrHeader,
+		},
+		"no ra": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0x4, 0x0, // XLEN
+				'A', 'Z', 0x0, 0x0,
+			},
+			n:   16,
+			err: ErrHeader,
+		},
+		"bad version": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2, 0x6, 0x0,
+				0x2, 0x0, // VER
+				0x0, 0x1, 0x0, 0x0,
+			},
+			n:   22,
+			err: ErrHeader,
+		},
+		"short ra": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0x8, 0x0, // XLEN
+				RASI1, RASI2, 0x4, 0x0,
+				0x1, 0x0, 0x0, 0x1, // VER, CHLEN
+			},
+			n:   20,
+			err: ErrHeader,
+		},
+		"chunk CRC count": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0x16, 0x0, // XLEN
+				RASI1, RASI2, 0xa, 0x0, 0x1, 0x0, 0x0, 0x1, 0x2, 0x0, 0x10, 0x0, 0x20, 0x0,
+				ChunkCRCSI1, ChunkCRCSI2, 0x4, 0x0, 0x1, 0x2, 0x3, 0x4,
+			},
+			n:   34,
+			err: ErrHeader,
+		},
+		"chunk CRC length": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0x13, 0x0, // XLEN
+				RASI1, RASI2, 0x8, 0x0, 0x1, 0x0, 0x0, 0x1, 0x1, 0x0, 0x10, 0x0,
+				ChunkCRCSI1, ChunkCRCSI2, 0x3, 0x0, 0x1, 0x2, 0x3,
+			},
+			n:   31,
+			err: ErrHeader,
+		},
+		"chunk CRC and CRC-32C": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0x1c, 0x0, // XLEN
+				RASI1, RASI2, 0x8, 0x0, 0x1, 0x0, 0x0, 0x1, 0x1, 0x0, 0x10, 0x0,
+				ChunkCRCSI1, ChunkCRCSI2, 0x4, 0x0, 0x1, 0x2, 0x3, 0x4,
+				ChunkCRC32CSI1, ChunkCRC32CSI2, 0x4, 0x0, 0x1, 0x2, 0x3, 0x4,
+			},
+			n:   40,
+			err: ErrHeader,
+		},
+		"subfield length": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0x4, 0x0, // XLEN
+				'A', 'Z', 0x10, 0x0,
+			},
+			n:   16,
+			err: ErrSubfieldLength,
+		},
+		"tolerant subfield length": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xe, 0x0, // XLEN
+				RASI1, RASI2, 0x6, 0x0, 0x1, 0x0, 0x0, 0x1, 0x0, 0x0,
+				'A', 'Z', 0x10, 0x0,
+			},
+			opts: ParseOptions{TolerantExtra: true},
+			header: &Header{
+				Subfields:      [][2]byte{{RASI1, RASI2}},
+				ChunkSize:      256,
+				DiscardedExtra: 4,
+			},
+			n: 26,
+		},
+		"gzip": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgNAME, 0, 0, 0, 0, 0, 0x3,
+				'a', 0x0, // NAME
+			},
+			opts: ParseOptions{AllowGzip: true},
+			header: &Header{
+				OS:   0x3,
+				Name: "a",
+				Gzip: true,
+			},
+			n: 12,
+		},
+		"utf-8 strings": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgNAME | flgCOMMENT, 0, 0, 0, 0, 0, 0x3,
+				0xc3, 0xa9, 0x0, // NAME
+				'a', 0x0, // COMMENT
+			},
+			opts: ParseOptions{AllowGzip: true, UTF8Strings: true},
+			header: &Header{
+				OS:          0x3,
+				Name:        "é",
+				Comment:     "a",
+				Gzip:        true,
+				UTF8Strings: true,
+			},
+			n: 15,
+		},
+		"utf-8 strings latin-1": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgNAME, 0, 0, 0, 0, 0, 0x3,
+				0xe9, 0x0, // NAME
+			},
+			opts: ParseOptions{AllowGzip: true, UTF8Strings: true},
+			header: &Header{
+				OS:   0x3,
+				Name: "é",
+				Gzip: true,
+			},
+			n: 12,
+		},
+		"utf-8 strings not set": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgNAME, 0, 0, 0, 0, 0, 0x3,
+				0xc3, 0xa9, 0x0, // NAME
+			},
+			opts: ParseOptions{AllowGzip: true},
+			header: &Header{
+				OS:   0x3,
+				Name: "Ã©",
+				Gzip: true,
+			},
+			n: 13,
+		},
+		"gzip extra": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0x4, 0x0, // XLEN
+				'A', 'Z', 0x0, 0x0,
+			},
+			opts: ParseOptions{AllowGzip: true},
+			header: &Header{
+				Extra:     []byte{'A', 'Z', 0x0, 0x0},
+				Subfields: [][2]byte{{'A', 'Z'}},
+				Gzip:      true,
+			},
+			n: 16,
+		},
+		"no RA": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0x4, 0x0, // XLEN
+				'A', 'Z', 0x0, 0x0,
+			},
+			n:   16,
+			err: ErrHeader,
+		},
+		"strict": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0x2, 0x3,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2, 0x6, 0x0, 0x1, 0x0, 0x0, 0x1, 0x0, 0x0,
+			},
+			opts: ParseOptions{StrictRFC1952: true},
+			header: &Header{
+				XFL:       0x2,
+				OS:        0x3,
+				Subfields: [][2]byte{{RASI1, RASI2}},
+				ChunkSize: 256,
+			},
+			n: 22,
+		},
+		"reserved FLG": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA | 0x20, 0, 0, 0, 0, 0, 0,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2, 0x6, 0x0, 0x1, 0x0, 0x0, 0x1, 0x0, 0x0,
+			},
+			header: &Header{
+				Subfields: [][2]byte{{RASI1, RASI2}},
+				ChunkSize: 256,
+				Warnings:  []string{"reserved FLG bits set: 0x20"},
+			},
+			n: 22,
+		},
+		"strict header reserved FLG": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA | 0x20, 0, 0, 0, 0, 0, 0,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2, 0x6, 0x0, 0x1, 0x0, 0x0, 0x1, 0x0, 0x0,
+			},
+			opts: ParseOptions{StrictHeader: true},
+			n:    10,
+			err:  ErrHeader,
+		},
+		"strict reserved FLG": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA | 0x20, 0, 0, 0, 0, 0, 0,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2, 0x6, 0x0, 0x1, 0x0, 0x0, 0x1, 0x0, 0x0,
+			},
+			opts: ParseOptions{StrictRFC1952: true},
+			n:    10,
+			err:  ErrHeader,
+		},
+		"strict XFL": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0x6, 0,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2, 0x6, 0x0, 0x1, 0x0, 0x0, 0x1, 0x0, 0x0,
+			},
+			opts: ParseOptions{StrictRFC1952: true},
+			n:    10,
+			err:  ErrHeader,
+		},
+		"strict reserved subfield ID": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0,
+				0xe, 0x0, // XLEN
+				RASI1, RASI2, 0x6, 0x0, 0x1, 0x0, 0x0, 0x1, 0x0, 0x0,
+				'A', 0x0, 0x0, 0x0,
+			},
+			opts: ParseOptions{StrictRFC1952: true},
+			n:    26,
+			err:  ErrHeader,
+		},
+		"bad crc16": {
+			data: []byte{
+				ID1, ID2, CMDeflate, flgEXTRA | flgCRC, 0, 0, 0, 0, 0, 0,
+				0xa, 0x0, // XLEN
+				RASI1, RASI2, 0x6, 0x0, 0x1, 0x0, 0x0, 0x1, 0x0, 0x0,
+				0x0, 0x0, // CRC16
+			},
+			n:   24,
+			err: ErrHeader,
+		},
+		"empty": {
+			err: io.EOF,
+		},
+		"short": {
+			data: []byte{ID1, ID2, CMDeflate},
+			n:    3,
+			err:  io.ErrUnexpectedEOF,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h, n, err := ParseWithOptions(tc.data, tc.opts)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Parse (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.n, n); diff != "" {
+				t.Errorf("Parse n (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.header, h); diff != "" {
+				t.Errorf("Parse header (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestChunkTable(t *testing.T) {
+	t.Parallel()
+
+	head := []byte{ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 3}
+
+	testCases := map[string]struct {
+		data      []byte
+		off       int
+		entrySize int
+		err       error
+	}{
+		"version 1": {
+			data: append(head[:10:10],
+				0xe, 0x0, // XLEN
+				RASI1, RASI2,
+				0xa, 0x0, // LEN
+				0x1, 0x0, // VER
+				0x0, 0x1, // CHLEN
+				0x2, 0x0, // CHCNT
+				0x10, 0x0, // size
+				0x20, 0x0, // size
+			),
+			off:       22,
+			entrySize: 2,
+		},
+		"subfield before RA": {
+			data: append(head[:10:10],
+				0x15, 0x0, // XLEN
+				'X', 'Y',
+				0x3, 0x0, // LEN
+				'a', 'b', 'c',
+				RASI1, RASI2,
+				0xa, 0x0, // LEN
+				0x1, 0x0, // VER
+				0x0, 0x1, // CHLEN
+				0x2, 0x0, // CHCNT
+				0x10, 0x0, // size
+				0x20, 0x0, // size
+			),
+			off:       29,
+			entrySize: 2,
+		},
+		"version 2": {
+			data: append(head[:10:10],
+				0x16, 0x0, // XLEN
+				RASI1, RASI2,
+				0x12, 0x0, // LEN
+				0x2, 0x0, // VER
+				0x0, 0x0, 0x1, 0x0, // CHLEN
+				0x2, 0x0, 0x0, 0x0, // CHCNT
+				0x10, 0x0, 0x1, 0x0, // size
+				0x20, 0x0, 0x1, 0x0, // size
+			),
+			off:       26,
+			entrySize: 4,
+		},
+		"no RA": {
+			data: append(head[:10:10],
+				0x7, 0x0, // XLEN
+				'X', 'Y',
+				0x3, 0x0, // LEN
+				'a', 'b', 'c',
+			),
+			err: ErrHeader,
+		},
+		"no EXTRA": {
+			data: []byte{ID1, ID2, CMDeflate, 0, 0, 0, 0, 0, 0, 3},
+			err:  ErrHeader,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			off, entrySize, err := ChunkTable(tc.data)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ChunkTable (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.off, off); diff != "" {
+				t.Errorf("ChunkTable offset (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.entrySize, entrySize); diff != "" {
+				t.Errorf("ChunkTable entry size (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+
+			// The chunk table holds the sizes returned by Parse.
+			h, _, err := Parse(tc.data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			var sizes []int
+			for i := 0; i < len(h.Sizes); i++ {
+				entry := tc.data[off+i*entrySize : off+(i+1)*entrySize]
+				if entrySize == 2 {
+					sizes = append(sizes, int(binary.LittleEndian.Uint16(entry)))
+				} else {
+					sizes = append(sizes, int(binary.LittleEndian.Uint32(entry)))
+				}
+			}
+			if diff := cmp.Diff(h.Sizes, sizes); diff != "" {
+				t.Errorf("chunk table (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParse_truncated(t *testing.T) {
+	t.Parallel()
+
+	data, err := Append(nil, &Header{
+		Name:      "name",
+		Comment:   "comment",
+		ChunkSize: 100,
+		Sizes:     []int{10, 20, 30},
+		Extra:     []byte{'A', 'Z', 0x1, 0x0, 0xab},
+	})
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// Every prefix of the header should be reported as incomplete.
+	for i := 1; i < len(data); i++ {
+		_, _, err := Parse(data[:i])
+		if !errors.Is(err, io.ErrUnexpectedEOF) || !errors.Is(err, ErrHeader) {
+			t.Errorf("Parse(data[:%d]): %v", i, err)
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		header *Header
+		err    error
+	}{
+		"minimal": {
+			header: &Header{
+				OS:        0xff,
+				ChunkSize: 58315,
+			},
+		},
+		"all fields": {
+			header: &Header{
+				ModTime:      time.Unix(0x163e8b5c, 0),
+				XFL:          0x2,
+				OS:           0x3,
+				Extra:        []byte{'A', 'Z', 0x1, 0x0, 0xab},
+				Name:         "né",
+				Comment:      "comment",
+				ChunkSize:    256,
+				Sizes:        []int{16, 32},
+				SharedWindow: true,
+				ChunkCRCs:    []uint32{0xdeadbeef, 0x01020304},
+			},
+		},
+		"reserved chunks": {
+			header: &Header{
+				ChunkSize:      256,
+				Sizes:          []int{16, 32},
+				ReservedChunks: 3,
+			},
+		},
+		"negative reserved chunks": {
+			header: &Header{
+				ReservedChunks: -1,
+			},
+			err: ErrHeader,
+		},
+		"chunk CRC-32C": {
+			header: &Header{
+				OS:                 0xff,
+				ChunkSize:          256,
+				Sizes:              []int{16, 32},
+				ChunkCRCs:          []uint32{0xdeadbeef, 0x01020304},
+				ChunkCRCCastagnoli: true,
+			},
+		},
+		"chunk CRC count": {
+			header: &Header{
+				Sizes:     []int{16, 32},
+				ChunkCRCs: []uint32{0xdeadbeef},
+			},
+			err: ErrHeader,
+		},
+		"chunk size exceeded": {
+			header: &Header{
+				ChunkSize: 1 << 16,
+			},
+			err: ErrHeader,
+		},
+		"sizes exceeded": {
+			header: &Header{
+				Sizes: []int{1 << 16},
+			},
+			err: ErrHeader,
+		},
+		"non-Latin-1 name": {
+			header: &Header{
+				Name: "世界",
+			},
+			err: ErrHeader,
+		},
+		"utf-8 strings": {
+			header: &Header{
+				Name:        "世界",
+				Comment:     "日本語",
+				ChunkSize:   256,
+				UTF8Strings: true,
+			},
+		},
+		"invalid utf-8 string": {
+			header: &Header{
+				Name:        "\xff",
+				UTF8Strings: true,
+			},
+			err: ErrHeader,
+		},
+		"utf-8 string with NUL": {
+			header: &Header{
+				Comment:     "a\x00b",
+				UTF8Strings: true,
+			},
+			err: ErrHeader,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			prefix := []byte("prefix")
+			data, err := Append(prefix, tc.header)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("Append (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(prefix, data[:len(prefix)]); diff != "" {
+				t.Errorf("prefix (-want, +got):\n%s", diff)
+			}
+
+			h, n, err := ParseWithOptions(data[len(prefix):], ParseOptions{UTF8Strings: tc.header.UTF8Strings})
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if diff := cmp.Diff(len(data)-len(prefix), n); diff != "" {
+				t.Errorf("Parse n (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.header, h, cmpopts.IgnoreFields(Header{}, "Subfields")); diff != "" {
+				t.Errorf("Parse header (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAppend_subfieldOrder(t *testing.T) {
+	t.Parallel()
+
+	// A header written by another producer with subfields before and after
+	// the RA subfield.
+	var extra []byte
+	extra = append(extra, 'A', 'Z', 0x1, 0x0, 0xab)
+	extra = append(extra, RASI1, RASI2, 0x8, 0x0)
+	extra = binary.LittleEndian.AppendUint16(extra, RAVersion)
+	extra = binary.LittleEndian.AppendUint16(extra, 256)
+	extra = binary.LittleEndian.AppendUint16(extra, 1)
+	extra = binary.LittleEndian.AppendUint16(extra, 16)
+	extra = append(extra, 'B', 'Y', 0x2, 0x0, 0xcd, 0xef)
+	header := []byte{ID1, ID2, CMDeflate, flgEXTRA, 0, 0, 0, 0, 0, 0x3}
+	header = binary.LittleEndian.AppendUint16(header, uint16(len(extra)))
+	header = append(header, extra...)
+
+	h, _, err := Parse(header)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	got, err := Append(nil, h)
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if diff := cmp.Diff(header, got); diff != "" {
+		t.Errorf("Append (-want, +got):\n%s", diff)
+	}
+
+	// Removed subfields are skipped and added subfields are written at the
+	// end.
+	h.Extra = append(h.Extra[5:], 'C', 'X', 0x0, 0x0)
+	h.SharedWindow = true
+	got, err = Append(nil, h)
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	h2, _, err := Parse(got)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	wantIDs := [][2]byte{{RASI1, RASI2}, {'B', 'Y'}, {WindowSI1, WindowSI2}, {'C', 'X'}}
+	if diff := cmp.Diff(wantIDs, h2.Subfields); diff != "" {
+		t.Errorf("Subfields (-want, +got):\n%s", diff)
+	}
+}
+
+func TestAppendGzip(t *testing.T) {
+	t.Parallel()
+
+	// emptyStream is an empty deflate stream followed by the gzip trailer
+	// for empty data.
+	emptyStream := []byte{0x3, 0x0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	testCases := map[string]struct {
+		header *Header
+		want   gzip.Header
+		err    error
+	}{
+		"minimal": {
+			header: &Header{
+				OS:        0xff,
+				ChunkSize: 58315,
+				Sizes:     []int{16},
+			},
+			want: gzip.Header{
+				OS: 0xff,
+			},
+		},
+		"all fields": {
+			header: &Header{
+				ModTime:      time.Unix(0x163e8b5c, 0),
+				XFL:          0x2,
+				OS:           0x3,
+				Extra:        []byte{'A', 'Z', 0x1, 0x0, 0xab},
+				Name:         "né",
+				Comment:      "comment",
+				ChunkSize:    256,
+				Sizes:        []int{16, 32},
+				SharedWindow: true,
+				ChunkCRCs:    []uint32{0xdeadbeef, 0x01020304},
+			},
+			want: gzip.Header{
+				ModTime: time.Unix(0x163e8b5c, 0),
+				OS:      0x3,
+				Extra:   []byte{'A', 'Z', 0x1, 0x0, 0xab},
+				Name:    "né",
+				Comment: "comment",
+			},
+		},
+		"extra exceeded": {
+			header: &Header{
+				Extra: make([]byte, 1<<16),
+			},
+			err: ErrHeader,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := AppendGzip(nil, tc.header)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("AppendGzip (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+
+			// The header is a plain gzip header without dictzip
+			// subfields.
+			_, _, err = Parse(data)
+			if diff := cmp.Diff(ErrHeader, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Parse (-want, +got):\n%s", diff)
+			}
+
+			z, err := gzip.NewReader(bytes.NewReader(append(data, emptyStream...)))
+			if err != nil {
+				t.Fatalf("gzip.NewReader: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, z.Header); diff != "" {
+				t.Errorf("gzip header (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+func FuzzParse(f *testing.F) {
+	data, err := Append(nil, &Header{
+		Name:         "name",
+		Comment:      "comment",
+		ChunkSize:    100,
+		Sizes:        []int{10, 20},
+		SharedWindow: true,
+	})
+	if err != nil {
+		f.Fatalf("Append: %v", err)
+	}
+	f.Add(data)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h, n, err := Parse(data)
+		if err != nil {
+			return
+		}
+		if n > len(data) {
+			t.Fatalf("Parse: header length %d exceeds data length %d", n, len(data))
+		}
+
+		// Headers without a CRC-16 should round trip.
+		if h.HeaderCRC {
+			return
+		}
+		got, err := Append(nil, h)
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		h2, _, err := Parse(got)
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if diff := cmp.Diff(h, h2, cmpopts.IgnoreFields(Header{}, "Subfields")); diff != "" {
+			t.Errorf("Parse (-want, +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+var errTestRead = errors.New("test read error")
+
+// errReaderAt is an io.ReaderAt that always returns an error.
+type errReaderAt struct{}
+
+func (errReaderAt) ReadAt([]byte, int64) (int, error) {
+	return 0, errTestRead
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectFormat(t *testing.T) {
+	t.Parallel()
+
+	dzData, err := os.ReadFile("internal/testdata/hello.txt.dz")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		data   []byte
+		format Format
+	}{
+		{
+			name:   "dictzip",
+			data:   dzData,
+			format: FormatDictzip,
+		},
+		{
+			name:   "gzip",
+			data:   gzipBytes(t, []byte("Hello World!")),
+			format: FormatGzip,
+		},
+		{
+			name: "gzip with non-RA extra",
+			data: []byte{
+				// Header
+				hdrGzipID1,
+				hdrGzipID2,
+				hdrDeflateCM,
+				flgEXTRA,               // FLG
+				0x00, 0x00, 0x00, 0x00, // MTIME
+				0x0,       // XFL
+				OSUnknown, // OS
+
+				// EXTRA
+				0x7, 0x0, // XLEN // 7
+				'A', 'Z', // SI
+				0x3, 0x0, // LEN
+				0xab, 0xcd, 0xef,
+
+				0x03, 0x00, // Empty deflate data.
+
+				0x0, 0x0, 0x0, 0x0, // CRC32
+				0x0, 0x0, 0x0, 0x0, // ISIZE
+			},
+			format: FormatGzip,
+		},
+		{
+			name: "truncated gzip",
+			data: []byte{
+				hdrGzipID1,
+				hdrGzipID2,
+				hdrDeflateCM,
+				flgEXTRA, // FLG
+			},
+			format: FormatGzip,
+		},
+		{
+			name:   "text",
+			data:   []byte("Hello World!"),
+			format: FormatUnknown,
+		},
+		{
+			name:   "short",
+			data:   []byte{hdrGzipID1},
+			format: FormatUnknown,
+		},
+		{
+			name:   "empty",
+			data:   nil,
+			format: FormatUnknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			format, err := DetectFormat(bytes.NewReader(tc.data))
+			if err != nil {
+				t.Fatalf("DetectFormat: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.format, format); diff != "" {
+				t.Errorf("DetectFormat (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDetectFormat_error(t *testing.T) {
+	t.Parallel()
+
+	_, err := DetectFormat(errReaderAt{})
+	if diff := cmp.Diff(errTestRead, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("DetectFormat (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// NewReaderFile creates a new [Reader] reading from the [fs.File] f, such as
+// a file opened from an [embed.FS], a zip archive, or another [fs.FS]
+// implementation. f is read directly if it implements [io.Seeker], and
+// [Reader.ReadAt] uses positional reads if it also implements [io.ReaderAt].
+// Otherwise, the contents of f are read into memory.
+//
+// The caller is responsible for closing f after the Reader is closed.
+//
+// [embed.FS]: https://pkg.go.dev/embed#FS
+func NewReaderFile(f fs.File, opts ...ReaderOption) (*Reader, error) {
+	r, err := fileReadSeeker(f)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(r, opts...)
+}
+
+// OpenFS opens the file name in fsys and creates a new [Reader] reading from
+// it as with [NewReaderFile]. The file is closed when the Reader is closed.
+func OpenFS(fsys fs.FS, name string, opts ...ReaderOption) (*Reader, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: opening %q: %w", errDictzip, name, err)
+	}
+
+	z, err := NewReaderFile(f, opts...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	z.closer = f
+	return z, nil
+}
+
+// fileReadSeeker returns an [io.ReadSeeker] reading the contents of f. The
+// contents are read into memory if f does not implement [io.Seeker].
+func fileReadSeeker(f fs.File) (io.ReadSeeker, error) {
+	if r, ok := f.(io.ReadSeeker); ok {
+		return r, nil
+	}
+
+	var buf bytes.Buffer
+	if fInfo, err := f.Stat(); err == nil && fInfo.Mode().IsRegular() {
+		// NOTE: The size is only a hint since it may not be accurate for
+		// all file systems.
+		buf.Grow(int(fInfo.Size()))
+	}
+	if _, err := buf.ReadFrom(f); err != nil {
+		return nil, fmt.Errorf("%w: reading file: %w", errDictzip, err)
+	}
+	return bytes.NewReader(buf.Bytes()), nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// streamFile is an fs.File that does not implement io.Seeker. closed is set
+// when it is closed.
+type streamFile struct {
+	io.Reader
+	info   fs.FileInfo
+	closed *bool
+}
+
+func (f *streamFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *streamFile) Close() error {
+	*f.closed = true
+	return nil
+}
+
+// streamFS is an fs.FS whose files do not implement io.Seeker.
+type streamFS struct {
+	fstest.MapFS
+	closed bool
+}
+
+func (fsys *streamFS) Open(name string) (fs.File, error) {
+	f, err := fsys.MapFS.Open(name)
+	if err != nil {
+		//nolint:wrapcheck // errors are returned unchanged.
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		//nolint:wrapcheck // errors are returned unchanged.
+		return nil, err
+	}
+	return &streamFile{Reader: f, info: info, closed: &fsys.closed}, nil
+}
+
+func TestOpenFS(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("Hello, OpenFS!\n"), 1000)
+	archive := mustCompress(t, data)
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	w, err := zw.Create("dict/test.dict.dz")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := w.Write(archive); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(zipBuf.Bytes()), int64(zipBuf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+
+	mapFS := fstest.MapFS{
+		"dict/test.dict.dz": &fstest.MapFile{Data: archive},
+	}
+
+	testCases := map[string]fs.FS{
+		"map":    mapFS,
+		"zip":    zr,
+		"stream": &streamFS{MapFS: mapFS},
+	}
+
+	for name, fsys := range testCases {
+		fsys := fsys
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := OpenFS(fsys, "dict/test.dict.dz")
+			if err != nil {
+				t.Fatalf("OpenFS: %v", err)
+			}
+
+			got := make([]byte, 100)
+			if _, err := z.ReadAt(got, 1000); err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if diff := cmp.Diff(data[1000:1100], got); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+
+			all, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(data, all); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+
+			if err := z.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if s, ok := fsys.(*streamFS); ok && !s.closed {
+				t.Errorf("Close: file not closed")
+			}
+		})
+	}
+}
+
+func TestOpenFS_errors(t *testing.T) {
+	t.Parallel()
+
+	fsys := &streamFS{MapFS: fstest.MapFS{
+		"bad.dz": &fstest.MapFile{Data: []byte("not a dictzip file")},
+	}}
+
+	_, err := OpenFS(fsys, "missing.dz")
+	if diff := cmp.Diff(fs.ErrNotExist, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("OpenFS (-want, +got):\n%s", diff)
+	}
+
+	_, err = OpenFS(fsys, "bad.dz")
+	if diff := cmp.Diff(ErrHeader, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("OpenFS (-want, +got):\n%s", diff)
+	}
+	if !fsys.closed {
+		t.Errorf("OpenFS: file not closed after error")
+	}
+}
+
+func TestNewReaderFile(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("Hello, NewReaderFile!")
+	fsys := fstest.MapFS{
+		"test.dz": &fstest.MapFile{Data: mustCompress(t, data)},
+	}
+	f, err := fsys.Open("test.dz")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	z, err := NewReaderFile(f)
+	if err != nil {
+		t.Fatalf("NewReaderFile: %v", err)
+	}
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if diff := cmp.Diff(data, got); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// The file is not closed by the Reader.
+	if _, err := f.Stat(); err != nil {
+		t.Errorf("Stat: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrPlainGzip indicates that an operation requires dictzip chunks and is not
+// supported for plain gzip files read with [WithGzipFallback].
+var ErrPlainGzip = fmt.Errorf("%w: not supported for plain gzip files", errDictzip)
+
+// WithGzipFallback configures the [Reader] to accept plain gzip files without
+// the dictzip RA subfield rather than returning an error wrapping
+// [ErrHeader]. This allows .gz and .dz files to be read with the same code.
+// [Header.PlainGzip] reports whether the file is a plain gzip file.
+//
+// Plain gzip files have no chunks so data can only be read by inflating the
+// file sequentially. Sequential reads with [Reader.Read], and reads following
+// the previous read, continue from the previous read but reads before it
+// inflate the file again from the start. Reader options that depend on chunks
+// have no effect for plain gzip files. Only the first gzip member is read.
+func WithGzipFallback() ReaderOption {
+	return func(z *Reader) {
+		z.gzipFallback = true
+	}
+}
+
+// PlainGzip reports whether the file is a plain gzip file without the dictzip
+// RA subfield. It is only true for files read with [WithGzipFallback].
+func (h *Header) PlainGzip() bool {
+	return h.plainGzip
+}
+
+// gzipSource reads the compressed data of a plain gzip file sequentially.
+// The underlying reader is seeked before each read since other methods may
+// read from it.
+type gzipSource struct {
+	z   *Reader
+	off int64
+}
+
+// Read implements [io.Reader].
+func (s *gzipSource) Read(p []byte) (int, error) {
+	if _, err := s.z.r.Seek(s.off, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+	n, err := s.z.r.Read(p)
+	s.off += int64(n)
+	//nolint:wrapcheck // we must return unwrapped io.EOF for io.Reader
+	return n, err
+}
+
+// readGzip reads len(p) bytes of uncompressed data at offset off of a plain
+// gzip file into p.
+func (z *Reader) readGzip(p []byte, off int64) (int, error) {
+	if z.gzipSrc == nil || off < z.gzipOff {
+		// Inflate the file from the start.
+		z.gzipSrc = &gzipSource{z: z, off: z.offsets.at(0)}
+		z.gzipOff = 0
+		if err := z.z.Reset(bufio.NewReader(z.gzipSrc), nil); err != nil {
+			z.gzipSrc = nil
+			return 0, fmt.Errorf("%w: Reset: %w", errDictzip, err)
+		}
+	}
+
+	if off > z.gzipOff {
+		n, err := io.CopyN(io.Discard, z.z, off-z.gzipOff)
+		z.gzipOff += n
+		if err != nil {
+			return 0, z.gzipErr(err)
+		}
+	}
+
+	var n int
+	var err error
+	for err == nil && n < len(p) {
+		var m int
+		m, err = z.z.Read(p[n:])
+		n += m
+	}
+	z.gzipOff += int64(n)
+	if n == len(p) && errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return n, z.gzipErr(err)
+}
+
+// gzipErr returns the error for err returned when inflating a plain gzip
+// file. Decompression state is discarded after errors other than io.EOF.
+func (z *Reader) gzipErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, io.EOF):
+		//nolint:wrapcheck // we must return unwrapped io.EOF for io.Reader
+		return err
+	default:
+		z.gzipSrc = nil
+		return fmt.Errorf("%w: inflating: %w", errDictzip, err)
+	}
+}
+
+// gzipSize returns the size of the uncompressed data of a plain gzip file.
+// It is read from the ISIZE field of the trailer unless [WithExactSize] was
+// given, in which case the file is inflated.
+func (z *Reader) gzipSize() (int64, error) {
+	if z.exactSize {
+		z.gzipSrc = nil
+		var size int64
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := z.readGzip(buf, size)
+			size += int64(n)
+			if errors.Is(err, io.EOF) {
+				return size, nil
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	trailer, err := z.readTrailer()
+	if err != nil {
+		return 0, err
+	}
+	// NOTE: ISIZE is the size modulo 2^32.
+	return int64(binary.LittleEndian.Uint32(trailer[4:])), nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// gzipFile compresses data as a plain gzip file.
+func gzipFile(t *testing.T, data []byte, extra []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Name = "test.txt"
+	w.Extra = extra
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestWithGzipFallback(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("Hello, gzip fallback!\n"), 5000)
+
+	testCases := map[string]struct {
+		b    []byte
+		gzip bool
+	}{
+		"gzip": {
+			b:    gzipFile(t, data, nil),
+			gzip: true,
+		},
+		"gzip extra": {
+			b:    gzipFile(t, data, []byte{'A', 'Z', 0x1, 0x0, 0x1}),
+			gzip: true,
+		},
+		"dictzip": {
+			b: mustCompress(t, data),
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(tc.b), WithGzipFallback())
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			if got, want := z.PlainGzip(), tc.gzip; got != want {
+				t.Errorf("PlainGzip: got %v, want %v", got, want)
+			}
+
+			size, err := z.Size()
+			if err != nil {
+				t.Fatalf("Size: %v", err)
+			}
+			if got, want := size, int64(len(data)); got != want {
+				t.Errorf("Size: got %d, want %d", got, want)
+			}
+
+			got, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(data, got); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+
+			// Reads before the previous read and past the end of the data.
+			for _, off := range []int64{50000, 100, 100, 1000, int64(len(data)) - 10} {
+				buf := make([]byte, 20)
+				n, err := z.ReadAt(buf, off)
+				want := data[off:]
+				if len(want) > len(buf) {
+					want = want[:len(buf)]
+				}
+				var wantErr error
+				if len(want) < len(buf) {
+					wantErr = io.EOF
+				}
+				if diff := cmp.Diff(wantErr, err, cmpopts.EquateErrors()); diff != "" {
+					t.Errorf("ReadAt(%d) error (-want, +got):\n%s", off, diff)
+				}
+				if diff := cmp.Diff(want, buf[:n]); diff != "" {
+					t.Errorf("ReadAt(%d) (-want, +got):\n%s", off, diff)
+				}
+			}
+
+			results, err := z.ReadAtMulti([]RangeRequest{
+				{Offset: 1000, Size: 10},
+				{Offset: 10, Size: 10},
+			})
+			if err != nil {
+				t.Fatalf("ReadAtMulti: %v", err)
+			}
+			if diff := cmp.Diff([][]byte{data[1000:1010], data[10:20]}, results); diff != "" {
+				t.Errorf("ReadAtMulti (-want, +got):\n%s", diff)
+			}
+
+			sum, err := Checksum(z, sha256.New, 4)
+			if err != nil {
+				t.Fatalf("Checksum: %v", err)
+			}
+			want := sha256.Sum256(data)
+			if diff := cmp.Diff(want[:], sum); diff != "" {
+				t.Errorf("Checksum (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithGzipFallback_errors(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("Hello, gzip fallback!\n"), 5000)
+	b := gzipFile(t, data, nil)
+
+	t.Run("no fallback", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewReader(bytes.NewReader(b))
+		if diff := cmp.Diff(ErrHeader, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("NewReader (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		t.Parallel()
+
+		z, err := NewReader(bytes.NewReader(b[:len(b)/2]), WithGzipFallback())
+		if err != nil {
+			t.Fatalf("NewReader: %v", err)
+		}
+		_, err = io.ReadAll(z)
+		if err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("ReadAll: got %v, want inflate error", err)
+		}
+	})
+
+	t.Run("alignment report", func(t *testing.T) {
+		t.Parallel()
+
+		z, err := NewReader(bytes.NewReader(b), WithGzipFallback())
+		if err != nil {
+			t.Fatalf("NewReader: %v", err)
+		}
+		_, err = z.AlignmentReport(nil, 2)
+		if diff := cmp.Diff(ErrPlainGzip, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("AlignmentReport (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("chunk sections", func(t *testing.T) {
+		t.Parallel()
+
+		z, err := NewReader(bytes.NewReader(b), WithGzipFallback())
+		if err != nil {
+			t.Fatalf("NewReader: %v", err)
+		}
+		_, err = z.ChunkSections()
+		if diff := cmp.Diff(ErrPlainGzip, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("ChunkSections (-want, +got):\n%s", diff)
+		}
+	})
+}
+
+func TestWithGzipFallback_exactSize(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("Hello, gzip fallback!\n"), 5000)
+	b := gzipFile(t, data, nil)
+
+	z, err := NewReader(bytes.NewReader(b), WithGzipFallback(), WithExactSize())
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	size, err := z.Size()
+	if err != nil {
+		t.Fatalf("Size: %v", err)
+	}
+	if got, want := size, int64(len(data)); got != want {
+		t.Errorf("Size: got %d, want %d", got, want)
+	}
+}
+
+func TestNewStreamReader_gzipFallback(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("Hello, gzip fallback!\n"), 5000)
+	b := gzipFile(t, data, nil)
+
+	s, err := NewStreamReader(bytes.NewBuffer(b), WithGzipFallback())
+	if err != nil {
+		t.Fatalf("NewStreamReader: %v", err)
+	}
+	got, err := io.ReadAll(s)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if diff := cmp.Diff(data, got); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+// HeaderLen returns the length of the header written by [Writer.Close],
+// which is also the offset of the first compressed chunk in the file. It
+// returns zero if Close has not written the header.
+//
+// The header does not include the FHCRC header CRC-16 so fields such as
+// MTIME (bytes 4 to 7) can be patched in place.
+func (z *Writer) HeaderLen() int64 {
+	return int64(z.headerLen)
+}
+
+// ChunkTableRange returns the offset and length in bytes of the chunk table,
+// the compressed size of each chunk stored in the RA subfield, in the file
+// written by [Writer.Close]. The offset depends on the subfields written
+// before the RA subfield, such as with [Header.Subfields]. It returns zero for both if Close has not
+// written the header. Entries reserved by [WithReservedChunkEntries]
+// immediately follow the chunk table and are not included.
+func (z *Writer) ChunkTableRange() (int64, int64) {
+	if z.headerLen == 0 {
+		return 0, 0
+	}
+	return int64(z.chunkTableOff), int64(z.chunkEntrySize * len(z.sizes))
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+func TestWriter_HeaderLen(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		data      []byte
+		opts      []WriterOption
+		extra     []byte
+		subfields [][2]byte
+	}{
+		"empty": {},
+		"data": {
+			data: bytes.Repeat([]byte("Hello, header!\n"), 500),
+		},
+		"chunk checksums": {
+			data: bytes.Repeat([]byte("Hello, header!\n"), 500),
+			opts: []WriterOption{WithChunkChecksums(), WithManifest(Manifest{ManifestSource: "test"})},
+		},
+		"subfield before RA": {
+			data:      bytes.Repeat([]byte("Hello, header!\n"), 500),
+			extra:     []byte{'X', 'Y', 3, 0, 'a', 'b', 'c'},
+			subfields: [][2]byte{{'X', 'Y'}, {format.RASI1, format.RASI2}},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			z, err := NewWriterLevel(&buf, DefaultCompression, 1000, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			z.Name = "test.txt"
+			z.Extra = tc.extra
+			z.Subfields = tc.subfields
+			if _, err := z.Write(tc.data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			if got := z.HeaderLen(); got != 0 {
+				t.Errorf("HeaderLen before Close: got %d, want 0", got)
+			}
+			if off, n := z.ChunkTableRange(); off != 0 || n != 0 {
+				t.Errorf("ChunkTableRange before Close: got %d, %d, want 0, 0", off, n)
+			}
+
+			if err := z.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			b := buf.Bytes()
+			h, hdrLen, err := format.Parse(b)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got, want := z.HeaderLen(), int64(hdrLen); got != want {
+				t.Errorf("HeaderLen: got %d, want %d", got, want)
+			}
+
+			off, n := z.ChunkTableRange()
+			if got, want := n, int64(2*len(h.Sizes)); got != want {
+				t.Errorf("ChunkTableRange length: got %d, want %d", got, want)
+			}
+			var sizes []int
+			for i := off; i < off+n; i += 2 {
+				sizes = append(sizes, int(binary.LittleEndian.Uint16(b[i:])))
+			}
+			if diff := cmp.Diff(h.Sizes, sizes); diff != "" {
+				t.Errorf("chunk table (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// WithStrictHeader configures the [Reader] to reject header anomalies that
+// are otherwise accepted with an error wrapping [ErrHeader]. The anomalies
+// are reserved FLG bits being set, an unsupported RA subfield version, and a
+// zero chunk size (CHLEN). By default, reserved FLG bits are accepted and
+// recorded in [Header.Warnings], and a zero chunk size is only accepted if
+// the archive has no chunks.
+func WithStrictHeader() ReaderOption {
+	return func(z *Reader) {
+		z.strictHeader = true
+		z.lenientHeader = false
+	}
+}
+
+// WithLenientHeader configures the [Reader] to accept header anomalies that
+// are rejected by default and to record them in [Header.Warnings]. This
+// allows slightly malformed files produced by old versions of dictzip to be
+// read. An RA subfield with an unsupported version is read as version 1. If
+// the chunk size (CHLEN) is zero, the chunk size is the length of the
+// uncompressed data of the first chunk, which is inflated by [NewReader].
+func WithLenientHeader() ReaderOption {
+	return func(z *Reader) {
+		z.lenientHeader = true
+		z.strictHeader = false
+	}
+}
+
+// Warnings returns descriptions of the anomalies found in the header that
+// were accepted when reading, or nil if there were none.
+// See [WithLenientHeader].
+func (h *Header) Warnings() []string {
+	return h.warnings
+}
+
+// inferChunkSize sets the chunk size to the length of the uncompressed data
+// of the first chunk. It is used if CHLEN is zero.
+func (z *Reader) inferChunkSize() error {
+	if _, err := z.r.Seek(z.offsets.at(0), io.SeekStart); err != nil {
+		return fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+	if err := z.z.Reset(io.LimitReader(z.r, z.offsets.at(1)-z.offsets.at(0)), nil); err != nil {
+		return fmt.Errorf("%w: Reset: %w", errDictzip, err)
+	}
+
+	// NOTE: Chunks other than the last end with a sync flush so all of the
+	// chunk's data is returned before the end of its compressed data.
+	n, err := io.Copy(io.Discard, z.z)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: inferring chunk size: inflating chunk 0: %w", ErrHeader, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: inferring chunk size: chunk 0 has %d bytes", ErrHeader, n)
+	}
+	z.chunkSize = int(n)
+	z.warnings = append(z.warnings, fmt.Sprintf("inferred chunk size %d from the first chunk", n))
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReader_headerAnomalies(t *testing.T) {
+	t.Parallel()
+
+	var data []byte
+	for i := 0; len(data) < 5000; i++ {
+		data = fmt.Appendf(data, "line %d\n", i)
+	}
+
+	// corrupt returns the archive modified by f.
+	corrupt := func(f func(b []byte)) []byte {
+		b := bytes.Clone(compressStream(t, data, 1000))
+		f(b)
+		return b
+	}
+	reservedFLG := corrupt(func(b []byte) { b[3] |= 0x20 })
+	// NOTE: The RA subfield is the first subfield so VER is at offset 16 and
+	// CHLEN is at offset 18.
+	version := corrupt(func(b []byte) { b[16] = 3 })
+	zeroCHLEN := corrupt(func(b []byte) { b[18], b[19] = 0, 0 })
+
+	testCases := map[string]struct {
+		archive  []byte
+		opts     []ReaderOption
+		warnings []string
+		err      error
+	}{
+		"reserved FLG": {
+			archive:  reservedFLG,
+			warnings: []string{"reserved FLG bits set: 0x20"},
+		},
+		"strict reserved FLG": {
+			archive: reservedFLG,
+			opts:    []ReaderOption{WithStrictHeader()},
+			err:     ErrHeader,
+		},
+		"unsupported version": {
+			archive: version,
+			err:     ErrHeader,
+		},
+		"lenient unsupported version": {
+			archive:  version,
+			opts:     []ReaderOption{WithLenientHeader()},
+			warnings: []string{"unsupported RA version 3 read as version 1"},
+		},
+		"zero CHLEN": {
+			archive: zeroCHLEN,
+			err:     ErrHeader,
+		},
+		"lenient zero CHLEN": {
+			archive: zeroCHLEN,
+			opts:    []ReaderOption{WithLenientHeader()},
+			warnings: []string{
+				"CHLEN is zero",
+				"inferred chunk size 1000 from the first chunk",
+			},
+		},
+		"lenient then strict": {
+			archive: zeroCHLEN,
+			opts:    []ReaderOption{WithLenientHeader(), WithStrictHeader()},
+			err:     ErrHeader,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(tc.archive), tc.opts...)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("NewReader (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+			defer z.Close()
+
+			if diff := cmp.Diff(tc.warnings, z.Warnings()); diff != "" {
+				t.Errorf("Warnings (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(1000, z.ChunkSize()); diff != "" {
+				t.Errorf("ChunkSize (-want, +got):\n%s", diff)
+			}
+
+			got := make([]byte, 1500)
+			if _, err := z.ReadAt(got, 2500); err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if diff := cmp.Diff(data[2500:4000], got); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+
+			all, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(data, all); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package index implements reading of dictd(8) .index files which map
+// headwords to the location of their definitions in the uncompressed .dict
+// data.
+//
+// Each line of an index file contains a headword, the offset of the
+// definition, and the size of the definition separated by tabs. Offsets and
+// sizes are encoded as base64 numbers using the dictd alphabet.
+package index
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	// errIndex is the base error for all index errors.
+	errIndex = errors.New("index")
+
+	// ErrFormat indicates that the index data is malformed.
+	ErrFormat = fmt.Errorf("%w: invalid format", errIndex)
+)
+
+// b64Alphabet is the dictd base64 alphabet. Unlike standard base64 encoding,
+// numbers are encoded as big-endian base 64 digits without padding.
+const b64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// maxDigits is the maximum number of base64 digits in an offset or size. 10
+// digits hold 60 bits so offsets and sizes must be less than 2^60.
+const maxDigits = 10
+
+// Entry is a single index entry.
+type Entry struct {
+	// Headword is the word being defined.
+	Headword string
+
+	// Offset is the offset of the definition in the uncompressed data.
+	Offset int64
+
+	// Size is the size of the definition in bytes.
+	Size int64
+}
+
+// Index is a parsed dictd index.
+type Index struct {
+	// Entries are the index entries in the order they appear in the file.
+	Entries []Entry
+}
+
+// Read reads and parses index data from r.
+func Read(r io.Reader) (*Index, error) {
+	idx := &Index{}
+
+	s := bufio.NewScanner(r)
+	var lineNum int
+	for s.Scan() {
+		lineNum++
+		line := s.Text()
+		if line == "" {
+			continue
+		}
+
+		// NOTE: Some indexes include additional fields which are ignored.
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%w: line %d: expected 3 fields, got %d", ErrFormat, lineNum, len(fields))
+		}
+
+		offset, err := DecodeOffset(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: offset: %w", lineNum, err)
+		}
+		size, err := DecodeOffset(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: size: %w", lineNum, err)
+		}
+
+		idx.Entries = append(idx.Entries, Entry{
+			Headword: fields[0],
+			Offset:   offset,
+			Size:     size,
+		})
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("%w: reading index: %w", errIndex, err)
+	}
+
+	return idx, nil
+}
+
+// Lookup returns all entries with the given headword. Headwords are compared
+// case-insensitively as is done by dictd(8).
+func (idx *Index) Lookup(headword string) []Entry {
+	var entries []Entry
+	for _, e := range idx.Entries {
+		if strings.EqualFold(e.Headword, headword) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// DecodeOffset decodes an offset or size encoded as a base64 number using the
+// dictd alphabet as found in index files. It returns an error wrapping
+// [ErrFormat] if s is not a valid number.
+func DecodeOffset(s string) (int64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("%w: empty number", ErrFormat)
+	}
+	if len(s) > maxDigits {
+		return 0, fmt.Errorf("%w: number too large: %q", ErrFormat, s)
+	}
+
+	var n int64
+	for _, c := range s {
+		d := strings.IndexRune(b64Alphabet, c)
+		if d < 0 {
+			return 0, fmt.Errorf("%w: invalid character %q in %q", ErrFormat, c, s)
+		}
+		n = n*64 + int64(d)
+	}
+	return n, nil
+}
+
+// EncodeOffset encodes the offset or size n as a base64 number using the
+// dictd alphabet as written to index files. Zero is encoded as "A". It returns
+// an error wrapping [ErrFormat] if n is negative or is not less than 2^60, so
+// that every encoded number can be decoded by [DecodeOffset].
+func EncodeOffset(n int64) (string, error) {
+	if n < 0 || n >= 1<<(6*maxDigits) {
+		return "", fmt.Errorf("%w: number out of range: %d", ErrFormat, n)
+	}
+
+	var buf [maxDigits]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = b64Alphabet[n%64]
+		n /= 64
+		if n == 0 {
+			break
+		}
+	}
+	return string(buf[i:]), nil
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		data    string
+		entries []Entry
+		err     error
+	}{
+		"empty": {
+			data: "",
+		},
+		"entries": {
+			data: "00databasealphabet\tA\tc\n" +
+				"apple\tBa\tBM\n" +
+				"Apple\tCAA\t/\n",
+			entries: []Entry{
+				{Headword: "00databasealphabet", Offset: 0, Size: 28},
+				{Headword: "apple", Offset: 90, Size: 76},
+				{Headword: "Apple", Offset: 8192, Size: 63},
+			},
+		},
+		"extra fields": {
+			data: "apple\tBa\tBM\tAPPLE\n",
+			entries: []Entry{
+				{Headword: "apple", Offset: 90, Size: 76},
+			},
+		},
+		"blank lines": {
+			data: "\napple\tBa\tBM\n\n",
+			entries: []Entry{
+				{Headword: "apple", Offset: 90, Size: 76},
+			},
+		},
+		"missing fields": {
+			data: "apple\tBa\n",
+			err:  ErrFormat,
+		},
+		"invalid offset": {
+			data: "apple\tB-\tBM\n",
+			err:  ErrFormat,
+		},
+		"empty size": {
+			data: "apple\tBa\t\n",
+			err:  ErrFormat,
+		},
+		"number too large": {
+			data: "apple\tBBBBBBBBBBB\tBM\n",
+			err:  ErrFormat,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			idx, err := Read(strings.NewReader(tc.data))
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("Read (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.entries, idx.Entries); diff != "" {
+				t.Errorf("Entries (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIndex_Lookup(t *testing.T) {
+	t.Parallel()
+
+	idx := &Index{
+		Entries: []Entry{
+			{Headword: "apple", Offset: 90, Size: 76},
+			{Headword: "banana", Offset: 166, Size: 10},
+			{Headword: "Apple", Offset: 8192, Size: 63},
+		},
+	}
+
+	want := []Entry{
+		{Headword: "apple", Offset: 90, Size: 76},
+		{Headword: "Apple", Offset: 8192, Size: 63},
+	}
+	if diff := cmp.Diff(want, idx.Lookup("APPLE")); diff != "" {
+		t.Errorf("Lookup (-want, +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]Entry(nil), idx.Lookup("cherry")); diff != "" {
+		t.Errorf("Lookup (-want, +got):\n%s", diff)
+	}
+}
+
+func TestEncodeOffset(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		n    int64
+		want string
+		err  error
+	}{
+		"zero": {
+			n:    0,
+			want: "A",
+		},
+		"one": {
+			n:    1,
+			want: "B",
+		},
+		"one digit": {
+			n:    63,
+			want: "/",
+		},
+		"two digits": {
+			n:    64,
+			want: "BA",
+		},
+		"two digits mixed": {
+			n:    90,
+			want: "Ba",
+		},
+		"three digits": {
+			n:    8192,
+			want: "CAA",
+		},
+		"max": {
+			n:    1<<60 - 1,
+			want: "//////////",
+		},
+		"too large": {
+			n:   1 << 60,
+			err: ErrFormat,
+		},
+		"max int64": {
+			n:   math.MaxInt64,
+			err: ErrFormat,
+		},
+		"negative": {
+			n:   -1,
+			err: ErrFormat,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := EncodeOffset(tc.n)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("EncodeOffset(%d) (-want, +got):\n%s", tc.n, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("EncodeOffset(%d) (-want, +got):\n%s", tc.n, diff)
+			}
+			if err != nil {
+				return
+			}
+
+			// Encoded numbers can be decoded.
+			n, err := DecodeOffset(got)
+			if err != nil {
+				t.Fatalf("DecodeOffset(%q): %v", got, err)
+			}
+			if diff := cmp.Diff(tc.n, n); diff != "" {
+				t.Errorf("DecodeOffset(%q) (-want, +got):\n%s", got, diff)
+			}
+		})
+	}
+}
+
+func TestDecodeOffset(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		s   string
+		n   int64
+		err error
+	}{
+		"zero": {
+			s: "A",
+		},
+		"two digits": {
+			s: "Ba",
+			n: 90,
+		},
+		"max": {
+			s: "//////////",
+			n: 1<<60 - 1,
+		},
+		"empty": {
+			err: ErrFormat,
+		},
+		"too large": {
+			s:   "BAAAAAAAAAA",
+			err: ErrFormat,
+		},
+		"invalid character": {
+			s:   "A=",
+			err: ErrFormat,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			n, err := DecodeOffset(tc.s)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("DecodeOffset (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.n, n); diff != "" {
+				t.Errorf("DecodeOffset (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
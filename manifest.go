@@ -0,0 +1,225 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// ErrManifest indicates that a [Manifest] is invalid.
+var ErrManifest = fmt.Errorf("%w: invalid manifest", errDictzip)
+
+// manifestVersion is the version of the manifest subfield encoding.
+const manifestVersion = 1
+
+// Well-known [Manifest] keys.
+const (
+	// ManifestContentHash is the hash of the uncompressed data in the form
+	// "algorithm:hex", such as "sha256:...". See [WithContentHash].
+	ManifestContentHash = "content-hash"
+
+	// ManifestSource is the URI of the source of the data.
+	ManifestSource = "source"
+
+	// ManifestBuild describes the build that created the archive, such as a
+	// tool version or build ID.
+	ManifestBuild = "build"
+)
+
+// Manifest is a small set of key/value metadata stored in an EXTRA subfield
+// so that provenance information travels inside the archive. Keys must be
+// non-empty and at most 255 bytes, and keys and values must be valid UTF-8.
+// The encoded manifest must fit in a single EXTRA subfield of at most 65535
+// bytes.
+type Manifest map[string]string
+
+// ContentHash returns the [ManifestContentHash] value.
+func (m Manifest) ContentHash() string {
+	return m[ManifestContentHash]
+}
+
+// Source returns the [ManifestSource] value.
+func (m Manifest) Source() string {
+	return m[ManifestSource]
+}
+
+// Build returns the [ManifestBuild] value.
+func (m Manifest) Build() string {
+	return m[ManifestBuild]
+}
+
+// WithManifest configures the [Writer] to write the manifest m in an EXTRA
+// subfield. [NewWriter] and [NewWriterLevel] return an error wrapping
+// [ErrManifest] if m is invalid. A manifest subfield in [Header.Extra] is
+// replaced.
+func WithManifest(m Manifest) WriterOption {
+	return func(z *Writer) {
+		if z.manifest == nil {
+			z.manifest = Manifest{}
+		}
+		for k, v := range m {
+			z.manifest[k] = v
+		}
+	}
+}
+
+// WithContentHash configures the [Writer] to store the SHA-256 hash of the
+// uncompressed data in the manifest under [ManifestContentHash]. The hash can
+// be checked using [Checksum] with [crypto/sha256.New].
+func WithContentHash() WriterOption {
+	return func(z *Writer) {
+		z.contentHash = sha256.New()
+	}
+}
+
+// Manifest returns the manifest stored in the EXTRA subfields. It returns nil
+// if there is no manifest and an error wrapping [ErrManifest] if the
+// manifest is malformed.
+func (h *Header) Manifest() (Manifest, error) {
+	fields, err := h.ExtraFields()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if f.ID == [2]byte{format.ManifestSI1, format.ManifestSI2} {
+			return parseManifest(f.Data)
+		}
+	}
+	return nil, nil
+}
+
+// appendManifest appends the encoding of m to b. Entries are sorted by key
+// so that the encoding is deterministic.
+func appendManifest(b []byte, m Manifest) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b = append(b, manifestVersion)
+	for _, k := range keys {
+		v := m[k]
+		//nolint:gosec // the key length is checked by validateManifest.
+		b = append(b, byte(len(k)))
+		b = append(b, k...)
+		//nolint:gosec // the value length is checked by validateManifest.
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(v)))
+		b = append(b, v...)
+	}
+	return b
+}
+
+// parseManifest decodes a manifest subfield.
+func parseManifest(data []byte) (Manifest, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("%w: empty subfield", ErrManifest)
+	}
+	if data[0] != manifestVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrManifest, data[0])
+	}
+	data = data[1:]
+
+	m := Manifest{}
+	for len(data) > 0 {
+		keyLen := int(data[0])
+		if len(data) < 1+keyLen+2 {
+			return nil, fmt.Errorf("%w: truncated entry", ErrManifest)
+		}
+		key := string(data[1 : 1+keyLen])
+		valueLen := int(binary.LittleEndian.Uint16(data[1+keyLen:]))
+		data = data[1+keyLen+2:]
+		if len(data) < valueLen {
+			return nil, fmt.Errorf("%w: %q: truncated value", ErrManifest, key)
+		}
+		if _, ok := m[key]; ok {
+			return nil, fmt.Errorf("%w: %q: duplicate key", ErrManifest, key)
+		}
+		m[key] = string(data[:valueLen])
+		data = data[valueLen:]
+	}
+	if err := validateManifest(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// validateManifest checks that m can be encoded.
+func validateManifest(m Manifest) error {
+	size := 1
+	for k, v := range m {
+		if k == "" || len(k) > math.MaxUint8 {
+			return fmt.Errorf("%w: %q: key must be 1 to %d bytes", ErrManifest, k, math.MaxUint8)
+		}
+		if !utf8.ValidString(k) || !utf8.ValidString(v) {
+			return fmt.Errorf("%w: %q: not valid UTF-8", ErrManifest, k)
+		}
+		size += 1 + len(k) + 2 + len(v)
+	}
+	if size > math.MaxUint16 {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrManifest, size, math.MaxUint16)
+	}
+	return nil
+}
+
+// manifestExtra returns the EXTRA subfields to write with the manifest
+// subfield appended. A manifest subfield already in extra is removed. extra
+// is returned unchanged if no manifest is written.
+func (z *Writer) manifestExtra(extra []byte) ([]byte, error) {
+	if z.manifest == nil && z.contentHash == nil {
+		return extra, nil
+	}
+
+	m := Manifest{}
+	for k, v := range z.manifest {
+		m[k] = v
+	}
+	if z.contentHash != nil {
+		m[ManifestContentHash] = "sha256:" + hex.EncodeToString(z.contentHash.Sum(nil))
+	}
+	if err := validateManifest(m); err != nil {
+		return nil, err
+	}
+
+	extra = removeSubfield(extra, format.ManifestSI1, format.ManifestSI2)
+	return appendSubfield(extra, format.ManifestSI1, format.ManifestSI2, string(appendManifest(nil, m))), nil
+}
+
+// removeSubfield returns the EXTRA subfields in extra without the subfields
+// with the given ID.
+func removeSubfield(extra []byte, si1, si2 byte) []byte {
+	var rest []byte
+	for len(extra) >= 4 {
+		subLen := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if subLen > len(extra)-4 {
+			break
+		}
+		sub := extra[:4+subLen]
+		extra = extra[4+subLen:]
+		if sub[0] != si1 || sub[1] != si2 {
+			rest = append(rest, sub...)
+		}
+	}
+	// Keep any trailing data as-is.
+	return append(rest, extra...)
+}
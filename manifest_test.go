@@ -0,0 +1,267 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+func TestWithManifest(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("Hello, manifest!\n"), 500)
+	sum := sha256.Sum256(data)
+	wantHash := "sha256:" + hex.EncodeToString(sum[:])
+
+	for _, compressFrom := range []bool{false, true} {
+		compressFrom := compressFrom
+		name := "write"
+		if compressFrom {
+			name = "compress from"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriterLevel(&buf, DefaultCompression, 1000,
+				WithManifest(Manifest{
+					ManifestSource: "https://example.com/dict.txt",
+					ManifestBuild:  "v1.2.3",
+				}),
+				WithContentHash(),
+				WithExtraFields(ExtraField{ID: [2]byte{'X', 'Y'}, Data: []byte("other")}),
+			)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if compressFrom {
+				_, err = w.CompressFrom(bytes.NewReader(data), int64(len(data)), 2)
+			} else {
+				_, err = w.Write(data)
+			}
+			if err != nil {
+				t.Fatalf("writing: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			z, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			m, err := z.Manifest()
+			if err != nil {
+				t.Fatalf("Manifest: %v", err)
+			}
+			want := Manifest{
+				ManifestSource:      "https://example.com/dict.txt",
+				ManifestBuild:       "v1.2.3",
+				ManifestContentHash: wantHash,
+			}
+			if diff := cmp.Diff(want, m); diff != "" {
+				t.Errorf("Manifest (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("v1.2.3", m.Build()); diff != "" {
+				t.Errorf("Build (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("https://example.com/dict.txt", m.Source()); diff != "" {
+				t.Errorf("Source (-want, +got):\n%s", diff)
+			}
+
+			// The content hash matches the data read.
+			got, err := Checksum(z, sha256.New, 0)
+			if err != nil {
+				t.Fatalf("Checksum: %v", err)
+			}
+			if diff := cmp.Diff(m.ContentHash(), "sha256:"+hex.EncodeToString(got)); diff != "" {
+				t.Errorf("ContentHash (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithManifest_replace(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, WithManifest(Manifest{ManifestBuild: "old"}))
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	z, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	// Recompress keeping the EXTRA subfields and replacing the manifest.
+	var out bytes.Buffer
+	w, err = NewWriter(&out, WithManifest(Manifest{ManifestBuild: "new"}))
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	w.Extra = z.Extra
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	z2, err := NewReader(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z2.Close()
+
+	fields, err := z2.ExtraFields()
+	if err != nil {
+		t.Fatalf("ExtraFields: %v", err)
+	}
+	if diff := cmp.Diff(1, len(fields)); diff != "" {
+		t.Errorf("ExtraFields count (-want, +got):\n%s", diff)
+	}
+	m, err := z2.Manifest()
+	if err != nil {
+		t.Fatalf("Manifest: %v", err)
+	}
+	if diff := cmp.Diff(Manifest{ManifestBuild: "new"}, m); diff != "" {
+		t.Errorf("Manifest (-want, +got):\n%s", diff)
+	}
+}
+
+func TestHeader_Manifest(t *testing.T) {
+	t.Parallel()
+
+	subfield := func(data ...byte) []byte {
+		return appendSubfield(nil, format.ManifestSI1, format.ManifestSI2, string(data))
+	}
+
+	testCases := map[string]struct {
+		extra []byte
+		want  Manifest
+		err   error
+	}{
+		"none": {
+			extra: appendSubfield(nil, 'X', 'Y', "data"),
+		},
+		"empty": {
+			extra: subfield(manifestVersion),
+			want:  Manifest{},
+		},
+		"entry": {
+			extra: subfield(manifestVersion, 1, 'k', 1, 0, 'v'),
+			want:  Manifest{"k": "v"},
+		},
+		"no version": {
+			extra: subfield(),
+			err:   ErrManifest,
+		},
+		"unsupported version": {
+			extra: subfield(2),
+			err:   ErrManifest,
+		},
+		"truncated key": {
+			extra: subfield(manifestVersion, 5, 'k'),
+			err:   ErrManifest,
+		},
+		"truncated value": {
+			extra: subfield(manifestVersion, 1, 'k', 5, 0, 'v'),
+			err:   ErrManifest,
+		},
+		"duplicate key": {
+			extra: subfield(manifestVersion, 1, 'k', 0, 0, 1, 'k', 0, 0),
+			err:   ErrManifest,
+		},
+		"empty key": {
+			extra: subfield(manifestVersion, 0, 0, 0),
+			err:   ErrManifest,
+		},
+		"malformed extra": {
+			extra: []byte{'D', 'M', 10, 0},
+			err:   ErrSubfieldLength,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := Header{Extra: tc.extra}
+			got, err := h.Manifest()
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Manifest error (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Manifest (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithManifest_invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		opts []WriterOption
+		err  error
+	}{
+		"empty key": {
+			opts: []WriterOption{WithManifest(Manifest{"": "v"})},
+			err:  ErrManifest,
+		},
+		"long key": {
+			opts: []WriterOption{WithManifest(Manifest{strings.Repeat("k", 256): "v"})},
+			err:  ErrManifest,
+		},
+		"invalid UTF-8": {
+			opts: []WriterOption{WithManifest(Manifest{"k": "\xff"})},
+			err:  ErrManifest,
+		},
+		"too large": {
+			opts: []WriterOption{WithManifest(Manifest{"k": strings.Repeat("v", 1<<16)})},
+			err:  ErrManifest,
+		},
+		"reserved subfield": {
+			opts: []WriterOption{WithExtraFields(ExtraField{ID: [2]byte{format.ManifestSI1, format.ManifestSI2}})},
+			err:  ErrExtraField,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			_, err := NewWriter(&buf, tc.opts...)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("NewWriter (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"fmt"
+	"io"
+)
+
+// ErrMemoryLimit indicates that the archive can't be read within the memory
+// limit given to [WithMemoryLimit].
+var ErrMemoryLimit = fmt.Errorf("%w: memory limit exceeded", errDictzip)
+
+// minCacheSize is the minimum size of the read cache when memory use is
+// limited. Smaller caches are unlikely to be useful so they are disabled.
+const minCacheSize = 4096
+
+// WithMemoryLimit configures the [Reader] to limit the memory used by its
+// buffers to approximately n bytes for use on devices with little memory.
+// The limit applies to the buffers used to read the header, to read data,
+// to cache reads and chunks (see [WithReadCache] and [WithChunkCache]), and
+// to prefetch chunks (see [WithPrefetch]). It does not include the inflate state or the chunk sizes
+// read from the header.
+//
+// Rather than failing, the Reader degrades as the limit is reduced. Reads
+// larger than half of the limit are split into smaller reads, [Reader.Read]
+// returns at most that many bytes, each cache is limited to a quarter of the
+// limit, and prefetching is limited to the chunks fitting in a quarter of
+// the limit. The caches are disabled if that is less than 4096 bytes and
+// prefetching is disabled if fewer than two chunks fit.
+// [NewReader] returns an error wrapping [ErrMemoryLimit] only if the header
+// is larger than n.
+//
+// [Reader.ReadAtMulti] and [Reader.ReadAtVerified] inflate whole chunks and
+// are not limited.
+func WithMemoryLimit(n int) ReaderOption {
+	return func(z *Reader) {
+		z.memoryLimit = n
+	}
+}
+
+// applyMemoryLimit limits the buffers configured by other options to fit
+// within the memory limit. It is called after all options have been applied.
+func (z *Reader) applyMemoryLimit() {
+	if z.memoryLimit <= 0 {
+		return
+	}
+
+	z.readLimit = z.memoryLimit / 2
+	if z.readLimit < 1 {
+		z.readLimit = 1
+	}
+
+	if z.readCache != nil {
+		if cacheLimit := z.memoryLimit / 4; z.readCache.maxBytes > cacheLimit {
+			z.readCache.maxBytes = cacheLimit
+		}
+		if z.readCache.maxBytes < minCacheSize {
+			z.readCache = nil
+		}
+	}
+	if z.chunkCache != nil {
+		if cacheLimit := z.memoryLimit / 4; z.chunkCache.maxBytes > cacheLimit {
+			z.chunkCache.maxBytes = cacheLimit
+		}
+		if z.chunkCache.maxBytes < minCacheSize {
+			z.chunkCache = nil
+		}
+	}
+
+	// NOTE: Compressed chunks are at most MaxChunkSize bytes unless the
+	// archive has a version 2 RA subfield, which is not known until the
+	// header is read.
+	if maxChunks := z.memoryLimit / 4 / MaxChunkSize; z.prefetch > maxChunks {
+		z.prefetch = maxChunks
+	}
+}
+
+// readInto reads len(p) bytes of uncompressed data at offset off into p. If
+// the memory limit is set the data is read in pieces no larger than the read
+// limit.
+func (z *Reader) readInto(p []byte, off int64) (int, error) {
+	if z.plainGzip {
+		return z.readGzip(p, off)
+	}
+	if z.members != nil {
+		return z.readMembers(p, off, (*Reader).readInto)
+	}
+	if z.chunkCache != nil && z.scratch == nil {
+		return z.readCached(p, off)
+	}
+	if z.parallel(off, len(p)) {
+		return z.readParallel(p, off)
+	}
+	if z.readLimit <= 0 || len(p) <= z.readLimit {
+		buf, err := z.readChunk(off, len(p))
+		return copy(p, buf), err
+	}
+
+	var n int
+	for n < len(p) {
+		size := len(p) - n
+		if size > z.readLimit {
+			size = z.readLimit
+		}
+		buf, err := z.readChunk(off+int64(n), size)
+		n += copy(p[n:], buf)
+		if err != nil {
+			return n, err
+		}
+		if len(buf) == 0 {
+			return n, io.EOF
+		}
+	}
+	return n, nil
+}
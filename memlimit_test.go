@@ -0,0 +1,153 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestWithMemoryLimit(t *testing.T) {
+	t.Parallel()
+
+	var data []byte
+	for i := 0; len(data) < 4500; i++ {
+		data = append(data, fmt.Sprintf("line %04d\n", i)...)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 1000)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()), WithMemoryLimit(600))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	// Large reads are split into smaller reads.
+	p := make([]byte, 3000)
+	n, err := z.ReadAt(p, 950)
+	if err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if diff := cmp.Diff(data[950:3950], p[:n]); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+
+	// Reads past the end return the remaining data.
+	n, err = z.ReadAt(p, 4000)
+	if diff := cmp.Diff(io.EOF, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(data[4000:], p[:n]); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+
+	// Read returns at most half of the limit.
+	n, err = z.Read(p)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if diff := cmp.Diff(300, n); diff != "" {
+		t.Errorf("Read (-want, +got):\n%s", diff)
+	}
+
+	rest, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if diff := cmp.Diff(data, append(p[:n:n], rest...)); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWithMemoryLimit_options(t *testing.T) {
+	t.Parallel()
+
+	data := mustCompress(t, []byte("Hello, World!"))
+
+	testCases := map[string]struct {
+		opts []ReaderOption
+
+		wantCache    bool
+		wantPrefetch int
+		err          error
+	}{
+		"cache disabled": {
+			opts: []ReaderOption{WithReadCache(1 << 20), WithMemoryLimit(1 << 12)},
+		},
+		"cache": {
+			opts:      []ReaderOption{WithReadCache(1 << 20), WithMemoryLimit(1 << 16)},
+			wantCache: true,
+		},
+		"prefetch disabled": {
+			opts: []ReaderOption{WithPrefetch(8), WithMemoryLimit(1 << 16)},
+		},
+		"prefetch limited": {
+			opts:         []ReaderOption{WithPrefetch(8), WithMemoryLimit(1 << 20)},
+			wantPrefetch: 4,
+		},
+		"header": {
+			opts: []ReaderOption{WithMemoryLimit(20)},
+			err:  ErrMemoryLimit,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(data), tc.opts...)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("NewReader (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+			defer z.Close()
+
+			if diff := cmp.Diff(tc.wantCache, z.readCache != nil); diff != "" {
+				t.Errorf("cache (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantPrefetch, z.prefetch); diff != "" {
+				t.Errorf("prefetch (-want, +got):\n%s", diff)
+			}
+
+			b := make([]byte, 5)
+			n, err := z.ReadAt(b, 7)
+			if err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if diff := cmp.Diff("World", string(b[:n])); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
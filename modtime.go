@@ -0,0 +1,81 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	// ErrModTime indicates that a modification time can not be stored in the
+	// 32-bit MTIME header field.
+	ErrModTime = fmt.Errorf("%w: modification time out of range", errDictzip)
+)
+
+// MaxModTime is the latest modification time that can be stored in the MTIME
+// header field, 2106-02-07 06:28:15 UTC.
+var MaxModTime = time.Unix(math.MaxUint32, 0).UTC()
+
+// WithClampModTime configures the [Writer] to clamp modification times that
+// can not be stored in the MTIME header field rather than returning an error
+// wrapping [ErrModTime]. Times before 1970 are stored as zero, meaning that
+// the modification time is not set, and times after [MaxModTime] are stored
+// as MaxModTime.
+func WithClampModTime() WriterOption {
+	return func(z *Writer) {
+		z.clampModTime = true
+	}
+}
+
+// SetModTime sets Header.ModTime to t, normalized to UTC and truncated to
+// seconds as it is stored in the MTIME header field. The zero value clears the
+// modification time.
+//
+// An error wrapping [ErrModTime] is returned if t is before 1970 or after
+// [MaxModTime] unless [WithClampModTime] was given. The same check is done
+// for a ModTime set directly when the header is written.
+func (z *Writer) SetModTime(t time.Time) error {
+	modTime, err := z.normalizeModTime(t)
+	if err != nil {
+		return err
+	}
+	z.ModTime = modTime
+	return nil
+}
+
+// normalizeModTime returns t as it is stored in the MTIME header field.
+func (z *Writer) normalizeModTime(t time.Time) (time.Time, error) {
+	if t.IsZero() {
+		return time.Time{}, nil
+	}
+
+	t = t.UTC().Truncate(time.Second)
+	switch {
+	case t.Before(time.Unix(0, 0)):
+		if !z.clampModTime {
+			return time.Time{}, fmt.Errorf("%w: %s is before 1970", ErrModTime, t.Format(time.RFC3339))
+		}
+		return time.Time{}, nil
+	case t.After(MaxModTime):
+		if !z.clampModTime {
+			return time.Time{}, fmt.Errorf("%w: %s is after %s", ErrModTime,
+				t.Format(time.RFC3339), MaxModTime.Format(time.RFC3339))
+		}
+		return MaxModTime, nil
+	}
+	return t, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestWriter_SetModTime(t *testing.T) {
+	t.Parallel()
+
+	jst := time.FixedZone("JST", 9*60*60)
+
+	testCases := map[string]struct {
+		modTime time.Time
+		clamp   bool
+		want    time.Time
+		err     error
+	}{
+		"zero": {
+			modTime: time.Time{},
+			want:    time.Time{},
+		},
+		"utc": {
+			modTime: time.Date(2024, 11, 17, 12, 30, 0, 0, time.UTC),
+			want:    time.Date(2024, 11, 17, 12, 30, 0, 0, time.UTC),
+		},
+		"zone": {
+			modTime: time.Date(2024, 11, 17, 21, 30, 0, 0, jst),
+			want:    time.Date(2024, 11, 17, 12, 30, 0, 0, time.UTC),
+		},
+		"nanoseconds": {
+			modTime: time.Date(2024, 11, 17, 12, 30, 0, 999, time.UTC),
+			want:    time.Date(2024, 11, 17, 12, 30, 0, 0, time.UTC),
+		},
+		"max": {
+			modTime: MaxModTime,
+			want:    MaxModTime,
+		},
+		"before epoch": {
+			modTime: time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC),
+			err:     ErrModTime,
+		},
+		"after max": {
+			modTime: MaxModTime.Add(time.Second),
+			err:     ErrModTime,
+		},
+		"clamp before epoch": {
+			modTime: time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC),
+			clamp:   true,
+			want:    time.Time{},
+		},
+		"clamp after max": {
+			modTime: MaxModTime.Add(time.Hour),
+			clamp:   true,
+			want:    MaxModTime,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []WriterOption
+			if tc.clamp {
+				opts = append(opts, WithClampModTime())
+			}
+			var buf bytes.Buffer
+			z, err := NewWriter(&buf, opts...)
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			defer z.Close()
+
+			err = z.SetModTime(tc.modTime)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("SetModTime (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, z.ModTime); diff != "" {
+				t.Errorf("ModTime (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriter_ModTimeRange(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		clamp bool
+		want  time.Time
+		err   error
+	}{
+		"error": {
+			err: ErrModTime,
+		},
+		"clamp": {
+			clamp: true,
+			want:  MaxModTime,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []WriterOption
+			if tc.clamp {
+				opts = append(opts, WithClampModTime())
+			}
+			var buf bytes.Buffer
+			z, err := NewWriter(&buf, opts...)
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			// NOTE: ModTime is set directly and checked when the header is
+			// written rather than silently overflowing.
+			z.ModTime = MaxModTime.AddDate(1, 0, 0)
+			if _, err := z.Write([]byte("Hello, MTIME!")); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			err = z.Close()
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("Close (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+
+			r, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer r.Close()
+			if diff := cmp.Diff(tc.want, r.ModTime.UTC()); diff != "" {
+				t.Errorf("ModTime (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// MultiFormatWriter is a [Writer] that writes a standard gzip file along with
+// the dictzip file. Each chunk is compressed once and the compressed data is
+// written to both files, so the gzip file contains the same deflate stream as
+// the dictzip file but has a plain gzip header without the dictzip
+// subfields.
+//
+// Unlike the dictzip file, the gzip file is written as chunks are completed.
+// The gzip header is written with the first chunk so the [Header] fields must
+// be set before data is written. If writing fails, or a chunk is rejected
+// (see [WithOnChunk]), the gzip file may be incomplete.
+type MultiFormatWriter struct {
+	*Writer
+}
+
+// NewMultiFormatWriter initializes a new [MultiFormatWriter] writing a dictzip
+// file to dz and a gzip file to gz with the given compression level and chunk
+// size. It returns an error under the same conditions as [NewWriterLevel].
+func NewMultiFormatWriter(dz, gz io.Writer, level, chunkSize int, opts ...WriterOption) (*MultiFormatWriter, error) {
+	z, err := NewWriterLevel(dz, level, chunkSize, opts...)
+	if err != nil {
+		return nil, err
+	}
+	z.gz = gz
+	return &MultiFormatWriter{Writer: z}, nil
+}
+
+// teeChunk writes the compressed data of a chunk to the gzip file, if any,
+// writing the gzip header first if necessary.
+func (z *Writer) teeChunk(compressed []byte) error {
+	if z.gz == nil {
+		return nil
+	}
+	if err := z.writeGzipHeader(); err != nil {
+		return err
+	}
+	if _, err := z.gz.Write(compressed); err != nil {
+		return fmt.Errorf("%w: writing gzip: %w", errDictzip, err)
+	}
+	return nil
+}
+
+// writeGzipHeader writes the gzip header to the gzip file if it has not been
+// written.
+func (z *Writer) writeGzipHeader() error {
+	if z.gzHeader {
+		return nil
+	}
+	h, err := z.formatHeader()
+	if err != nil {
+		return err
+	}
+	header, err := format.AppendGzip(nil, h)
+	if err != nil {
+		return fmt.Errorf("%w: writing gzip header: %w", errDictzip, err)
+	}
+	if _, err := z.gz.Write(header); err != nil {
+		return fmt.Errorf("%w: writing gzip header: %w", errDictzip, err)
+	}
+	z.gzHeader = true
+	return nil
+}
+
+// closeGzip writes the final deflate markers and the trailer to the gzip
+// file, if any.
+func (z *Writer) closeGzip(final, trailer []byte) error {
+	if z.gz == nil {
+		return nil
+	}
+	// NOTE: The header has not been written if no data was written.
+	if err := z.writeGzipHeader(); err != nil {
+		return err
+	}
+	if _, err := z.gz.Write(final); err != nil {
+		return fmt.Errorf("%w: writing gzip: %w", errDictzip, err)
+	}
+	if _, err := z.gz.Write(trailer); err != nil {
+		return fmt.Errorf("%w: writing gzip: %w", errDictzip, err)
+	}
+	return nil
+}
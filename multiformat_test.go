@@ -0,0 +1,129 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+func TestMultiFormatWriter(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		data    []byte
+		workers int
+		opts    []WriterOption
+	}{
+		"empty": {},
+		"write": {
+			data: bytes.Repeat([]byte("multi format "), 20),
+		},
+		"compress from": {
+			data:    bytes.Repeat([]byte("multi format "), 20),
+			workers: 2,
+		},
+		"shared window": {
+			data: bytes.Repeat([]byte("multi format "), 20),
+			opts: []WriterOption{WithSharedWindow()},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var dzBuf, gzBuf bytes.Buffer
+			w, err := NewMultiFormatWriter(&dzBuf, &gzBuf, DefaultCompression, 50, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewMultiFormatWriter: %v", err)
+			}
+			w.Name = "multi.txt"
+			w.Comment = "comment"
+			if tc.workers > 0 {
+				_, err = w.CompressFrom(bytes.NewReader(tc.data), int64(len(tc.data)), tc.workers)
+			} else {
+				_, err = w.Write(tc.data)
+			}
+			if err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			z, err := NewReader(bytes.NewReader(dzBuf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+			got, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(tc.data, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("dictzip data (-want, +got):\n%s", diff)
+			}
+
+			gz, err := gzip.NewReader(bytes.NewReader(gzBuf.Bytes()))
+			if err != nil {
+				t.Fatalf("gzip.NewReader: %v", err)
+			}
+			defer gz.Close()
+			got, err = io.ReadAll(gz)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(tc.data, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("gzip data (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("multi.txt", gz.Name); diff != "" {
+				t.Errorf("gzip Name (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("comment", gz.Comment); diff != "" {
+				t.Errorf("gzip Comment (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]byte(nil), gz.Extra); diff != "" {
+				t.Errorf("gzip Extra (-want, +got):\n%s", diff)
+			}
+
+			// Both files contain the same compressed data.
+			_, dzLen, err := format.Parse(dzBuf.Bytes())
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			gzHeader, err := format.AppendGzip(nil, &format.Header{
+				OS:      OSUnknown,
+				XFL:     LevelXFL(DefaultCompression),
+				Name:    "multi.txt",
+				Comment: "comment",
+			})
+			if err != nil {
+				t.Fatalf("AppendGzip: %v", err)
+			}
+			if diff := cmp.Diff(dzBuf.Bytes()[dzLen:], gzBuf.Bytes()[len(gzHeader):]); diff != "" {
+				t.Errorf("compressed data (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
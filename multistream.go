@@ -0,0 +1,330 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrMultistream indicates that an operation requires a single member and is
+// not supported for multistream files read with [WithMultistream].
+var ErrMultistream = fmt.Errorf("%w: not supported for multistream files", errDictzip)
+
+// trailerSize is the size of the gzip trailer (CRC-32 and ISIZE).
+const trailerSize = 8
+
+// WithMultistream configures the [Reader] to read files made up of multiple
+// concatenated dictzip members, such as those produced by
+// "cat a.dz b.dz > c.dz", as one logical stream of uncompressed data in the
+// same way as [gzip.Reader.Multistream]. The data of each member follows the
+// data of the previous member. Random access is supported across members.
+//
+// The [Header] of the Reader, including the chunk sizes, is that of the
+// first member. See [Reader.Members] for the other members. Chunks are
+// numbered across all members by [Reader.ChunkCount], [Reader.ChunkRange],
+// [Reader.ReadChunk], and [Reader.ChunkSections].
+//
+// The [WithTolerantExtra], [WithStrictRFC1952], [WithStrictHeader],
+// [WithLenientHeader], [WithReadUTF8], [WithReadUTF8Strings],
+// [WithExactSize], and [WithDecompressor] options apply to each member.
+// Members do not use other options. The end of each member is found by
+// inflating its last chunk so the decompressor given by [WithDecompressor]
+// must not read past the end of the deflate stream when given an
+// [io.ByteReader]. WithMultistream has no effect for plain gzip files or if
+// [WithChunkIndex], [WithSalvage], or [WithDownloaded] is also given.
+//
+// [gzip.Reader.Multistream]: https://pkg.go.dev/compress/gzip#Reader.Multistream
+func WithMultistream() ReaderOption {
+	return func(z *Reader) {
+		z.multistream = true
+	}
+}
+
+// Member describes a gzip member of a multistream file. See
+// [WithMultistream].
+type Member struct {
+	// Header is the header of the member.
+	Header Header
+
+	// Offset is the offset of the member in the file.
+	Offset int64
+
+	// Start is the offset of the member's data in the uncompressed data of
+	// the file.
+	Start int64
+
+	// Size is the size of the member's uncompressed data.
+	Size int64
+}
+
+// member is a member of a multistream file and the reader reading it.
+type member struct {
+	Member
+	r *Reader
+}
+
+// Members returns the members of a multistream file. It returns nil if the
+// file has a single member or the Reader was not created with
+// [WithMultistream].
+func (z *Reader) Members() []Member {
+	z.lock()
+	defer z.unlock()
+
+	if z.members == nil {
+		return nil
+	}
+	members := make([]Member, 0, len(z.members))
+	for _, m := range z.members {
+		members = append(members, m.Member)
+	}
+	return members
+}
+
+// findMembers finds the members following the first member of the file. The
+// caller must have read the header of the first member.
+func (z *Reader) findMembers() (err error) {
+	end, err := z.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+	n, err := z.memberLen()
+	if err != nil {
+		return err
+	}
+	if n >= end {
+		// NOTE: The file has a single member.
+		return nil
+	}
+
+	// NOTE: The first member is read again so that all members are read
+	// from their own sources.
+
+	var members []*member
+	defer func() {
+		// NOTE: The members found so far are closed if a member can't be
+		// read.
+		if err != nil {
+			for _, m := range members {
+				_ = m.r.Close()
+			}
+		}
+	}()
+
+	var start int64
+	for off := int64(0); off < end; {
+		src := &memberSource{z: z, base: off, size: end - off}
+		m, err := z.newMember(src)
+		if err != nil {
+			return fmt.Errorf("member %d at offset %d: %w", len(members), off, err)
+		}
+		n, err := m.memberLen()
+		if err != nil {
+			_ = m.Close()
+			return fmt.Errorf("member %d at offset %d: %w", len(members), off, err)
+		}
+		if n > src.size {
+			_ = m.Close()
+			return fmt.Errorf("%w: member %d at offset %d is truncated", ErrTrailer, len(members), off)
+		}
+		// The member's trailer is at the end of its source.
+		src.size = n
+
+		size, err := m.Size()
+		if err != nil {
+			_ = m.Close()
+			return fmt.Errorf("member %d at offset %d: %w", len(members), off, err)
+		}
+		members = append(members, &member{
+			Member: Member{
+				Header: m.Header,
+				Offset: off,
+				Start:  start,
+				Size:   size,
+			},
+			r: m,
+		})
+		start += size
+		off += n
+	}
+	z.members = members
+	return nil
+}
+
+// memberLen returns the length of the member read by z, including the
+// trailer. The last chunk, or all chunks if they share the deflate window, is
+// inflated to find the end of the deflate stream.
+func (z *Reader) memberLen() (int64, error) {
+	start := z.offsets.at(0)
+	if !z.sharedWindow && len(z.sizes) > 0 {
+		start = z.offsets.at(len(z.sizes) - 1)
+	}
+	if _, err := z.r.Seek(start, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+
+	// NOTE: The decompressor reads from br directly since it implements
+	// io.ByteReader so the data consumed is that read from cr less the data
+	// buffered in br.
+	cr := &countingReader{r: z.r}
+	br := bufio.NewReader(cr)
+	// NOTE: The Reader's decompressor is reused so plain gzip files are
+	// inflated from the start by the next read.
+	z.gzipSrc = nil
+	if err := z.z.Reset(br, nil); err != nil {
+		return 0, fmt.Errorf("%w: Reset: %w", errDictzip, err)
+	}
+	if _, err := io.Copy(io.Discard, z.z); err != nil {
+		return 0, fmt.Errorf("%w: inflating: %w", errDictzip, err)
+	}
+	return start + cr.n - int64(br.Buffered()) + trailerSize, nil
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read implements [io.Reader].
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	//nolint:wrapcheck // we must return unwrapped io.EOF for io.Reader
+	return n, err
+}
+
+// newMember creates a Reader reading the member from r using the options of
+// z that apply to members.
+func (z *Reader) newMember(r io.ReadSeeker) (*Reader, error) {
+	m := &Reader{
+		newDecompressor: z.newDecompressor,
+		downloaded:      -1,
+		tolerant:        z.tolerant,
+		strict:          z.strict,
+		strictHeader:    z.strictHeader,
+		lenientHeader:   z.lenientHeader,
+		readUTF8:        z.readUTF8,
+		utf8Strings:     z.utf8Strings,
+		exactSize:       z.exactSize,
+	}
+	fr, err := m.decompressor(r)
+	if err != nil {
+		return nil, err
+	}
+	m.z = fr
+	if err := m.Reset(r); err != nil {
+		_ = m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// closeMembers closes the readers of the members and discards them.
+func (z *Reader) closeMembers() {
+	for _, m := range z.members {
+		_ = m.r.Close()
+	}
+	z.members = nil
+}
+
+// membersSize returns the size of the uncompressed data of all members.
+func (z *Reader) membersSize() int64 {
+	last := z.members[len(z.members)-1]
+	return last.Start + last.Size
+}
+
+// readMembers reads len(p) bytes of uncompressed data at offset off of a
+// multistream file into p. The data of each member is read using read.
+func (z *Reader) readMembers(p []byte, off int64, read func(m *Reader, p []byte, off int64) (int, error)) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+
+	var n int
+	for _, m := range z.members {
+		if n == len(p) {
+			break
+		}
+		pos := off + int64(n)
+		if pos >= m.Start+m.Size {
+			continue
+		}
+
+		size := len(p) - n
+		if rem := m.Start + m.Size - pos; int64(size) > rem {
+			size = int(rem)
+		}
+		k, err := read(m.r, p[n:n+size], pos-m.Start)
+		n += k
+		if err != nil && !errors.Is(err, io.EOF) {
+			return n, err
+		}
+		if k < size {
+			return n, fmt.Errorf("%w: member at offset %d is shorter than ISIZE", ErrTrailer, m.Offset)
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// memberSource reads the size bytes of the underlying reader of z starting
+// at base. The underlying reader is seeked before each read since it is
+// shared by all members.
+type memberSource struct {
+	z    *Reader
+	base int64
+	size int64
+	off  int64
+}
+
+// Read implements [io.Reader].
+func (s *memberSource) Read(p []byte) (int, error) {
+	if s.off >= s.size {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > s.size-s.off {
+		p = p[:s.size-s.off]
+	}
+	if _, err := s.z.r.Seek(s.base+s.off, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+	n, err := s.z.r.Read(p)
+	s.off += int64(n)
+	//nolint:wrapcheck // we must return unwrapped io.EOF for io.Reader
+	return n, err
+}
+
+// Seek implements [io.Seeker].
+func (s *memberSource) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.off
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, fmt.Errorf("%w: %v", ErrUnsupportedSeek, whence)
+	}
+	if offset < 0 {
+		return 0, ErrNegativeOffset
+	}
+	s.off = offset
+	return offset, nil
+}
@@ -0,0 +1,361 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// multistreamFile returns the concatenation of the members compressed from
+// each of data with the corresponding chunk size and the concatenated data.
+func multistreamFile(t *testing.T, data [][]byte, chunkSizes []int, opts ...WriterOption) ([]byte, []byte) {
+	t.Helper()
+
+	var b, all []byte
+	for i := range data {
+		b = append(b, compressStream(t, data[i], chunkSizes[i], opts...)...)
+		all = append(all, data[i]...)
+	}
+	return b, all
+}
+
+func TestWithMultistream(t *testing.T) {
+	t.Parallel()
+
+	data := [][]byte{
+		bytes.Repeat([]byte("first member\n"), 300),
+		{},
+		bytes.Repeat([]byte("second member\n"), 200),
+		bytes.Repeat([]byte("third member\n"), 400),
+	}
+	chunkSizes := []int{1000, 1000, 700, 1500}
+	b, all := multistreamFile(t, data, chunkSizes, WithChunkChecksums())
+
+	z, err := NewReader(bytes.NewReader(b), WithMultistream())
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	var wantMembers []Member
+	var off, start int64
+	for i := range data {
+		n := int64(len(compressStream(t, data[i], chunkSizes[i], WithChunkChecksums())))
+		wantMembers = append(wantMembers, Member{Offset: off, Start: start, Size: int64(len(data[i]))})
+		off += n
+		start += int64(len(data[i]))
+	}
+	if diff := cmp.Diff(wantMembers, z.Members(), cmpopts.IgnoreFields(Member{}, "Header")); diff != "" {
+		t.Errorf("Members (-want, +got):\n%s", diff)
+	}
+	for i, m := range z.Members() {
+		if got, want := m.Header.ChunkSize(), chunkSizes[i]; got != want {
+			t.Errorf("Members[%d].Header.ChunkSize: got %d, want %d", i, got, want)
+		}
+	}
+	if got, want := z.ChunkSize(), chunkSizes[0]; got != want {
+		t.Errorf("ChunkSize: got %d, want %d", got, want)
+	}
+
+	size, err := z.Size()
+	if err != nil {
+		t.Fatalf("Size: %v", err)
+	}
+	if got, want := size, int64(len(all)); got != want {
+		t.Errorf("Size: got %d, want %d", got, want)
+	}
+
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if diff := cmp.Diff(all, got); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+
+	// Reads spanning members.
+	for _, off := range []int64{3800, 0, 6600, int64(len(all)) - 10} {
+		for name, readAt := range map[string]func([]byte, int64) (int, error){
+			"ReadAt":         z.ReadAt,
+			"ReadAtVerified": z.ReadAtVerified,
+		} {
+			buf := make([]byte, 500)
+			n, err := readAt(buf, off)
+			want := all[off:]
+			if len(want) > len(buf) {
+				want = want[:len(buf)]
+			}
+			var wantErr error
+			if len(want) < len(buf) {
+				wantErr = io.EOF
+			}
+			if diff := cmp.Diff(wantErr, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("%s(%d) error (-want, +got):\n%s", name, off, diff)
+			}
+			if diff := cmp.Diff(want, buf[:n]); diff != "" {
+				t.Errorf("%s(%d) (-want, +got):\n%s", name, off, diff)
+			}
+		}
+	}
+
+	results, err := z.ReadAtMulti([]RangeRequest{
+		{Offset: 3800, Size: 100},
+		{Offset: 10, Size: 10},
+	})
+	if err != nil {
+		t.Fatalf("ReadAtMulti: %v", err)
+	}
+	if diff := cmp.Diff([][]byte{all[3800:3900], all[10:20]}, results); diff != "" {
+		t.Errorf("ReadAtMulti (-want, +got):\n%s", diff)
+	}
+
+	sum, err := Checksum(z, sha256.New, 4)
+	if err != nil {
+		t.Fatalf("Checksum: %v", err)
+	}
+	wantSum := sha256.Sum256(all)
+	if diff := cmp.Diff(wantSum[:], sum); diff != "" {
+		t.Errorf("Checksum (-want, +got):\n%s", diff)
+	}
+
+	sections, err := z.ChunkSections()
+	if err != nil {
+		t.Fatalf("ChunkSections: %v", err)
+	}
+	// NOTE: The chunks of each member: 4 + 0 + 4 + 4.
+	if got, want := len(sections), 12; got != want {
+		t.Errorf("ChunkSections: got %d sections, want %d", got, want)
+	}
+	var fromSections []byte
+	for _, s := range sections {
+		b, err := io.ReadAll(s)
+		if err != nil {
+			t.Fatalf("ReadAll: %v", err)
+		}
+		fromSections = append(fromSections, b...)
+	}
+	if diff := cmp.Diff(all, fromSections); diff != "" {
+		t.Errorf("ChunkSections (-want, +got):\n%s", diff)
+	}
+
+	_, err = z.AlignmentReport(nil, 2)
+	if diff := cmp.Diff(ErrMultistream, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("AlignmentReport (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWithMultistream_single(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("single member\n"), 300)
+	z, err := NewReader(bytes.NewReader(compressStream(t, data, 1000)), WithMultistream())
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	if got := z.Members(); got != nil {
+		t.Errorf("Members: got %v, want nil", got)
+	}
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if diff := cmp.Diff(data, got); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWithMultistream_errors(t *testing.T) {
+	t.Parallel()
+
+	data := [][]byte{
+		bytes.Repeat([]byte("first member\n"), 300),
+		bytes.Repeat([]byte("second member\n"), 200),
+	}
+	b, _ := multistreamFile(t, data, []int{1000, 1000})
+	badSize := append([]byte(nil), b...)
+	badSize[len(badSize)-1] ^= 0xff
+
+	testCases := map[string]struct {
+		b   []byte
+		err error
+	}{
+		"truncated": {
+			b:   b[:len(b)-4],
+			err: ErrTrailer,
+		},
+		"trailing garbage": {
+			b:   append(append([]byte(nil), b...), "garbage"...),
+			err: ErrHeader,
+		},
+		"bad ISIZE": {
+			b:   badSize,
+			err: ErrTrailer,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// The readers of the members are closed if a member can't be
+			// read.
+			var created, closed int
+			newDecompressor := func(r io.Reader) io.ReadCloser {
+				created++
+				return &closeCountingDecompressor{
+					readCloseResetter: flate.NewReader(r).(readCloseResetter),
+					closed:            &closed,
+				}
+			}
+
+			_, err := NewReader(bytes.NewReader(tc.b), WithMultistream(), WithDecompressor(newDecompressor))
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("NewReader (-want, +got):\n%s", diff)
+			}
+			if created < 2 {
+				t.Errorf("created: got %d, want at least 2", created)
+			}
+			if diff := cmp.Diff(created, closed); diff != "" {
+				t.Errorf("closed (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type closeCountingDecompressor struct {
+	readCloseResetter
+	closed *int
+}
+
+func (d *closeCountingDecompressor) Close() error {
+	*d.closed++
+	//nolint:wrapcheck // errors are returned unchanged.
+	return d.readCloseResetter.Close()
+}
+
+func TestWithMultistream_sharedWindow(t *testing.T) {
+	t.Parallel()
+
+	data := [][]byte{
+		bytes.Repeat([]byte("first member\n"), 300),
+		bytes.Repeat([]byte("second member\n"), 200),
+	}
+	b, all := multistreamFile(t, data, []int{1000, 700}, WithSharedWindow())
+
+	z, err := NewReader(bytes.NewReader(b), WithMultistream())
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	if got, want := len(z.Members()), 2; got != want {
+		t.Errorf("Members: got %d members, want %d", got, want)
+	}
+	buf := make([]byte, 1000)
+	n, err := z.ReadAt(buf, 3500)
+	if err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if diff := cmp.Diff(all[3500:4500], buf[:n]); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWithMultistream_chunks(t *testing.T) {
+	t.Parallel()
+
+	data := [][]byte{
+		bytes.Repeat([]byte("first member\n"), 300),
+		{},
+		bytes.Repeat([]byte("second member\n"), 200),
+	}
+	chunkSizes := []int{1000, 1000, 700}
+	b, all := multistreamFile(t, data, chunkSizes)
+
+	z, err := NewReader(bytes.NewReader(b), WithMultistream())
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	sections, err := z.ChunkSections()
+	if err != nil {
+		t.Fatalf("ChunkSections: %v", err)
+	}
+	// NOTE: 3900 bytes in chunks of 1000 and 2800 bytes in chunks of 700.
+	if diff := cmp.Diff(8, z.ChunkCount()); diff != "" {
+		t.Errorf("ChunkCount (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(len(sections), z.ChunkCount()); diff != "" {
+		t.Errorf("ChunkCount (-want, +got):\n%s", diff)
+	}
+
+	var prevEnd int64
+	for i := 0; i < z.ChunkCount(); i++ {
+		compOff, compLen, uncompOff, uncompLen, err := z.ChunkRange(i)
+		if err != nil {
+			t.Fatalf("ChunkRange(%d): %v", i, err)
+		}
+		if compOff < prevEnd || compOff+compLen > int64(len(b)) {
+			t.Errorf("ChunkRange(%d): compressed range [%d, %d) out of order", i, compOff, compOff+compLen)
+		}
+		prevEnd = compOff + compLen
+
+		section, err := io.ReadAll(sections[i])
+		if err != nil {
+			t.Fatalf("ReadAll: %v", err)
+		}
+		if !bytes.Equal(all[uncompOff:uncompOff+uncompLen], section) {
+			t.Errorf("ChunkRange(%d): range does not match chunk section %d", i, i)
+		}
+
+		buf := make([]byte, 1000)
+		n, err := z.ReadChunk(i, buf)
+		if err != nil {
+			t.Fatalf("ReadChunk(%d): %v", i, err)
+		}
+		if !bytes.Equal(all[uncompOff:uncompOff+uncompLen], buf[:n]) {
+			t.Errorf("ReadChunk(%d): data does not match", i)
+		}
+	}
+
+	_, _, _, _, err = z.ChunkRange(z.ChunkCount())
+	if diff := cmp.Diff(ErrChunkRange, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("ChunkRange (-want, +got):\n%s", diff)
+	}
+
+	st, err := Stats(z)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if diff := cmp.Diff(z.ChunkCount(), st.Chunks); diff != "" {
+		t.Errorf("Stats Chunks (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(int64(len(all)), st.Size); diff != "" {
+		t.Errorf("Stats Size (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(st.Chunks, len(st.Ratios)); diff != "" {
+		t.Errorf("Stats Ratios (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrUnsafeName indicates that the NAME header field can't safely be used as
+// a file name.
+var ErrUnsafeName = fmt.Errorf("%w: unsafe file name", errDictzip)
+
+// windowsInvalidChars are characters that are not allowed in file names on
+// Windows.
+const windowsInvalidChars = `<>:"|?*`
+
+// windowsDeviceNames are reserved device names on Windows which can't be used
+// as file names with or without an extension.
+var windowsDeviceNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// normalizeName normalizes a file name stored in the NAME header field
+// following gzip conventions. Directories are removed and Windows path
+// separators and drive letters are handled so that only the base file name
+// remains.
+func normalizeName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+
+	// Remove a Windows drive letter (e.g. "C:").
+	if len(name) >= 2 && name[1] == ':' &&
+		(name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z') {
+		name = name[2:]
+	}
+
+	name = strings.TrimRight(name, "/")
+	if name == "" {
+		return ""
+	}
+	name = path.Base(name)
+	if name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
+
+// SafeName returns the NAME header field as a file name that is safe to
+// create in the current directory on all platforms, such as when restoring
+// the original name of a file from an untrusted archive. Directories are
+// removed as by [Header.Name] so that the name can't refer to another
+// directory. SafeName returns an error wrapping [ErrUnsafeName] if no name
+// remains or the name includes control characters or characters not allowed
+// on Windows, ends with a dot or space, or is a reserved Windows device name.
+func (h *Header) SafeName() (string, error) {
+	return safeName(h.rawName)
+}
+
+// safeName implements SafeName for the raw NAME header field name.
+func safeName(name string) (string, error) {
+	base := normalizeName(name)
+	if base == "" {
+		return "", fmt.Errorf("%w: %q: no file name", ErrUnsafeName, name)
+	}
+
+	for _, r := range base {
+		// NOTE: C1 control characters are included since NAME is Latin-1.
+		if r < 0x20 || r >= 0x7f && r <= 0x9f {
+			return "", fmt.Errorf("%w: %q: control character %U", ErrUnsafeName, name, r)
+		}
+		if strings.ContainsRune(windowsInvalidChars, r) {
+			return "", fmt.Errorf("%w: %q: invalid character %q", ErrUnsafeName, name, r)
+		}
+	}
+	if strings.HasSuffix(base, ".") || strings.HasSuffix(base, " ") {
+		return "", fmt.Errorf("%w: %q: trailing dot or space", ErrUnsafeName, name)
+	}
+
+	device, _, _ := strings.Cut(base, ".")
+	if windowsDeviceNames[strings.ToUpper(strings.TrimRight(device, " "))] {
+		return "", fmt.Errorf("%w: %q: reserved device name", ErrUnsafeName, name)
+	}
+
+	return base, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestNormalizeName(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"":                         "",
+		"hello.txt":                "hello.txt",
+		"dir/hello.txt":            "hello.txt",
+		"/abs/path/hello.txt":      "hello.txt",
+		"dir\\hello.txt":           "hello.txt",
+		"C:\\Users\\me\\hello.txt": "hello.txt",
+		"c:hello.txt":              "hello.txt",
+		"dir/":                     "dir",
+		"/":                        "",
+		"..":                       "",
+		"C:\\":                     "",
+		"über/naïve.txt":           "naïve.txt",
+		"weird:name.txt":           "weird:name.txt",
+	}
+
+	for name, want := range testCases {
+		name, want := name, want
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(want, normalizeName(name)); diff != "" {
+				t.Errorf("normalizeName (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSafeName(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		want string
+		err  error
+	}{
+		"hello.txt":            {want: "hello.txt"},
+		"../../etc/passwd":     {want: "passwd"},
+		"C:\\Windows\\win.ini": {want: "win.ini"},
+		"naïve.txt":            {want: "naïve.txt"},
+		"console.txt":          {want: "console.txt"},
+		"":                     {err: ErrUnsafeName},
+		"..":                   {err: ErrUnsafeName},
+		"dir/..":               {err: ErrUnsafeName},
+		"bad\nname":            {err: ErrUnsafeName},
+		"bad\u0085name":        {err: ErrUnsafeName},
+		"bad\x7fname":          {err: ErrUnsafeName},
+		"weird:name.txt":       {err: ErrUnsafeName},
+		"what?.txt":            {err: ErrUnsafeName},
+		"trailing.":            {err: ErrUnsafeName},
+		"trailing ":            {err: ErrUnsafeName},
+		"NUL":                  {err: ErrUnsafeName},
+		"con.txt":              {err: ErrUnsafeName},
+		"Lpt1 .tar":            {err: ErrUnsafeName},
+	}
+
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := Header{rawName: name}
+			got, err := h.SafeName()
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("SafeName error (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("SafeName (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+// offsetBlock is the number of chunks whose offsets are stored relative to
+// the same base offset in an [offsetTable].
+const offsetBlock = 1024
+
+// offsetTable is a table of the compressed data offsets of each chunk. The
+// last offset is the end of the last chunk.
+//
+// Archives with small chunk sizes can have millions of chunks so offsets are
+// stored as 32-bit deltas from a base offset recorded every offsetBlock
+// chunks, which halves the memory needed. If a delta does not fit in 32 bits,
+// which is only possible with version 2 RA subfields, the offsets are stored
+// in full instead.
+type offsetTable struct {
+	// base is the offset of every offsetBlock-th chunk.
+	base []int64
+
+	// deltas are the offsets of each chunk relative to the base offset of
+	// its block.
+	deltas []uint32
+
+	// wide holds the offsets if they can't be stored as deltas. It is nil
+	// otherwise.
+	wide []int64
+}
+
+// chunkOffsets returns the offsets of the chunks with the given compressed
+// sizes following a header of size hdrLen. The last offset is the end of the
+// last chunk.
+func chunkOffsets(hdrLen int64, sizes []int) offsetTable {
+	var t offsetTable
+	t.base = make([]int64, 0, len(sizes)/offsetBlock+1)
+	t.deltas = make([]uint32, 0, len(sizes)+1)
+
+	off := hdrLen
+	for i := 0; i <= len(sizes); i++ {
+		if i%offsetBlock == 0 {
+			t.base = append(t.base, off)
+		}
+		delta := off - t.base[len(t.base)-1]
+		if delta > maxOffsetDelta {
+			return wideOffsets(hdrLen, sizes)
+		}
+		t.deltas = append(t.deltas, uint32(delta))
+		if i < len(sizes) {
+			off += int64(sizes[i])
+		}
+	}
+	return t
+}
+
+// maxOffsetDelta is the maximum delta stored in an [offsetTable].
+const maxOffsetDelta = 1<<32 - 1
+
+// wideOffsets returns an [offsetTable] storing the offsets in full.
+func wideOffsets(hdrLen int64, sizes []int) offsetTable {
+	wide := make([]int64, len(sizes)+1)
+	wide[0] = hdrLen
+	for i := 0; i < len(sizes); i++ {
+		wide[i+1] = wide[i] + int64(sizes[i])
+	}
+	return offsetTable{wide: wide}
+}
+
+// at returns the offset of chunk i.
+func (t *offsetTable) at(i int) int64 {
+	if t.wide != nil {
+		return t.wide[i]
+	}
+	return t.base[i/offsetBlock] + int64(t.deltas[i])
+}
+
+// len returns the number of offsets, which is one more than the number of
+// chunks.
+func (t *offsetTable) len() int {
+	if t.wide != nil {
+		return len(t.wide)
+	}
+	return len(t.deltas)
+}
+
+// truncate discards all but the first n offsets.
+func (t *offsetTable) truncate(n int) {
+	if t.wide != nil {
+		t.wide = t.wide[:n]
+		return
+	}
+	t.deltas = t.deltas[:n]
+	t.base = t.base[:(n+offsetBlock-1)/offsetBlock]
+}
+
+// slice returns the offsets as a new slice.
+func (t *offsetTable) slice() []int64 {
+	offsets := make([]int64, t.len())
+	for i := range offsets {
+		offsets[i] = t.at(i)
+	}
+	return offsets
+}
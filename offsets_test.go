@@ -0,0 +1,237 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// repeatSize returns n chunk sizes of size.
+func repeatSize(size, n int) []int {
+	sizes := make([]int, n)
+	for i := range sizes {
+		sizes[i] = size
+	}
+	return sizes
+}
+
+func TestChunkOffsets(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		sizes []int
+		wide  bool
+	}{
+		"empty": {},
+		"one chunk": {
+			sizes: []int{10},
+		},
+		"block boundary": {
+			sizes: repeatSize(65535, offsetBlock*2+1),
+		},
+		"wide": {
+			sizes: []int{math.MaxInt32, math.MaxInt32, math.MaxInt32},
+			wide:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			want := make([]int64, len(tc.sizes)+1)
+			want[0] = 22
+			for i, size := range tc.sizes {
+				want[i+1] = want[i] + int64(size)
+			}
+
+			offsets := chunkOffsets(22, tc.sizes)
+			if diff := cmp.Diff(want, offsets.slice()); diff != "" {
+				t.Errorf("offsets (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wide, offsets.wide != nil); diff != "" {
+				t.Errorf("wide (-want, +got):\n%s", diff)
+			}
+
+			n := len(want) / 2
+			offsets.truncate(n)
+			if diff := cmp.Diff(want[:n], offsets.slice()); diff != "" {
+				t.Errorf("truncated offsets (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// memberChunks is the number of chunks in each member written by
+// manyChunksFile. The XLEN field limits the number of chunks in a member to
+// about 32K.
+const memberChunks = 32000
+
+// manyChunksFile returns a compressed file of data with the given chunk size.
+// If there are more than memberChunks chunks, the file is made up of
+// multiple members which must be read with [WithMultistream].
+func manyChunksFile(tb testing.TB, data []byte, chunkSize int) []byte {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	for off := 0; off == 0 || off < len(data); off += memberChunks * chunkSize {
+		end := off + memberChunks*chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		w, err := NewWriterLevel(&buf, BestSpeed, chunkSize)
+		if err != nil {
+			tb.Fatalf("NewWriterLevel: %v", err)
+		}
+		if _, err := w.Write(data[off:end]); err != nil {
+			tb.Fatalf("Write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			tb.Fatalf("Close: %v", err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestReader_ReadAt_smallChunks(t *testing.T) {
+	t.Parallel()
+
+	const chunkSize = 16
+	data := benchmarkData(3 * offsetBlock * chunkSize)
+	archive := manyChunksFile(t, data, chunkSize)
+
+	for _, tc := range []struct{ off, size int }{
+		{0, len(data)},
+		{5, 3},
+		{offsetBlock*chunkSize - 7, 100},
+		{100, 2 * offsetBlock * chunkSize},
+		{len(data) - 20, 20},
+	} {
+		tc := tc
+		t.Run(fmt.Sprintf("%d+%d", tc.off, tc.size), func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(archive))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			got := make([]byte, tc.size)
+			if _, err := z.ReadAt(got, int64(tc.off)); err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if diff := cmp.Diff(data[tc.off:tc.off+tc.size], got); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func BenchmarkChunkOffsets(b *testing.B) {
+	sizes := make([]int, 1<<20)
+	for i := range sizes {
+		sizes[i] = 40 + i%20
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		offsets := chunkOffsets(22, sizes)
+		_ = offsets.at(len(sizes))
+	}
+}
+
+func BenchmarkReaderReadAt_smallChunks(b *testing.B) {
+	const chunkSize = 64
+	data := benchmarkData(memberChunks * chunkSize)
+	archive := manyChunksFile(b, data, chunkSize)
+
+	for _, size := range []int{chunkSize, 64 << 10} {
+		for _, positional := range []bool{true, false} {
+			b.Run(fmt.Sprintf("size=%d/positional=%t", size, positional), func(b *testing.B) {
+				z, err := NewReader(readSeekerOnly(archive, positional))
+				if err != nil {
+					b.Fatalf("NewReader: %v", err)
+				}
+				defer z.Close()
+
+				p := make([]byte, size)
+				b.SetBytes(int64(len(p)))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					off := int64(i) * 7919 % int64(len(data)-size)
+					if _, err := z.ReadAt(p, off); err != nil {
+						b.Fatalf("ReadAt: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkReaderReadAt_millionChunks(b *testing.B) {
+	const chunkSize = 64
+	data := benchmarkData(1 << 20 * chunkSize)
+	archive := manyChunksFile(b, data, chunkSize)
+
+	b.Run("NewReader", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z, err := NewReader(bytes.NewReader(archive), WithMultistream())
+			if err != nil {
+				b.Fatalf("NewReader: %v", err)
+			}
+			z.Close()
+		}
+	})
+
+	b.Run("ReadAt", func(b *testing.B) {
+		z, err := NewReader(bytes.NewReader(archive), WithMultistream())
+		if err != nil {
+			b.Fatalf("NewReader: %v", err)
+		}
+		defer z.Close()
+
+		p := make([]byte, 64<<10)
+		b.SetBytes(int64(len(p)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			off := int64(i) * 7919 * chunkSize % int64(len(data)-len(p))
+			if _, err := z.ReadAt(p, off); err != nil {
+				b.Fatalf("ReadAt: %v", err)
+			}
+		}
+	})
+}
+
+// readSeekerOnly returns a reader of b. If positional is false, the reader
+// does not implement [io.ReaderAt].
+func readSeekerOnly(b []byte, positional bool) io.ReadSeeker {
+	r := bytes.NewReader(b)
+	if positional {
+		return r
+	}
+	return struct{ io.ReadSeeker }{r}
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"fmt"
+	"math"
+)
+
+// ErrChunkRejected indicates that a chunk was rejected by the function given
+// to [WithOnChunk].
+var ErrChunkRejected = fmt.Errorf("%w: chunk rejected", errDictzip)
+
+// ChunkFunc is called for each chunk before it is written. index is the
+// chunk's index, n is its uncompressed length, and compressed is its
+// compressed data. compressed must not be modified or retained after the
+// function returns. Returning a non-nil error aborts writing.
+type ChunkFunc func(index, n int, compressed []byte) error
+
+// WithOnChunk configures the [Writer] to call fn for each chunk before it is
+// committed to the archive. If fn returns an error, the chunk is not written
+// and the error is returned, wrapped with [ErrChunkRejected], from the
+// current and all later calls to [Writer.Write], [Writer.CompressFrom], and
+// [Writer.Close]. No archive is written to the underlying writer once a chunk
+// has been rejected.
+//
+// fn is called sequentially in chunk order, including when chunks are
+// compressed in parallel by [Writer.CompressFrom].
+func WithOnChunk(fn ChunkFunc) WriterOption {
+	return func(z *Writer) {
+		z.onChunk = fn
+	}
+}
+
+// checkChunk checks the size of the next compressed chunk and calls the
+// onChunk function, if any, for it.
+func (z *Writer) checkChunk(n int, compressed []byte) error {
+	if z.rejected != nil {
+		return z.rejected
+	}
+	index := len(z.sizes)
+	if len(compressed) > math.MaxUint16 {
+		// NOTE: The size is checked as each chunk is written so that writing
+		// fails early rather than in Close.
+		z.rejected = fmt.Errorf("%w: chunk %d is %d bytes compressed, more than %d",
+			ErrCompressedChunkSize, index, len(compressed), math.MaxUint16)
+		return z.rejected
+	}
+	if z.onChunk == nil {
+		return nil
+	}
+	if err := z.onChunk(index, n, compressed); err != nil {
+		z.rejected = fmt.Errorf("%w: chunk %d: %w", ErrChunkRejected, index, err)
+		return z.rejected
+	}
+	return nil
+}
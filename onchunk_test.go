@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestWithOnChunk(t *testing.T) {
+	t.Parallel()
+
+	type chunk struct {
+		Index int
+		N     int
+		Data  string
+	}
+
+	data := bytes.Repeat([]byte("onchunk "), 40)
+
+	testCases := map[string]struct {
+		compressFrom bool
+	}{
+		"write":         {compressFrom: false},
+		"compress from": {compressFrom: true},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []chunk
+			var buf bytes.Buffer
+			z, err := NewWriterLevel(&buf, DefaultCompression, 100, WithOnChunk(func(index, n int, compressed []byte) error {
+				// Inflate the compressed data to check that it contains the
+				// chunk.
+				b, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+				if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Errorf("inflating chunk %d: %v", index, err)
+				}
+				got = append(got, chunk{Index: index, N: n, Data: string(b)})
+				return nil
+			}))
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if tc.compressFrom {
+				_, err = z.CompressFrom(bytes.NewReader(data), int64(len(data)), 2)
+			} else {
+				_, err = z.Write(data)
+			}
+			if err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := z.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			want := []chunk{
+				{Index: 0, N: 100, Data: string(data[:100])},
+				{Index: 1, N: 100, Data: string(data[100:200])},
+				{Index: 2, N: 100, Data: string(data[200:300])},
+				{Index: 3, N: 20, Data: string(data[300:])},
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("chunks (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithOnChunk_reject(t *testing.T) {
+	t.Parallel()
+
+	errReject := errors.New("rejected")
+	data := bytes.Repeat([]byte("reject "), 40)
+
+	testCases := map[string]struct {
+		compressFrom bool
+	}{
+		"write":         {compressFrom: false},
+		"compress from": {compressFrom: true},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls int
+			var buf bytes.Buffer
+			z, err := NewWriterLevel(&buf, DefaultCompression, 100, WithOnChunk(func(index, _ int, _ []byte) error {
+				calls++
+				if index == 1 {
+					return errReject
+				}
+				return nil
+			}))
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if tc.compressFrom {
+				_, err = z.CompressFrom(bytes.NewReader(data), int64(len(data)), 2)
+			} else {
+				_, err = z.Write(data)
+			}
+			if diff := cmp.Diff(ErrChunkRejected, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Write (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(errReject, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Write (-want, +got):\n%s", diff)
+			}
+
+			// Later calls return the same error.
+			_, err = z.Write([]byte("more"))
+			if diff := cmp.Diff(ErrChunkRejected, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Write (-want, +got):\n%s", diff)
+			}
+			err = z.Close()
+			if diff := cmp.Diff(ErrChunkRejected, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Close (-want, +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(2, calls); diff != "" {
+				t.Errorf("calls (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(0, buf.Len()); diff != "" {
+				t.Errorf("output length (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
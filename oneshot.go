@@ -0,0 +1,111 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// chunkFile stores the compressed chunks written by the [Writer] until it is
+// closed.
+type chunkFile interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Sync() error
+}
+
+// memFile is a chunkFile that stores chunks in memory.
+type memFile struct {
+	bytes.Buffer
+}
+
+// Seek implements [io.Seeker.Seek]. memFile is only read once from the
+// start so Seek does nothing.
+func (*memFile) Seek(int64, int) (int64, error) {
+	return 0, nil
+}
+
+// Sync does nothing.
+func (*memFile) Sync() error {
+	return nil
+}
+
+// Close does nothing.
+func (*memFile) Close() error {
+	return nil
+}
+
+// WithMemoryBuffer configures the [Writer] to buffer compressed chunks in
+// memory rather than in a temporary file. The whole compressed archive is
+// held in memory until [Writer.Close] is called so this is only suitable for
+// small inputs.
+func WithMemoryBuffer() WriterOption {
+	return func(z *Writer) {
+		z.memoryBuffer = true
+	}
+}
+
+// Compress returns data compressed as a dictzip archive using the default
+// compression level and chunk size. Compression is done in memory without
+// temporary files. See [NewWriter] for the options.
+func Compress(data []byte, opts ...WriterOption) ([]byte, error) {
+	var buf bytes.Buffer
+	z, err := NewWriter(&buf, append(opts, WithMemoryBuffer())...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := z.Write(data); err != nil {
+		_ = z.Close()
+		return nil, err
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress returns the uncompressed data of the dictzip archive data. Unlike
+// [Reader], Decompress verifies the CRC-32 and size in the gzip trailer and
+// returns an error wrapping [ErrTrailer] if they do not match the data.
+func Decompress(data []byte) ([]byte, error) {
+	z, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+
+	out, err := io.ReadAll(z)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decompressing: %w", errDictzip, err)
+	}
+
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: missing trailer", ErrTrailer)
+	}
+	trailer := data[len(data)-8:]
+	if crc := binary.LittleEndian.Uint32(trailer[:4]); crc != crc32.ChecksumIEEE(out) {
+		return nil, fmt.Errorf("%w: CRC-32 %08x does not match data", ErrTrailer, crc)
+	}
+	//nolint:gosec // ISIZE is the size modulo 2^32 per RFC-1952 Section 2.3.1.
+	if isize := binary.LittleEndian.Uint32(trailer[4:]); isize != uint32(len(out)) {
+		return nil, fmt.Errorf("%w: ISIZE %d does not match data", ErrTrailer, isize)
+	}
+
+	return out, nil
+}
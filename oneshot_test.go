@@ -0,0 +1,113 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		data []byte
+		opts []WriterOption
+	}{
+		"empty": {},
+		"data": {
+			data: []byte("Hello, World!"),
+		},
+		"shared window": {
+			data: bytes.Repeat([]byte("Hello, World!"), 10000),
+			opts: []WriterOption{WithSharedWindow()},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			compressed, err := Compress(tc.data, tc.opts...)
+			if err != nil {
+				t.Fatalf("Compress: %v", err)
+			}
+
+			got, err := Decompress(compressed)
+			if err != nil {
+				t.Fatalf("Decompress: %v", err)
+			}
+			if diff := cmp.Diff(tc.data, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Decompress (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // t.Setenv can't be used with t.Parallel.
+func TestCompress_noTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	if _, err := Compress([]byte("Hello, World!")); err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if diff := cmp.Diff(0, len(entries)); diff != "" {
+		t.Errorf("temp files (-want, +got):\n%s", diff)
+	}
+}
+
+func TestDecompress_trailer(t *testing.T) {
+	t.Parallel()
+
+	compressed, err := Compress([]byte("Hello, World!"))
+	if err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+
+	testCases := map[string]struct {
+		// offset is the offset from the end of the archive of the byte
+		// that is modified.
+		offset int
+	}{
+		"CRC-32": {offset: 8},
+		"ISIZE":  {offset: 4},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data := append([]byte(nil), compressed...)
+			data[len(data)-tc.offset] ^= 0xff
+
+			_, err := Decompress(data)
+			if diff := cmp.Diff(ErrTrailer, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Decompress (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"io"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// ParseHeader reads and parses the dictzip header at the start of r without
+// creating a [Reader]. It returns the header and the offset of the start of
+// the compressed data following the header. The chunk table is available
+// using [Header.ChunkSize] and [Header.Sizes].
+//
+// ParseHeader may read past the end of the header. It returns an error
+// wrapping [ErrHeader] if r does not start with a valid dictzip header.
+func ParseHeader(r io.Reader) (*Header, int64, error) {
+	h, _, hdrLen, err := readHeaderFrom(r, format.ParseOptions{}, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var z Reader
+	z.setHeader(h)
+	z.chunkSize = h.ChunkSize
+	hdr := z.Header
+	return &hdr, int64(hdrLen), nil
+}
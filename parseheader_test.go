@@ -0,0 +1,69 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParseHeader(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("parse my header\n"), 500)
+	b := compressStream(t, data, 1000, WithChunkChecksums())
+
+	z, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	h, off, err := ParseHeader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ParseHeader: %v", err)
+	}
+	if diff := cmp.Diff(&z.Header, h, cmp.AllowUnexported(Header{})); diff != "" {
+		t.Errorf("ParseHeader (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(z.offsets.at(0), off); diff != "" {
+		t.Errorf("ParseHeader offset (-want, +got):\n%s", diff)
+	}
+}
+
+func TestParseHeader_invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string][]byte{
+		"empty":      nil,
+		"plain gzip": gzipFile(t, []byte("plain gzip"), nil),
+		"truncated":  compressStream(t, []byte("truncated"), 1000)[:12],
+	}
+
+	for name, b := range testCases {
+		b := b
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := ParseHeader(bytes.NewReader(b))
+			if diff := cmp.Diff(ErrHeader, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ParseHeader (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
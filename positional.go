@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// positional returns the underlying reader as an [io.ReaderAt] and whether
+// ReadAt may read from it concurrently without holding the lock. Positional
+// reads are only used if no options requiring shared state are given.
+func (z *Reader) positional() (io.ReaderAt, bool) {
+	if z.ra == nil || z.sharedWindow || z.plainGzip || z.members != nil || z.salvage || z.downloaded >= 0 ||
+		z.readCache != nil || z.chunkCache != nil || z.memoryLimit > 0 || z.prefetch >= 2 || z.concurrency >= 2 {
+		return nil, false
+	}
+	return z.ra, true
+}
+
+// positionalBatchSize is the maximum size of the compressed data read from
+// the underlying reader at once by positional reads spanning multiple chunks.
+const positionalBatchSize = 1 << 20
+
+// readAtPositional implements ReadAt using positional reads of ra and pooled
+// decompressors so that it does not modify the Reader's state.
+//
+// Since chunks end on a deflate block boundary, consecutive chunks are
+// inflated as one stream. The compressed data of the chunks are read with a
+// single positional read of up to positionalBatchSize bytes so that reads
+// spanning many small chunks don't make a request and reset a decompressor
+// for each chunk.
+func (z *Reader) readAtPositional(ra io.ReaderAt, p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+
+	chunkSize := int64(z.chunkSize)
+	chunkCount := int64(len(z.sizes))
+	var n int
+	for n < len(p) {
+		pos := off + int64(n)
+		first := pos / chunkSize
+		if first >= chunkCount {
+			return n, io.EOF
+		}
+
+		last := (pos + int64(len(p)-n) - 1) / chunkSize
+		if last >= chunkCount {
+			last = chunkCount - 1
+		}
+		for last > first && z.offsets.at(int(last+1))-z.offsets.at(int(first)) > positionalBatchSize {
+			last--
+		}
+
+		skip := pos - first*chunkSize
+		size := int64(len(p) - n)
+		if rem := (last+1)*chunkSize - pos; size > rem {
+			size = rem
+		}
+		m, err := z.readChunksAt(ra, first, last, p[n:n+int(size)], skip)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// readChunksAt inflates the chunks first through last read from ra into p
+// after discarding skip bytes of uncompressed data.
+func (z *Reader) readChunksAt(ra io.ReaderAt, first, last int64, p []byte, skip int64) (int, error) {
+	fr, err := z.pooledDecompressor()
+	if err != nil {
+		return 0, err
+	}
+	defer z.pool.Put(fr)
+
+	start := z.offsets.at(int(first))
+	end := z.offsets.at(int(last + 1))
+	var r io.Reader
+	if last == int64(len(z.sizes))-1 {
+		// NOTE: The last chunk is followed by the final deflate block so it
+		// is read until the end of the deflate stream.
+		r = io.NewSectionReader(ra, start, math.MaxInt64-start)
+	} else {
+		// NOTE: The data may be short if the archive is truncated. Errors are
+		// returned when the missing chunks are inflated.
+		buf := make([]byte, end-start)
+		m, err := ra.ReadAt(buf, start)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("%w: reading chunks: %w", errDictzip, err)
+		}
+		r = bytes.NewReader(buf[:m])
+	}
+	if err := fr.Reset(r, nil); err != nil {
+		return 0, fmt.Errorf("%w: Reset: %w", errDictzip, err)
+	}
+
+	if _, err := io.CopyN(io.Discard, fr, skip); err != nil {
+		if errors.Is(err, io.EOF) {
+			//nolint:wrapcheck // we must return unwrapped io.EOF for io.Reader
+			return 0, err
+		}
+		return 0, fmt.Errorf("%w: inflating chunk: %w", errDictzip, err)
+	}
+
+	var n int
+	for n < len(p) && err == nil {
+		var m int
+		m, err = fr.Read(p[n:])
+		n += m
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, fmt.Errorf("%w: inflating chunk: %w", errDictzip, err)
+	}
+	//nolint:wrapcheck // we must return unwrapped io.EOF for io.Reader
+	return n, err
+}
+
+// pooledDecompressor returns a decompressor from the pool.
+func (z *Reader) pooledDecompressor() (readCloseResetter, error) {
+	if fr, ok := z.pool.Get().(readCloseResetter); ok {
+		return fr, nil
+	}
+	return z.decompressor(eofReader{})
+}
+
+// eofReader is an [io.Reader] which is always at EOF. It is used to create
+// decompressors which are reset before use.
+type eofReader struct{}
+
+// Read implements [io.Reader.Read].
+func (eofReader) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReader_ReadAt_concurrent(t *testing.T) {
+	t.Parallel()
+
+	var data []byte
+	for i := 0; i < 5000; i++ {
+		data = append(data, []byte("Hello, concurrent ReadAt!\n")...)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 1000)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// NOTE: The Reader is not created with WithLocking.
+	z, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for g := range errs {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < 50; i++ {
+				off := rng.Intn(len(data))
+				n := rng.Intn(3000)
+				p := make([]byte, n)
+				m, err := z.ReadAt(p, int64(off))
+				if err != nil && !errors.Is(err, io.EOF) {
+					errs[g] = err
+					return
+				}
+				want := data[off:]
+				if len(want) > n {
+					want = want[:n]
+				}
+				if !bytes.Equal(want, p[:m]) {
+					errs[g] = errors.New("data mismatch")
+					return
+				}
+			}
+		}(g)
+	}
+
+	// Sequential reads may be done concurrently with ReadAt.
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Errorf("ReadAll: %v", err)
+	}
+	wg.Wait()
+
+	for g, err := range errs {
+		if err != nil {
+			t.Errorf("goroutine %d: ReadAt: %v", g, err)
+		}
+	}
+	if diff := cmp.Diff(data, got); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReader_ReadAt_positionalEOF(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("Hello, positional reads!")
+	z, err := NewReader(bytes.NewReader(mustCompress(t, data)))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	if _, ok := z.positional(); !ok {
+		t.Fatalf("positional: got false, want true")
+	}
+
+	p := make([]byte, 10)
+	n, err := z.ReadAt(p, int64(len(data)-4))
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("ReadAt: got error %v, want io.EOF", err)
+	}
+	if diff := cmp.Diff("ads!", string(p[:n])); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+
+	n, err = z.ReadAt(p, int64(len(data)+10))
+	if n != 0 || !errors.Is(err, io.EOF) {
+		t.Errorf("ReadAt past end: got (%d, %v), want (0, io.EOF)", n, err)
+	}
+}
+
+func TestReader_ReadAt_negativeOffset(t *testing.T) {
+	t.Parallel()
+
+	compressed := compressStream(t, benchmarkData(10000), 1000)
+
+	testCases := map[string][]ReaderOption{
+		"positional": nil,
+		"locking":    {WithLocking(), WithChunkCache(4)},
+	}
+
+	for name, opts := range testCases {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(compressed), opts...)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			var wg sync.WaitGroup
+			errs := make([]error, 8)
+			for i := range errs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					buf := make([]byte, 100)
+					n, err := z.ReadAt(buf, -int64(i+1))
+					if n != 0 {
+						err = errors.New("data read at negative offset")
+					}
+					errs[i] = err
+				}(i)
+			}
+			wg.Wait()
+
+			for i, err := range errs {
+				if diff := cmp.Diff(ErrNegativeOffset, err, cmpopts.EquateErrors()); diff != "" {
+					t.Errorf("ReadAt(%d) (-want, +got):\n%s", -(i + 1), diff)
+				}
+			}
+		})
+	}
+}
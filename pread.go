@@ -0,0 +1,30 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+// WithPread configures the [Reader] to read compressed data from an
+// underlying [*os.File] with positional reads, such as pread(2) on Linux,
+// rather than a Seek followed by a Read. Positional reads don't share the
+// file offset so they need fewer system calls, and reads spanning several
+// chunks, such as those done with [WithConcurrency], read the compressed data
+// of the chunks with a single positional read.
+//
+// WithPread has no effect if the underlying reader is not an [*os.File] or
+// on platforms other than Linux, where [os.File.ReadAt] is used as usual.
+func WithPread() ReaderOption {
+	return func(z *Reader) {
+		z.pread = true
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package dictzip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+// preadFile is an [io.ReadSeeker] reading from f whose ReadAt method calls
+// pread(2) on the file descriptor directly.
+//
+// NOTE: io_uring is not used since it is not supported by the syscall
+// package. Reads are batched by reading consecutive chunks at once instead.
+type preadFile struct {
+	*os.File
+	rc syscall.RawConn
+}
+
+// newPreadReader returns a reader of r using pread(2) for positional reads
+// if r is an [*os.File]. Otherwise, r is returned.
+func newPreadReader(r io.ReadSeeker) io.ReadSeeker {
+	f, ok := r.(*os.File)
+	if !ok {
+		return r
+	}
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return r
+	}
+	return &preadFile{File: f, rc: rc}
+}
+
+// ReadAt implements [io.ReaderAt.ReadAt].
+func (f *preadFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+
+	var n int
+	var preadErr error
+	err := f.rc.Control(func(fd uintptr) {
+		for n < len(p) {
+			m, err := syscall.Pread(int(fd), p[n:], off+int64(n))
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			if err != nil {
+				preadErr = err
+				return
+			}
+			if m == 0 {
+				preadErr = io.EOF
+				return
+			}
+			n += m
+		}
+	})
+	if err != nil {
+		return n, fmt.Errorf("pread %s: %w", f.Name(), err)
+	}
+	if preadErr != nil && !errors.Is(preadErr, io.EOF) {
+		return n, fmt.Errorf("pread %s: %w", f.Name(), preadErr)
+	}
+	//nolint:wrapcheck // we must return unwrapped io.EOF for io.ReaderAt
+	return n, preadErr
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithPread(t *testing.T) {
+	t.Parallel()
+
+	data := benchmarkData(100000)
+	prefix := []byte("prefix")
+	path := filepath.Join(t.TempDir(), "test.dz")
+	if err := os.WriteFile(path, append(bytes.Clone(prefix), compressStream(t, data, 1024)...), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	testCases := map[string][]ReaderOption{
+		"positional":  {WithPread()},
+		"concurrency": {WithPread(), WithConcurrency(4)},
+	}
+
+	for name, opts := range testCases {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer f.Close()
+
+			opts = append(opts, WithBaseOffset(int64(len(prefix))))
+			z, err := NewReader(f, opts...)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			for _, off := range []int64{0, 1000, 50000, 99000} {
+				buf := make([]byte, 5000)
+				n, err := z.ReadAt(buf, off)
+				if err != nil && !errors.Is(err, io.EOF) {
+					t.Fatalf("ReadAt(%d): %v", off, err)
+				}
+				if !bytes.Equal(data[off:off+int64(n)], buf[:n]) {
+					t.Errorf("ReadAt(%d): data does not match", off)
+				}
+			}
+
+			got, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if !bytes.Equal(data, got) {
+				t.Errorf("ReadAll: data does not match")
+			}
+		})
+	}
+}
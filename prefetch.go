@@ -0,0 +1,100 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// WithPrefetch configures the [Reader] to read the compressed data of up to
+// chunks consecutive chunks from the underlying reader in a single request
+// and to serve subsequent reads of those chunks from memory. This coalesces
+// the requests made by linear scans which reduces latency for underlying
+// readers with a high cost per request, such as readers backed by HTTP range
+// requests or object storage, at the cost of reading data that may not be
+// used by random access.
+//
+// Prefetching is disabled if chunks is less than 2.
+func WithPrefetch(chunks int) ReaderOption {
+	return func(z *Reader) {
+		z.prefetch = chunks
+	}
+}
+
+// chunkReader returns a reader of the compressed data starting at the chunk
+// chunkNum. Data following the chunk is read from the underlying reader.
+func (z *Reader) chunkReader(chunkNum int64) (io.Reader, error) {
+	if z.prefetch < 2 || chunkNum >= int64(len(z.sizes)) {
+		if _, err := z.r.Seek(z.offsets.at(int(chunkNum)), io.SeekStart); err != nil {
+			return nil, fmt.Errorf("%w: Seek: %w", errDictzip, err)
+		}
+		return z.r, nil
+	}
+
+	if chunkNum < z.prefetchChunk || chunkNum >= z.prefetchChunk+int64(z.prefetchCount) {
+		if err := z.prefetchChunks(chunkNum); err != nil {
+			return nil, err
+		}
+	}
+
+	start := z.offsets.at(int(chunkNum)) - z.offsets.at(int(z.prefetchChunk))
+	if start > int64(len(z.prefetchBuf)) {
+		// NOTE: The prefetched data was short so the chunk is missing.
+		start = int64(len(z.prefetchBuf))
+	}
+
+	// Position the underlying reader after the prefetched data so that reads
+	// past the prefetched chunks continue from there.
+	if _, err := z.r.Seek(z.offsets.at(int(z.prefetchChunk))+int64(len(z.prefetchBuf)), io.SeekStart); err != nil {
+		return nil, fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+
+	return io.MultiReader(bytes.NewReader(z.prefetchBuf[start:]), z.r), nil
+}
+
+// prefetchChunks reads the compressed data of the chunks starting at chunkNum
+// from the underlying reader.
+func (z *Reader) prefetchChunks(chunkNum int64) error {
+	last := chunkNum + int64(z.prefetch)
+	if last > int64(len(z.sizes)) {
+		last = int64(len(z.sizes))
+	}
+	// NOTE: Chunks that are not downloaded are not read.
+	if chunks := int64(z.downloadedChunks()); last > chunks && chunks > chunkNum {
+		last = chunks
+	}
+
+	start, end := z.offsets.at(int(chunkNum)), z.offsets.at(int(last))
+	if _, err := z.r.Seek(start, io.SeekStart); err != nil {
+		return fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+
+	// NOTE: The data may be short if the archive is truncated. Errors are
+	// returned when the missing chunks are inflated.
+	buf := make([]byte, end-start)
+	n, err := io.ReadFull(z.r, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: reading chunks: %w", errDictzip, err)
+	}
+
+	z.prefetchBuf = buf[:n]
+	z.prefetchChunk = chunkNum
+	z.prefetchCount = int(last - chunkNum)
+
+	return nil
+}
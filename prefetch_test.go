@@ -0,0 +1,154 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithPrefetch(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		prefetch int
+		opts     []WriterOption
+	}{
+		"disabled": {
+			prefetch: 0,
+		},
+		"one": {
+			prefetch: 1,
+		},
+		"four": {
+			prefetch: 4,
+		},
+		"more than chunks": {
+			prefetch: 1000,
+		},
+		"shared window": {
+			prefetch: 4,
+			opts:     []WriterOption{WithSharedWindow()},
+		},
+	}
+
+	var data []byte
+	for i := 0; i < 64; i++ {
+		data = append(data, bytes.Repeat([]byte(fmt.Sprintf("chunk %02d;", i)), 10)...)
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriterLevel(&buf, DefaultCompression, 100, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			z, err := NewReader(bytes.NewReader(buf.Bytes()), WithPrefetch(tc.prefetch))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			got, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(data, got); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+
+			// Read ranges in reverse which spans prefetched chunks.
+			for off := len(data) - 150; off >= 0; off -= 250 {
+				p := make([]byte, 150)
+				if _, err := z.ReadAt(p, int64(off)); err != nil {
+					t.Fatalf("ReadAt: %v", err)
+				}
+				if diff := cmp.Diff(data[off:off+150], p); diff != "" {
+					t.Errorf("ReadAt(%d) (-want, +got):\n%s", off, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestWithPrefetch_reads(t *testing.T) {
+	t.Parallel()
+
+	var data []byte
+	for i := 0; i < 64; i++ {
+		data = append(data, bytes.Repeat([]byte(fmt.Sprintf("chunk %02d;", i)), 10)...)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 100)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reads := map[int]int{}
+	for _, prefetch := range []int{0, 16} {
+		r := &countingReadSeeker{ReadSeeker: bytes.NewReader(buf.Bytes())}
+		z, err := NewReader(r, WithPrefetch(prefetch))
+		if err != nil {
+			t.Fatalf("NewReader: %v", err)
+		}
+
+		r.reads = 0
+		for off := 0; off < len(data); off += 100 {
+			p := make([]byte, 100)
+			if len(data)-off < len(p) {
+				p = p[:len(data)-off]
+			}
+			// NOTE: ReadAt may return io.EOF when reading the last chunk.
+			if n, err := z.ReadAt(p, int64(off)); n != len(p) {
+				t.Fatalf("ReadAt: %v", err)
+			}
+		}
+		reads[prefetch] = r.reads
+
+		if err := z.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	// Each 16 chunks should be read in a single request.
+	if diff := cmp.Diff(4, reads[16]); diff != "" {
+		t.Errorf("reads (-want, +got):\n%s", diff)
+	}
+	if reads[0] <= reads[16] {
+		t.Errorf("reads without prefetch: %d, want > %d", reads[0], reads[16])
+	}
+}
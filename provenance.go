@@ -0,0 +1,135 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Producer is the program likely to have produced an archive.
+type Producer int
+
+const (
+	// ProducerUnknown indicates that the producer could not be determined.
+	ProducerUnknown Producer = iota
+
+	// ProducerGo indicates that the archive was likely written by this
+	// package.
+	ProducerGo
+
+	// ProducerDictzip indicates that the archive was likely written by
+	// dictzip(1).
+	ProducerDictzip
+)
+
+// String returns a short name for the producer.
+func (p Producer) String() string {
+	switch p {
+	case ProducerGo:
+		return "go-dictzip"
+	case ProducerDictzip:
+		return "dictzip"
+	case ProducerUnknown:
+		return "unknown"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	// syncMarker is the empty stored block written by a deflate sync flush.
+	syncMarker = []byte{0x00, 0x00, 0xff, 0xff}
+
+	// dictzipFinal is the final deflate data written by dictzip(1), an empty
+	// final fixed Huffman block.
+	dictzipFinal = []byte{0x03, 0x00}
+)
+
+// Provenance records hints about the program that produced an archive. It is
+// intended for diagnosing interoperability differences between archives
+// written by this package and those written by dictzip(1).
+type Provenance struct {
+	// XFL is the XFL (extra flags) header field. dictzip(1) always writes
+	// [XFLSlowest].
+	XFL byte
+
+	// OS is the OS header field.
+	OS byte
+
+	// Subfields are the IDs of the EXTRA subfields in the order they appear
+	// in the header.
+	Subfields [][2]byte
+
+	// FinalData is the deflate data following the last chunk.
+	FinalData []byte
+
+	// SyncMarker indicates that the final deflate data ends with a sync
+	// marker (an empty stored block) as written by this package when built
+	// with some Go versions. dictzip(1) ends the data with an empty fixed
+	// Huffman block.
+	SyncMarker bool
+
+	// Producer is the likely producer of the archive based on the hints
+	// above. It is a best guess and should not be relied upon.
+	Producer Producer
+}
+
+// Provenance returns hints about the program that produced the archive. The
+// final deflate data is read from the underlying reader.
+func (z *Reader) Provenance() (*Provenance, error) {
+	z.lock()
+	defer z.unlock()
+
+	end, err := z.trailerOffset()
+	if err != nil {
+		return nil, err
+	}
+
+	// The final data is between the last chunk and the CRC-32 and ISIZE.
+	start := z.offsets.at(z.offsets.len() - 1)
+	if end < start {
+		return nil, fmt.Errorf("%w: reading final data: %w", errDictzip, io.ErrUnexpectedEOF)
+	}
+	final := make([]byte, end-start)
+	if _, err := z.r.Seek(start, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+	if _, err := io.ReadFull(z.r, final); err != nil {
+		return nil, fmt.Errorf("%w: reading final data: %w", errDictzip, err)
+	}
+
+	p := &Provenance{
+		XFL:        z.XFL,
+		OS:         z.OS,
+		Subfields:  append([][2]byte(nil), z.Subfields...),
+		FinalData:  final,
+		SyncMarker: bytes.HasSuffix(final, syncMarker),
+	}
+
+	switch {
+	case p.SyncMarker, z.sharedWindow, p.OS == OSUnknown:
+		// NOTE: dictzip(1) never writes the shared window subfield and always
+		// sets the OS header.
+		p.Producer = ProducerGo
+	case bytes.Equal(final, dictzipFinal) && p.XFL == XFLSlowest:
+		p.Producer = ProducerDictzip
+	default:
+		p.Producer = ProducerUnknown
+	}
+
+	return p, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReader_Provenance(t *testing.T) {
+	t.Parallel()
+
+	writeData := func(t *testing.T, opts ...WriterOption) []byte {
+		t.Helper()
+
+		var buf bytes.Buffer
+		z, err := NewWriter(&buf, opts...)
+		if err != nil {
+			t.Fatalf("NewWriter: %v", err)
+		}
+		if _, err := z.Write([]byte("Hello World!\n")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := z.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	hello, err := os.ReadFile("internal/testdata/hello.txt.dz")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	testCases := map[string]struct {
+		data []byte
+		want *Provenance
+
+		// ignoreFinal indicates that the final data should not be compared
+		// as it depends on the version of compress/flate.
+		ignoreFinal bool
+	}{
+		"dictzip": {
+			data: hello,
+			want: &Provenance{
+				XFL:        XFLSlowest,
+				OS:         0x3,
+				Subfields:  [][2]byte{{'R', 'A'}},
+				FinalData:  []byte{0x03, 0x00},
+				SyncMarker: false,
+				Producer:   ProducerDictzip,
+			},
+		},
+		"go": {
+			data: writeData(t),
+			want: &Provenance{
+				XFL:       0,
+				OS:        OSUnknown,
+				Subfields: [][2]byte{{'R', 'A'}},
+				Producer:  ProducerGo,
+			},
+			ignoreFinal: true,
+		},
+		"go shared window": {
+			data: writeData(t, WithSharedWindow()),
+			want: &Provenance{
+				XFL:       0,
+				OS:        OSUnknown,
+				Subfields: [][2]byte{{'R', 'A'}, {'R', 'W'}},
+				Producer:  ProducerGo,
+			},
+			ignoreFinal: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(tc.data))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			got, err := z.Provenance()
+			if err != nil {
+				t.Fatalf("Provenance: %v", err)
+			}
+			var opts []cmp.Option
+			if tc.ignoreFinal {
+				opts = append(opts, cmpopts.IgnoreFields(Provenance{}, "FinalData", "SyncMarker"))
+			}
+			if diff := cmp.Diff(tc.want, got, opts...); diff != "" {
+				t.Errorf("Provenance (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -15,16 +15,15 @@
 package dictzip
 
 import (
-	"bytes"
 	"compress/flate"
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"hash"
-	"hash/crc32"
 	"io"
-	"strings"
+	"sync"
 	"time"
+
+	"github.com/ianlewis/go-dictzip/format"
 )
 
 var (
@@ -32,10 +31,24 @@ var (
 	errDictzip = errors.New("dictzip")
 
 	// ErrHeader indicates an error with gzip header data.
-	ErrHeader = fmt.Errorf("%w: invalid header", errDictzip)
+	ErrHeader = format.ErrHeader
+
+	// ErrTrailer indicates an error with the gzip trailer data.
+	ErrTrailer = fmt.Errorf("%w: invalid trailer", errDictzip)
+
+	// ErrSubfieldLength indicates that an EXTRA subfield's length exceeds the
+	// size of the EXTRA area given by XLEN.
+	ErrSubfieldLength = format.ErrSubfieldLength
+
+	// ErrUnsupportedSeek indicates that the whence value passed to Seek is
+	// not one of io.SeekStart, io.SeekCurrent, or io.SeekEnd.
+	ErrUnsupportedSeek = fmt.Errorf("%w: unsupported seek mode", errDictzip)
+
+	// ErrNegativeOffset indicates that an offset passed to a read or seek
+	// method, or the offset resulting from a seek, is negative.
+	ErrNegativeOffset = fmt.Errorf("%w: negative offset", errDictzip)
 
-	errUnsupportedSeek = fmt.Errorf("%w: unsupported seek mode", errDictzip)
-	errNegativeOffset  = fmt.Errorf("%w: negative offset", errDictzip)
+	errDecompressor = fmt.Errorf("%w: decompressor does not implement flate.Resetter", errDictzip)
 )
 
 const (
@@ -93,6 +106,20 @@ const (
 	XFLFastest byte = 0x4
 )
 
+// LevelXFL returns the XFL (extra flags) header value for the given
+// compression level. As with gzip(1), [BestCompression] maps to [XFLSlowest],
+// [BestSpeed] maps to [XFLFastest], and all other levels map to zero.
+func LevelXFL(level int) byte {
+	switch level {
+	case BestCompression:
+		return XFLSlowest
+	case BestSpeed:
+		return XFLFastest
+	default:
+		return 0
+	}
+}
+
 func headerErr(err error) error {
 	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 		return fmt.Errorf("%w: %w", ErrHeader, err)
@@ -111,7 +138,8 @@ type readCloseResetter interface {
 // Header is the gzip file header.
 //
 // Strings must be UTF-8 encoded and may only contain Unicode code points
-// U+0001 through U+00FF, due to limitations of the gzip file format.
+// U+0001 through U+00FF, due to limitations of the gzip file format, unless
+// [WithWriteUTF8] or [WithWriteUTF8Strings] is given.
 type Header struct {
 	// Comment is the COMMENT header field.
 	Comment string
@@ -122,17 +150,60 @@ type Header struct {
 	// ModTime is the MTIME modification time field.
 	ModTime time.Time
 
-	// Name is the NAME header field.
+	// Name is the NAME header field. Directories are removed from the name
+	// when reading, see [Header.RawName], and when writing unless
+	// [WithRawName] is given.
 	Name string
 
 	// OS is the OS header field.
 	OS byte
 
+	// XFL is the XFL (extra flags) header field. When writing, it is
+	// initialized from the compression level by [LevelXFL]. It can be copied
+	// from a [Reader] so that it is preserved when recompressing an archive.
+	XFL byte
+
+	// Subfields are the IDs of the EXTRA subfields in the order they appear,
+	// including the dictzip subfields. When writing, the subfields are
+	// written in this order if it is not nil, as with
+	// [format.Header.Subfields]. It can be copied from a [Reader] so that the
+	// order of the subfields is preserved when recompressing an archive. It
+	// is ignored with [WithDictdCompatible].
+	Subfields [][2]byte
+
 	// chunkSize is the size of uncompressed dictzip chunks.
 	chunkSize int
 
 	// sizes is a list of sizes of the compressed chunks in the file.
 	sizes []int
+
+	// reservedChunks is the number of reserved chunk entries following the
+	// sizes in the RA subfield. See [WithReservedChunkEntries].
+	reservedChunks int
+
+	// sharedWindow indicates that chunks share the deflate window.
+	// See [WithSharedWindow].
+	sharedWindow bool
+
+	// rawName is the NAME header field as read before normalization.
+	rawName string
+
+	// chunkCRCs are the CRC-32 checksums of the uncompressed data of each
+	// chunk. It is nil if the archive does not include chunk checksums.
+	// See [WithChunkChecksums].
+	chunkCRCs []uint32
+
+	// chunkCRCCastagnoli indicates that chunkCRCs use the Castagnoli
+	// polynomial. See [WithCastagnoliChunkChecksums].
+	chunkCRCCastagnoli bool
+
+	// plainGzip indicates that the file is a plain gzip file without the
+	// RA subfield. See [WithGzipFallback].
+	plainGzip bool
+
+	// warnings describe header anomalies that were accepted when reading.
+	// See [WithLenientHeader].
+	warnings []string
 }
 
 // ChunkSize returns the dictzip uncompressed data chunk size.
@@ -145,8 +216,37 @@ func (h *Header) Sizes() []int {
 	return h.sizes
 }
 
+// ChunkCount returns the number of dictzip chunks. All chunks except the last
+// contain ChunkSize bytes of uncompressed data.
+// See [Reader.LastChunkLen].
+func (h *Header) ChunkCount() int {
+	return len(h.sizes)
+}
+
+// ChunkCRCs returns the CRC-32 checksums of the uncompressed data of each
+// chunk using the polynomial returned by [Header.ChunkCRCPolynomial]. It
+// returns nil if the archive does not include chunk checksums.
+// See [WithChunkChecksums].
+func (h *Header) ChunkCRCs() []uint32 {
+	return h.chunkCRCs
+}
+
+// RawName returns the NAME header field as it was read. The Name field
+// contains the name with any directories removed.
+func (h *Header) RawName() string {
+	return h.rawName
+}
+
+// SharedWindow returns true if the archive's chunks share the deflate window.
+// See [WithSharedWindow].
+func (h *Header) SharedWindow() bool {
+	return h.sharedWindow
+}
+
 // Reader implements [io.Reader] and [io.ReaderAt]. It provides random access
 // to the compressed data.
+//
+// A Reader is not safe for concurrent use unless created with [WithLocking].
 type Reader struct {
 	// Header is the gzip header data and is valid after [NewReader] or
 	// [Reader.Reset].
@@ -155,15 +255,234 @@ type Reader struct {
 	r io.ReadSeeker
 	z readCloseResetter
 
+	// ra is r if it implements io.ReaderAt. It is used for positional reads
+	// by ReadAt and pool holds the decompressors used by them.
+	ra   io.ReaderAt
+	pool sync.Pool
+
+	// scratch is the buffer given to ReadAtBuffer. It is nil otherwise.
+	scratch []byte
+
 	// offset is the offset into the uncompressed data.
 	offset int64
 
-	// offsets is a list of offsets to the compressed chunks in the file.
-	offsets []int64
+	// offsets is a table of offsets to the compressed chunks in the file.
+	offsets offsetTable
+
+	// win is the cached deflate window preceding the chunk winChunk. It is
+	// only used if the chunks share the deflate window.
+	win      []byte
+	winChunk int64
+
+	// readCache caches the results of ReadAt. It is nil if caching is
+	// disabled.
+	readCache *lru[readRange]
+
+	// chunkCache caches inflated chunks keyed by chunk number. It is nil if
+	// chunk caching is disabled. See [WithChunkCache].
+	chunkCache *lru[int64]
+
+	// tolerant indicates that malformed non-RA EXTRA subfields are skipped.
+	// See [WithTolerantExtra].
+	tolerant bool
+
+	// strict indicates that headers that do not strictly conform to RFC
+	// 1952 are rejected. See [WithStrictRFC1952].
+	strict bool
+
+	// strictHeader and lenientHeader indicate that header anomalies are
+	// rejected or accepted. See [WithStrictHeader] and [WithLenientHeader].
+	strictHeader  bool
+	lenientHeader bool
+
+	// newDecompressor creates new deflate decompressors.
+	// See [WithDecompressor].
+	newDecompressor func(r io.Reader) io.ReadCloser
+
+	// salvage indicates that the archive is read in salvage mode and
+	// salvaged is the size of the recoverable uncompressed data.
+	// See [WithSalvage].
+	salvage  bool
+	salvaged int64
+
+	// salvageReport describes the chunks that could not be recovered in
+	// salvage mode. See [Reader.SalvageReport].
+	salvageReport *SalvageReport
+
+	// discardedExtra is the number of bytes of malformed EXTRA data skipped
+	// in tolerant mode.
+	discardedExtra int
+
+	// closer is closed when the Reader is closed. It is the file opened by
+	// [OpenFS].
+	closer io.Closer
+
+	// prefetch is the number of chunks read from the underlying reader at
+	// once. prefetchBuf holds the compressed data of the prefetchCount chunks
+	// starting at prefetchChunk.
+	// See [WithPrefetch].
+	prefetch      int
+	prefetchBuf   []byte
+	prefetchChunk int64
+	prefetchCount int
+
+	// lastChunkLen is the cached uncompressed length of the last chunk or -1
+	// if it is not yet known.
+	lastChunkLen int
+
+	// trailerOff is the cached offset of the gzip trailer in the underlying
+	// reader or -1 if it is not yet known.
+	trailerOff int64
+
+	// pread indicates that compressed data is read from files using
+	// positional reads of the file descriptor. See [WithPread].
+	pread bool
+
+	// base is the offset of the dictzip file in the underlying reader. See
+	// [WithBaseOffset].
+	base int64
+
+	// utf8Strings indicates that the NAME and COMMENT fields are decoded as
+	// UTF-8 if valid. See [WithReadUTF8Strings].
+	utf8Strings bool
+
+	// readUTF8 indicates that Name and Comment are read from the UTF-8
+	// EXTRA subfields if present. See [WithReadUTF8].
+	readUTF8 bool
+
+	// downloaded is the number of bytes of the archive that have been
+	// downloaded or -1 if the whole archive is available.
+	// See [WithDownloaded].
+	downloaded int64
+
+	// memoryLimit is the approximate limit on the memory used by buffers
+	// or zero if memory use is not limited. readLimit is the maximum size
+	// of the data read from chunks at once. See [WithMemoryLimit].
+	memoryLimit int
+	readLimit   int
+
+	// concurrency is the maximum number of chunks inflated in parallel and
+	// inflaters are the decompressors used to inflate them.
+	// See [WithConcurrency].
+	concurrency int
+	inflaters   []readCloseResetter
+
+	// exactSize indicates that the size is calculated by inflating the last
+	// chunk rather than from ISIZE. See [WithExactSize].
+	exactSize bool
+
+	// chunkIndex is the chunk map used instead of reading the header. It is
+	// nil if the header is read. See [WithChunkIndex].
+	chunkIndex *ChunkIndex
+
+	// gzipFallback indicates that plain gzip files are accepted. gzipSrc
+	// is the source of the compressed data of a plain gzip file being
+	// inflated and gzipOff is the offset of the uncompressed data inflated
+	// from it. See [WithGzipFallback].
+	gzipFallback bool
+	gzipSrc      *gzipSource
+	gzipOff      int64
+
+	// multistream indicates that files with multiple members are read as
+	// one stream and members are the members of such a file. members is nil
+	// if the file has a single member. See [WithMultistream].
+	multistream bool
+	members     []*member
+
+	// readVerifier computes the CRC-32 of data read sequentially from the
+	// start of the data by Read. It is nil if Read has not read from the
+	// start or the reads were not sequential.
+	readVerifier *dataVerifier
+
+	// locking indicates that methods are serialized by mu.
+	// See [WithLocking].
+	locking bool
+	mu      sync.Mutex
+}
 
-	// digest is the CRC-32 digest (IEEE polynomial).
-	// See RFC-1952 Section 2.3.1.
-	digest hash.Hash32
+// readRange is a range of uncompressed data requested by ReadAt.
+type readRange struct {
+	off  int64
+	size int
+}
+
+// ReaderOption is an option that configures a [Reader].
+type ReaderOption func(*Reader)
+
+// WithReadCache configures the [Reader] to cache the results of
+// [Reader.ReadAt] keyed by the requested offset and size. Subsequent calls to
+// ReadAt for exactly the same range are served from the cache without reading
+// the underlying reader. At most maxBytes bytes of data are cached.
+//
+// This is useful for applications such as dictionary servers which request
+// the same ranges repeatedly.
+func WithReadCache(maxBytes int) ReaderOption {
+	return func(z *Reader) {
+		z.readCache = newLRU[readRange](maxBytes)
+	}
+}
+
+// WithTolerantExtra configures the [Reader] to skip malformed EXTRA
+// subfields rather than returning an error. A subfield whose length exceeds
+// the EXTRA area is discarded along with any data following it in the EXTRA
+// area. The dictzip RA subfield must still be well-formed.
+func WithTolerantExtra() ReaderOption {
+	return func(z *Reader) {
+		z.tolerant = true
+	}
+}
+
+// WithStrictRFC1952 configures the [Reader] to reject headers that do not
+// strictly conform to RFC 1952 with an error wrapping [ErrHeader]. Headers
+// with reserved FLG bits set, XFL values not defined for deflate, or reserved
+// subfield IDs are rejected. This is useful for validating files from
+// untrusted sources. By default, such headers are accepted since they are
+// found in real-world files.
+func WithStrictRFC1952() ReaderOption {
+	return func(z *Reader) {
+		z.strict = true
+	}
+}
+
+// WithDecompressor configures the [Reader] to use deflate decompressors
+// created by newDecompressor rather than [flate.NewReader]. The returned
+// decompressors must also implement [flate.Resetter] otherwise [NewReader]
+// returns an error. This allows other compatible inflate implementations, or
+// instrumented ones, to be used. For example:
+//
+//	z, err := dictzip.NewReader(f, dictzip.WithDecompressor(flate.NewReader))
+func WithDecompressor(newDecompressor func(r io.Reader) io.ReadCloser) ReaderOption {
+	return func(z *Reader) {
+		z.newDecompressor = newDecompressor
+	}
+}
+
+// WithLocking configures the [Reader] to serialize calls to its methods
+// with an internal lock so that a single Reader may be used concurrently by
+// multiple goroutines. Without it, the Reader is not safe for concurrent use
+// since reads share the underlying reader's offset and the inflate state.
+//
+// Calls to [Reader.Read] and [Reader.Seek] from multiple goroutines are safe
+// but share the Reader's offset so concurrent readers should generally use
+// [Reader.ReadAt] instead.
+func WithLocking() ReaderOption {
+	return func(z *Reader) {
+		z.locking = true
+	}
+}
+
+// lock acquires the Reader's lock if locking is enabled.
+func (z *Reader) lock() {
+	if z.locking {
+		z.mu.Lock()
+	}
+}
+
+// unlock releases the Reader's lock if locking is enabled.
+func (z *Reader) unlock() {
+	if z.locking {
+		z.mu.Unlock()
+	}
 }
 
 // NewReader returns a new dictzip [Reader] reading compressed data from the
@@ -176,12 +495,24 @@ type Reader struct {
 //
 // It is the callers responsibility to call [Reader.Close] on the returned
 // [Reader] when done.
-func NewReader(r io.ReadSeeker) (*Reader, error) {
-	fr := flate.NewReader(r)
+func NewReader(r io.ReadSeeker, opts ...ReaderOption) (*Reader, error) {
 	z := &Reader{
-		z: fr.(readCloseResetter),
+		newDecompressor: flate.NewReader,
+		downloaded:      -1,
+	}
+	for _, opt := range opts {
+		opt(z)
+	}
+	z.applyMemoryLimit()
+
+	fr, err := z.decompressor(r)
+	if err != nil {
+		return nil, err
 	}
+	z.z = fr
+
 	if err := z.Reset(r); err != nil {
+		_ = z.Close()
 		return nil, err
 	}
 
@@ -192,21 +523,78 @@ func NewReader(r io.ReadSeeker) (*Reader, error) {
 // returned by NewReader but reading from the r instead.
 //
 // Reset will call Seek on the given reader to ensure that it is being read
-// from the beginning.
+// from the beginning, or from the base offset given by [WithBaseOffset] or a
+// previous call to [Reader.ResetAt].
 func (z *Reader) Reset(r io.ReadSeeker) error {
+	z.lock()
+	defer z.unlock()
+
+	return z.reset(r)
+}
+
+// reset implements [Reader.Reset]. The caller must hold the lock.
+func (z *Reader) reset(r io.ReadSeeker) error {
+	if z.base < 0 {
+		return fmt.Errorf("%w: base offset %d", ErrNegativeOffset, z.base)
+	}
+	if z.pread {
+		r = newPreadReader(r)
+	}
+	if z.base > 0 {
+		r = newBaseReader(r, z.base)
+	}
 	z.r = r
+	z.ra, _ = r.(io.ReaderAt)
 	z.offset = 0
+	z.Header = Header{}
+	z.lastChunkLen = -1
+	z.trailerOff = -1
+	z.gzipSrc = nil
+	z.gzipOff = 0
+	z.readVerifier = nil
+	z.closeMembers()
+	z.resetState()
+	if z.readCache != nil {
+		z.readCache.clear()
+	}
+	if z.chunkCache != nil {
+		z.chunkCache.clear()
+	}
 	if _, err := r.Seek(z.offset, io.SeekStart); err != nil {
 		return fmt.Errorf("%w: Seek: %w", errDictzip, err)
 	}
 
-	// Read the first 10 bytes of the header.
-	_, chunkSize, offsets, err := z.readHeader()
-	if err != nil {
-		return err
+	if z.chunkIndex != nil {
+		// NOTE: The header is not read if the chunk map is given.
+		if err := z.setChunkIndex(z.chunkIndex); err != nil {
+			return err
+		}
+	} else {
+		_, chunkSize, offsets, err := z.readHeader()
+		if err != nil {
+			return err
+		}
+		z.chunkSize = chunkSize
+		z.offsets = offsets
+		if chunkSize == 0 && len(z.sizes) > 0 {
+			// NOTE: A zero CHLEN is only accepted in lenient mode.
+			if err := z.inferChunkSize(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if z.salvage {
+		if err := z.salvageChunks(); err != nil {
+			return err
+		}
+	}
+
+	if z.multistream && !z.plainGzip && z.chunkIndex == nil && !z.salvage && z.downloaded < 0 {
+		if err := z.findMembers(); err != nil {
+			return err
+		}
 	}
-	z.chunkSize = chunkSize
-	z.offsets = offsets
 
 	if err := z.z.Reset(r, nil); err != nil {
 		return fmt.Errorf("%w: Reset: %w", errDictzip, err)
@@ -215,68 +603,270 @@ func (z *Reader) Reset(r io.ReadSeeker) error {
 	return nil
 }
 
-// Close closes the reader. It does not close the underlying io.Reader.
+// Close closes the reader. It does not close the underlying io.Reader unless
+// it was opened by [OpenFS].
 func (z *Reader) Close() error {
+	z.lock()
+	defer z.unlock()
+
+	err := z.z.Close()
+	z.closeMembers()
+	if z.closer != nil {
+		if cErr := z.closer.Close(); err == nil {
+			err = cErr
+		}
+		z.closer = nil
+	}
 	//nolint:wrapcheck // error does not need to be wrapped
-	return z.z.Close()
+	return err
 }
 
-// Read implements [io.Reader].
+// Read implements [io.Reader]. When the data is read sequentially from the
+// start to the end, Read verifies the CRC-32 and ISIZE fields of the gzip
+// trailer and returns an error wrapping [ErrTrailer] instead of io.EOF if
+// they do not match. Use [Reader.Verify] to verify the data without reading
+// it.
 func (z *Reader) Read(p []byte) (int, error) {
-	buf, err := z.readChunk(z.offset, len(p))
-	n := copy(p, buf)
+	z.lock()
+	defer z.unlock()
+
+	if z.readLimit > 0 && len(p) > z.readLimit {
+		p = p[:z.readLimit]
+	}
+	off := z.offset
+	n, err := z.readInto(p, off)
 	z.offset += int64(n)
+	if vErr := z.trackRead(p[:n], off, err); vErr != nil {
+		return n, vErr
+	}
 	return n, err
 }
 
-// ReadAt implements [io.ReaderAt.ReadAt].
+// ReadAt implements [io.ReaderAt.ReadAt]. It returns [ErrNegativeOffset] if
+// off is negative.
+//
+// If the underlying reader implements [io.ReaderAt], ReadAt uses positional
+// reads and a decompressor from a pool rather than the Reader's shared state
+// so that it may be called concurrently by multiple goroutines, including
+// concurrently with other methods except [Reader.Reset], [Reader.ResetAt],
+// and [Reader.Close], which must not be called until the reads return. This
+// is not done if the [WithSharedWindow] writer option was used or if the
+// [WithReadCache], [WithChunkCache], [WithPrefetch], [WithConcurrency],
+// [WithMemoryLimit], [WithSalvage], or [WithDownloaded] reader options are
+// given. Use [WithLocking] in that case.
 func (z *Reader) ReadAt(p []byte, off int64) (int, error) {
-	buf, err := z.readChunk(off, len(p))
-	return copy(p, buf), err
+	if ra, ok := z.positional(); ok {
+		return z.readAtPositional(ra, p, off)
+	}
+
+	z.lock()
+	defer z.unlock()
+
+	return z.readAt(p, off)
 }
 
-// Seek implements [io.Seeker.Seek].
+// readAt implements ReadAt. The caller must hold the lock.
+func (z *Reader) readAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+
+	key := readRange{off: off, size: len(p)}
+	if z.readCache != nil {
+		if buf, ok := z.readCache.get(key); ok {
+			return copy(p, buf), nil
+		}
+	}
+
+	n, err := z.readInto(p, off)
+
+	// Only cache complete reads.
+	if z.readCache != nil && err == nil && n == len(p) {
+		z.readCache.add(key, append([]byte(nil), p[:n]...))
+	}
+
+	return n, err
+}
+
+// Seek implements [io.Seeker.Seek]. Seek returns an error wrapping
+// [ErrUnsupportedSeek] for an invalid whence and [ErrNegativeOffset] if the
+// resulting offset would be negative.
 func (z *Reader) Seek(offset int64, whence int) (int64, error) {
+	z.lock()
+	defer z.unlock()
+
 	var err error
 
 	switch whence {
 	case io.SeekStart:
 		if offset < 0 {
-			err = errNegativeOffset
+			err = ErrNegativeOffset
 		} else {
 			z.offset = offset
 		}
 	case io.SeekCurrent:
 		newOffset := z.offset + offset
 		if newOffset < 0 {
-			err = errNegativeOffset
+			err = ErrNegativeOffset
 		} else {
 			z.offset = newOffset
 		}
 	default:
-		err = fmt.Errorf("%w: %v", errUnsupportedSeek, whence)
+		err = fmt.Errorf("%w: %v", ErrUnsupportedSeek, whence)
 	}
 
 	return z.offset, err
 }
 
+// Size returns the size of the uncompressed data. It is calculated from the
+// number of chunks and the ISIZE field of the gzip trailer which is read from
+// the underlying reader. The trailer is found by inflating the last chunk so
+// that data following the gzip member is ignored. If [WithExactSize] was
+// given, the last chunk is inflated to find its size instead of reading ISIZE.
+func (z *Reader) Size() (int64, error) {
+	z.lock()
+	defer z.unlock()
+
+	if z.plainGzip {
+		return z.gzipSize()
+	}
+
+	if z.members != nil {
+		return z.membersSize(), nil
+	}
+
+	if z.salvage {
+		return z.salvaged, nil
+	}
+
+	chunkCount := int64(len(z.sizes))
+	if chunkCount == 0 {
+		return 0, nil
+	}
+
+	if z.downloaded >= 0 || z.exactSize {
+		// NOTE: The trailer may not be downloaded or ISIZE may not be
+		// trusted so the size of the last chunk is found by inflating it.
+		if z.downloaded >= 0 && z.downloadedChunks() < len(z.sizes) {
+			return 0, fmt.Errorf("%w: size", ErrNotDownloaded)
+		}
+		lastLen, err := z.lastChunk()
+		if err != nil {
+			return 0, err
+		}
+		return (chunkCount-1)*int64(z.chunkSize) + int64(lastLen), nil
+	}
+
+	trailer, err := z.readTrailer()
+	if err != nil {
+		return 0, err
+	}
+	isize := binary.LittleEndian.Uint32(trailer[4:])
+
+	// ISIZE is the size modulo 2^32 so only use it to determine the size of
+	// the last chunk.
+	fullSize := (chunkCount - 1) * int64(z.chunkSize)
+	//nolint:gosec // intentionally calculated modulo 2^32.
+	lastLen := int64(isize - uint32(fullSize))
+	if lastLen == 0 || lastLen > int64(z.chunkSize) {
+		return 0, fmt.Errorf("%w: ISIZE %d does not match chunks", ErrTrailer, isize)
+	}
+
+	return fullSize + lastLen, nil
+}
+
+// LastChunkLen returns the length of the uncompressed data in the last chunk.
+// The last chunk is inflated the first time LastChunkLen is called and the
+// result is cached. Together with [Header.ChunkSize] and [Header.ChunkCount]
+// it allows the offset of any uncompressed data to be calculated exactly.
+//
+// LastChunkLen returns zero if the archive has no chunks. In salvage mode it
+// returns the length of the recoverable data in the last chunk.
+func (z *Reader) LastChunkLen() (int, error) {
+	z.lock()
+	defer z.unlock()
+
+	return z.lastChunk()
+}
+
+// Offsets returns the offsets of the dictzip chunks in the compressed file.
+// It returns one more offset than the number of chunks where the last offset
+// is the end of the last chunk, so that chunk i is stored at
+// [Offsets()[i], Offsets()[i+1]). With [WithMultistream] the offsets are those
+// of the chunks of the first member, counted by [Header.ChunkCount], unlike
+// [Reader.ChunkRange]. See [Reader.Members].
+func (z *Reader) Offsets() []int64 {
+	z.lock()
+	defer z.unlock()
+
+	return z.offsets.slice()
+}
+
+// lastChunk implements LastChunkLen. The caller must hold the lock.
+func (z *Reader) lastChunk() (int, error) {
+	if z.lastChunkLen >= 0 {
+		return z.lastChunkLen, nil
+	}
+
+	chunkCount := len(z.sizes)
+	if chunkCount == 0 {
+		z.lastChunkLen = 0
+		return 0, nil
+	}
+
+	buf, err := z.readChunk(int64(chunkCount-1)*int64(z.chunkSize), z.chunkSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	z.lastChunkLen = len(buf)
+
+	return z.lastChunkLen, nil
+}
+
+// decompressor returns a new deflate decompressor reading from r.
+func (z *Reader) decompressor(r io.Reader) (readCloseResetter, error) {
+	fr, ok := z.newDecompressor(r).(readCloseResetter)
+	if !ok {
+		return nil, errDecompressor
+	}
+	return fr, nil
+}
+
 // readChunk reads and decompresses data of size at offset. It returns the
 // number of bytes advanced in the underlying reader and bytes read.
-func (z *Reader) readChunk(offset int64, size int) ([]byte, error) {
+//
+// If an error other than io.EOF occurs, cached decompression state is
+// discarded so that subsequent reads do not depend on state from the failed
+// read.
+func (z *Reader) readChunk(offset int64, size int) (b []byte, err error) {
+	defer func() {
+		if err != nil && !errors.Is(err, io.EOF) {
+			z.resetState()
+		}
+	}()
+
 	chunkNum := offset / int64(z.chunkSize)
-	if chunkNum >= int64(len(z.offsets)) {
+	if chunkNum >= int64(z.offsets.len()) {
 		// NOTE: We are trying to seek past the end of the file.
 		return nil, io.EOF
 	}
-	chunkOffset := z.offsets[chunkNum]
 
-	if _, err := z.r.Seek(chunkOffset, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("Seek: %w", err)
+	var dict []byte
+	if z.sharedWindow {
+		dict, err = z.window(chunkNum)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cr, err := z.chunkReader(chunkNum)
+	if err != nil {
+		return nil, err
 	}
 
 	// Reset the flate.Reader
-	if err := z.z.Reset(z.r, nil); err != nil {
-		return nil, fmt.Errorf("Reset: %w", err)
+	if err = z.z.Reset(cr, dict); err != nil {
+		return nil, fmt.Errorf("%w: Reset: %w", errDictzip, err)
 	}
 
 	// The offset into the file at the start of the chunk.
@@ -289,9 +879,46 @@ func (z *Reader) readChunk(offset int64, size int) ([]byte, error) {
 	readStart := (offset - chunkFileOffset)
 	chunkReadSize := int64size + readStart
 
-	buf := make([]byte, chunkReadSize)
+	// In salvage mode reads are limited to the recoverable data since the
+	// data following it may be missing or corrupt.
+	var truncated bool
+	if z.salvage {
+		if avail := z.salvaged - chunkFileOffset; chunkReadSize > avail {
+			if readStart >= avail {
+				return nil, io.EOF
+			}
+			chunkReadSize = avail
+			truncated = true
+		}
+	}
+
+	// Reads of partially downloaded archives are limited to the downloaded
+	// chunks.
+	var notDownloaded bool
+	if avail := z.downloadedData(); avail >= 0 {
+		if avail -= chunkFileOffset; chunkReadSize > avail {
+			if readStart >= avail {
+				return nil, ErrNotDownloaded
+			}
+			chunkReadSize = avail
+			notDownloaded = true
+		}
+	}
+
+	// NOTE: If memory use is limited, the data preceding the read in the
+	// chunk is discarded rather than buffered.
+	if z.memoryLimit > 0 && readStart > 0 {
+		n, err := io.CopyN(io.Discard, z.z, readStart)
+		if n < readStart {
+			//nolint:wrapcheck // we must return unwrapped io.EOF for io.Reader
+			return nil, err
+		}
+		chunkReadSize -= readStart
+		readStart = 0
+	}
+
+	buf := z.chunkBuffer(chunkReadSize)
 	totalRead := int64(0)
-	var err error
 
 	// Attempt to read the full amount requested.
 	// NOTE: It seems that the flate.Reader may read less than the given buffer
@@ -309,10 +936,66 @@ func (z *Reader) readChunk(offset int64, size int) ([]byte, error) {
 		return nil, err
 	}
 
+	if truncated && err == nil {
+		err = io.EOF
+	}
+	if notDownloaded && err == nil {
+		err = ErrNotDownloaded
+	}
+
 	//nolint:wrapcheck // we must return unwrapped io.EOF for io.Reader
 	return buf[readStart:totalRead], err
 }
 
+// resetState discards cached decompression state. It is called after a failed
+// read since the state may be inconsistent with the underlying reader.
+func (z *Reader) resetState() {
+	z.win = nil
+	z.winChunk = 0
+	z.prefetchBuf = nil
+	z.prefetchChunk = 0
+	z.prefetchCount = 0
+}
+
+// window returns the uncompressed data preceding the chunk chunkNum that is
+// used as the deflate dictionary when chunks share the deflate window.
+// Preceding chunks are inflated as necessary. The last window is cached so
+// that sequential reads only need to inflate the previous chunk.
+func (z *Reader) window(chunkNum int64) ([]byte, error) {
+	start, win := int64(0), []byte(nil)
+	if z.winChunk <= chunkNum {
+		start, win = z.winChunk, z.win
+	}
+
+	buf := make([]byte, z.chunkSize)
+	for i := start; i < chunkNum; i++ {
+		cr, err := z.chunkReader(i)
+		if err != nil {
+			return nil, err
+		}
+		if err := z.z.Reset(cr, win); err != nil {
+			return nil, fmt.Errorf("%w: Reset: %w", errDictzip, err)
+		}
+
+		n, err := io.ReadFull(z.z, buf)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			//nolint:wrapcheck // we must return unwrapped io.EOF for io.Reader
+			return nil, err
+		}
+
+		// NOTE: Allocate a new slice since the previous window may be in use.
+		win = append(append([]byte{}, win...), buf[:n]...)
+		if len(win) > windowSize {
+			win = win[len(win)-windowSize:]
+		}
+	}
+
+	z.win = win
+	z.winChunk = chunkNum
+
+	return win, nil
+}
+
 // gzip Header Values
 //nolint:godot // diagram
 /*
@@ -322,23 +1005,44 @@ func (z *Reader) readChunk(offset int64, size int) ([]byte, error) {
 */
 const (
 	// hdrGzipID1 is the gzip header value for ID1
-	hdrGzipID1 byte = 0x1f
+	hdrGzipID1 = format.ID1
 
 	// hdrGzipID2 is the gzip header value for ID2
-	hdrGzipID2 byte = 0x8b
+	hdrGzipID2 = format.ID2
 
 	// hdrDeflateCM is the deflate CM (Compression method).
-	hdrDeflateCM byte = 0x08
+	hdrDeflateCM = format.CMDeflate
 )
 
 const (
 	// hdrDictzipSI1 is the dictzip random access subfield ID value SI1.
-	hdrDictzipSI1 = byte('R')
+	hdrDictzipSI1 = format.RASI1
 
 	// hdrDictzipSI2 is the dictzip random access subfield ID value SI2.
-	hdrDictzipSI2 = byte('A')
+	hdrDictzipSI2 = format.RASI2
+
+	// hdrWindowSI1 is the shared window subfield ID value SI1.
+	hdrWindowSI1 = format.WindowSI1
+
+	// hdrWindowSI2 is the shared window subfield ID value SI2.
+	hdrWindowSI2 = format.WindowSI2
 )
 
+// raVersion is the version of the random access subfield data that is read
+// and written.
+const raVersion = format.RAVersion
+
+// raVersion2 is the version of the extended random access subfield data with
+// 32-bit sizes. It is read but not written.
+const raVersion2 = format.RAVersion2
+
+// headerReadSize is the size of the data initially read when reading the
+// header. The size read is doubled until the full header is read.
+const headerReadSize = 512
+
+// windowSize is the size of the deflate (LZ77) window.
+const windowSize = 1 << 15
+
 // FLG (Flags).
 // bit 0 : FTEXT (ignored).
 // bit 1 : FHCRC.
@@ -355,253 +1059,97 @@ const (
 	flgCOMMENT = byte(1 << 4)
 )
 
-// readFlg reads and validates the gzip header, and returns the FLG byte.
-func (z *Reader) readFlg() (int, byte, error) {
-	head := make([]byte, 10)
-	n, err := io.ReadFull(z.r, head)
+// readHeader reads the gzip header for dictzip specific headers and returns
+// offsets and blocksize used for random access. The header is parsed by
+// [format.ParseWithOptions] and is read from z.r until it is complete.
+func (z *Reader) readHeader() (int64, int, offsetTable, error) {
+	// NOTE: The header may be shorter than the data read. z.r is seeked
+	// before reading chunks so reading past the header is not a problem.
+	h, _, hdrLen, err := readHeaderFrom(z.r, z.parseOptions(), z.memoryLimit)
 	if err != nil {
-		return n, 0, headerErr(fmt.Errorf("reading header: %w", err))
-	}
-
-	if head[0] != hdrGzipID1 || head[1] != hdrGzipID2 {
-		return n, head[3], fmt.Errorf("%w: ID1,ID2: %x", ErrHeader, head[0:2])
-	}
-
-	if head[2] != hdrDeflateCM {
-		return n, head[3], fmt.Errorf("%w: CM: %x", ErrHeader, head[2])
-	}
-
-	// NOTE: The zero value for MTIME means that the modified time is not set.
-	if mtime := binary.LittleEndian.Uint32(head[4:8]); mtime > 0 {
-		z.Header.ModTime = time.Unix(int64(mtime), 0)
+		return int64(hdrLen), 0, offsetTable{}, err
 	}
+	z.setHeader(h)
 
-	// NOTE: XFL (head[8]) is ignored.
-
-	z.Header.OS = head[9]
-
-	z.digest = crc32.NewIEEE()
-
-	return n, head[3], nil
+	return int64(hdrLen), h.ChunkSize, chunkOffsets(int64(hdrLen), h.Sizes), nil
 }
 
-// readExtra parses the EXTRA header. It returns dictzip chunk size before
-// compression (before compression all chunks have equal size), and a list of
-// chunk sizes after compression.
-func (z *Reader) readExtra() (int, int, []int, error) {
-	var totalRead int
-
-	// FEXTRA
-	buf := make([]byte, 2)
-	n, err := io.ReadFull(z.r, buf)
-	totalRead += n
-	if err != nil {
-		return totalRead, 0, nil, headerErr(fmt.Errorf("EXTRA XLEN: %w", err))
-	}
-	xlen := binary.LittleEndian.Uint16(buf)
-	z.digest.Write(buf)
-
-	extra := make([]byte, xlen)
-	n, err = io.ReadFull(z.r, extra)
-	totalRead += n
-	if err != nil {
-		return totalRead, 0, nil, headerErr(fmt.Errorf("reading EXTRA: %w", err))
-	}
-	z.digest.Write(extra)
-
-	// NOTE: The EXTRA field could could contain multiple sub-fields.
-	var chunkSize int
-	var sizes []int
-
-	er := bytes.NewReader(extra)
-	var foundRAField bool
-	for er.Len() > 0 {
-		// Read SI1, SI2, and LEN
-		buf = make([]byte, 4)
-		_, err = io.ReadFull(er, buf)
-		if err != nil {
-			return totalRead, 0, nil, headerErr(fmt.Errorf("reading EXTRA: %w", err))
-		}
-
-		si1 := buf[0]
-		si2 := buf[1]
-		extraLen := binary.LittleEndian.Uint16(buf[2:])
-
-		// Read the subfield data.
-		extraBuf := make([]byte, extraLen)
-		_, err = io.ReadFull(er, extraBuf)
-		if err != nil {
-			return totalRead, 0, nil, headerErr(fmt.Errorf("reading EXTRA: %w", err))
-		}
-
-		// This is the dictzip 'R'andom 'A'ccess data field.
-		if si1 == hdrDictzipSI1 && si2 == hdrDictzipSI2 {
-			var err error
-			chunkSize, sizes, err = readExtraSizes(bytes.NewReader(extraBuf))
-			if err != nil {
-				return totalRead, 0, nil, err
-			}
-			foundRAField = true
-		} else {
-			// Append the non-RA extra data field.
-			z.Extra = append(z.Extra, buf...)
-			z.Extra = append(z.Extra, extraBuf...)
-		}
-	}
-
-	if !foundRAField {
-		return totalRead, 0, nil, fmt.Errorf("%w: no RA EXTRA field", ErrHeader)
+// parseOptions returns the header parsing options for the reader options.
+func (z *Reader) parseOptions() format.ParseOptions {
+	return format.ParseOptions{
+		TolerantExtra: z.tolerant,
+		AllowGzip:     z.gzipFallback,
+		StrictRFC1952: z.strict,
+		StrictHeader:  z.strictHeader,
+		LenientHeader: z.lenientHeader,
+		UTF8Strings:   z.utf8Strings,
 	}
-
-	return totalRead, chunkSize, sizes, nil
 }
 
-// readExtraSizes reads the dictzip uncompressed chunk size and compressed
-// chunk sizes from the EXTRA field data.
-func readExtraSizes(r io.Reader) (int, []int, error) {
+// readHeaderFrom reads and parses the dictzip header at the start of r. It
+// returns the header, the data read from r, which may extend past the end of
+// the header, and the length of the header. If memoryLimit is greater than
+// zero, at most memoryLimit bytes are read.
+func readHeaderFrom(r io.Reader, opts format.ParseOptions, memoryLimit int) (*format.Header, []byte, int, error) {
 	var buf []byte
-
-	// Read VER
-	buf = make([]byte, 2)
-	_, err := io.ReadFull(r, buf)
-	if err != nil {
-		return 0, nil, headerErr(fmt.Errorf("VER: %w", err))
-	}
-	ver := binary.LittleEndian.Uint16(buf)
-
-	if ver != 1 {
-		return 0, nil, fmt.Errorf("%w: unsupported version: %d", ErrHeader, ver)
-	}
-
-	// Read CHLEN
-	buf = make([]byte, 2)
-	_, err = io.ReadFull(r, buf)
-	if err != nil {
-		return 0, nil, headerErr(fmt.Errorf("CHLEN: %w", err))
-	}
-	chlen := binary.LittleEndian.Uint16(buf)
-
-	// Read CHCNT
-	buf = make([]byte, 2)
-	_, err = io.ReadFull(r, buf)
-	if err != nil {
-		return 0, nil, headerErr(fmt.Errorf("CHCNT: %w", err))
-	}
-	chcnt := binary.LittleEndian.Uint16(buf)
-
-	// Read Sizes
-	var sizes []int
-	for i := 0; i < int(chcnt); i++ {
-		buf = make([]byte, 2)
-		_, err = io.ReadFull(r, buf)
-		if err != nil {
-			return 0, nil, headerErr(fmt.Errorf("chunk sizes: %w", err))
+	readSize := headerReadSize
+	for {
+		if memoryLimit > 0 && len(buf)+readSize > memoryLimit {
+			readSize = memoryLimit - len(buf)
+			if readSize <= 0 {
+				return nil, buf, len(buf), fmt.Errorf("%w: header larger than %d bytes", ErrMemoryLimit, memoryLimit)
+			}
 		}
-		sizes = append(sizes, int(binary.LittleEndian.Uint16(buf)))
-	}
 
-	return int(chlen), sizes, nil
-}
-
-// readString reads a null terminated string from z.r.
-func (z *Reader) readString() (int64, string, error) {
-	var totalRead int64
-	var b strings.Builder
-
-	strBuf := make([]byte, 512)
-	buf := make([]byte, 1)
-	for i := 0; ; i++ {
-		if i >= len(strBuf) {
-			return totalRead, b.String(), fmt.Errorf("%w: string header len exceeded", ErrHeader)
+		chunk := make([]byte, readSize)
+		n, err := io.ReadFull(r, chunk)
+		buf = append(buf, chunk[:n]...)
+		eof := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !eof {
+			return nil, buf, len(buf), headerErr(fmt.Errorf("reading header: %w", err))
 		}
 
-		n, err := io.ReadFull(z.r, buf)
-		totalRead += int64(n)
+		h, hdrLen, err := format.ParseWithOptions(buf, opts)
 		if err != nil {
-			return totalRead, "", headerErr(fmt.Errorf("string header: %w", err))
-		}
-		strBuf[i] = buf[0]
-
-		if buf[0] == 0 {
-			// NOTE: The CRC digest includes the zero byte null terminator.
-			z.digest.Write(strBuf[:i+1])
-
-			// Strings are ISO 8859-1, Latin-1 (RFC 1952, section 2.3.1).
-			s := make([]rune, 0, i)
-			for _, v := range strBuf[:i] {
-				s = append(s, rune(v))
+			if !eof && errors.Is(err, io.ErrUnexpectedEOF) {
+				// The header is incomplete so read more data.
+				readSize = len(buf)
+				continue
 			}
-			return totalRead, string(s), nil
+			//nolint:wrapcheck // errors from the format package are dictzip errors.
+			return nil, buf, hdrLen, err
 		}
+		return h, buf, hdrLen, nil
 	}
 }
 
-// readHeader reads the gzip header for dictzip specific headers and returns
-// offsets and blocksize used for random access.
-func (z *Reader) readHeader() (int64, int, []int64, error) {
-	var chunkSize int
-	var sizes []int
-	var startOffset int64
-
-	n, flg, err := z.readFlg()
-	startOffset += int64(n)
-	if err != nil {
-		return startOffset, 0, nil, err
-	}
-
-	if flg&flgEXTRA == 0 {
-		return startOffset, 0, nil, fmt.Errorf("%w: no EXTRA field", ErrHeader)
-	}
-
-	// Read the EXTRA field
-	n, chunkSize, sizes, err = z.readExtra()
-	startOffset += int64(n)
-	if err != nil {
-		return startOffset, 0, nil, err
-	}
-	z.sizes = sizes
-
-	// Read the NAME field.
-	if flg&flgNAME != 0 {
-		n, fname, err := z.readString()
-		startOffset += n
-		if err != nil {
-			return startOffset, 0, nil, err
-		}
-		z.Name = fname
-	}
-
-	// Read the COMMENT field.
-	if flg&flgCOMMENT != 0 {
-		n, fcomment, err := z.readString()
-		startOffset += n
-		if err != nil {
-			return startOffset, 0, nil, err
-		}
-		z.Comment = fcomment
-	}
-
-	// Perform a CRC check.
-	if flg&flgCRC != 0 {
-		buf := make([]byte, 2)
-		n, err := io.ReadFull(z.r, buf)
-		startOffset += int64(n)
-		if err != nil {
-			return startOffset, 0, nil, headerErr(fmt.Errorf("CRC-16: %w", err))
+// setHeader sets the header fields from the parsed header h.
+func (z *Reader) setHeader(h *format.Header) {
+	z.ModTime = h.ModTime
+	z.XFL = h.XFL
+	z.OS = h.OS
+	z.Extra = h.Extra
+	z.Name = normalizeName(h.Name)
+	z.rawName = h.Name
+	z.Comment = h.Comment
+	if z.readUTF8 {
+		var name, comment string
+		z.Extra, name, comment = splitUTF8Subfields(z.Extra)
+		if name != "" {
+			z.Name = normalizeName(name)
+			z.rawName = name
 		}
-		digest := binary.LittleEndian.Uint16(buf)
-		//nolint:gosec // we intentionally take the two lowest order bits of the CRC digest.
-		if digest != uint16(z.digest.Sum32()) {
-			return startOffset, 0, nil, fmt.Errorf("%w: bad CRC-16 digest", ErrHeader)
+		if comment != "" {
+			z.Comment = comment
 		}
 	}
-
-	// Calculate the dictzip offsets.
-	offsets := make([]int64, len(sizes)+1)
-	offsets[0] = startOffset
-	for i := 0; i < len(sizes); i++ {
-		offsets[i+1] = offsets[i] + int64(sizes[i])
-	}
-
-	return startOffset, chunkSize, offsets, nil
+	z.sizes = h.Sizes
+	z.reservedChunks = h.ReservedChunks
+	z.sharedWindow = h.SharedWindow
+	z.chunkCRCs = h.ChunkCRCs
+	z.chunkCRCCastagnoli = h.ChunkCRCCastagnoli
+	z.Subfields = h.Subfields
+	z.discardedExtra = h.DiscardedExtra
+	z.plainGzip = h.Gzip
+	z.warnings = h.Warnings
 }
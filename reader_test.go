@@ -16,12 +16,21 @@ package dictzip
 
 import (
 	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
 	"io"
+	"math"
+	"math/rand"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip/format"
 )
 
 func TestReader(t *testing.T) {
@@ -33,6 +42,7 @@ func TestReader(t *testing.T) {
 
 		fname     string
 		fcomment  string
+		xfl       byte
 		os        byte
 		extra     []byte
 		chunkSize int
@@ -74,6 +84,7 @@ func TestReader(t *testing.T) {
 
 			fname:     "empty.txt",
 			bytes:     []byte{},
+			xfl:       XFLSlowest,
 			os:        0x3,
 			chunkSize: 58315,
 			offsets:   []int64{32},
@@ -108,10 +119,44 @@ func TestReader(t *testing.T) {
 			},
 			fcomment:  "fcomment.txt",
 			bytes:     []byte{},
+			xfl:       XFLSlowest,
 			os:        0x3,
 			chunkSize: 58315,
 			offsets:   []int64{35},
 		},
+		{
+			name:   "subfield length exceeds extra",
+			data:   subfieldLengthData(),
+			newErr: ErrSubfieldLength,
+		},
+		{
+			name: "subfield header exceeds extra",
+			data: []byte{
+				// Header
+				hdrGzipID1,
+				hdrGzipID2,
+				hdrDeflateCM,
+				flgEXTRA,               // FLG
+				0x00, 0x00, 0x00, 0x00, // MTIME
+				0x0,       // XFL
+				OSUnknown, // OS
+
+				// EXTRA
+				0xc, 0x0, // XLEN // 12
+				0x52, 0x41, // 'R', 'A'
+				0x6, 0x0, // LEN // 6
+				0x1, 0x0, // VER // 1
+				0xcb, 0xe3, // CHLEN // 58315
+				0x0, 0x0, // CHCNT // 0
+				0x58, 0x59, // 'X', 'Y' (truncated)
+
+				0x3, 0x0, 0x0, // Empty deflate data.
+
+				0x0, 0x0, 0x0, 0x0, // CRC32
+				0x0, 0x0, 0x0, 0x0, // ISIZE
+			},
+			newErr: ErrSubfieldLength,
+		},
 		{
 			name: "with extra",
 			data: []byte{
@@ -181,6 +226,7 @@ func TestReader(t *testing.T) {
 				0x0, 0x0, 0x0, 0x0, // ISIZE
 			},
 			bytes:     []byte{},
+			xfl:       XFLSlowest,
 			os:        0x3,
 			chunkSize: 58315,
 			offsets:   []int64{24},
@@ -284,6 +330,10 @@ func TestReader(t *testing.T) {
 				t.Errorf("Name (-want, +got):\n%s", diff)
 			}
 
+			if diff := cmp.Diff(tc.xfl, z.XFL); diff != "" {
+				t.Errorf("XFL (-want, +got):\n%s", diff)
+			}
+
 			if diff := cmp.Diff(tc.os, z.OS); diff != "" {
 				t.Errorf("OS (-want, +got):\n%s", diff)
 			}
@@ -296,7 +346,7 @@ func TestReader(t *testing.T) {
 				t.Errorf("ChunkSize (-want, +got):\n%s", diff)
 			}
 
-			if diff := cmp.Diff(tc.offsets, z.offsets); diff != "" {
+			if diff := cmp.Diff(tc.offsets, z.Offsets()); diff != "" {
 				t.Errorf("Offsets (-want, +got):\n%s", diff)
 			}
 
@@ -678,7 +728,7 @@ func TestReader_Seek_SeekStart_negative(t *testing.T) {
 		t.Errorf("r.offset (-want, +got):\n%s", diff)
 	}
 
-	if diff := cmp.Diff(errNegativeOffset, err, cmpopts.EquateErrors()); diff != "" {
+	if diff := cmp.Diff(ErrNegativeOffset, err, cmpopts.EquateErrors()); diff != "" {
 		t.Errorf("Seek (-want, +got):\n%s", diff)
 	}
 }
@@ -711,7 +761,7 @@ func TestReader_Seek_SeekCurrent_negative(t *testing.T) {
 		t.Errorf("r.offset (-want, +got):\n%s", diff)
 	}
 
-	if diff := cmp.Diff(errNegativeOffset, err, cmpopts.EquateErrors()); diff != "" {
+	if diff := cmp.Diff(ErrNegativeOffset, err, cmpopts.EquateErrors()); diff != "" {
 		t.Errorf("Seek (-want, +got):\n%s", diff)
 	}
 }
@@ -745,7 +795,7 @@ func TestReader_Seek_SeekEnd(t *testing.T) {
 		t.Errorf("r.offset (-want, +got):\n%s", diff)
 	}
 
-	if diff := cmp.Diff(errUnsupportedSeek, err, cmpopts.EquateErrors()); diff != "" {
+	if diff := cmp.Diff(ErrUnsupportedSeek, err, cmpopts.EquateErrors()); diff != "" {
 		t.Errorf("Seek (-want, +got):\n%s", diff)
 	}
 }
@@ -788,3 +838,740 @@ func TestReader_ReadAt(t *testing.T) {
 		t.Fatalf("r.offset (-want, +got):\n%s", diff)
 	}
 }
+
+func TestLevelXFL(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[int]byte{
+		HuffmanOnly:        0,
+		DefaultCompression: 0,
+		NoCompression:      0,
+		BestSpeed:          XFLFastest,
+		2:                  0,
+		8:                  0,
+		BestCompression:    XFLSlowest,
+	}
+
+	for level, want := range testCases {
+		if diff := cmp.Diff(want, LevelXFL(level)); diff != "" {
+			t.Errorf("LevelXFL(%d) (-want, +got):\n%s", level, diff)
+		}
+	}
+}
+
+// countingReadSeeker counts the number of calls to Read.
+type countingReadSeeker struct {
+	io.ReadSeeker
+	reads int
+}
+
+func (r *countingReadSeeker) Read(p []byte) (int, error) {
+	r.reads++
+	//nolint:wrapcheck // error does not need to be wrapped
+	return r.ReadSeeker.Read(p)
+}
+
+func TestReader_ReadAt_readCache(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("internal/testdata/test.txt.dz")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	cr := &countingReadSeeker{ReadSeeker: f}
+	r, err := NewReader(cr, WithReadCache(1024))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	for i := 0; i < 3; i++ {
+		buf := make([]byte, 10)
+		n, err := r.ReadAt(buf, 124)
+		if err != nil {
+			t.Fatalf("ReadAt: %v", err)
+		}
+
+		if diff := cmp.Diff([]byte("0123456789"), buf[:n]); diff != "" {
+			t.Fatalf("ReadAt (-want, +got):\n%s", diff)
+		}
+	}
+
+	// Reads of the same range should be served from the cache.
+	reads := cr.reads
+	buf := make([]byte, 10)
+	if _, err := r.ReadAt(buf, 124); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if diff := cmp.Diff(reads, cr.reads); diff != "" {
+		t.Errorf("reads (-want, +got):\n%s", diff)
+	}
+
+	// A different range is not served from the cache.
+	if _, err := r.ReadAt(buf, 125); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if cr.reads == reads {
+		t.Errorf("ReadAt: want underlying reader read")
+	}
+	if diff := cmp.Diff([]byte("123456789a"), buf); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+}
+
+// faultyReadSeeker is an io.ReadSeeker that returns errors from Seek or Read
+// when enabled.
+type faultyReadSeeker struct {
+	io.ReadSeeker
+	failSeek bool
+	failRead bool
+}
+
+func (r *faultyReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if r.failSeek {
+		return 0, errTestRead
+	}
+	//nolint:wrapcheck // error does not need to be wrapped
+	return r.ReadSeeker.Seek(offset, whence)
+}
+
+func (r *faultyReadSeeker) Read(p []byte) (int, error) {
+	if r.failRead {
+		return 0, errTestRead
+	}
+	//nolint:wrapcheck // error does not need to be wrapped
+	return r.ReadSeeker.Read(p)
+}
+
+func TestReader_ReadAt_transientError(t *testing.T) {
+	t.Parallel()
+
+	var data []byte
+	for i := 0; len(data) < 4096; i++ {
+		data = append(data, []byte(fmt.Sprintf("entry %d\n", i))...)
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []WriterOption
+		failSeek bool
+		failRead bool
+	}{
+		{
+			name:     "seek",
+			failSeek: true,
+		},
+		{
+			name:     "read",
+			failRead: true,
+		},
+		{
+			name:     "seek shared window",
+			opts:     []WriterOption{WithSharedWindow()},
+			failSeek: true,
+		},
+		{
+			name:     "read shared window",
+			opts:     []WriterOption{WithSharedWindow()},
+			failRead: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			zw, err := NewWriterLevel(&buf, DefaultCompression, 256, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := zw.Write(data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			f := &faultyReadSeeker{ReadSeeker: bytes.NewReader(buf.Bytes())}
+			z, err := NewReader(f)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			p := make([]byte, 300)
+			if _, err := z.ReadAt(p, 1000); err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+
+			// Reads fail while the underlying reader is failing.
+			f.failSeek = tc.failSeek
+			f.failRead = tc.failRead
+			_, err = z.ReadAt(p, 2000)
+			if diff := cmp.Diff(errTestRead, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("ReadAt (-want, +got):\n%s", diff)
+			}
+			n, err := z.Read(p)
+			if diff := cmp.Diff(errTestRead, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("Read (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(0, n); diff != "" {
+				t.Errorf("Read (-want, +got):\n%s", diff)
+			}
+
+			// Reads succeed after the underlying reader recovers.
+			f.failSeek = false
+			f.failRead = false
+			for _, off := range []int64{2000, 1000, 3000, 0} {
+				n, err := z.ReadAt(p, off)
+				if err != nil {
+					t.Fatalf("ReadAt(%d): %v", off, err)
+				}
+				if diff := cmp.Diff(data[off:off+int64(n)], p[:n]); diff != "" {
+					t.Errorf("ReadAt(%d) (-want, +got):\n%s", off, diff)
+				}
+			}
+
+			b, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(data, b); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// subfieldLengthData returns an empty dictzip file with a trailing EXTRA
+// subfield whose LEN exceeds the EXTRA area.
+func subfieldLengthData() []byte {
+	return []byte{
+		// Header
+		hdrGzipID1,
+		hdrGzipID2,
+		hdrDeflateCM,
+		flgEXTRA,               // FLG
+		0x00, 0x00, 0x00, 0x00, // MTIME
+		0x0,       // XFL
+		OSUnknown, // OS
+
+		// EXTRA
+		0x10, 0x0, // XLEN // 16
+		0x52, 0x41, // 'R', 'A'
+		0x6, 0x0, // LEN // 6
+		0x1, 0x0, // VER // 1
+		0xcb, 0xe3, // CHLEN // 58315
+		0x0, 0x0, // CHCNT // 0
+		0x58, 0x59, // 'X', 'Y'
+		0xa, 0x0, // LEN // 10 (only 2 bytes follow)
+		0x1, 0x2,
+
+		0x3, 0x0, 0x0, // Empty deflate data.
+
+		0x0, 0x0, 0x0, 0x0, // CRC32
+		0x0, 0x0, 0x0, 0x0, // ISIZE
+	}
+}
+
+func TestReader_WithTolerantExtra(t *testing.T) {
+	t.Parallel()
+
+	z, err := NewReader(bytes.NewReader(subfieldLengthData()), WithTolerantExtra())
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	if diff := cmp.Diff([]byte(nil), z.Extra); diff != "" {
+		t.Errorf("Extra (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(58315, z.ChunkSize()); diff != "" {
+		t.Errorf("ChunkSize (-want, +got):\n%s", diff)
+	}
+
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if diff := cmp.Diff([]byte{}, got); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReader_WithStrictRFC1952(t *testing.T) {
+	t.Parallel()
+
+	valid := mustCompress(t, []byte("Hello, strict!\n"))
+
+	testCases := map[string]struct {
+		// modify modifies a copy of the valid archive.
+		modify func(b []byte)
+		err    error
+	}{
+		"valid": {
+			modify: func([]byte) {},
+		},
+		"reserved FLG": {
+			modify: func(b []byte) { b[3] |= 0x80 },
+			err:    ErrHeader,
+		},
+		"unknown XFL": {
+			modify: func(b []byte) { b[8] = 0x1 },
+			err:    ErrHeader,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b := append([]byte(nil), valid...)
+			tc.modify(b)
+
+			// NOTE: The header is accepted by default.
+			z, err := NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			_ = z.Close()
+
+			_, err = NewReader(bytes.NewReader(b), WithStrictRFC1952())
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("NewReader (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReader_Size(t *testing.T) {
+	t.Parallel()
+
+	write := func(t *testing.T, data []byte) []byte {
+		t.Helper()
+
+		var buf bytes.Buffer
+		z, err := NewWriterLevel(&buf, DefaultCompression, 6)
+		if err != nil {
+			t.Fatalf("NewWriterLevel: %v", err)
+		}
+		if _, err := z.Write(data); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := z.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	// badISIZE has an ISIZE that doesn't match the chunk sizes.
+	badISIZE := write(t, []byte("chunk1chunk2chu"))
+	binary.LittleEndian.PutUint32(badISIZE[len(badISIZE)-4:], 100)
+
+	testCases := map[string]struct {
+		data []byte
+		size int64
+		err  error
+	}{
+		"empty": {
+			data: write(t, nil),
+			size: 0,
+		},
+		"exact chunks": {
+			data: write(t, []byte("chunk1chunk2")),
+			size: 12,
+		},
+		"partial chunk": {
+			data: write(t, []byte("chunk1chunk2chu")),
+			size: 15,
+		},
+		"bad ISIZE": {
+			data: badISIZE,
+			err:  ErrTrailer,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(tc.data))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			size, err := z.Size()
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("Size (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.size, size); diff != "" {
+				t.Errorf("Size (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// countingDecompressor is a deflate decompressor that counts calls to Reset.
+type countingDecompressor struct {
+	readCloseResetter
+	resets *int
+}
+
+func (d *countingDecompressor) Reset(r io.Reader, dict []byte) error {
+	*d.resets++
+	//nolint:wrapcheck // errors are returned unchanged.
+	return d.readCloseResetter.Reset(r, dict)
+}
+
+func TestReader_WithDecompressor(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("internal/testdata/hello.txt.dz")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	var created, resets int
+	newDecompressor := func(r io.Reader) io.ReadCloser {
+		created++
+		return &countingDecompressor{
+			readCloseResetter: flate.NewReader(r).(readCloseResetter),
+			resets:            &resets,
+		}
+	}
+
+	z, err := NewReader(f, WithDecompressor(newDecompressor))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if diff := cmp.Diff("     Hello World!     \n", string(got)); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(1, created); diff != "" {
+		t.Errorf("created (-want, +got):\n%s", diff)
+	}
+	if resets == 0 {
+		t.Errorf("decompressor was not reset")
+	}
+}
+
+func TestReader_WithDecompressor_notResetter(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("internal/testdata/hello.txt.dz")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	newDecompressor := func(r io.Reader) io.ReadCloser {
+		return io.NopCloser(r)
+	}
+
+	_, err = NewReader(f, WithDecompressor(newDecompressor))
+	if diff := cmp.Diff(errDecompressor, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("NewReader (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReader_WithLocking(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string][]WriterOption{
+		"independent chunks": nil,
+		"shared window":      {WithSharedWindow()},
+	}
+
+	for name, opts := range testCases {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var data []byte
+			for i := 0; i < 64; i++ {
+				data = append(data, bytes.Repeat([]byte(fmt.Sprintf("chunk %02d;", i)), 10)...)
+			}
+
+			var buf bytes.Buffer
+			w, err := NewWriterLevel(&buf, DefaultCompression, 100, opts...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			z, err := NewReader(bytes.NewReader(buf.Bytes()), WithLocking(), WithReadCache(1024))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 50; i++ {
+						off := (g*397 + i*131) % (len(data) - 150)
+						p := make([]byte, 150)
+						if _, err := z.ReadAt(p, int64(off)); err != nil {
+							t.Errorf("ReadAt: %v", err)
+							return
+						}
+						if diff := cmp.Diff(data[off:off+150], p); diff != "" {
+							t.Errorf("ReadAt (-want, +got):\n%s", diff)
+							return
+						}
+					}
+				}(g)
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					if _, err := z.Seek(int64(i*10), io.SeekStart); err != nil {
+						t.Errorf("Seek: %v", err)
+						return
+					}
+					if _, err := z.Read(make([]byte, 10)); err != nil {
+						t.Errorf("Read: %v", err)
+						return
+					}
+					size, err := z.Size()
+					if err != nil {
+						t.Errorf("Size: %v", err)
+						return
+					}
+					if diff := cmp.Diff(int64(len(data)), size); diff != "" {
+						t.Errorf("Size (-want, +got):\n%s", diff)
+						return
+					}
+				}
+			}()
+
+			wg.Wait()
+		})
+	}
+}
+
+func TestReader_LastChunkLen(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		data         []byte
+		opts         []WriterOption
+		chunkCount   int
+		lastChunkLen int
+	}{
+		"empty": {
+			data: nil,
+		},
+		"exact chunks": {
+			data:         []byte("chunk1chunk2"),
+			chunkCount:   2,
+			lastChunkLen: 6,
+		},
+		"partial chunk": {
+			data:         []byte("chunk1chunk2chu"),
+			chunkCount:   3,
+			lastChunkLen: 3,
+		},
+		"shared window": {
+			data:         []byte("chunk1chunk2chu"),
+			opts:         []WriterOption{WithSharedWindow()},
+			chunkCount:   3,
+			lastChunkLen: 3,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriterLevel(&buf, DefaultCompression, 6, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := w.Write(tc.data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			var resets int
+			newDecompressor := func(r io.Reader) io.ReadCloser {
+				return &countingDecompressor{
+					readCloseResetter: flate.NewReader(r).(readCloseResetter),
+					resets:            &resets,
+				}
+			}
+
+			z, err := NewReader(bytes.NewReader(buf.Bytes()), WithDecompressor(newDecompressor))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			if diff := cmp.Diff(tc.chunkCount, z.ChunkCount()); diff != "" {
+				t.Errorf("ChunkCount (-want, +got):\n%s", diff)
+			}
+
+			for i := 0; i < 2; i++ {
+				got, err := z.LastChunkLen()
+				if err != nil {
+					t.Fatalf("LastChunkLen: %v", err)
+				}
+				if diff := cmp.Diff(tc.lastChunkLen, got); diff != "" {
+					t.Errorf("LastChunkLen (-want, +got):\n%s", diff)
+				}
+
+				// The last chunk should only be inflated once.
+				if i == 0 {
+					resets = 0
+				} else if diff := cmp.Diff(0, resets); diff != "" {
+					t.Errorf("resets (-want, +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestReader_Offsets(t *testing.T) {
+	t.Parallel()
+
+	b := compressStream(t, []byte("chunk1chunk2chu"), 6)
+	z, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	_, hdrLen, err := ParseHeader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ParseHeader: %v", err)
+	}
+	want := []int64{hdrLen}
+	for _, size := range z.Sizes() {
+		want = append(want, want[len(want)-1]+int64(size))
+	}
+	if diff := cmp.Diff(want, z.Offsets()); diff != "" {
+		t.Errorf("Offsets (-want, +got):\n%s", diff)
+	}
+
+	// The last chunk is followed by the final deflate block and the trailer.
+	if diff := cmp.Diff(int64(len(b)-2-trailerSize), want[len(want)-1]); diff != "" {
+		t.Errorf("end of last chunk (-want, +got):\n%s", diff)
+	}
+}
+
+// ra2File returns a dictzip file with a version 2 RA subfield for data split
+// into chunks of chunkSize bytes.
+func ra2File(t *testing.T, data []byte, chunkSize int) []byte {
+	t.Helper()
+
+	var chunks bytes.Buffer
+	var sizes []uint32
+	for off := 0; off < len(data); off += chunkSize {
+		end := off + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		n := chunks.Len()
+		fw, err := flate.NewWriter(&chunks, flate.BestSpeed)
+		if err != nil {
+			t.Fatalf("flate.NewWriter: %v", err)
+		}
+		if _, err := fw.Write(data[off:end]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := fw.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		//nolint:gosec // chunk sizes are small.
+		sizes = append(sizes, uint32(chunks.Len()-n))
+	}
+
+	ra := binary.LittleEndian.AppendUint16(nil, format.RAVersion2)
+	//nolint:gosec // chunkSize is small.
+	ra = binary.LittleEndian.AppendUint32(ra, uint32(chunkSize))
+	//nolint:gosec // the chunk count is small.
+	ra = binary.LittleEndian.AppendUint32(ra, uint32(len(sizes)))
+	for _, size := range sizes {
+		ra = binary.LittleEndian.AppendUint32(ra, size)
+	}
+
+	b := []byte{hdrGzipID1, hdrGzipID2, hdrDeflateCM, flgEXTRA, 0, 0, 0, 0, 0, OSUnix}
+	//nolint:gosec // the subfield is small.
+	b = binary.LittleEndian.AppendUint16(b, uint16(4+len(ra)))
+	b = append(b, format.RASI1, format.RASI2)
+	//nolint:gosec // the subfield is small.
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(ra)))
+	b = append(b, ra...)
+	b = append(b, chunks.Bytes()...)
+	// The final empty deflate block and the trailer.
+	b = append(b, 0x03, 0x00)
+	b = binary.LittleEndian.AppendUint32(b, crc32.ChecksumIEEE(data))
+	//nolint:gosec // the data is small.
+	return binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+}
+
+func TestReader_RAVersion2(t *testing.T) {
+	t.Parallel()
+
+	const chunkSize = 100000
+
+	// The first chunk is incompressible so that its compressed size does
+	// not fit in 16 bits.
+	data := make([]byte, chunkSize)
+	rand.New(rand.NewSource(1)).Read(data)
+	for i := 0; len(data) < 250000; i++ {
+		data = fmt.Appendf(data, "line %d\n", i)
+	}
+
+	z, err := NewReader(bytes.NewReader(ra2File(t, data, chunkSize)))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	if diff := cmp.Diff(chunkSize, z.ChunkSize()); diff != "" {
+		t.Errorf("ChunkSize (-want, +got):\n%s", diff)
+	}
+	if z.Sizes()[0] <= math.MaxUint16 {
+		t.Errorf("Sizes: first chunk size %d fits in 16 bits", z.Sizes()[0])
+	}
+
+	got := make([]byte, 1000)
+	if _, err := z.ReadAt(got, 199500); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if diff := cmp.Diff(data[199500:200500], got); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+	if err := z.Verify(); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"errors"
+	"io"
+	"sort"
+)
+
+// RangeRequest is a request to read Size bytes of uncompressed data at
+// Offset. See [Reader.ReadAtMulti].
+type RangeRequest struct {
+	Offset int64
+	Size   int
+}
+
+// ReadAtMulti reads multiple ranges of uncompressed data. Each chunk needed
+// by any of the requests is inflated only once, in order, and all requests
+// that overlap it are served from it. This is much faster than calling
+// [Reader.ReadAt] for each request when the requests are close together, such
+// as when looking up multiple words in a dictionary.
+//
+// The returned slices correspond to the requests. If a request extends past
+// the end of the data, its result is short and [io.EOF] is returned along
+// with the results.
+func (z *Reader) ReadAtMulti(reqs []RangeRequest) ([][]byte, error) {
+	z.lock()
+	defer z.unlock()
+
+	if z.plainGzip || z.members != nil {
+		return z.readAtMultiEach(reqs)
+	}
+
+	chunkSize := int64(z.chunkSize)
+
+	// Find the chunks needed by the requests.
+	needed := map[int64]bool{}
+	for _, req := range reqs {
+		if req.Offset < 0 {
+			return nil, ErrNegativeOffset
+		}
+		if req.Size <= 0 {
+			continue
+		}
+		last := (req.Offset + int64(req.Size) - 1) / chunkSize
+		for c := req.Offset / chunkSize; c <= last && c < int64(len(z.sizes)); c++ {
+			needed[c] = true
+		}
+	}
+	chunkNums := make([]int64, 0, len(needed))
+	for c := range needed {
+		chunkNums = append(chunkNums, c)
+	}
+	sort.Slice(chunkNums, func(i, j int) bool { return chunkNums[i] < chunkNums[j] })
+
+	// Inflate each chunk once in order.
+	chunks := make(map[int64][]byte, len(chunkNums))
+	for _, c := range chunkNums {
+		b, err := z.readChunk(c*chunkSize, z.chunkSize)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		chunks[c] = b
+	}
+
+	// Serve the requests from the inflated chunks.
+	var short bool
+	results := make([][]byte, len(reqs))
+	for i, req := range reqs {
+		buf := make([]byte, 0, req.Size)
+		off := req.Offset
+		end := req.Offset + int64(req.Size)
+		for off < end {
+			chunk, ok := chunks[off/chunkSize]
+			start := off % chunkSize
+			if !ok || start >= int64(len(chunk)) {
+				break
+			}
+			n := int64(len(chunk)) - start
+			if n > end-off {
+				n = end - off
+			}
+			buf = append(buf, chunk[start:start+n]...)
+			off += n
+		}
+		if len(buf) < req.Size {
+			short = true
+		}
+		results[i] = buf
+	}
+
+	if short {
+		return results, io.EOF
+	}
+	return results, nil
+}
+
+// readAtMultiEach implements ReadAtMulti for plain gzip and multistream files
+// by reading each request in order of their offsets.
+func (z *Reader) readAtMultiEach(reqs []RangeRequest) ([][]byte, error) {
+	order := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		if req.Offset < 0 {
+			return nil, ErrNegativeOffset
+		}
+		order = append(order, i)
+	}
+	sort.Slice(order, func(i, j int) bool { return reqs[order[i]].Offset < reqs[order[j]].Offset })
+
+	results := make([][]byte, len(reqs))
+	var eof bool
+	for _, i := range order {
+		if reqs[i].Size <= 0 {
+			results[i] = []byte{}
+			continue
+		}
+		buf := make([]byte, reqs[i].Size)
+		n, err := z.readInto(buf, reqs[i].Offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		results[i] = buf[:n]
+		eof = eof || n < len(buf)
+	}
+	if eof {
+		return results, io.EOF
+	}
+	return results, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReader_ReadAtMulti(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 1050)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	write := func(t *testing.T, opts ...WriterOption) []byte {
+		t.Helper()
+
+		var buf bytes.Buffer
+		w, err := NewWriterLevel(&buf, DefaultCompression, 100, opts...)
+		if err != nil {
+			t.Fatalf("NewWriterLevel: %v", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	testCases := map[string]struct {
+		opts []WriterOption
+		reqs []RangeRequest
+
+		// want is the expected result for each request as a range of data.
+		want [][2]int
+
+		// resets is the expected number of chunks inflated.
+		resets int
+		err    error
+	}{
+		"single": {
+			reqs:   []RangeRequest{{Offset: 10, Size: 20}},
+			want:   [][2]int{{10, 30}},
+			resets: 1,
+		},
+		"overlapping": {
+			reqs: []RangeRequest{
+				{Offset: 250, Size: 100},
+				{Offset: 210, Size: 10},
+				{Offset: 10, Size: 20},
+				{Offset: 290, Size: 20},
+			},
+			want:   [][2]int{{250, 350}, {210, 220}, {10, 30}, {290, 310}},
+			resets: 3,
+		},
+		"empty": {
+			reqs:   []RangeRequest{{Offset: 10, Size: 0}},
+			want:   [][2]int{{10, 10}},
+			resets: 0,
+		},
+		"past end": {
+			reqs: []RangeRequest{
+				{Offset: 1040, Size: 20},
+				{Offset: 2000, Size: 20},
+			},
+			want:   [][2]int{{1040, 1050}, {1050, 1050}},
+			resets: 1,
+			err:    io.EOF,
+		},
+		"shared window": {
+			opts: []WriterOption{WithSharedWindow()},
+			reqs: []RangeRequest{
+				{Offset: 250, Size: 100},
+				{Offset: 10, Size: 20},
+			},
+			want: [][2]int{{250, 350}, {10, 30}},
+			// NOTE: Preceding chunks are also inflated to build the window
+			// for chunks 2 and 3.
+			resets: 6,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var resets int
+			newDecompressor := func(r io.Reader) io.ReadCloser {
+				return &countingDecompressor{
+					readCloseResetter: flate.NewReader(r).(readCloseResetter),
+					resets:            &resets,
+				}
+			}
+
+			z, err := NewReader(bytes.NewReader(write(t, tc.opts...)), WithDecompressor(newDecompressor))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			// Ignore resets done by NewReader.
+			resets = 0
+
+			got, err := z.ReadAtMulti(tc.reqs)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ReadAtMulti (-want, +got):\n%s", diff)
+			}
+
+			want := make([][]byte, len(tc.want))
+			for i, r := range tc.want {
+				want[i] = data[r[0]:r[1]]
+			}
+			if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("ReadAtMulti (-want, +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tc.resets, resets); diff != "" {
+				t.Errorf("resets (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReader_ReadAtMulti_negative(t *testing.T) {
+	t.Parallel()
+
+	f := bytes.NewReader(mustCompress(t, []byte("chunk1chunk2")))
+	z, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	_, err = z.ReadAtMulti([]RangeRequest{{Offset: -1, Size: 1}})
+	if diff := cmp.Diff(ErrNegativeOffset, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("ReadAtMulti (-want, +got):\n%s", diff)
+	}
+}
+
+// mustCompress returns data compressed as a dictzip archive.
+func mustCompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"fmt"
+	"io"
+)
+
+// ReadSeeker wraps a [Reader] and implements [io.ReadSeeker] and
+// [io.ReaderAt] over the uncompressed data with the full [io.Seeker]
+// contract, including seeking relative to the end of the data. It can be used
+// wherever a regular [os.File] containing the uncompressed data is expected.
+type ReadSeeker struct {
+	z *Reader
+
+	// size is the size of the uncompressed data.
+	size int64
+
+	// offset is the current offset into the uncompressed data.
+	offset int64
+}
+
+// NewReadSeeker returns a new [ReadSeeker] reading from z. The size of the
+// uncompressed data is determined using [Reader.Size].
+func NewReadSeeker(z *Reader) (*ReadSeeker, error) {
+	size, err := z.Size()
+	if err != nil {
+		return nil, err
+	}
+	return &ReadSeeker{
+		z:    z,
+		size: size,
+	}, nil
+}
+
+// Size returns the size of the uncompressed data.
+func (s *ReadSeeker) Size() int64 {
+	return s.size
+}
+
+// Read implements [io.Reader.Read].
+func (s *ReadSeeker) Read(p []byte) (int, error) {
+	n, err := s.ReadAt(p, s.offset)
+	s.offset += int64(n)
+	return n, err
+}
+
+// ReadAt implements [io.ReaderAt.ReadAt]. Reads are limited to the size of
+// the uncompressed data.
+func (s *ReadSeeker) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+	if off >= s.size {
+		return 0, io.EOF
+	}
+
+	var short bool
+	if remaining := s.size - off; int64(len(p)) > remaining {
+		p = p[:remaining]
+		short = true
+	}
+
+	n, err := s.z.ReadAt(p, off)
+	if err == nil && n < len(p) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil && short {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Seek implements [io.Seeker.Seek]. Seeking past the end of the data is
+// allowed and subsequent reads return [io.EOF].
+func (s *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = s.offset + offset
+	case io.SeekEnd:
+		abs = s.size + offset
+	default:
+		return s.offset, fmt.Errorf("%w: %v", ErrUnsupportedSeek, whence)
+	}
+	if abs < 0 {
+		return s.offset, ErrNegativeOffset
+	}
+	s.offset = abs
+	return abs, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// testReadSeeker returns a new ReadSeeker for internal/testdata/test.txt.dz
+// along with the expected uncompressed data.
+func testReadSeeker(t *testing.T) (*ReadSeeker, []byte) {
+	t.Helper()
+
+	data, err := os.ReadFile("internal/testdata/test.txt.dz")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	want, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("io.ReadAll: %v", err)
+	}
+
+	z, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	t.Cleanup(func() { z.Close() })
+
+	s, err := NewReadSeeker(z)
+	if err != nil {
+		t.Fatalf("NewReadSeeker: %v", err)
+	}
+	return s, want
+}
+
+func TestReadSeeker_Size(t *testing.T) {
+	t.Parallel()
+
+	s, want := testReadSeeker(t)
+	if diff := cmp.Diff(int64(len(want)), s.Size()); diff != "" {
+		t.Errorf("Size (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReadSeeker_Read(t *testing.T) {
+	t.Parallel()
+
+	s, want := testReadSeeker(t)
+	got, err := io.ReadAll(s)
+	if err != nil {
+		t.Fatalf("io.ReadAll: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("io.ReadAll (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReadSeeker_Seek(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		offset int64
+		whence int
+
+		// wantOff is the expected offset relative to the end of the data.
+		wantOff int64
+		err     error
+	}{
+		"SeekEnd": {
+			offset:  -10,
+			whence:  io.SeekEnd,
+			wantOff: -10,
+		},
+		"SeekEnd past end": {
+			offset:  10,
+			whence:  io.SeekEnd,
+			wantOff: 10,
+		},
+		"SeekEnd negative": {
+			offset: -1 << 32,
+			whence: io.SeekEnd,
+			err:    ErrNegativeOffset,
+		},
+		"invalid whence": {
+			offset: 0,
+			whence: 42,
+			err:    ErrUnsupportedSeek,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s, want := testReadSeeker(t)
+			off, err := s.Seek(tc.offset, tc.whence)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("Seek (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				// The offset is unchanged.
+				if diff := cmp.Diff(int64(0), off); diff != "" {
+					t.Errorf("Seek (-want, +got):\n%s", diff)
+				}
+				return
+			}
+
+			wantOff := int64(len(want)) + tc.wantOff
+			if diff := cmp.Diff(wantOff, off); diff != "" {
+				t.Errorf("Seek (-want, +got):\n%s", diff)
+			}
+
+			got, err := io.ReadAll(s)
+			if err != nil {
+				t.Fatalf("io.ReadAll: %v", err)
+			}
+			wantData := []byte{}
+			if wantOff < int64(len(want)) {
+				wantData = want[wantOff:]
+			}
+			if diff := cmp.Diff(wantData, got); diff != "" {
+				t.Errorf("io.ReadAll (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadSeeker_ReadAt(t *testing.T) {
+	t.Parallel()
+
+	s, want := testReadSeeker(t)
+
+	// Reads past the end of the data are short.
+	off := int64(len(want) - 5)
+	got := make([]byte, 10)
+	n, err := s.ReadAt(got, off)
+	if diff := cmp.Diff(io.EOF, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want[off:], got[:n]); diff != "" {
+		t.Errorf("ReadAt (-want, +got):\n%s", diff)
+	}
+
+	// section readers can be used to read part of the data.
+	sr := io.NewSectionReader(s, 10, 20)
+	got, err = io.ReadAll(sr)
+	if err != nil {
+		t.Fatalf("io.ReadAll: %v", err)
+	}
+	if diff := cmp.Diff(want[10:30], got); diff != "" {
+		t.Errorf("io.ReadAll (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+// WithReservedChunkEntries configures the [Writer] to reserve space for n
+// additional chunk sizes in the RA subfield. The reserved entries are written
+// as zeros following the sizes of the chunks written, so that chunks can
+// later be appended to the archive by updating the header in place rather
+// than rewriting the compressed data. The reserved space counts towards the
+// 65535 byte limit of the EXTRA field.
+//
+// Archives with reserved entries remain compatible with dictzip(1) and
+// gzip(1). See [Header.ReservedChunkEntries].
+func WithReservedChunkEntries(n int) WriterOption {
+	return func(z *Writer) {
+		z.reservedChunks = n
+	}
+}
+
+// ReservedChunkEntries returns the number of reserved chunk entries
+// following the chunk sizes in the RA subfield. Each entry can hold the size
+// of an appended chunk without changing the length of the header.
+// See [WithReservedChunkEntries].
+func (h *Header) ReservedChunkEntries() int {
+	return h.reservedChunks
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestWithReservedChunkEntries(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("chunk1chunk2chu")
+	b := compressStream(t, data, 6, WithReservedChunkEntries(10))
+	plain := compressStream(t, data, 6)
+	if diff := cmp.Diff(len(plain)+20, len(b)); diff != "" {
+		t.Errorf("file size (-want, +got):\n%s", diff)
+	}
+
+	z, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	if diff := cmp.Diff(10, z.ReservedChunkEntries()); diff != "" {
+		t.Errorf("ReservedChunkEntries (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(3, z.ChunkCount()); diff != "" {
+		t.Errorf("ChunkCount (-want, +got):\n%s", diff)
+	}
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if diff := cmp.Diff(string(data), string(got)); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+
+	// The reserved entries are zero and follow the chunk table.
+	w, err := NewWriterLevel(io.Discard, DefaultCompression, 6, WithReservedChunkEntries(10))
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	off, n := w.ChunkTableRange()
+	if diff := cmp.Diff(make([]byte, 20), b[off+n:off+n+20]); diff != "" {
+		t.Errorf("reserved entries (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWithReservedChunkEntries_negative(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWriterLevel(io.Discard, DefaultCompression, 6, WithReservedChunkEntries(-1))
+	if diff := cmp.Diff(ErrHeader, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("NewWriterLevel (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// WithSalvage configures the [Reader] to open truncated or tail-corrupted
+// archives in salvage mode. The header must be intact but the trailer is
+// ignored. When the [Reader] is reset, each chunk is inflated in order and
+// only the chunks preceding the first chunk that is missing or fails to
+// inflate can be read. Reads past the recoverable data return [io.EOF].
+// [Reader.SalvageReport] describes the chunks that were skipped.
+//
+// Since dictzip chunks have no checksums, corruption that results in valid
+// deflate data cannot be detected.
+func WithSalvage() ReaderOption {
+	return func(z *Reader) {
+		z.salvage = true
+	}
+}
+
+// Salvaged returns the size of the uncompressed data that can be recovered.
+// The recoverable data is the range [0, Salvaged()). It is only valid in
+// salvage mode.
+// See [WithSalvage].
+func (z *Reader) Salvaged() int64 {
+	return z.salvaged
+}
+
+// SalvageErrorKind describes why a chunk could not be recovered in salvage
+// mode.
+type SalvageErrorKind int
+
+const (
+	// SalvageMissing indicates that the chunk's compressed data is past the
+	// end of the archive.
+	SalvageMissing SalvageErrorKind = iota + 1
+
+	// SalvageTruncated indicates that part of the chunk's compressed data is
+	// past the end of the archive.
+	SalvageTruncated
+
+	// SalvageCorrupt indicates that the chunk fails to inflate.
+	SalvageCorrupt
+
+	// SalvageShort indicates that the chunk inflates to less than the chunk
+	// size but is not the last chunk.
+	SalvageShort
+
+	// SalvageUnreachable indicates that the chunk follows a chunk that can
+	// not be recovered. The chunk itself may be intact but it can not be
+	// read since only the data preceding the first damaged chunk is
+	// recovered. Chunks of archives written with [WithSharedWindow] are not
+	// inflated after the first damaged chunk since they depend on it.
+	SalvageUnreachable
+)
+
+// String returns a short description of the kind.
+func (k SalvageErrorKind) String() string {
+	switch k {
+	case SalvageMissing:
+		return "missing"
+	case SalvageTruncated:
+		return "truncated"
+	case SalvageCorrupt:
+		return "corrupt"
+	case SalvageShort:
+		return "short"
+	case SalvageUnreachable:
+		return "unreachable"
+	default:
+		return fmt.Sprintf("SalvageErrorKind(%d)", int(k))
+	}
+}
+
+// SkippedChunk describes a chunk that could not be recovered in salvage mode.
+type SkippedChunk struct {
+	// Index is the index of the chunk.
+	Index int
+
+	// Kind is the reason the chunk was skipped.
+	Kind SalvageErrorKind
+
+	// Err is the error returned when inflating the chunk. It is only set
+	// for chunks of kind SalvageCorrupt.
+	Err error
+
+	// Offset and Size are the offset and size of the compressed chunk in
+	// the archive.
+	Offset int64
+	Size   int
+
+	// Start and End are the range [Start, End) of the chunk's uncompressed
+	// data. The length of the last chunk is not known unless it inflates so
+	// End is an upper bound for it.
+	Start int64
+	End   int64
+}
+
+// SalvageReport describes the data recovered from an archive in salvage mode
+// so that data loss can be quantified.
+type SalvageReport struct {
+	// Chunks is the number of chunks listed in the header.
+	Chunks int
+
+	// Recovered is the number of chunks that can be read and
+	// RecoveredBytes is the size of their uncompressed data.
+	Recovered      int
+	RecoveredBytes int64
+
+	// LostBytes is the size of the uncompressed data of the skipped chunks.
+	// It is an upper bound if the last chunk is skipped.
+	LostBytes int64
+
+	// Skipped are the chunks that can not be read in chunk order.
+	Skipped []SkippedChunk
+
+	// DiscardedExtra is the number of bytes of malformed EXTRA data that
+	// were skipped. See [WithTolerantExtra].
+	DiscardedExtra int
+}
+
+// SalvageReport returns a report of the chunks recovered and skipped when the
+// [Reader] was last reset. It returns nil unless the Reader is in salvage
+// mode.
+// See [WithSalvage].
+func (z *Reader) SalvageReport() *SalvageReport {
+	return z.salvageReport
+}
+
+// salvageChunks inflates each chunk to find the chunks that can be recovered
+// and discards the rest.
+func (z *Reader) salvageChunks() error {
+	end, err := z.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+
+	report := &SalvageReport{
+		Chunks:         len(z.sizes),
+		DiscardedExtra: z.discardedExtra,
+	}
+	var win []byte
+	buf := make([]byte, z.chunkSize)
+	z.salvaged = 0
+	for i := range z.sizes {
+		var n int
+		var skip *SkippedChunk
+		switch {
+		case z.offsets.at(i) >= end:
+			skip = &SkippedChunk{Kind: SalvageMissing}
+		case z.offsets.at(i+1) > end:
+			skip = &SkippedChunk{Kind: SalvageTruncated}
+		case len(report.Skipped) > 0 && z.sharedWindow:
+			// NOTE: Chunks sharing the window can not be inflated without
+			// the preceding chunks.
+			skip = &SkippedChunk{Kind: SalvageUnreachable}
+		default:
+			n, skip, err = z.salvageChunk(i, win, buf)
+			if err != nil {
+				return err
+			}
+			if skip == nil && len(report.Skipped) > 0 {
+				skip = &SkippedChunk{Kind: SalvageUnreachable}
+			}
+		}
+
+		if skip != nil {
+			skip.Index = i
+			skip.Offset = z.offsets.at(i)
+			skip.Size = z.sizes[i]
+			skip.Start = int64(i) * int64(z.chunkSize)
+			skip.End = skip.Start + int64(z.chunkSize)
+			if skip.Kind == SalvageUnreachable && i == len(z.sizes)-1 && !z.sharedWindow {
+				skip.End = skip.Start + int64(n)
+			}
+			report.Skipped = append(report.Skipped, *skip)
+			report.LostBytes += skip.End - skip.Start
+			continue
+		}
+
+		if z.sharedWindow {
+			win = append(append([]byte{}, win...), buf[:n]...)
+			if len(win) > windowSize {
+				win = win[len(win)-windowSize:]
+			}
+		}
+
+		report.Recovered++
+		z.salvaged += int64(n)
+	}
+	report.RecoveredBytes = z.salvaged
+	z.salvageReport = report
+
+	z.sizes = z.sizes[:report.Recovered]
+	z.offsets.truncate(report.Recovered + 1)
+
+	if _, err := z.r.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+
+	return nil
+}
+
+// salvageChunk inflates chunk i into buf using the preset dictionary dict.
+// It returns the length of the chunk's data or the reason it can not be
+// recovered.
+func (z *Reader) salvageChunk(i int, dict, buf []byte) (int, *SkippedChunk, error) {
+	if _, err := z.r.Seek(z.offsets.at(i), io.SeekStart); err != nil {
+		return 0, nil, fmt.Errorf("%w: Seek: %w", errDictzip, err)
+	}
+	if !z.sharedWindow {
+		dict = nil
+	}
+	if err := z.z.Reset(io.LimitReader(z.r, int64(z.sizes[i])), dict); err != nil {
+		return 0, nil, fmt.Errorf("%w: Reset: %w", errDictzip, err)
+	}
+
+	// NOTE: Chunks end with a sync marker rather than a final block so
+	// reading to the end of the chunk returns io.ErrUnexpectedEOF.
+	n, err := io.ReadFull(z.z, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, &SkippedChunk{Kind: SalvageCorrupt, Err: err}, nil
+	}
+	// Only the last chunk may be shorter than the chunk size.
+	if n < z.chunkSize && i != len(z.sizes)-1 {
+		return n, &SkippedChunk{Kind: SalvageShort}, nil
+	}
+	return n, nil, nil
+}
@@ -0,0 +1,310 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReader_WithSalvage(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 550)
+	rand.New(rand.NewSource(1)).Read(data)
+	for i := range data {
+		data[i] %= 8
+	}
+
+	write := func(t *testing.T, opts ...WriterOption) ([]byte, []int64) {
+		t.Helper()
+
+		var buf bytes.Buffer
+		w, err := NewWriterLevel(&buf, DefaultCompression, 100, opts...)
+		if err != nil {
+			t.Fatalf("NewWriterLevel: %v", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+
+		z, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("NewReader: %v", err)
+		}
+		defer z.Close()
+
+		return buf.Bytes(), z.Offsets()
+	}
+
+	testCases := map[string]struct {
+		opts []WriterOption
+
+		// damage modifies the archive given the chunk offsets.
+		damage func(b []byte, offsets []int64) []byte
+
+		salvaged int64
+	}{
+		"intact": {
+			damage: func(b []byte, _ []int64) []byte {
+				return b
+			},
+			salvaged: 550,
+		},
+		"truncated trailer": {
+			damage: func(b []byte, _ []int64) []byte {
+				return b[:len(b)-8]
+			},
+			salvaged: 550,
+		},
+		"truncated chunk": {
+			damage: func(b []byte, offsets []int64) []byte {
+				return b[:offsets[3]+5]
+			},
+			salvaged: 300,
+		},
+		"corrupt chunk": {
+			damage: func(b []byte, offsets []int64) []byte {
+				for i := offsets[4]; i < offsets[5]; i++ {
+					b[i] = 0xff
+				}
+				return b
+			},
+			salvaged: 400,
+		},
+		"shared window truncated chunk": {
+			opts: []WriterOption{WithSharedWindow()},
+			damage: func(b []byte, offsets []int64) []byte {
+				return b[:offsets[3]+5]
+			},
+			salvaged: 300,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b, offsets := write(t, tc.opts...)
+			b = tc.damage(b, offsets)
+
+			z, err := NewReader(bytes.NewReader(b), WithSalvage())
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			if diff := cmp.Diff(tc.salvaged, z.Salvaged()); diff != "" {
+				t.Errorf("Salvaged (-want, +got):\n%s", diff)
+			}
+			size, err := z.Size()
+			if err != nil {
+				t.Fatalf("Size: %v", err)
+			}
+			if diff := cmp.Diff(tc.salvaged, size); diff != "" {
+				t.Errorf("Size (-want, +got):\n%s", diff)
+			}
+
+			got, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(data[:tc.salvaged], got); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+
+			// Reads past the recoverable data return io.EOF.
+			_, err = z.ReadAt(make([]byte, 10), tc.salvaged)
+			if diff := cmp.Diff(io.EOF, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReader_SalvageReport(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 550)
+	rand.New(rand.NewSource(1)).Read(data)
+	for i := range data {
+		data[i] %= 8
+	}
+
+	testCases := map[string]struct {
+		opts []WriterOption
+
+		// damage modifies the archive given the chunk offsets.
+		damage func(b []byte, offsets []int64) []byte
+
+		// skipped are the kinds of the skipped chunks by index.
+		skipped map[int]SalvageErrorKind
+
+		lost int64
+	}{
+		"intact": {
+			damage: func(b []byte, _ []int64) []byte {
+				return b
+			},
+		},
+		"truncated chunk": {
+			damage: func(b []byte, offsets []int64) []byte {
+				return b[:offsets[3]+5]
+			},
+			skipped: map[int]SalvageErrorKind{
+				3: SalvageTruncated,
+				4: SalvageMissing,
+				5: SalvageMissing,
+			},
+			// NOTE: The length of the last chunk is not known.
+			lost: 300,
+		},
+		"corrupt chunk": {
+			damage: func(b []byte, offsets []int64) []byte {
+				for i := offsets[1]; i < offsets[2]; i++ {
+					b[i] = 0xff
+				}
+				return b
+			},
+			skipped: map[int]SalvageErrorKind{
+				1: SalvageCorrupt,
+				2: SalvageUnreachable,
+				3: SalvageUnreachable,
+				4: SalvageUnreachable,
+				5: SalvageUnreachable,
+			},
+			lost: 450,
+		},
+		"shared window corrupt chunk": {
+			opts: []WriterOption{WithSharedWindow()},
+			damage: func(b []byte, offsets []int64) []byte {
+				for i := offsets[4]; i < offsets[5]; i++ {
+					b[i] = 0xff
+				}
+				return b
+			},
+			skipped: map[int]SalvageErrorKind{
+				4: SalvageCorrupt,
+				5: SalvageUnreachable,
+			},
+			lost: 200,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriterLevel(&buf, DefaultCompression, 100, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			b := buf.Bytes()
+
+			z, err := NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			offsets := z.Offsets()
+			z.Close()
+
+			z, err = NewReader(bytes.NewReader(tc.damage(b, offsets)), WithSalvage())
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			report := z.SalvageReport()
+			if report == nil {
+				t.Fatalf("SalvageReport: got nil")
+			}
+
+			skipped := map[int]SalvageErrorKind{}
+			for _, s := range report.Skipped {
+				skipped[s.Index] = s.Kind
+				if diff := cmp.Diff(offsets[s.Index], s.Offset); diff != "" {
+					t.Errorf("chunk %d Offset (-want, +got):\n%s", s.Index, diff)
+				}
+				if diff := cmp.Diff(int64(s.Index*100), s.Start); diff != "" {
+					t.Errorf("chunk %d Start (-want, +got):\n%s", s.Index, diff)
+				}
+				if (s.Err != nil) != (s.Kind == SalvageCorrupt) {
+					t.Errorf("chunk %d: unexpected Err %v for kind %v", s.Index, s.Err, s.Kind)
+				}
+			}
+			if len(tc.skipped) == 0 {
+				tc.skipped = map[int]SalvageErrorKind{}
+			}
+			if diff := cmp.Diff(tc.skipped, skipped); diff != "" {
+				t.Errorf("skipped chunks (-want, +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(6, report.Chunks); diff != "" {
+				t.Errorf("Chunks (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(6-len(tc.skipped), report.Recovered); diff != "" {
+				t.Errorf("Recovered (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(z.Salvaged(), report.RecoveredBytes); diff != "" {
+				t.Errorf("RecoveredBytes (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.lost, report.LostBytes); diff != "" {
+				t.Errorf("LostBytes (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReader_SalvageReport_disabled(t *testing.T) {
+	t.Parallel()
+
+	z, err := NewReader(bytes.NewReader(mustCompress(t, []byte("Hello World!"))))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	if report := z.SalvageReport(); report != nil {
+		t.Errorf("SalvageReport: got %+v, want nil", report)
+	}
+}
+
+func TestSalvageErrorKind_String(t *testing.T) {
+	t.Parallel()
+
+	if diff := cmp.Diff("unreachable", SalvageUnreachable.String()); diff != "" {
+		t.Errorf("String (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("SalvageErrorKind(0)", SalvageErrorKind(0).String()); diff != "" {
+		t.Errorf("String (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+// ReadAtBuffer is like [Reader.ReadAt] but uses scratch as the buffer for
+// the inflated data rather than allocating one. Data is inflated into scratch
+// before it is copied to p so scratch must not overlap p. If scratch is
+// smaller than needed, a buffer is allocated as for ReadAt. A scratch buffer
+// of [Reader.ScratchSize] bytes is always large enough.
+//
+// ReadAtBuffer is intended for callers that manage their own memory. It
+// always uses the Reader's shared decompressor, so it is not safe for
+// concurrent use unless [WithLocking] is given, and it does not use or
+// populate the read cache (see [WithReadCache]). Buffers are still
+// allocated if [WithConcurrency] or [WithPrefetch] is given, or if the
+// chunks share the deflate window.
+func (z *Reader) ReadAtBuffer(p []byte, off int64, scratch []byte) (int, error) {
+	z.lock()
+	defer z.unlock()
+
+	z.scratch = scratch
+	defer func() {
+		z.scratch = nil
+	}()
+
+	return z.readInto(p, off)
+}
+
+// ScratchSize returns the size of a scratch buffer for [Reader.ReadAtBuffer]
+// that is large enough to read n bytes at any offset.
+func (z *Reader) ScratchSize(n int) int {
+	// NOTE: The data preceding the offset in the first chunk is inflated
+	// into the buffer along with the data read.
+	return n + z.ChunkSize()
+}
+
+// chunkBuffer returns a buffer of size bytes for inflated chunk data. The
+// scratch buffer given to ReadAtBuffer is used if it is large enough.
+func (z *Reader) chunkBuffer(size int64) []byte {
+	if int64(cap(z.scratch)) >= size {
+		return z.scratch[:size]
+	}
+	return make([]byte, size)
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReader_ReadAtBuffer(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("Hello, ReadAtBuffer!\n"), 1000)
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, 1000)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	testCases := map[string]struct {
+		off     int64
+		size    int
+		scratch int
+	}{
+		"in chunk": {
+			off:     5050,
+			size:    100,
+			scratch: -1,
+		},
+		"spanning chunks": {
+			off:     950,
+			size:    2500,
+			scratch: -1,
+		},
+		"small scratch": {
+			off:     5050,
+			size:    100,
+			scratch: 10,
+		},
+		"no scratch": {
+			off:     5050,
+			size:    100,
+			scratch: 0,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			scratchSize := tc.scratch
+			if scratchSize < 0 {
+				scratchSize = z.ScratchSize(tc.size)
+			}
+			scratch := make([]byte, scratchSize)
+
+			p := make([]byte, tc.size)
+			n, err := z.ReadAtBuffer(p, tc.off, scratch)
+			if err != nil {
+				t.Fatalf("ReadAtBuffer: %v", err)
+			}
+			if diff := cmp.Diff(data[tc.off:tc.off+int64(tc.size)], p[:n]); diff != "" {
+				t.Errorf("ReadAtBuffer (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// NOTE: testing.AllocsPerRun can't be used in parallel tests.
+func TestReader_ReadAtBuffer_allocs(t *testing.T) {
+	data := bytes.Repeat([]byte("Hello, ReadAtBuffer!\n"), 1000)
+	z, err := NewReader(bytes.NewReader(mustCompress(t, data)))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	p := make([]byte, 100)
+	scratch := make([]byte, z.ScratchSize(len(p)))
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := z.ReadAtBuffer(p, 5050, scratch); err != nil {
+			t.Fatalf("ReadAtBuffer: %v", err)
+		}
+	})
+	if diff := cmp.Diff(0.0, allocs); diff != "" {
+		t.Errorf("allocs (-want, +got):\n%s", diff)
+	}
+}
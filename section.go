@@ -0,0 +1,36 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"io"
+	"math"
+)
+
+// Section returns an [io.SectionReader] reading n bytes of uncompressed data
+// starting at offset off, such as the definition identified by the offset
+// and length of a dictd index entry. If n is negative, the section extends
+// to the end of the data.
+//
+// The section reads using [Reader.ReadAt] so it does not change the offset of
+// z. Sections may be read concurrently if ReadAt may be called concurrently,
+// for example with [WithLocking].
+func (z *Reader) Section(off, n int64) *io.SectionReader {
+	if n < 0 {
+		// NOTE: io.NewSectionReader limits the section to the maximum offset.
+		n = math.MaxInt64
+	}
+	return io.NewSectionReader(z, off, n)
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReader_Section(t *testing.T) {
+	t.Parallel()
+
+	data := benchmarkData(10000)
+	compressed := compressStream(t, data, 1024)
+
+	testCases := map[string]struct {
+		off, n   int64
+		want     []byte
+		wantSize int64
+	}{
+		"within chunk": {
+			off:      10,
+			n:        100,
+			want:     data[10:110],
+			wantSize: 100,
+		},
+		"across chunks": {
+			off:      1000,
+			n:        3000,
+			want:     data[1000:4000],
+			wantSize: 3000,
+		},
+		"past end": {
+			off:      9000,
+			n:        2000,
+			want:     data[9000:],
+			wantSize: 2000,
+		},
+		"to end": {
+			off:      9000,
+			n:        -1,
+			want:     data[9000:],
+			wantSize: math.MaxInt64 - 9000,
+		},
+		"empty": {
+			off:  500,
+			want: []byte{},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			s := z.Section(tc.off, tc.n)
+			if diff := cmp.Diff(tc.wantSize, s.Size()); diff != "" {
+				t.Errorf("Size (-want, +got):\n%s", diff)
+			}
+			got, err := io.ReadAll(s)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ReadAll (-want, +got):\n%s", diff)
+			}
+
+			// Reading the section does not change the offset of z.
+			off, err := z.Seek(0, io.SeekCurrent)
+			if err != nil {
+				t.Fatalf("Seek: %v", err)
+			}
+			if diff := cmp.Diff(int64(0), off); diff != "" {
+				t.Errorf("Seek (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"fmt"
+	"io"
+)
+
+// ChunkSections returns an [io.SectionReader] for the uncompressed data of
+// each chunk in order. The sections read from z using [Reader.ReadAt] so that
+// work can be split by chunk without computing chunk offsets. Each section can
+// be read without inflating any other chunk unless the chunks share the
+// deflate window.
+//
+// The sections share z so they may only be read concurrently if
+// [Reader.ReadAt] is safe for concurrent use or z was created using
+// [WithLocking]. In salvage mode only the recoverable data is covered. It
+// returns an error wrapping [ErrPlainGzip] for plain gzip files. The chunks
+// of all members of multistream files are included.
+func (z *Reader) ChunkSections() ([]*io.SectionReader, error) {
+	if z.PlainGzip() {
+		return nil, fmt.Errorf("%w: chunk sections", ErrPlainGzip)
+	}
+
+	if members := z.Members(); members != nil {
+		var sections []*io.SectionReader
+		for _, m := range members {
+			sections = append(sections, z.chunkSections(m.Start, m.Size, m.Header.ChunkSize())...)
+		}
+		return sections, nil
+	}
+
+	size, err := z.Size()
+	if err != nil {
+		return nil, err
+	}
+	return z.chunkSections(0, size, z.ChunkSize()), nil
+}
+
+// chunkSections returns sections of chunkSize bytes covering the size bytes
+// of uncompressed data starting at start.
+func (z *Reader) chunkSections(start, size int64, chunkSize int) []*io.SectionReader {
+	var sections []*io.SectionReader
+	for off := int64(0); off < size; off += int64(chunkSize) {
+		n := int64(chunkSize)
+		if size-off < n {
+			n = size - off
+		}
+		sections = append(sections, io.NewSectionReader(z, start+off, n))
+	}
+	return sections
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReader_ChunkSections(t *testing.T) {
+	t.Parallel()
+
+	var data []byte
+	for i := 0; i < 100; i++ {
+		data = append(data, []byte("Hello, ChunkSections!\n")...)
+	}
+
+	testCases := map[string]struct {
+		chunkSize int
+		want      int
+	}{
+		"single chunk": {
+			chunkSize: len(data),
+			want:      1,
+		},
+		"even": {
+			chunkSize: len(data) / 4,
+			want:      4,
+		},
+		"uneven": {
+			chunkSize: 300,
+			want:      8,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriterLevel(&buf, DefaultCompression, tc.chunkSize)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			z, err := NewReader(bytes.NewReader(buf.Bytes()), WithLocking())
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			sections, err := z.ChunkSections()
+			if err != nil {
+				t.Fatalf("ChunkSections: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, len(sections)); diff != "" {
+				t.Fatalf("len(sections) (-want, +got):\n%s", diff)
+			}
+
+			// Read the sections concurrently.
+			got := make([][]byte, len(sections))
+			errs := make([]error, len(sections))
+			var wg sync.WaitGroup
+			for i, s := range sections {
+				wg.Add(1)
+				go func(i int, s *io.SectionReader) {
+					defer wg.Done()
+					got[i], errs[i] = io.ReadAll(s)
+				}(i, s)
+			}
+			wg.Wait()
+
+			for i, err := range errs {
+				if err != nil {
+					t.Fatalf("ReadAll(sections[%d]): %v", i, err)
+				}
+			}
+			if diff := cmp.Diff(data, bytes.Join(got, nil)); diff != "" {
+				t.Errorf("sections (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReader_ChunkSections_empty(t *testing.T) {
+	t.Parallel()
+
+	z, err := NewReader(bytes.NewReader(mustCompress(t, nil)))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	sections, err := z.ChunkSections()
+	if err != nil {
+		t.Fatalf("ChunkSections: %v", err)
+	}
+	if diff := cmp.Diff(0, len(sections)); diff != "" {
+		t.Errorf("len(sections) (-want, +got):\n%s", diff)
+	}
+}
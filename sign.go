@@ -0,0 +1,102 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrSignature indicates that an archive's signature is not valid.
+var ErrSignature = fmt.Errorf("%w: invalid signature", errDictzip)
+
+// signatureContext is written to the digest before the archive data so that
+// archive signatures can't be used for other purposes.
+const signatureContext = "dictzip signature v1\x00"
+
+// SignFunc returns the signature of the digest of an archive.
+type SignFunc func(digest []byte) ([]byte, error)
+
+// VerifyFunc returns an error if sig is not a valid signature of the digest
+// of an archive.
+type VerifyFunc func(digest, sig []byte) error
+
+// SignatureDigest returns the digest of the archive read from r that is
+// signed by [Sign]. It is the SHA-256 hash of a fixed context string followed by all of
+// the data of the archive, including the header, the chunk table, the chunks,
+// and the trailer.
+func SignatureDigest(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	h.Write([]byte(signatureContext))
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fmt.Errorf("%w: reading archive: %w", errDictzip, err)
+	}
+	return h.Sum(nil), nil
+}
+
+// Sign returns a detached signature of the archive read from r created by
+// sign. The signature is intended to be distributed alongside the archive,
+// for example in a FILE.dz.sig file, and checked using [VerifySignature].
+func Sign(r io.Reader, sign SignFunc) ([]byte, error) {
+	digest, err := SignatureDigest(r)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sign(digest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signing: %w", errDictzip, err)
+	}
+	return sig, nil
+}
+
+// VerifySignature checks the detached signature sig of the archive read from
+// r using verify. It returns an error wrapping [ErrSignature] if the
+// signature is not valid. Unlike [Reader.Verify], it doesn't check the
+// archive's checksums.
+func VerifySignature(r io.Reader, sig []byte, verify VerifyFunc) error {
+	digest, err := SignatureDigest(r)
+	if err != nil {
+		return err
+	}
+	if err := verify(digest, sig); err != nil {
+		return fmt.Errorf("%w: %w", ErrSignature, err)
+	}
+	return nil
+}
+
+// Ed25519Signer returns a [SignFunc] that signs digests using the Ed25519
+// private key.
+func Ed25519Signer(key ed25519.PrivateKey) SignFunc {
+	return func(digest []byte) ([]byte, error) {
+		return ed25519.Sign(key, digest), nil
+	}
+}
+
+// errEd25519 indicates that an Ed25519 signature does not match.
+var errEd25519 = errors.New("ed25519 signature mismatch")
+
+// Ed25519Verifier returns a [VerifyFunc] that verifies signatures created by
+// [Ed25519Signer] using the Ed25519 public key.
+func Ed25519Verifier(key ed25519.PublicKey) VerifyFunc {
+	return func(digest, sig []byte) error {
+		if !ed25519.Verify(key, digest, sig) {
+			return errEd25519
+		}
+		return nil
+	}
+}
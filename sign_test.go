@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+
+	archive := mustCompress(t, []byte("Hello, signature!"))
+
+	// NOTE: A fixed seed is used so that the test is deterministic.
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x1}, ed25519.SeedSize))
+	pub, _ := priv.Public().(ed25519.PublicKey)
+	otherPub, _ := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x2}, ed25519.SeedSize)).Public().(ed25519.PublicKey)
+
+	sig, err := Sign(bytes.NewReader(archive), Ed25519Signer(priv))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	// tamper returns a copy of the archive with the byte at i modified.
+	tamper := func(i int) []byte {
+		b := append([]byte(nil), archive...)
+		b[i] ^= 0x1
+		return b
+	}
+
+	testCases := map[string]struct {
+		archive []byte
+		sig     []byte
+		key     ed25519.PublicKey
+		err     error
+	}{
+		"valid": {
+			archive: archive,
+			sig:     sig,
+			key:     pub,
+		},
+		"header": {
+			// NOTE: the MTIME field.
+			archive: tamper(4),
+			sig:     sig,
+			key:     pub,
+			err:     ErrSignature,
+		},
+		"chunk": {
+			archive: tamper(len(archive) - 10),
+			sig:     sig,
+			key:     pub,
+			err:     ErrSignature,
+		},
+		"trailer": {
+			archive: tamper(len(archive) - 1),
+			sig:     sig,
+			key:     pub,
+			err:     ErrSignature,
+		},
+		"truncated": {
+			archive: archive[:len(archive)-1],
+			sig:     sig,
+			key:     pub,
+			err:     ErrSignature,
+		},
+		"wrong key": {
+			archive: archive,
+			sig:     sig,
+			key:     otherPub,
+			err:     ErrSignature,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := VerifySignature(bytes.NewReader(tc.archive), tc.sig, Ed25519Verifier(tc.key))
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("VerifySignature (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSign_error(t *testing.T) {
+	t.Parallel()
+
+	errSign := errors.New("sign")
+	_, err := Sign(bytes.NewReader(nil), func([]byte) ([]byte, error) {
+		return nil, errSign
+	})
+	if diff := cmp.Diff(errSign, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("Sign (-want, +got):\n%s", diff)
+	}
+}
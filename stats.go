@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+// statsBuckets is the number of buckets in [ArchiveStats.Distribution].
+const statsBuckets = 10
+
+// ArchiveStats are statistics about the chunks of an archive. They are
+// intended to help choose a chunk size.
+type ArchiveStats struct {
+	// ChunkSize is the uncompressed chunk size.
+	ChunkSize int
+
+	// Chunks is the number of chunks.
+	Chunks int
+
+	// Size is the size of the uncompressed data.
+	Size int64
+
+	// CompressedSize is the total size of all compressed chunks.
+	CompressedSize int64
+
+	// MinChunkLen, MaxChunkLen, and AvgChunkLen are the minimum, maximum,
+	// and average compressed chunk lengths.
+	MinChunkLen int
+	MaxChunkLen int
+	AvgChunkLen float64
+
+	// Ratios are the ratios of compressed to uncompressed size for each
+	// chunk.
+	Ratios []float64
+
+	// Distribution is the distribution of chunk ratios. Distribution[i] is
+	// the number of chunks with a ratio in [i/10, (i+1)/10). The last bucket
+	// also includes chunks with a ratio of 1 or more, that is chunks that
+	// did not compress.
+	Distribution [statsBuckets]int
+
+	// FinalDataLen is the length of the deflate data following the last
+	// chunk. This data contains no uncompressed data and is wasted space.
+	FinalDataLen int
+}
+
+// Stats returns statistics about the chunks of the archive read by z. The
+// trailer and final deflate data are read from the underlying reader. With
+// [WithMultistream] the statistics cover the chunks of all members and
+// ChunkSize is the chunk size of the first member.
+func Stats(z *Reader) (*ArchiveStats, error) {
+	z.lock()
+	members := z.members
+	z.unlock()
+	if members != nil {
+		var s *ArchiveStats
+		for _, m := range members {
+			ms, err := Stats(m.r)
+			if err != nil {
+				return nil, err
+			}
+			s = s.merge(ms)
+		}
+		return s, nil
+	}
+
+	size, err := z.Size()
+	if err != nil {
+		return nil, err
+	}
+	p, err := z.Provenance()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ArchiveStats{
+		ChunkSize:    z.chunkSize,
+		Chunks:       len(z.sizes),
+		Size:         size,
+		Ratios:       make([]float64, len(z.sizes)),
+		FinalDataLen: len(p.FinalData),
+	}
+
+	remaining := size
+	for i, chunkLen := range z.sizes {
+		s.CompressedSize += int64(chunkLen)
+		if i == 0 || chunkLen < s.MinChunkLen {
+			s.MinChunkLen = chunkLen
+		}
+		if chunkLen > s.MaxChunkLen {
+			s.MaxChunkLen = chunkLen
+		}
+
+		uncompressedLen := int64(z.chunkSize)
+		if remaining < uncompressedLen {
+			uncompressedLen = remaining
+		}
+		remaining -= uncompressedLen
+
+		ratio := float64(chunkLen) / float64(uncompressedLen)
+		s.Ratios[i] = ratio
+
+		bucket := int(ratio * statsBuckets)
+		if bucket >= statsBuckets {
+			bucket = statsBuckets - 1
+		}
+		s.Distribution[bucket]++
+	}
+	if s.Chunks > 0 {
+		s.AvgChunkLen = float64(s.CompressedSize) / float64(s.Chunks)
+	}
+
+	return s, nil
+}
+
+// merge returns the statistics of s followed by those of o. If s is nil, o is
+// returned.
+func (s *ArchiveStats) merge(o *ArchiveStats) *ArchiveStats {
+	if s == nil {
+		return o
+	}
+	if o.Chunks > 0 {
+		if s.Chunks == 0 || o.MinChunkLen < s.MinChunkLen {
+			s.MinChunkLen = o.MinChunkLen
+		}
+		if o.MaxChunkLen > s.MaxChunkLen {
+			s.MaxChunkLen = o.MaxChunkLen
+		}
+	}
+	s.Chunks += o.Chunks
+	s.Size += o.Size
+	s.CompressedSize += o.CompressedSize
+	s.Ratios = append(s.Ratios, o.Ratios...)
+	for i, n := range o.Distribution {
+		s.Distribution[i] += n
+	}
+	s.FinalDataLen += o.FinalDataLen
+	if s.Chunks > 0 {
+		s.AvgChunkLen = float64(s.CompressedSize) / float64(s.Chunks)
+	}
+	return s
+}
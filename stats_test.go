@@ -0,0 +1,112 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	// Two compressible chunks followed by a partial incompressible chunk.
+	data := bytes.Repeat([]byte("a"), 2000)
+	random := make([]byte, 500)
+	rand.New(rand.NewSource(1)).Read(random)
+	data = append(data, random...)
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, BestCompression, 1000)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	sizes := w.Sizes()
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	got, err := Stats(z)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	p, err := z.Provenance()
+	if err != nil {
+		t.Fatalf("Provenance: %v", err)
+	}
+
+	want := &ArchiveStats{
+		ChunkSize:      1000,
+		Chunks:         3,
+		Size:           2500,
+		CompressedSize: int64(sizes[0] + sizes[1] + sizes[2]),
+		MinChunkLen:    sizes[0],
+		MaxChunkLen:    sizes[2],
+		AvgChunkLen:    float64(sizes[0]+sizes[1]+sizes[2]) / 3,
+		Ratios: []float64{
+			float64(sizes[0]) / 1000,
+			float64(sizes[1]) / 1000,
+			float64(sizes[2]) / 500,
+		},
+		// NOTE: The random chunk is larger than its uncompressed data.
+		Distribution: [statsBuckets]int{0: 2, 9: 1},
+		FinalDataLen: len(p.FinalData),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Stats (-want, +got):\n%s", diff)
+	}
+}
+
+func TestStats_empty(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer z.Close()
+
+	got, err := Stats(z)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if diff := cmp.Diff(0, got.Chunks); diff != "" {
+		t.Errorf("Chunks (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(0.0, got.AvgChunkLen); diff != "" {
+		t.Errorf("AvgChunkLen (-want, +got):\n%s", diff)
+	}
+}
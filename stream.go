@@ -0,0 +1,132 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StreamReader decompresses a dictzip file sequentially from an [io.Reader]
+// that does not support seeking, such as a pipe or network connection. The
+// header, including the chunk sizes, is available as with [Reader] but random
+// access is not supported.
+//
+// The CRC-32 checksums of chunks are verified if the archive includes chunk
+// checksums. The gzip trailer is verified once all data has been read.
+type StreamReader struct {
+	Header
+
+	r  *bufio.Reader
+	fr io.ReadCloser
+
+	// v verifies the data read.
+	v *dataVerifier
+
+	// err is returned by all reads once set.
+	err error
+}
+
+// NewStreamReader creates a new [StreamReader] reading the dictzip file from
+// r and reads its header. The [WithTolerantExtra], [WithStrictRFC1952],
+// [WithStrictHeader], [WithLenientHeader], [WithReadUTF8],
+// [WithReadUTF8Strings], [WithMemoryLimit], [WithGzipFallback], and
+// [WithDecompressor] options are supported. Other reader options only apply
+// to random access and are ignored.
+//
+// The decompressor given by [WithDecompressor] is passed an [io.ByteReader]
+// and must not read past the end of the deflate stream, as is the case for
+// [flate.NewReader].
+//
+// The caller is responsible for closing r after the StreamReader is closed.
+func NewStreamReader(r io.Reader, opts ...ReaderOption) (*StreamReader, error) {
+	cfg := &Reader{
+		newDecompressor: flate.NewReader,
+		downloaded:      -1,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.applyMemoryLimit()
+
+	h, buf, hdrLen, err := readHeaderFrom(r, cfg.parseOptions(), cfg.memoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	cfg.setHeader(h)
+	cfg.chunkSize = h.ChunkSize
+
+	// NOTE: Data read past the end of the header is the start of the first
+	// chunk.
+	br := bufio.NewReader(io.MultiReader(bytes.NewReader(buf[hdrLen:]), r))
+	return &StreamReader{
+		Header: cfg.Header,
+		r:      br,
+		fr:     cfg.newDecompressor(br),
+		v:      newDataVerifier(h.ChunkSize, h.ChunkCRCs, cfg.chunkCRCTable()),
+	}, nil
+}
+
+// Read implements [io.Reader]. It returns an error wrapping
+// [ErrChunkChecksum] if a chunk does not match its checksum and an error
+// wrapping [ErrTrailer] if the data does not match the trailer.
+func (s *StreamReader) Read(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	n, err := s.fr.Read(p)
+	if _, vErr := s.v.Write(p[:n]); vErr != nil {
+		s.err = vErr
+		return 0, vErr
+	}
+	if errors.Is(err, io.EOF) {
+		err = s.readTrailer()
+	} else if err != nil {
+		err = fmt.Errorf("%w: inflating chunk %d: %w", errDictzip, s.v.chunk(), err)
+	}
+	s.err = err
+	return n, err
+}
+
+// Close closes the StreamReader. It does not close the underlying
+// [io.Reader].
+func (s *StreamReader) Close() error {
+	//nolint:wrapcheck // error does not need to be wrapped
+	return s.fr.Close()
+}
+
+// readTrailer reads the gzip trailer following the deflate stream and
+// verifies it against the data read. It returns io.EOF if the trailer
+// matches.
+func (s *StreamReader) readTrailer() error {
+	trailer := make([]byte, trailerSize)
+	if _, err := io.ReadFull(s.r, trailer); err != nil {
+		return fmt.Errorf("%w: missing trailer: %w", ErrTrailer, err)
+	}
+	if err := s.v.verifyTrailer(trailer); err != nil {
+		return err
+	}
+	if !s.plainGzip {
+		if err := s.v.verifyChunkCount(len(s.sizes)); err != nil {
+			return err
+		}
+	}
+	return io.EOF
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// compressStream compresses data with the given chunk size and options.
+func compressStream(t *testing.T, data []byte, chunkSize int, opts ...WriterOption) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, DefaultCompression, chunkSize, opts...)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	w.Name = "test.txt"
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestStreamReader(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	var text bytes.Buffer
+	for text.Len() < 10000 {
+		fmt.Fprintf(&text, "line %d\n", rng.Intn(1000))
+	}
+	random := make([]byte, 5000)
+	_, _ = rng.Read(random)
+
+	testCases := map[string]struct {
+		data []byte
+		opts []WriterOption
+	}{
+		"empty": {},
+		"text": {
+			data: text.Bytes(),
+		},
+		"chunk checksums": {
+			data: text.Bytes(),
+			opts: []WriterOption{WithChunkChecksums()},
+		},
+		"exact chunks": {
+			data: text.Bytes()[:3000],
+			opts: []WriterOption{WithChunkChecksums()},
+		},
+		"shared window": {
+			data: text.Bytes(),
+			opts: []WriterOption{WithSharedWindow()},
+		},
+		"stored": {
+			data: random,
+			opts: []WriterOption{WithStoreIncompressible(), WithChunkChecksums()},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b := compressStream(t, tc.data, 1000, tc.opts...)
+			h, _, err := format.Parse(b)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			// NOTE: iotest.OneByteReader does not implement io.Seeker.
+			s, err := NewStreamReader(iotest.OneByteReader(bytes.NewReader(b)))
+			if err != nil {
+				t.Fatalf("NewStreamReader: %v", err)
+			}
+			defer s.Close()
+
+			if got, want := s.Name, "test.txt"; got != want {
+				t.Errorf("Name: got %q, want %q", got, want)
+			}
+			if got, want := s.ChunkSize(), 1000; got != want {
+				t.Errorf("ChunkSize: got %d, want %d", got, want)
+			}
+			if diff := cmp.Diff(h.Sizes, s.Sizes(), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Sizes (-want, +got):\n%s", diff)
+			}
+
+			got, err := io.ReadAll(s)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if diff := cmp.Diff(tc.data, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("data (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStreamReader_errors(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("dictzip stream test\n"), 500)
+
+	testCases := map[string]struct {
+		// corrupt modifies the compressed file.
+		corrupt func(t *testing.T, b []byte) []byte
+		err     error
+	}{
+		"chunk checksum": {
+			corrupt: func(t *testing.T, b []byte) []byte {
+				t.Helper()
+
+				h, hdrLen, err := format.Parse(b)
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				h.ChunkCRCs[2]++
+				hdr, err := format.Append(nil, h)
+				if err != nil {
+					t.Fatalf("Append: %v", err)
+				}
+				return append(hdr, b[hdrLen:]...)
+			},
+			err: ErrChunkChecksum,
+		},
+		"trailer CRC-32": {
+			corrupt: func(_ *testing.T, b []byte) []byte {
+				b[len(b)-8]++
+				return b
+			},
+			err: ErrTrailer,
+		},
+		"trailer ISIZE": {
+			corrupt: func(_ *testing.T, b []byte) []byte {
+				b[len(b)-4]++
+				return b
+			},
+			err: ErrTrailer,
+		},
+		"missing trailer": {
+			corrupt: func(_ *testing.T, b []byte) []byte {
+				return b[:len(b)-8]
+			},
+			err: ErrTrailer,
+		},
+		"header": {
+			corrupt: func(_ *testing.T, b []byte) []byte {
+				return b[:10]
+			},
+			err: ErrHeader,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b := tc.corrupt(t, compressStream(t, data, 1000, WithChunkChecksums()))
+			s, err := NewStreamReader(bytes.NewBuffer(b))
+			if err == nil {
+				_, err = io.ReadAll(s)
+				// NOTE: Errors are returned by all subsequent reads.
+				if _, rErr := s.Read(make([]byte, 1)); rErr != err {
+					t.Errorf("Read: got %v, want %v", rErr, err)
+				}
+			}
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("error (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
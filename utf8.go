@@ -0,0 +1,150 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"encoding/binary"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ianlewis/go-dictzip/format"
+)
+
+// WithWriteUTF8 configures the [Writer] to support Name and Comment header
+// values that can't be encoded in ISO 8859-1 (Latin-1). Such values are
+// written as UTF-8 in additional EXTRA subfields and the NAME and COMMENT
+// fields contain a Latin-1 fallback with unsupported characters replaced by
+// '_'. Values that can be encoded in Latin-1 are written as usual.
+//
+// The subfields are read by a [Reader] created with [WithReadUTF8]. Other
+// programs, such as gzip(1) and dictzip(1), use the Latin-1 fallback.
+func WithWriteUTF8() WriterOption {
+	return func(z *Writer) {
+		z.writeUTF8 = true
+	}
+}
+
+// WithReadUTF8 configures the [Reader] to read the Name and Comment header
+// values from the UTF-8 EXTRA subfields written by a [Writer] created with
+// [WithWriteUTF8], if present. The subfields are removed from
+// [Header.Extra].
+func WithReadUTF8() ReaderOption {
+	return func(z *Reader) {
+		z.readUTF8 = true
+	}
+}
+
+// WithWriteUTF8Strings configures the [Writer] to write the NAME and COMMENT
+// header fields encoded as UTF-8 rather than ISO 8859-1 (Latin-1), as is done
+// by some other tools, so that non-Latin-1 values are written as is. An error
+// wrapping [ErrHeader] is returned when the header is written if Name or
+// Comment is not valid UTF-8. [WithWriteUTF8] has no effect if
+// WithWriteUTF8Strings is given.
+//
+// Programs that decode the fields as Latin-1, such as gzip(1), show
+// non-ASCII characters incorrectly. Use [WithReadUTF8Strings] to read them.
+func WithWriteUTF8Strings() WriterOption {
+	return func(z *Writer) {
+		z.utf8Strings = true
+	}
+}
+
+// WithReadUTF8Strings configures the [Reader] to decode the NAME and COMMENT
+// header fields as UTF-8 if they are valid UTF-8, such as those written with
+// [WithWriteUTF8Strings]. Other values are decoded as ISO 8859-1 (Latin-1) as
+// usual. ASCII values are the same in both encodings and values written in
+// Latin-1 are rarely valid UTF-8, so files written either way can be read.
+func WithReadUTF8Strings() ReaderOption {
+	return func(z *Reader) {
+		z.utf8Strings = true
+	}
+}
+
+// isLatin1 returns true if s can be written to a gzip string header field.
+func isLatin1(s string) bool {
+	for _, r := range s {
+		if r == 0 || r > 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// latin1Fallback returns s with characters that can't be written to a gzip
+// string header field replaced by '_'.
+func latin1Fallback(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == 0 || r > 0xff {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// utf8Extra returns the EXTRA subfields and the NAME and COMMENT field values
+// to write for the given values. UTF-8 subfields already in extra are
+// replaced.
+func utf8Extra(extra []byte, name, comment string) ([]byte, string, string) {
+	extra, _, _ = splitUTF8Subfields(extra)
+	if !isLatin1(name) {
+		extra = appendSubfield(extra, format.UTF8NameSI1, format.UTF8NameSI2, name)
+		name = latin1Fallback(name)
+	}
+	if !isLatin1(comment) {
+		extra = appendSubfield(extra, format.UTF8CommentSI1, format.UTF8CommentSI2, comment)
+		comment = latin1Fallback(comment)
+	}
+	return extra, name, comment
+}
+
+// appendSubfield appends an EXTRA subfield with the given ID and data.
+func appendSubfield(extra []byte, si1, si2 byte, data string) []byte {
+	// NOTE: The subfield length is checked when the header is written.
+	extra = append(extra, si1, si2)
+	//nolint:gosec // the length is checked by format.Append.
+	extra = binary.LittleEndian.AppendUint16(extra, uint16(len(data)))
+	return append(extra, data...)
+}
+
+// splitUTF8Subfields removes the UTF-8 NAME and COMMENT subfields from the
+// EXTRA subfields in extra and returns their values. Values are empty if the
+// subfield is not present or is not valid UTF-8.
+func splitUTF8Subfields(extra []byte) ([]byte, string, string) {
+	var rest []byte
+	var name, comment string
+	for len(extra) >= 4 {
+		subLen := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if subLen > len(extra)-4 {
+			break
+		}
+		sub, data := extra[:4+subLen], extra[4:4+subLen]
+		extra = extra[4+subLen:]
+
+		switch {
+		case sub[0] == format.UTF8NameSI1 && sub[1] == format.UTF8NameSI2:
+			if utf8.Valid(data) {
+				name = string(data)
+			}
+		case sub[0] == format.UTF8CommentSI1 && sub[1] == format.UTF8CommentSI2:
+			if utf8.Valid(data) {
+				comment = string(data)
+			}
+		default:
+			rest = append(rest, sub...)
+		}
+	}
+	// Keep any trailing data as-is.
+	return append(rest, extra...), name, comment
+}
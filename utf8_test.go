@@ -0,0 +1,208 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestUTF8(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		name    string
+		comment string
+		extra   []byte
+
+		// writeUTF8 and readUTF8 indicate whether WithWriteUTF8 and
+		// WithReadUTF8 are used.
+		writeUTF8 bool
+		readUTF8  bool
+
+		// writeStrings and readStrings indicate whether
+		// WithWriteUTF8Strings and WithReadUTF8Strings are used.
+		writeStrings bool
+		readStrings  bool
+
+		wantName    string
+		wantComment string
+		wantExtra   []byte
+	}{
+		"utf-8": {
+			name:        "辞書.txt",
+			comment:     "日本語",
+			writeUTF8:   true,
+			readUTF8:    true,
+			wantName:    "辞書.txt",
+			wantComment: "日本語",
+		},
+		"utf-8 with extra": {
+			name:      "辞書.txt",
+			extra:     []byte{'A', 'Z', 0x1, 0x0, 0xab},
+			writeUTF8: true,
+			readUTF8:  true,
+			wantName:  "辞書.txt",
+			wantExtra: []byte{'A', 'Z', 0x1, 0x0, 0xab},
+		},
+		"fallback": {
+			name:        "辞書.txt",
+			comment:     "日本語 é",
+			writeUTF8:   true,
+			wantName:    "__.txt",
+			wantComment: "___ é",
+			wantExtra: append(
+				[]byte{'U', 'N', 0xa, 0x0}, append([]byte("辞書.txt"),
+					append([]byte{'U', 'C', 0xc, 0x0}, []byte("日本語 é")...)...)...,
+			),
+		},
+		"latin-1": {
+			name:        "naïve.txt",
+			comment:     "é",
+			writeUTF8:   true,
+			readUTF8:    true,
+			wantName:    "naïve.txt",
+			wantComment: "é",
+		},
+		"utf-8 strings": {
+			name:         "日本語.dict",
+			comment:      "辞書 é",
+			writeStrings: true,
+			readStrings:  true,
+			wantName:     "日本語.dict",
+			wantComment:  "辞書 é",
+		},
+		"utf-8 strings ignores writeUTF8": {
+			name:         "日本語.dict",
+			writeStrings: true,
+			writeUTF8:    true,
+			readStrings:  true,
+			wantName:     "日本語.dict",
+		},
+		"utf-8 strings read as latin-1": {
+			name:         "é.dict",
+			writeStrings: true,
+			wantName:     "Ã©.dict",
+		},
+		"latin-1 read with utf-8 strings": {
+			name:        "naïve.txt",
+			comment:     "é",
+			readStrings: true,
+			wantName:    "naïve.txt",
+			wantComment: "é",
+		},
+		"directories": {
+			name:      "/dir/辞書/辞書.txt",
+			writeUTF8: true,
+			readUTF8:  true,
+			wantName:  "辞書.txt",
+		},
+		"replaces existing subfields": {
+			name:      "辞書.txt",
+			extra:     []byte{'U', 'N', 0x3, 0x0, 'o', 'l', 'd'},
+			writeUTF8: true,
+			readUTF8:  true,
+			wantName:  "辞書.txt",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var wOpts []WriterOption
+			if tc.writeUTF8 {
+				wOpts = append(wOpts, WithWriteUTF8())
+			}
+			if tc.writeStrings {
+				wOpts = append(wOpts, WithWriteUTF8Strings())
+			}
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, wOpts...)
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			w.Name = tc.name
+			w.Comment = tc.comment
+			w.Extra = tc.extra
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			var rOpts []ReaderOption
+			if tc.readUTF8 {
+				rOpts = append(rOpts, WithReadUTF8())
+			}
+			if tc.readStrings {
+				rOpts = append(rOpts, WithReadUTF8Strings())
+			}
+			z, err := NewReader(bytes.NewReader(buf.Bytes()), rOpts...)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			if diff := cmp.Diff(tc.wantName, z.Name); diff != "" {
+				t.Errorf("Name (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantComment, z.Comment); diff != "" {
+				t.Errorf("Comment (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantExtra, z.Extra); diff != "" {
+				t.Errorf("Extra (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithWriteUTF8Strings_invalid(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, WithWriteUTF8Strings())
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	w.Name = "\xff.dict"
+	err = w.Close()
+	if diff := cmp.Diff(ErrHeader, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("Close (-want, +got):\n%s", diff)
+	}
+}
+
+func TestSplitUTF8Subfields(t *testing.T) {
+	t.Parallel()
+
+	extra := []byte{
+		'U', 'N', 0x2, 0x0, 0xff, 0xfe, // invalid UTF-8
+		'A', 'Z', 0x1, 0x0, 0xab,
+		'U', 'C', 0x2, 0x0, 'o', 'k',
+	}
+
+	rest, name, comment := splitUTF8Subfields(extra)
+	if diff := cmp.Diff([]byte{'A', 'Z', 0x1, 0x0, 0xab}, rest); diff != "" {
+		t.Errorf("rest (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("", name); diff != "" {
+		t.Errorf("name (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("ok", comment); diff != "" {
+		t.Errorf("comment (-want, +got):\n%s", diff)
+	}
+}
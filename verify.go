@@ -0,0 +1,242 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// Verify inflates all chunks and verifies the data against the CRC-32 and
+// ISIZE fields of the gzip trailer, and against the chunk checksums if the
+// archive includes them. It returns an error wrapping [ErrTrailer] or
+// [ErrChunkChecksum] if the data does not match. Each member of multistream
+// files is verified against its own trailer.
+//
+// Chunks are inflated in parallel as with [Checksum]. Verify does not change
+// the offset of z.
+func (z *Reader) Verify() error {
+	z.lock()
+	members := z.members
+	if members != nil {
+		defer z.unlock()
+		for i, m := range members {
+			if err := m.r.Verify(); err != nil {
+				return fmt.Errorf("member %d at offset %d: %w", i, m.Offset, err)
+			}
+		}
+		return nil
+	}
+	v := newDataVerifier(z.chunkSize, z.chunkCRCs, z.chunkCRCTable())
+	chunks := len(z.sizes)
+	z.unlock()
+
+	if _, err := Checksum(z, func() hash.Hash { return v }, 0); err != nil {
+		return err
+	}
+
+	z.lock()
+	defer z.unlock()
+	trailer, err := z.readTrailer()
+	if err != nil {
+		return err
+	}
+	if err := v.verifyTrailer(trailer); err != nil {
+		return err
+	}
+	if !z.plainGzip {
+		return v.verifyChunkCount(chunks)
+	}
+	return nil
+}
+
+// readTrailer reads the gzip trailer following the final deflate block of
+// the member.
+func (z *Reader) readTrailer() ([]byte, error) {
+	off, err := z.trailerOffset()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := z.r.Seek(off, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("%w: missing trailer: %w", ErrTrailer, err)
+	}
+	trailer := make([]byte, trailerSize)
+	if _, err := io.ReadFull(z.r, trailer); err != nil {
+		return nil, fmt.Errorf("%w: missing trailer: %w", ErrTrailer, err)
+	}
+	return trailer, nil
+}
+
+// trailerOffset returns the offset of the gzip trailer in the underlying
+// reader. The trailer immediately follows the end of the deflate stream,
+// which is found by inflating the last chunk and the final deflate block, so
+// that data following the member, such as padding or other members, is not
+// mistaken for the trailer. The offset is cached.
+func (z *Reader) trailerOffset() (int64, error) {
+	if z.trailerOff >= 0 {
+		return z.trailerOff, nil
+	}
+	n, err := z.memberLen()
+	if err != nil {
+		return 0, err
+	}
+	z.trailerOff = n - trailerSize
+	return z.trailerOff, nil
+}
+
+// trackRead updates the CRC-32 of data read sequentially with [Reader.Read]
+// from the start of the data with the data p read at off and verifies the
+// trailer once err is io.EOF. Data read after seeking is not verified.
+func (z *Reader) trackRead(p []byte, off int64, err error) error {
+	if z.members != nil || z.salvage || z.downloaded >= 0 {
+		// NOTE: The trailer at the end of the file does not cover all
+		// data read or may not be available.
+		return nil
+	}
+	if off == 0 {
+		z.readVerifier = newDataVerifier(0, nil, nil)
+	}
+	if z.readVerifier == nil || off != z.readVerifier.n {
+		z.readVerifier = nil
+		return nil
+	}
+	_, _ = z.readVerifier.Write(p)
+	if err != io.EOF { //nolint:errorlint // readInto returns unwrapped io.EOF.
+		return nil
+	}
+
+	trailer, err := z.readTrailer()
+	if err != nil {
+		return err
+	}
+	return z.readVerifier.verifyTrailer(trailer)
+}
+
+// dataVerifier is a [hash.Hash32] computing the CRC-32 of the uncompressed
+// data written to it. If chunkCRCs is not nil, the data is verified against
+// the chunk checksums as each chunk is completed and Write returns an error
+// wrapping [ErrChunkChecksum] if a chunk does not match.
+type dataVerifier struct {
+	hash.Hash32
+
+	chunkSize int
+	chunkCRCs []uint32
+
+	// chunkDigest is the CRC-32 of the data written to the current chunk.
+	// It is nil if chunkCRCs is nil.
+	chunkDigest hash.Hash32
+
+	// n is the number of bytes written.
+	n int64
+}
+
+// newDataVerifier returns a new dataVerifier for an archive with the given
+// chunk size and chunk checksums using the CRC-32 table tab.
+func newDataVerifier(chunkSize int, chunkCRCs []uint32, tab *crc32.Table) *dataVerifier {
+	v := &dataVerifier{
+		Hash32:    crc32.NewIEEE(),
+		chunkSize: chunkSize,
+		chunkCRCs: chunkCRCs,
+	}
+	if chunkCRCs != nil && chunkSize > 0 {
+		v.chunkDigest = crc32.New(tab)
+	}
+	return v
+}
+
+// Write implements [io.Writer].
+func (v *dataVerifier) Write(p []byte) (int, error) {
+	_, _ = v.Hash32.Write(p)
+	if v.chunkDigest == nil {
+		v.n += int64(len(p))
+		return len(p), nil
+	}
+
+	written := len(p)
+	chunkSize := int64(v.chunkSize)
+	for len(p) > 0 {
+		n := len(p)
+		if rem := chunkSize - v.n%chunkSize; int64(n) > rem {
+			n = int(rem)
+		}
+		_, _ = v.chunkDigest.Write(p[:n])
+		v.n += int64(n)
+		p = p[n:]
+		if v.n%chunkSize == 0 {
+			if err := v.verifyChunk(v.n/chunkSize - 1); err != nil {
+				return written - len(p), err
+			}
+		}
+	}
+	return written, nil
+}
+
+// chunk returns the index of the chunk at the current offset.
+func (v *dataVerifier) chunk() int64 {
+	if v.chunkSize == 0 {
+		return 0
+	}
+	return v.n / int64(v.chunkSize)
+}
+
+// verifyChunk verifies the checksum of chunk c against the data written to
+// chunkDigest and resets it.
+func (v *dataVerifier) verifyChunk(c int64) error {
+	defer v.chunkDigest.Reset()
+	if c >= int64(len(v.chunkCRCs)) {
+		return fmt.Errorf("%w: data beyond chunk %d", ErrChunkChecksum, len(v.chunkCRCs)-1)
+	}
+	if v.chunkDigest.Sum32() != v.chunkCRCs[c] {
+		return fmt.Errorf("%w: chunk %d", ErrChunkChecksum, c)
+	}
+	return nil
+}
+
+// verifyTrailer verifies the last chunk, if it is partial, and the gzip
+// trailer against the data written.
+func (v *dataVerifier) verifyTrailer(trailer []byte) error {
+	if v.chunkDigest != nil && v.n%int64(v.chunkSize) != 0 {
+		if err := v.verifyChunk(v.chunk()); err != nil {
+			return err
+		}
+	}
+
+	if len(trailer) < trailerSize {
+		return fmt.Errorf("%w: missing trailer", ErrTrailer)
+	}
+	if crc := binary.LittleEndian.Uint32(trailer[:4]); crc != v.Sum32() {
+		return fmt.Errorf("%w: CRC-32 %08x does not match data", ErrTrailer, crc)
+	}
+	//nolint:gosec // ISIZE is the size modulo 2^32.
+	if isize := binary.LittleEndian.Uint32(trailer[4:]); isize != uint32(v.n) {
+		return fmt.Errorf("%w: ISIZE %d does not match data", ErrTrailer, isize)
+	}
+	return nil
+}
+
+// verifyChunkCount verifies that the data written fills the given number of
+// chunks.
+func (v *dataVerifier) verifyChunkCount(chunks int) error {
+	if v.chunkSize <= 0 {
+		return nil
+	}
+	if got := (v.n + int64(v.chunkSize) - 1) / int64(v.chunkSize); got != int64(chunks) {
+		return fmt.Errorf("%w: data has %d chunks but the header has %d", ErrTrailer, got, chunks)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// corruptTrailer returns a copy of the dictzip file b with a bad CRC-32.
+func corruptTrailer(b []byte) []byte {
+	c := bytes.Clone(b)
+	c[len(c)-trailerSize] ^= 0xff
+	return c
+}
+
+// corruptChunkCRC returns a copy of the dictzip file b with a bad checksum
+// for the first chunk.
+func corruptChunkCRC(t *testing.T, b []byte) []byte {
+	t.Helper()
+
+	z, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	crc := make([]byte, 4)
+	binary.LittleEndian.PutUint32(crc, z.ChunkCRCs()[0])
+	i := bytes.Index(b, crc)
+	if i < 0 {
+		t.Fatalf("chunk checksum not found")
+	}
+	c := bytes.Clone(b)
+	c[i] ^= 0xff
+	return c
+}
+
+func TestReader_Verify(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("verify me please\n"), 1000)
+	valid := compressStream(t, data, 1000)
+	withCRCs := compressStream(t, data, 1000, WithChunkChecksums())
+	multi, _ := multistreamFile(t, [][]byte{data, []byte("second member")}, []int{1000, 100})
+
+	testCases := map[string]struct {
+		file []byte
+		opts []ReaderOption
+		err  error
+	}{
+		"valid": {
+			file: valid,
+		},
+		"valid with chunk checksums": {
+			file: withCRCs,
+		},
+		"bad crc": {
+			file: corruptTrailer(valid),
+			err:  ErrTrailer,
+		},
+		"bad chunk checksum": {
+			file: corruptChunkCRC(t, withCRCs),
+			err:  ErrChunkChecksum,
+		},
+		"multistream": {
+			file: multi,
+			opts: []ReaderOption{WithMultistream()},
+		},
+		"multistream bad crc": {
+			file: corruptTrailer(multi),
+			opts: []ReaderOption{WithMultistream()},
+			err:  ErrTrailer,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(tc.file), tc.opts...)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			err = z.Verify()
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Verify (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReader_Read_trailer(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("read me please\n"), 1000)
+	valid := compressStream(t, data, 1000)
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+
+		z, err := NewReader(bytes.NewReader(valid))
+		if err != nil {
+			t.Fatalf("NewReader: %v", err)
+		}
+		got, err := io.ReadAll(z)
+		if err != nil {
+			t.Fatalf("ReadAll: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("ReadAll: data does not match")
+		}
+	})
+
+	t.Run("bad crc", func(t *testing.T) {
+		t.Parallel()
+
+		z, err := NewReader(bytes.NewReader(corruptTrailer(valid)))
+		if err != nil {
+			t.Fatalf("NewReader: %v", err)
+		}
+		_, err = io.ReadAll(z)
+		if diff := cmp.Diff(ErrTrailer, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("ReadAll (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("bad crc after seek", func(t *testing.T) {
+		t.Parallel()
+
+		// NOTE: The trailer is not verified if the data was not read
+		// from the start.
+		z, err := NewReader(bytes.NewReader(corruptTrailer(valid)))
+		if err != nil {
+			t.Fatalf("NewReader: %v", err)
+		}
+		if _, err := z.Seek(100, io.SeekStart); err != nil {
+			t.Fatalf("Seek: %v", err)
+		}
+		if _, err := io.ReadAll(z); err != nil {
+			t.Errorf("ReadAll: %v", err)
+		}
+	})
+}
+
+func TestReader_trailingData(t *testing.T) {
+	t.Parallel()
+
+	data := benchmarkData(70000)
+	archive := compressStream(t, data, 1024)
+	other := compressStream(t, []byte("other member"), 1024)
+
+	testCases := map[string]struct {
+		file []byte
+		opts []ReaderOption
+	}{
+		"padding": {
+			file: append(bytes.Clone(archive), make([]byte, 512)...),
+		},
+		"concatenated members": {
+			file: append(bytes.Clone(archive), other...),
+		},
+		"base offset with trailing data": {
+			file: append(append([]byte("prefix"), archive...), "trailing data"...),
+			opts: []ReaderOption{WithBaseOffset(6)},
+		},
+		"gzip padding": {
+			file: append(gzipFile(t, data, nil), make([]byte, 512)...),
+			opts: []ReaderOption{WithGzipFallback()},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			z, err := NewReader(bytes.NewReader(tc.file), tc.opts...)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer z.Close()
+
+			size, err := z.Size()
+			if err != nil {
+				t.Fatalf("Size: %v", err)
+			}
+			if diff := cmp.Diff(int64(len(data)), size); diff != "" {
+				t.Errorf("Size (-want, +got):\n%s", diff)
+			}
+
+			got, err := io.ReadAll(z)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if !bytes.Equal(data, got) {
+				t.Errorf("ReadAll: data does not match")
+			}
+
+			if err := z.Verify(); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+		})
+	}
+}
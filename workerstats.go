@@ -0,0 +1,53 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import "time"
+
+// WorkerStats are statistics for a worker used by [Writer.CompressFrom] to
+// compress chunks in parallel.
+type WorkerStats struct {
+	// Chunks is the number of chunks compressed by the worker.
+	Chunks int
+
+	// Bytes is the number of uncompressed bytes compressed by the worker.
+	Bytes int64
+
+	// Duration is the time the worker spent reading and compressing chunks.
+	Duration time.Duration
+}
+
+// Throughput returns the number of uncompressed bytes compressed per second by
+// the worker.
+func (s WorkerStats) Throughput() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Duration.Seconds()
+}
+
+// WorkerStats returns statistics for each worker used by the last call to
+// [Writer.CompressFrom]. Chunks that are compressed sequentially, such as the
+// partial chunks at the start and end of the data, are not included. It
+// returns nil if CompressFrom has not compressed chunks in parallel.
+func (z *Writer) WorkerStats() []WorkerStats {
+	return z.workerStats
+}
+
+// compressWorker is a worker used by [Writer.CompressFrom].
+type compressWorker struct {
+	fw    Compressor
+	stats WorkerStats
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dictzip
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriter_WorkerStats(t *testing.T) {
+	t.Parallel()
+
+	var data []byte
+	for i := 0; i < 1000; i++ {
+		data = append(data, []byte("Hello, WorkerStats!\n")...)
+	}
+
+	var buf bytes.Buffer
+	z, err := NewWriterLevel(&buf, DefaultCompression, 1000)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	defer z.Close()
+
+	if got := z.WorkerStats(); got != nil {
+		t.Errorf("WorkerStats before CompressFrom: got %v, want nil", got)
+	}
+
+	// Write a partial chunk so that the first chunk is completed
+	// sequentially.
+	if _, err := z.Write(data[:500]); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := z.CompressFrom(bytes.NewReader(data[500:]), int64(len(data)-500), 3); err != nil {
+		t.Fatalf("CompressFrom: %v", err)
+	}
+
+	stats := z.WorkerStats()
+	if diff := cmp.Diff(3, len(stats)); diff != "" {
+		t.Fatalf("len(WorkerStats) (-want, +got):\n%s", diff)
+	}
+
+	var chunks int
+	var n int64
+	for _, s := range stats {
+		chunks += s.Chunks
+		n += s.Bytes
+		if s.Chunks > 0 && s.Throughput() <= 0 {
+			t.Errorf("Throughput: got %v, want > 0", s.Throughput())
+		}
+	}
+	// The 19 full chunks after the first partial chunk.
+	if diff := cmp.Diff(19, chunks); diff != "" {
+		t.Errorf("chunks (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(int64(19000), n); diff != "" {
+		t.Errorf("bytes (-want, +got):\n%s", diff)
+	}
+}
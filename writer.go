@@ -17,19 +17,50 @@ package dictzip
 import (
 	"bytes"
 	"compress/flate"
+	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"hash"
 	"hash/crc32"
 	"io"
 	"math"
 	"os"
+	"runtime"
 	"time"
+
+	"github.com/ianlewis/go-dictzip/format"
 )
 
 const (
 	// DefaultChunkSize is the default chunk size used when writing dictzip files.
 	DefaultChunkSize = math.MaxUint16
+
+	// MaxChunkSize is the maximum uncompressed chunk size supported by the
+	// dictzip format. The chunk size is stored in the 16-bit CHLEN field.
+	MaxChunkSize = math.MaxUint16
+
+	// MaxStoredChunkSize is the maximum chunk size for which chunks stored
+	// uncompressed by [WithStoreIncompressible] are within the format's limit
+	// on the size of compressed chunks.
+	MaxStoredChunkSize = MaxChunkSize - storedOverhead
+)
+
+// storedOverhead is the size of the stored block header and empty sync block
+// written by storedBlock.
+const storedOverhead = 10
+
+var (
+	// ErrChunkSize indicates that an invalid chunk size was given to the
+	// [Writer].
+	ErrChunkSize = fmt.Errorf("%w: invalid chunk size", errDictzip)
+
+	// ErrCompressedChunkSize indicates that a chunk was larger than the
+	// 65535 bytes that can be recorded in the RA subfield once compressed.
+	// It is returned as soon as the chunk is compressed by [Writer.Write] or
+	// [Writer.CompressFrom], and from all later calls. See
+	// [WithStoreIncompressible].
+	ErrCompressedChunkSize = fmt.Errorf("%w: compressed chunk too large", errDictzip)
 )
 
 const (
@@ -54,8 +85,9 @@ const (
 )
 
 // Writer implements [io.WriteCloser] for writing dictzip files. Writer writes
-// chunks to a temporary file during write and copies the resulting data to the
-// final file when [Writer.Close] is called.
+// chunks to a temporary file, or to memory (see [WithMemoryBuffer]), during
+// write and copies the resulting data to the final file when [Writer.Close] is
+// called.
 //
 // For this reason, [Writer.Close] must be called in order to write the file
 // correctly.
@@ -64,7 +96,21 @@ type Writer struct {
 	Header
 
 	// tmp is the temporary file where chunks will be written.
-	tmp *os.File
+	tmp chunkFile
+
+	// headerLen is the length of the header written by Close.
+	// See [Writer.HeaderLen].
+	headerLen int
+
+	// chunkTableOff and chunkEntrySize are the offset of the chunk table in
+	// the header written by Close and the size of its entries. See
+	// [Writer.ChunkTableRange].
+	chunkTableOff  int
+	chunkEntrySize int
+
+	// memoryBuffer indicates that chunks are written to memory rather than
+	// a temporary file. See [WithMemoryBuffer].
+	memoryBuffer bool
 
 	// hasData is true if data has been written to the chunk buffer but hasn't
 	// been finalized and written to tmp. We need this because we can't simply
@@ -78,7 +124,10 @@ type Writer struct {
 
 	// compressor is the compression writer used to write the current
 	// compressed chunk to chunkBuf.
-	compressor *flate.Writer
+	compressor Compressor
+
+	// newCompressor creates new deflate compressors. See [WithCompressor].
+	newCompressor func(w io.Writer, level int) (Compressor, error)
 
 	// w is the io.Writer for the final destination for the compressed file.
 	w io.Writer
@@ -95,64 +144,263 @@ type Writer struct {
 
 	// closed indicates the writer has been closed.
 	closed bool
+
+	// sharedWindow indicates that chunks share the deflate window.
+	sharedWindow bool
+
+	// storeIncompressible indicates that chunks which would be larger when
+	// compressed are stored uncompressed. See [WithStoreIncompressible].
+	storeIncompressible bool
+
+	// chunkData is the uncompressed data of the current chunk. It is only
+	// kept if storeIncompressible is set.
+	chunkData []byte
+
+	// rawName indicates that the Name is written as-is without
+	// normalization. See [WithRawName].
+	rawName bool
+
+	// chunkDigest is the CRC-32 digest of the current chunk. It is nil
+	// unless chunk checksums are written. See [WithChunkChecksums].
+	chunkDigest hash.Hash32
+
+	// utf8Strings indicates that Name and Comment are written as UTF-8.
+	// See [WithWriteUTF8Strings].
+	utf8Strings bool
+
+	// writeUTF8 indicates that non-Latin-1 Name and Comment values are
+	// written to UTF-8 EXTRA subfields. See [WithWriteUTF8].
+	writeUTF8 bool
+
+	// onChunk is called for each chunk before it is written. See
+	// [WithOnChunk].
+	onChunk ChunkFunc
+
+	// rejected is the error returned after a chunk was rejected by onChunk
+	// or was too large.
+	rejected error
+
+	// gz is the writer for the plain gzip file written along with the
+	// dictzip file. It is nil unless created by [NewMultiFormatWriter].
+	// gzHeader indicates that the gzip header has been written.
+	gz       io.Writer
+	gzHeader bool
+
+	// extraFields are the EXTRA subfields written before Header.Extra.
+	// See [WithExtraFields].
+	extraFields []ExtraField
+
+	// dictdCompatible indicates that archives compatible with dictd are
+	// written. See [WithDictdCompatible].
+	dictdCompatible bool
+
+	// clampModTime indicates that modification times which can not be
+	// stored are clamped rather than rejected. See [WithClampModTime].
+	clampModTime bool
+
+	// workerStats are the statistics for the workers used by the last call
+	// to CompressFrom. See [Writer.WorkerStats].
+	workerStats []WorkerStats
+
+	// dedupHash is the SHA-256 hash of the current chunk's uncompressed
+	// data. dedupSeen maps the hashes of the chunks written to the index of
+	// the first chunk with the hash and duplicateOf maps the indexes of
+	// duplicate chunks to the first chunk. They are nil unless duplicate
+	// chunks are detected. See [WithDedup].
+	dedupHash   hash.Hash
+	dedupSeen   map[[sha256.Size]byte]int
+	duplicateOf map[int]int
+	dedupStats  DedupStats
+
+	// checkpointPath is the path of the checkpoint file and
+	// checkpointInterval is the number of chunks between checkpoints.
+	// checkpointDigest and checkpointContentHash are the encoded CRC-32
+	// and content hash digests at the last chunk boundary. resume is the
+	// checkpoint state to continue from. See [WithCheckpoint] and
+	// [ResumeWriter].
+	checkpointPath        string
+	checkpointInterval    int
+	checkpointDigest      []byte
+	checkpointContentHash []byte
+	resume                *checkpointState
+
+	// manifest is the manifest written to the header and contentHash is
+	// the SHA-256 digest of the uncompressed data stored in it. See
+	// [WithManifest] and [WithContentHash].
+	manifest    Manifest
+	contentHash hash.Hash
+}
+
+// WriterOption is an option that configures a [Writer].
+type WriterOption func(*Writer)
+
+// WithSharedWindow configures the [Writer] to share the deflate (LZ77) window
+// between consecutive chunks rather than starting each chunk with an empty
+// window. This improves the compression ratio, especially for small chunk
+// sizes, at the cost of random access performance since the [Reader] must
+// inflate the preceding chunk in order to read a chunk.
+//
+// Archives written with this option are marked with an additional EXTRA
+// sub-field and are NOT compatible with dictzip(1) or other dictzip
+// implementations, though they remain readable by gzip(1).
+func WithSharedWindow() WriterOption {
+	return func(z *Writer) {
+		z.sharedWindow = true
+	}
+}
+
+// WithStoreIncompressible configures the [Writer] to store chunks that would
+// be larger when compressed as uncompressed deflate blocks, as is done by
+// gzip(1). Stored chunks are readable by any deflate decompressor.
+//
+// Incompressible chunks of more than [MaxStoredChunkSize] bytes are too large
+// for the format once compressed, and are too large to be stored. Use a chunk
+// size of at most MaxStoredChunkSize, such as [DictdChunkSize], with this
+// option so that any data can be written.
+//
+// This option has no effect if [WithSharedWindow] is also given.
+func WithStoreIncompressible() WriterOption {
+	return func(z *Writer) {
+		z.storeIncompressible = true
+	}
+}
+
+// WithRawName configures the [Writer] to write the Name header field as-is.
+// By default, directories are removed from the name as is done by gzip(1) so
+// that paths are not leaked and archives created on Windows list cleanly on
+// other platforms.
+func WithRawName() WriterOption {
+	return func(z *Writer) {
+		z.rawName = true
+	}
 }
 
 // NewWriter initializes a new dictzip [Writer] with the default compression
 // level and chunk size.
 //
 // The OS Header is always set to [OSUnknown] (0xff) by default.
-func NewWriter(w io.Writer) (*Writer, error) {
-	return NewWriterLevel(w, DefaultCompression, DefaultChunkSize)
+func NewWriter(w io.Writer, opts ...WriterOption) (*Writer, error) {
+	return NewWriterLevel(w, DefaultCompression, DefaultChunkSize, opts...)
 }
 
 // NewWriterLevel initializes a new dictzip [Writer] with the given compression
-// level and chunk size.
+// level and chunk size. The chunk size must be between 1 and [MaxChunkSize]
+// otherwise an error wrapping [ErrChunkSize] is returned.
 //
-// The OS Header is always set to [OSUnknown] (0xff) by default.
-func NewWriterLevel(w io.Writer, level, chunkSize int) (*Writer, error) {
-	tmp, err := os.CreateTemp("", "dictzip.*")
-	if err != nil {
-		return nil, fmt.Errorf("%w: creating temp file: %w", errDictzip, err)
-	}
-
-	var buf bytes.Buffer
-	fw, err := flate.NewWriter(&buf, level)
-	if err != nil {
-		return nil, fmt.Errorf("%w: initializing deflate writer: %w", errDictzip, err)
+// The OS Header is always set to [OSUnknown] (0xff) by default. The XFL header
+// is set based on the compression level (see [LevelXFL]).
+func NewWriterLevel(w io.Writer, level, chunkSize int, opts ...WriterOption) (*Writer, error) {
+	if chunkSize <= 0 || chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("%w: %d: must be between 1 and %d", ErrChunkSize, chunkSize, MaxChunkSize)
 	}
 
 	digest := crc32.NewIEEE()
 	z := Writer{
 		Header: Header{
-			OS: OSUnknown,
+			OS:  OSUnknown,
+			XFL: LevelXFL(level),
 		},
-		tmp:        tmp,
-		hasData:    false,
-		chunkBuf:   &buf,
-		compressor: fw,
-		w:          w,
-		digest:     digest,
-		level:      level,
+		hasData:       false,
+		chunkBuf:      &bytes.Buffer{},
+		newCompressor: newFlateCompressor,
+		w:             w,
+		digest:        digest,
+		level:         level,
 	}
 	z.chunkSize = chunkSize
 
+	for _, opt := range opts {
+		opt(&z)
+	}
+
+	fw, err := z.newCompressor(z.chunkBuf, level)
+	if err != nil {
+		return nil, fmt.Errorf("%w: initializing deflate writer: %w", errDictzip, err)
+	}
+	z.compressor = fw
+	z.applyDictdCompatible()
+	if err := validateExtraFields(z.extraFields); err != nil {
+		return nil, err
+	}
+	if err := validateManifest(z.manifest); err != nil {
+		return nil, err
+	}
+	if z.reservedChunks < 0 {
+		return nil, fmt.Errorf("%w: negative reserved chunk entries: %d", ErrHeader, z.reservedChunks)
+	}
+
+	switch {
+	case z.checkpointPath != "":
+		if err := z.openChunkFile(); err != nil {
+			return nil, err
+		}
+	case z.memoryBuffer:
+		z.tmp = &memFile{}
+	default:
+		tmp, err := os.CreateTemp("", "dictzip.*")
+		if err != nil {
+			return nil, fmt.Errorf("%w: creating temp file: %w", errDictzip, err)
+		}
+		z.tmp = tmp
+	}
+	if z.sharedWindow {
+		// NOTE: Stored chunks would break the shared deflate stream.
+		z.storeIncompressible = false
+	}
+
 	return &z, nil
 }
 
+// Level returns the compression level of the Writer. For a Writer created by
+// [ResumeWriter] it is the level read from the checkpoint.
+func (z *Writer) Level() int {
+	return z.level
+}
+
+// EstimateRatio returns the estimated ratio of compressed to uncompressed
+// size for data similar to sample when compressed at the given level. A ratio
+// greater than 1 indicates that the compressed data would be larger than the
+// input. EstimateRatio returns 1 for an empty sample.
+func EstimateRatio(sample []byte, level int) (float64, error) {
+	if len(sample) == 0 {
+		return 1, nil
+	}
+
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, level)
+	if err != nil {
+		return 0, fmt.Errorf("%w: initializing deflate writer: %w", errDictzip, err)
+	}
+	if _, err := fw.Write(sample); err != nil {
+		return 0, fmt.Errorf("%w: compressing: %w", errDictzip, err)
+	}
+	// NOTE: Chunks are flushed rather than closed so the sync marker is
+	// included in the estimate.
+	if err := fw.Flush(); err != nil {
+		return 0, fmt.Errorf("%w: compressing: %w", errDictzip, err)
+	}
+
+	return float64(buf.Len()) / float64(len(sample)), nil
+}
+
 func (z *Writer) Write(p []byte) (int, error) {
 	if z.closed {
 		return 0, fmt.Errorf("%w: Write called on closed writer", errDictzip)
 	}
+	if z.rejected != nil {
+		return 0, z.rejected
+	}
 
 	// Write chunks to z.compressor, resetting the Writer, and flushing chunks
 	// to the z.tmp as necessary.
 	var i int
 	for i < len(p) {
-		// Get the end index by adding the chunk size minus any already written
-		// part of the current chunk.
-		j := i + z.chunkSize - int(z.isize%int64(z.chunkSize))
-		if j > len(p) {
-			j = len(p)
+		// Get the end index by adding the remaining size of the current
+		// chunk. The comparison is done using int64 so that it is correct
+		// regardless of the total size written.
+		j := len(p)
+		if rem := z.chunkRemaining(); int64(j-i) > rem {
+			j = i + int(rem)
 		}
 
 		// Compress the data to chunkBuf.
@@ -166,12 +414,24 @@ func (z *Writer) Write(p []byte) (int, error) {
 		if err != nil {
 			return i + n, fmt.Errorf("%w: updating digest: %w", errDictzip, err)
 		}
+		if z.storeIncompressible {
+			z.chunkData = append(z.chunkData, p[i:i+n]...)
+		}
+		if z.chunkDigest != nil {
+			z.chunkDigest.Write(p[i : i+n])
+		}
+		if z.dedupHash != nil {
+			z.dedupHash.Write(p[i : i+n])
+		}
+		if z.contentHash != nil {
+			z.contentHash.Write(p[i : i+n])
+		}
 		i += n
 		if n > 0 {
 			z.hasData = true
 		}
 
-		if z.isize%int64(z.chunkSize) == 0 {
+		if z.chunkRemaining() == int64(z.chunkSize) {
 			err = z.flushCompressor()
 			if err != nil {
 				return i, err
@@ -182,6 +442,244 @@ func (z *Writer) Write(p []byte) (int, error) {
 	return i, nil
 }
 
+// chunkRemaining returns the number of uncompressed bytes that can be written
+// before the current chunk is full. It returns the chunk size if the current
+// chunk is empty.
+func (z *Writer) chunkRemaining() int64 {
+	chunkSize := int64(z.chunkSize)
+	return chunkSize - z.isize%chunkSize
+}
+
+// chunkResult is the result of compressing a single chunk in parallel.
+type chunkResult struct {
+	// data is the uncompressed chunk data.
+	data []byte
+
+	// compressed is the compressed chunk data.
+	compressed []byte
+
+	err error
+}
+
+// CompressFrom compresses size bytes read from r using up to workers
+// goroutines and writes them to the [Writer]. The input is partitioned on
+// chunk boundaries and chunks are compressed concurrently before being
+// written in order. The output is identical to calling [Writer.Write] with
+// the same data.
+//
+// If workers is less than 1, [runtime.NumCPU] workers are used. If
+// [WithSharedWindow] was given the chunks are not independent and are
+// compressed sequentially.
+//
+// CompressFrom returns the number of bytes read from r.
+func (z *Writer) CompressFrom(r io.ReaderAt, size int64, workers int) (int64, error) {
+	if z.closed {
+		return 0, fmt.Errorf("%w: CompressFrom called on closed writer", errDictzip)
+	}
+	if z.rejected != nil {
+		return 0, z.rejected
+	}
+
+	if z.sharedWindow {
+		//nolint:wrapcheck // Writer.Write errors are already wrapped.
+		return io.Copy(z, io.NewSectionReader(r, 0, size))
+	}
+
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	chunkSize := int64(z.chunkSize)
+	var off int64
+
+	// Fill the current partial chunk sequentially so that the remaining data
+	// starts on a chunk boundary.
+	if head := z.chunkRemaining(); head != chunkSize {
+		if head > size {
+			head = size
+		}
+		n, err := io.Copy(z, io.NewSectionReader(r, 0, head))
+		off += n
+		if err != nil {
+			//nolint:wrapcheck // Writer.Write errors are already wrapped.
+			return off, err
+		}
+	}
+
+	// Compress full chunks in parallel.
+	fullChunks := (size - off) / chunkSize
+	if int64(workers) > fullChunks {
+		workers = int(fullChunks) + 1
+	}
+
+	// compressors holds the idle workers.
+	compressors := make(chan *compressWorker, workers)
+	allWorkers := make([]*compressWorker, workers)
+	for i := 0; i < workers; i++ {
+		fw, err := z.newCompressor(io.Discard, z.level)
+		if err != nil {
+			return off, fmt.Errorf("%w: initializing deflate writer: %w", errDictzip, err)
+		}
+		allWorkers[i] = &compressWorker{fw: fw}
+		compressors <- allWorkers[i]
+	}
+	z.workerStats = nil
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// queue holds result channels in chunk order. Its capacity limits the
+	// number of chunks being compressed at once.
+	queue := make(chan chan chunkResult, workers-1)
+	base := off
+	go func() {
+		defer close(queue)
+		for i := int64(0); i < fullChunks; i++ {
+			result := make(chan chunkResult, 1)
+			select {
+			case queue <- result:
+			case <-done:
+				return
+			}
+
+			go func(chunkOff int64) {
+				w := <-compressors
+				defer func() { compressors <- w }()
+				start := time.Now()
+				res := compressChunk(r, chunkOff, chunkSize, w.fw, z.storeIncompressible)
+				// NOTE: The stats are updated before the result is sent so
+				// that they are complete once all results are received.
+				w.stats.Chunks++
+				w.stats.Bytes += int64(len(res.data))
+				w.stats.Duration += time.Since(start)
+				result <- res
+			}(base + i*chunkSize)
+		}
+	}()
+
+	for result := range queue {
+		res := <-result
+		if res.err != nil {
+			return off, res.err
+		}
+		if err := z.checkChunk(len(res.data), res.compressed); err != nil {
+			return off, err
+		}
+		if err := z.teeChunk(res.compressed); err != nil {
+			return off, err
+		}
+
+		if _, err := z.digest.Write(res.data); err != nil {
+			return off, fmt.Errorf("%w: updating digest: %w", errDictzip, err)
+		}
+		if z.contentHash != nil {
+			z.contentHash.Write(res.data)
+		}
+		if _, err := z.tmp.Write(res.compressed); err != nil {
+			return off, fmt.Errorf("%w: compressing: %w", errDictzip, err)
+		}
+		z.sizes = append(z.sizes, len(res.compressed))
+		if z.chunkDigest != nil {
+			z.chunkCRCs = append(z.chunkCRCs, crc32.Checksum(res.data, z.chunkCRCTable()))
+		}
+		if z.dedupHash != nil {
+			z.dedupChunk(sha256.Sum256(res.data), len(res.data))
+		}
+		z.isize += int64(len(res.data))
+		off += int64(len(res.data))
+		if err := z.checkpointChunk(); err != nil {
+			return off, err
+		}
+	}
+
+	if fullChunks > 0 {
+		z.workerStats = make([]WorkerStats, workers)
+		for i, w := range allWorkers {
+			z.workerStats[i] = w.stats
+		}
+	}
+
+	// Write the remaining partial chunk sequentially.
+	n, err := io.Copy(z, io.NewSectionReader(r, off, size-off))
+	off += n
+	//nolint:wrapcheck // Writer.Write errors are already wrapped.
+	return off, err
+}
+
+// compressChunk reads and compresses the chunk of the given size at offset off
+// in r using fw. If store is true, the chunk is stored uncompressed if that is
+// smaller.
+func compressChunk(r io.ReaderAt, off, size int64, fw Compressor, store bool) chunkResult {
+	data := make([]byte, size)
+	n, err := r.ReadAt(data, off)
+	if int64(n) < size {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return chunkResult{err: fmt.Errorf("%w: reading: %w", errDictzip, err)}
+	}
+
+	var buf bytes.Buffer
+	fw.Reset(&buf)
+	if _, err := fw.Write(data); err != nil {
+		return chunkResult{err: fmt.Errorf("%w: compressing: %w", errDictzip, err)}
+	}
+	if err := fw.Flush(); err != nil {
+		return chunkResult{err: fmt.Errorf("%w: compressing: %w", errDictzip, err)}
+	}
+
+	compressed := buf.Bytes()
+	if store {
+		if stored := storedBlock(data); len(stored) < len(compressed) {
+			compressed = stored
+		}
+	}
+
+	return chunkResult{
+		data:       data,
+		compressed: compressed,
+	}
+}
+
+// storedBlock returns data as an uncompressed deflate block followed by an
+// empty sync block as written by [flate.Writer.Flush]. data must be no
+// larger than [MaxChunkSize].
+// See RFC 1951 Section 3.2.4.
+func storedBlock(data []byte) []byte {
+	b := make([]byte, 0, len(data)+storedOverhead)
+	//nolint:gosec // len(data) is at most MaxChunkSize.
+	n := uint16(len(data))
+
+	// BFINAL=0, BTYPE=00 (no compression), LEN, NLEN
+	b = append(b, 0x00)
+	b = binary.LittleEndian.AppendUint16(b, n)
+	b = binary.LittleEndian.AppendUint16(b, ^n)
+	b = append(b, data...)
+
+	// Empty sync block.
+	return append(b, 0x00, 0x00, 0x00, 0xff, 0xff)
+}
+
+// Ratios returns the ratio of compressed to uncompressed size for each chunk
+// written so far. A ratio greater than 1 indicates that the chunk is larger
+// than its uncompressed data.
+func (z *Writer) Ratios() []float64 {
+	ratios := make([]float64, len(z.sizes))
+	remaining := z.isize - z.isize%int64(z.chunkSize)
+	if z.closed {
+		remaining = z.isize
+	}
+	for i, size := range z.sizes {
+		chunkLen := int64(z.chunkSize)
+		if remaining < chunkLen {
+			chunkLen = remaining
+		}
+		remaining -= chunkLen
+		ratios[i] = float64(size) / float64(chunkLen)
+	}
+	return ratios
+}
+
 // Close closes the writer by writing the header with calculated offsets and
 // copying chunks from the temporary file to the final output file.
 func (z *Writer) Close() error {
@@ -191,6 +689,10 @@ func (z *Writer) Close() error {
 	z.closed = true
 	defer z.tmp.Close()
 
+	if z.rejected != nil {
+		return z.rejected
+	}
+
 	// Flush any compressed data chunks to z.tmp.
 	if err := z.flushCompressor(); err != nil {
 		return err
@@ -219,7 +721,8 @@ func (z *Writer) Close() error {
 
 	// Copy remaining data from chunkBuf to z.w. This is needed to write final
 	// deflate markers.
-	if _, err := io.Copy(z.w, z.chunkBuf); err != nil {
+	final := z.chunkBuf.Bytes()
+	if _, err := z.w.Write(final); err != nil {
 		return fmt.Errorf("%w: writing final chunk: %w", errDictzip, err)
 	}
 
@@ -232,130 +735,82 @@ func (z *Writer) Close() error {
 		return fmt.Errorf("%w: writing CRC-32 and isize: %w", errDictzip, err)
 	}
 
-	return nil
+	if err := z.closeGzip(final, buf); err != nil {
+		return err
+	}
+	return z.removeCheckpoint()
 }
 
 func (z *Writer) writeHeader() error {
-	header := make([]byte, 10)
-	header[0] = hdrGzipID1
-	header[1] = hdrGzipID2
-	header[2] = hdrDeflateCM
-	header[3] = flgEXTRA
-	if z.Name != "" {
-		header[3] |= flgNAME
-	}
-	if z.Comment != "" {
-		header[3] |= flgCOMMENT
-	}
-	if z.ModTime.After(time.Unix(0, 0)) {
-		// Section 2.3.1, the zero value for MTIME means that the
-		// modified time is not set.
-		// NOTE: since this is a uint32 timestamp, it should work until 2106-02-07.
-		//nolint:gosec // We will allow overflow of modtime. It is not a security issue.
-		binary.LittleEndian.PutUint32(header[4:8], uint32(z.ModTime.Unix()))
-	}
-	if z.level == BestCompression {
-		header[8] = 2
-	} else if z.level == BestSpeed {
-		header[8] = 4
-	}
-	header[9] = z.OS
-	if _, err := z.w.Write(header); err != nil {
-		return fmt.Errorf("%w: writing header: %w", errDictzip, err)
+	h, err := z.formatHeader()
+	if err != nil {
+		return err
 	}
-
-	if err := z.writeExtra(); err != nil {
+	header, err := format.Append(nil, h)
+	if err != nil {
+		//nolint:wrapcheck // errors from the format package are dictzip errors.
 		return err
 	}
 
-	if z.Name != "" {
-		if err := z.writeString(z.Name); err != nil {
-			return err
-		}
+	chunkTableOff, chunkEntrySize, err := format.ChunkTable(header)
+	if err != nil {
+		//nolint:wrapcheck // errors from the format package are dictzip errors.
+		return err
 	}
 
-	if z.Comment != "" {
-		if err := z.writeString(z.Comment); err != nil {
-			return err
-		}
+	if _, err := z.w.Write(header); err != nil {
+		return fmt.Errorf("%w: writing header: %w", errDictzip, err)
 	}
+	z.headerLen = len(header)
+	z.chunkTableOff, z.chunkEntrySize = chunkTableOff, chunkEntrySize
 
 	return nil
 }
 
-// writeExtra writes the EXTRA header starting with XLEN. The Dictzip random
-// access chunk size subfield is included first followed by user-specified
-// extra subfields in z.Extra.
-func (z *Writer) writeExtra() error {
-	// The extra header is written as follows.
-	// The RA random access dictzip field is written first.
-	// - RA subfield
-	//   - SI1 (1 byte) - gzip
-	//   - SI2 (1 byte) - gzip
-	//   - LEN (2 bytes) - gzip
-	//   - VER (2 bytes) - dictzip
-	//   - CHLEN (2 bytes) - dictzip
-	//   - CHCNT (2 bytes) - dictzip
-	//   - Chunk sizes (each 2 bytes).
-	// - User-specified z.Extra data.
-
-	// CHLEN
-	chlen := z.chunkSize
-	if chlen > math.MaxUint16 {
-		return fmt.Errorf("%w: CHLEN exceeded: %v", ErrHeader, chlen)
-	}
-
-	// CHCNT
-	chcnt := len(z.sizes)
-	if chcnt > math.MaxUint16 {
-		return fmt.Errorf("%w: CHCNT exceeded: %v", ErrHeader, chcnt)
-	}
-
-	// LEN field (includes VER, CHLEN, CHCNT, chunk sizes)
-	raLen := 6 + (chcnt * 2)
-
-	// XLEN (includes SI1, SI2, LEN, RA subfield, user-specified extra subfields)
-	xlen := 4 + raLen + len(z.Extra)
-	if xlen > math.MaxUint16 {
-		return fmt.Errorf("%w: XLEN exceeded: %v", ErrHeader, xlen)
-	}
-
-	// NOTE: Include 2 extra bytes for xlen itself.
-	extra := make([]byte, 2+xlen)
-
-	// Set XLEN (length of extra excluding XLEN)
-	//nolint:gosec // xlen max value is checked above.
-	binary.LittleEndian.PutUint16(extra[0:2], uint16(xlen))
-
-	// Write the RA subfield.
-	extra[2] = hdrDictzipSI1
-	extra[3] = hdrDictzipSI2
-	//nolint:gosec // raLen max value is checked above.
-	binary.LittleEndian.PutUint16(extra[4:6], uint16(raLen)) // LEN
-	binary.LittleEndian.PutUint16(extra[6:8], uint16(1))     // VER
-	//nolint:gosec // chlen max value is checked above.
-	binary.LittleEndian.PutUint16(extra[8:10], uint16(chlen))
-	// NOTE: chcnt max value is checked above. gosec doesn't seem to care about this.
-	binary.LittleEndian.PutUint16(extra[10:12], uint16(chcnt))
-
-	i := 12
-	for _, chSize := range z.sizes {
-		if chSize > math.MaxUint16 {
-			return fmt.Errorf("%w: chunk size exceeded: %v", ErrHeader, chSize)
-		}
-		//nolint:gosec // chSize max value is checked above.
-		binary.LittleEndian.PutUint16(extra[i:i+2], uint16(chSize))
-		i += 2
-	}
-
-	// Set the user specified extra data.
-	_ = copy(extra[i:], z.Extra)
-
-	_, err := z.w.Write(extra)
+// formatHeader returns the header to write.
+func (z *Writer) formatHeader() (*format.Header, error) {
+	modTime, err := z.normalizeModTime(z.ModTime)
 	if err != nil {
-		return fmt.Errorf("%w: writing EXTRA: %w", errDictzip, err)
+		return nil, err
 	}
-	return nil
+
+	name := z.Name
+	if !z.rawName {
+		name = normalizeName(name)
+	}
+
+	extra := appendExtraFields(nil, z.extraFields)
+	extra = append(extra, z.Extra...)
+	extra, err = z.manifestExtra(extra)
+	if err != nil {
+		return nil, err
+	}
+	comment := z.Comment
+	if z.writeUTF8 && !z.utf8Strings {
+		extra, name, comment = utf8Extra(extra, name, comment)
+	}
+
+	var subfields [][2]byte
+	if !z.dictdCompatible {
+		subfields = z.Subfields
+	}
+
+	return &format.Header{
+		ModTime:            modTime,
+		Subfields:          subfields,
+		XFL:                z.XFL,
+		OS:                 z.OS,
+		Extra:              extra,
+		Name:               name,
+		Comment:            comment,
+		ChunkSize:          z.chunkSize,
+		Sizes:              z.sizes,
+		ReservedChunks:     z.reservedChunks,
+		SharedWindow:       z.sharedWindow,
+		ChunkCRCs:          z.chunkCRCs,
+		ChunkCRCCastagnoli: z.chunkCRCCastagnoli,
+		UTF8Strings:        z.utf8Strings,
+	}, nil
 }
 
 func (z *Writer) flushCompressor() error {
@@ -366,39 +821,56 @@ func (z *Writer) flushCompressor() error {
 			return fmt.Errorf("%w: compressing: %w", errDictzip, err)
 		}
 
+		// Replace the chunk with a stored block if it is smaller.
+		if z.storeIncompressible {
+			if stored := storedBlock(z.chunkData); len(stored) < z.chunkBuf.Len() {
+				z.chunkBuf.Reset()
+				z.chunkBuf.Write(stored)
+			}
+			z.chunkData = z.chunkData[:0]
+		}
+
+		// NOTE: All previous chunks are full so the length of the current
+		// chunk is the remainder of the input.
+		chunkLen := z.isize - int64(len(z.sizes))*int64(z.chunkSize)
+		if err := z.checkChunk(int(chunkLen), z.chunkBuf.Bytes()); err != nil {
+			return err
+		}
+		if err := z.teeChunk(z.chunkBuf.Bytes()); err != nil {
+			return err
+		}
+
 		// Append the compressed chunk's length to the sizes.
 		z.sizes = append(z.sizes, z.chunkBuf.Len())
+		if z.chunkDigest != nil {
+			z.chunkCRCs = append(z.chunkCRCs, z.chunkDigest.Sum32())
+			z.chunkDigest.Reset()
+		}
+		if z.dedupHash != nil {
+			var sum [sha256.Size]byte
+			z.dedupHash.Sum(sum[:0])
+			z.dedupChunk(sum, int(chunkLen))
+			z.dedupHash.Reset()
+		}
 
 		// Copy chunkBuf to tmp.
 		if _, err := io.Copy(z.tmp, z.chunkBuf); err != nil {
 			return fmt.Errorf("%w: compressing: %w", errDictzip, err)
 		}
 
-		// Reset the chunk buffer and flate writer.
+		// Reset the chunk buffer and flate writer. The flate writer is not
+		// reset when sharing the window so that the next chunk may refer to
+		// data in previous chunks.
 		z.chunkBuf.Reset()
-		z.compressor.Reset(z.chunkBuf)
+		if !z.sharedWindow {
+			z.compressor.Reset(z.chunkBuf)
+		}
 		z.hasData = false
-	}
 
-	return nil
-}
-
-// writeString writes a string header value to z.w. The string
-// is encoded in ISO 8859-1, Latin-1 and terminated with a zero byte.
-func (z *Writer) writeString(s string) error {
-	// Strings are ISO 8859-1, Latin-1 (RFC 1952, section 2.3.1).
-	b := make([]byte, 0, len(s))
-	for _, r := range s {
-		if r == 0 || r > 0xff {
-			return fmt.Errorf("%w: non-Latin-1 header string", ErrHeader)
+		if chunkLen == int64(z.chunkSize) {
+			return z.checkpointChunk()
 		}
-		b = append(b, byte(r))
-	}
-	// strings are terminated by a zero byte.
-	b = append(b, byte(0))
-	_, err := z.w.Write(b)
-	if err != nil {
-		return fmt.Errorf("%w: writing string header: %w", errDictzip, err)
 	}
+
 	return nil
 }
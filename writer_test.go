@@ -16,8 +16,13 @@ package dictzip
 
 import (
 	"bytes"
+	"compress/flate"
 	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
+	"math/rand"
 	"testing"
 	"time"
 
@@ -475,6 +480,33 @@ func TestWriter(t *testing.T) {
 				0x16, 0x00, 0x00, 0x00, // ISIZE // 22 (len of data)
 			},
 		},
+		{
+			name: "chunk size too large",
+
+			os:        OSUnknown,
+			chunkSize: MaxChunkSize + 1,
+			level:     DefaultCompression,
+
+			newErr: ErrChunkSize,
+		},
+		{
+			name: "zero chunk size",
+
+			os:        OSUnknown,
+			chunkSize: 0,
+			level:     DefaultCompression,
+
+			newErr: ErrChunkSize,
+		},
+		{
+			name: "negative chunk size",
+
+			os:        OSUnknown,
+			chunkSize: -1,
+			level:     DefaultCompression,
+
+			newErr: ErrChunkSize,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -533,3 +565,659 @@ func TestWriter(t *testing.T) {
 		})
 	}
 }
+
+func TestWriter_sharedWindow(t *testing.T) {
+	t.Parallel()
+
+	// Repetitive data with repeats further apart than the chunk size.
+	var data []byte
+	for i := 0; len(data) < 8192; i++ {
+		data = append(data, []byte(fmt.Sprintf("entry %d: the quick brown fox jumps over the lazy dog\n", i%50))...)
+	}
+
+	compress := func(opts ...WriterOption) *bytes.Buffer {
+		var buf bytes.Buffer
+		z, err := NewWriterLevel(&buf, DefaultCompression, 512, opts...)
+		if err != nil {
+			t.Fatalf("NewWriterLevel: %v", err)
+		}
+		if _, err := z.Write(data); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := z.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		return &buf
+	}
+
+	independent := compress()
+	shared := compress(WithSharedWindow())
+
+	if shared.Len() >= independent.Len() {
+		t.Errorf("shared window size %d, want < %d", shared.Len(), independent.Len())
+	}
+
+	r, err := NewReader(bytes.NewReader(shared.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	if !r.SharedWindow() {
+		t.Errorf("SharedWindow: want true")
+	}
+
+	// Random access reads, including backwards and across chunk boundaries.
+	for _, off := range []int64{4000, 0, 7000, 510, 512, 3000, 3001} {
+		buf := make([]byte, 1000)
+		n, err := r.ReadAt(buf, off)
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatalf("ReadAt(%d): %v", off, err)
+		}
+		if diff := cmp.Diff(data[off:off+int64(n)], buf[:n]); diff != "" {
+			t.Errorf("ReadAt(%d) (-want, +got):\n%s", off, diff)
+		}
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if diff := cmp.Diff(data, b); diff != "" {
+		t.Errorf("ReadAll (-want, +got):\n%s", diff)
+	}
+
+	verifyGzip(t, shared, [][]byte{data})
+}
+
+func TestWriter_XFL(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	z, err := NewWriterLevel(&buf, BestSpeed, DefaultChunkSize)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+
+	if diff := cmp.Diff(XFLFastest, z.XFL); diff != "" {
+		t.Errorf("XFL (-want, +got):\n%s", diff)
+	}
+
+	// Preserve the XFL from a different archive.
+	z.XFL = XFLSlowest
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	if diff := cmp.Diff(XFLSlowest, r.XFL); diff != "" {
+		t.Errorf("XFL (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWriter_CompressFrom(t *testing.T) {
+	t.Parallel()
+
+	var data []byte
+	for i := 0; len(data) < 10000; i++ {
+		data = append(data, []byte(fmt.Sprintf("line %d\n", i))...)
+	}
+
+	testCases := []struct {
+		name    string
+		prefix  []byte
+		workers int
+	}{
+		{
+			name:    "single worker",
+			workers: 1,
+		},
+		{
+			name:    "multiple workers",
+			workers: 4,
+		},
+		{
+			name:    "default workers",
+			workers: 0,
+		},
+		{
+			name:    "partial chunk written first",
+			prefix:  []byte("prefix"),
+			workers: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Compress sequentially using Write.
+			var want bytes.Buffer
+			zw, err := NewWriterLevel(&want, DefaultCompression, 512)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := zw.Write(tc.prefix); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if _, err := zw.Write(data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			// Compress in parallel using CompressFrom.
+			var got bytes.Buffer
+			zc, err := NewWriterLevel(&got, DefaultCompression, 512)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if _, err := zc.Write(tc.prefix); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			n, err := zc.CompressFrom(bytes.NewReader(data), int64(len(data)), tc.workers)
+			if err != nil {
+				t.Fatalf("CompressFrom: %v", err)
+			}
+			if diff := cmp.Diff(int64(len(data)), n); diff != "" {
+				t.Errorf("CompressFrom (-want, +got):\n%s", diff)
+			}
+			if err := zc.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			if diff := cmp.Diff(want.Bytes(), got.Bytes()); diff != "" {
+				t.Errorf("CompressFrom (-want, +got):\n%s", diff)
+			}
+
+			verifyGzip(t, &got, [][]byte{tc.prefix, data})
+		})
+	}
+}
+
+func TestWriter_CompressFrom_short(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	z, err := NewWriterLevel(&buf, DefaultCompression, 16)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	defer z.Close()
+
+	// The reader has less data than requested.
+	_, err = z.CompressFrom(bytes.NewReader(make([]byte, 40)), 64, 2)
+	if diff := cmp.Diff(io.ErrUnexpectedEOF, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("CompressFrom (-want, +got):\n%s", diff)
+	}
+}
+
+// TestWriter_large tests chunk accounting when the total uncompressed size
+// is larger than 4GB. Rather than writing 4GB of data the Writer's total size
+// is initialized near the 4GB boundary and generated data is streamed across
+// it.
+func TestWriter_large(t *testing.T) {
+	t.Parallel()
+
+	// NOTE: 1<<32 is not a multiple of the chunk size.
+	const chunkSize = 1000
+	const start = int64(1<<32) - 1500
+	const size = 5000
+
+	var buf bytes.Buffer
+	z, err := NewWriterLevel(&buf, DefaultCompression, chunkSize)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	z.isize = start
+
+	// Stream generated data using writes of varying sizes.
+	rnd := rand.New(rand.NewSource(1))
+	var data []byte
+	for len(data) < size {
+		p := make([]byte, rnd.Intn(777)+1)
+		rnd.Read(p)
+		if _, err := z.Write(p); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		data = append(data, p...)
+
+		// A chunk is flushed every time a chunk boundary is crossed.
+		want := int(z.isize/chunkSize - start/chunkSize)
+		if diff := cmp.Diff(want, len(z.sizes)); diff != "" {
+			t.Fatalf("len(sizes) (-want, +got):\n%s", diff)
+		}
+	}
+	isize := z.isize
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// The first chunk completes the partially written chunk.
+	want := [][]byte{data[:chunkSize-start%chunkSize]}
+	for rest := data[len(want[0]):]; len(rest) > 0; {
+		n := chunkSize
+		if n > len(rest) {
+			n = len(rest)
+		}
+		want = append(want, rest[:n])
+		rest = rest[n:]
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	var got [][]byte
+	for i := range r.Sizes() {
+		fr := flate.NewReader(bytes.NewReader(buf.Bytes()[r.offsets.at(i):r.offsets.at(i+1)]))
+		chunk, err := io.ReadAll(fr)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("ReadAll: %v", err)
+		}
+		got = append(got, chunk)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("chunks (-want, +got):\n%s", diff)
+	}
+
+	// ISIZE is the total size modulo 2^32.
+	trailer := buf.Bytes()[buf.Len()-4:]
+	if diff := cmp.Diff(uint32(isize), binary.LittleEndian.Uint32(trailer)); diff != "" {
+		t.Errorf("ISIZE (-want, +got):\n%s", diff)
+	}
+}
+
+// largeStreamByte returns the byte at offset off of the data written by
+// TestWriter_largeStream. It depends on the bits above 2^32 so that chunks
+// past 4GB differ from those at the start of the data.
+func largeStreamByte(off int64) byte {
+	return byte(off) ^ byte(off>>16) ^ byte(off>>32)
+}
+
+func TestWriter_largeStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test writing more than 4GB in short mode")
+	}
+	t.Parallel()
+
+	// NOTE: A single archive holds at most math.MaxUint16 chunks of
+	// MaxChunkSize bytes, which is less than 2^32 bytes, so the data
+	// written here can't be written as an archive. The chunks are checked
+	// as they are compressed and Close must reject the archive.
+	const chunkSize = MaxChunkSize
+	const size = int64(1<<32) + 3*chunkSize + 123
+	lastChunk := int(size / chunkSize)
+
+	// Chunks crossing or following the 2^32 byte offset are inflated and
+	// compared to the data written.
+	checkFrom := int((1 << 32) / chunkSize)
+
+	var chunks int
+	onChunk := func(index, n int, compressed []byte) error {
+		if index != chunks {
+			return fmt.Errorf("want chunk %d", chunks)
+		}
+		chunks++
+
+		wantN := chunkSize
+		if index == lastChunk {
+			wantN = int(size % chunkSize)
+		}
+		if n != wantN {
+			return fmt.Errorf("got length %d, want %d", n, wantN)
+		}
+		if index < checkFrom {
+			return nil
+		}
+
+		data := make([]byte, n)
+		if _, err := io.ReadFull(flate.NewReader(bytes.NewReader(compressed)), data); err != nil {
+			return fmt.Errorf("inflating: %w", err)
+		}
+		off := int64(index) * chunkSize
+		for i, b := range data {
+			if want := largeStreamByte(off + int64(i)); b != want {
+				return fmt.Errorf("byte %d: got %#x, want %#x", i, b, want)
+			}
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	z, err := NewWriterLevel(&buf, BestSpeed, chunkSize, WithOnChunk(onChunk))
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+
+	// Stream generated data using writes of varying sizes.
+	rnd := rand.New(rand.NewSource(1))
+	p := make([]byte, 1<<20)
+	for off := int64(0); off < size; {
+		n := int64(rnd.Intn(len(p)) + 1)
+		if n > size-off {
+			n = size - off
+		}
+		for i := range p[:n] {
+			p[i] = largeStreamByte(off + int64(i))
+		}
+		if _, err := z.Write(p[:n]); err != nil {
+			t.Fatalf("Write at %d: %v", off, err)
+		}
+		off += n
+	}
+
+	err = z.Close()
+	if diff := cmp.Diff(ErrHeader, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("Close (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(lastChunk+1, chunks); diff != "" {
+		t.Errorf("chunks (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(0, buf.Len()); diff != "" {
+		t.Errorf("archive length (-want, +got):\n%s", diff)
+	}
+}
+
+func TestEstimateRatio(t *testing.T) {
+	t.Parallel()
+
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	testCases := map[string]struct {
+		sample []byte
+		level  int
+
+		// less indicates whether the ratio should be less than 1.
+		less bool
+		err  error
+	}{
+		"empty": {
+			sample: nil,
+			level:  DefaultCompression,
+		},
+		"compressible": {
+			sample: bytes.Repeat([]byte("abcd"), 1024),
+			level:  DefaultCompression,
+			less:   true,
+		},
+		"random": {
+			sample: random,
+			level:  BestCompression,
+		},
+		"invalid level": {
+			sample: random,
+			level:  42,
+			err:    errDictzip,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ratio, err := EstimateRatio(tc.sample, tc.level)
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("EstimateRatio (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.less, ratio < 1); diff != "" {
+				t.Errorf("ratio %v < 1 (-want, +got):\n%s", ratio, diff)
+			}
+		})
+	}
+}
+
+func TestWriter_Ratios(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	z, err := NewWriterLevel(&buf, DefaultCompression, 6)
+	if err != nil {
+		t.Fatalf("NewWriterLevel: %v", err)
+	}
+	if _, err := z.Write([]byte("chunk1chunk2chu")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// Only full chunks have been written.
+	ratios := z.Ratios()
+	if diff := cmp.Diff(2, len(ratios)); diff != "" {
+		t.Errorf("len(Ratios) (-want, +got):\n%s", diff)
+	}
+
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	sizes := z.Sizes()
+	want := []float64{
+		float64(sizes[0]) / 6,
+		float64(sizes[1]) / 6,
+		float64(sizes[2]) / 3,
+	}
+	if diff := cmp.Diff(want, z.Ratios()); diff != "" {
+		t.Errorf("Ratios (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWriter_storeIncompressible(t *testing.T) {
+	t.Parallel()
+
+	const chunkSize = 1000
+
+	data := make([]byte, 2500)
+	rand.New(rand.NewSource(1)).Read(data)
+	// The last chunk is compressible.
+	copy(data[2000:], bytes.Repeat([]byte("a"), 500))
+
+	testCases := map[string]struct {
+		compressFrom bool
+	}{
+		"write": {
+			compressFrom: false,
+		},
+		"compress from": {
+			compressFrom: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			z, err := NewWriterLevel(&buf, BestCompression, chunkSize, WithStoreIncompressible())
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			if tc.compressFrom {
+				_, err = z.CompressFrom(bytes.NewReader(data), int64(len(data)), 2)
+			} else {
+				_, err = z.Write(data)
+			}
+			if err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if err := z.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			// Random chunks are stored with 10 bytes of block overhead.
+			sizes := z.Sizes()
+			if diff := cmp.Diff([]int{chunkSize + 10, chunkSize + 10}, sizes[:2]); diff != "" {
+				t.Errorf("Sizes (-want, +got):\n%s", diff)
+			}
+			if sizes[2] >= 500 {
+				t.Errorf("compressible chunk size: %d", sizes[2])
+			}
+
+			// Stored chunks can be read randomly.
+			r, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer r.Close()
+			got := make([]byte, 100)
+			if _, err := r.ReadAt(got, 1950); err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if diff := cmp.Diff(data[1950:2050], got); diff != "" {
+				t.Errorf("ReadAt (-want, +got):\n%s", diff)
+			}
+
+			verifyGzip(t, &buf, [][]byte{data})
+		})
+	}
+}
+
+func TestWriter_compressedChunkSize(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 3*MaxChunkSize)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	testCases := map[string]struct {
+		chunkSize    int
+		opts         []WriterOption
+		compressFrom bool
+		err          error
+	}{
+		"write": {
+			chunkSize: MaxChunkSize,
+			err:       ErrCompressedChunkSize,
+		},
+		"compress from": {
+			chunkSize:    MaxChunkSize,
+			compressFrom: true,
+			err:          ErrCompressedChunkSize,
+		},
+		"stored too large": {
+			chunkSize: MaxChunkSize,
+			opts:      []WriterOption{WithStoreIncompressible()},
+			err:       ErrCompressedChunkSize,
+		},
+		"stored": {
+			chunkSize: MaxStoredChunkSize,
+			opts:      []WriterOption{WithStoreIncompressible()},
+		},
+		"stored compress from": {
+			chunkSize:    MaxStoredChunkSize,
+			opts:         []WriterOption{WithStoreIncompressible()},
+			compressFrom: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			z, err := NewWriterLevel(&buf, BestCompression, tc.chunkSize, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWriterLevel: %v", err)
+			}
+			var n int64
+			if tc.compressFrom {
+				n, err = z.CompressFrom(bytes.NewReader(data), int64(len(data)), 2)
+			} else {
+				var nw int
+				nw, err = z.Write(data)
+				n = int64(nw)
+			}
+			if diff := cmp.Diff(tc.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("write (-want, +got):\n%s", diff)
+			}
+			if err != nil {
+				// Writing fails at the first chunk.
+				if n > int64(tc.chunkSize) {
+					t.Errorf("write: %d bytes written, want at most %d", n, tc.chunkSize)
+				}
+				if diff := cmp.Diff(tc.err, z.Close(), cmpopts.EquateErrors()); diff != "" {
+					t.Errorf("Close (-want, +got):\n%s", diff)
+				}
+				return
+			}
+			if err := z.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			verifyGzip(t, &buf, [][]byte{data})
+		})
+	}
+}
+
+func TestWriter_Name(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		name    string
+		opts    []WriterOption
+		want    string
+		wantRaw string
+	}{
+		"normalized": {
+			name:    "C:\\Users\\me\\hello.txt",
+			want:    "hello.txt",
+			wantRaw: "hello.txt",
+		},
+		"raw": {
+			name:    "C:\\Users\\me\\hello.txt",
+			opts:    []WriterOption{WithRawName()},
+			want:    "hello.txt",
+			wantRaw: "C:\\Users\\me\\hello.txt",
+		},
+		"directory only": {
+			name: "/tmp/",
+			want: "tmp",
+			// NOTE: The raw name is the normalized name written.
+			wantRaw: "tmp",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			z, err := NewWriter(&buf, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			z.Name = tc.name
+			if err := z.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			r, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer r.Close()
+
+			if diff := cmp.Diff(tc.want, r.Name); diff != "" {
+				t.Errorf("Name (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantRaw, r.RawName()); diff != "" {
+				t.Errorf("RawName (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}